@@ -35,6 +35,14 @@
 
 package shellexpand
 
+import (
+	"context"
+	"io"
+	"strings"
+
+	glob "github.com/ganbarodigital/go_glob"
+)
+
 // AssignVar sets a key to a given value. If it cannot do so, it reports
 // an error to explain why
 type AssignVar func(string, string) error
@@ -50,6 +58,18 @@ type LookupVar func(string) (string, bool)
 // The search term is a prefix
 type MatchVarNames func(string) []string
 
+// AssignVarContext is the context-aware version of AssignVar. It is passed
+// the context.Context that was given to ExpandContext()
+type AssignVarContext func(context.Context, string, string) error
+
+// LookupVarContext is the context-aware version of LookupVar. It is passed
+// the context.Context that was given to ExpandContext()
+type LookupVarContext func(context.Context, string) (string, bool)
+
+// MatchVarNamesContext is the context-aware version of MatchVarNames. It is
+// passed the context.Context that was given to ExpandContext()
+type MatchVarNamesContext func(context.Context, string) []string
+
 // ExpansionCallbacks tell shellexpand how to work with your variable backing store
 type ExpansionCallbacks struct {
 	// AssignToVar is called whenever we need to set a variable in
@@ -67,4 +87,382 @@ type ExpansionCallbacks struct {
 	// MatchVarNames is called whenever we need to find a list of
 	// variable names from your backing store
 	MatchVarNames MatchVarNames
+
+	// IsExported is called whenever we need to know if a variable
+	// has been exported, e.g. for ${var@a}
+	//
+	// if it is nil, no variables are exported
+	IsExported IsExported
+
+	// ExportVar is called whenever we need to mark a variable as
+	// exported
+	ExportVar ExportVar
+
+	// Translate is called whenever we need to translate a $"..." string
+	//
+	// if it is nil, the string is not translated
+	Translate Translate
+
+	// RunCommand is called whenever we need to run the command in a
+	// $(...) command substitution
+	//
+	// if it is nil, $(...) is left as it is
+	RunCommand RunCommand
+
+	// AssignToVarContext is used instead of AssignToVar, if it is set
+	AssignToVarContext AssignVarContext
+
+	// LookupVarContext is used instead of LookupVar, if it is set
+	LookupVarContext LookupVarContext
+
+	// LookupHomeDirContext is used instead of LookupHomeDir, if it is set
+	LookupHomeDirContext LookupVarContext
+
+	// MatchVarNamesContext is used instead of MatchVarNames, if it is set
+	MatchVarNamesContext MatchVarNamesContext
+
+	// ctx is the context that the current expansion is running under
+	//
+	// it is set by ExpandContext()
+	ctx context.Context
+
+	// cache holds work that can be shared between expansions
+	//
+	// it is set by ExpandSlice() and ExpandMap(), and by Expander if
+	// the WithMemoizedLookups() option is set
+	cache *expansionCache
+
+	// opts holds the options that change how we expand things
+	//
+	// it is set by Expander
+	opts *options
+
+	// globs holds the glob patterns that we have already compiled
+	//
+	// it is set by Expander
+	globs *globCache
+
+	// budget limits how much work we do
+	//
+	// it is set by Expander if the WithBudget() option is set
+	budget *expansionBudget
+
+	// stats counts what we do
+	//
+	// it is set by Expander if the WithStats() option is set
+	stats *expansionStats
+
+	// secrets holds the values of the secret variables that we have
+	// looked up
+	//
+	// it is set by Expander if the WithSecrets() option is set
+	secrets *secretValues
+
+	// diagnostics collects the problems that we have recovered from
+	//
+	// it is set by ExpandBestEffort()
+	diagnostics *[]ExpansionError
+
+	// sourceMap records where each part of the output came from
+	//
+	// it is set by ExpandWithSourceMap()
+	sourceMap *sourceMapBuilder
+}
+
+func (cb ExpansionCallbacks) context() context.Context {
+	if cb.ctx == nil {
+		return context.Background()
+	}
+
+	return cb.ctx
+}
+
+func (cb ExpansionCallbacks) strict() bool {
+	return cb.opts != nil && cb.opts.strict
+}
+
+// checksBraces tells us if unmatched braces in the input are an error
+func (cb ExpansionCallbacks) checksBraces() bool {
+	return cb.strict() && cb.dialect().braceExpansion && cb.varSyntax() != VarSyntaxPercent
+}
+
+func (cb ExpansionCallbacks) dialect() dialectFeatures {
+	if cb.opts == nil {
+		return dialects[DialectBash]
+	}
+
+	retval := dialects[cb.opts.dialect]
+	if cb.opts.shellOpts.NoBraceExpand {
+		retval.braceExpansion = false
+	}
+
+	return retval
+}
+
+func (cb ExpansionCallbacks) windows() bool {
+	return cb.opts != nil && cb.opts.windows
+}
+
+func (cb ExpansionCallbacks) keepUnset() bool {
+	return cb.opts != nil && cb.opts.keepUnset
+}
+
+func (cb ExpansionCallbacks) escapeMode() EscapeMode {
+	if cb.opts == nil {
+		return EscapeAll
+	}
+
+	return cb.opts.escapeMode
+}
+
+func (cb ExpansionCallbacks) varSyntax() VarSyntax {
+	if cb.opts == nil {
+		return VarSyntaxShell
+	}
+
+	return cb.opts.varSyntax
+}
+
+func (cb ExpansionCallbacks) legacyArithmetic() bool {
+	return cb.opts != nil && cb.opts.legacyArithmetic && cb.opts.dialect == DialectBash
+}
+
+func (cb ExpansionCallbacks) floatArithmetic() bool {
+	return cb.opts != nil && cb.opts.floatArithmetic
+}
+
+func (cb ExpansionCallbacks) maxPasses() int {
+	if cb.opts == nil || cb.opts.maxPasses < 1 {
+		return DefaultMaxPasses
+	}
+	return cb.opts.maxPasses
+}
+
+func (cb ExpansionCallbacks) invalidInput() InvalidInputMode {
+	if cb.opts == nil {
+		return InvalidInputPassThrough
+	}
+	return cb.opts.invalidInput
+}
+
+func (cb ExpansionCallbacks) byteOffsets() bool {
+	return cb.opts != nil && cb.opts.byteOffsets
+}
+
+func (cb ExpansionCallbacks) tracing() bool {
+	return cb.opts != nil && cb.opts.trace != nil
+}
+
+func (cb ExpansionCallbacks) trace(event TraceEvent) {
+	if cb.tracing() {
+		cb.opts.trace(cb.maskEvent(event))
+	}
+}
+
+// writeError writes the given error's message to the WithErrorWriter()
+// writer, if there is one
+func (cb ExpansionCallbacks) writeError(err error) {
+	if cb.opts == nil || cb.opts.errorWriter == nil {
+		return
+	}
+
+	// just like a UNIX shell, there is nothing useful that we can do
+	// if this fails
+	io.WriteString(cb.opts.errorWriter, cb.secrets.mask(err.Error())+"\n")
+}
+
+// parseParameter parses the given parameter, using the cache if we have one
+func (cb ExpansionCallbacks) parseParameter(input string) (paramDesc, bool) {
+	if cb.dialect().zshFlags && strings.HasPrefix(input, "${(") {
+		return parseZshFlaggedParameter(input)
+	}
+
+	return cb.cache.parseParameter(input)
+}
+
+// compileGlob returns a glob for the given pattern, using the Expander's
+// cache if we have one
+func (cb ExpansionCallbacks) compileGlob(pattern string, matchType int) (*glob.Glob, error) {
+	return cb.globs.get(pattern, matchType)
+}
+
+func (cb ExpansionCallbacks) assignToVar(key, value string) error {
+	// we are not allowed to touch any variables
+	if cb.sandboxed() {
+		return ErrSandboxed{"assignment"}
+	}
+
+	// we are not allowed to touch this variable
+	if !cb.nameAllowed(key) {
+		return cb.checkName(key)
+	}
+
+	// we are not allowed to change this variable
+	if cb.isReadOnly(key) {
+		return ErrReadOnlyVar{key}
+	}
+
+	// we have no way to change any variables
+	if cb.AssignToVar == nil && cb.AssignToVarContext == nil {
+		return ErrReadOnlyVar{key}
+	}
+
+	// whatever we remember about this variable is about to be out of date
+	cb.cache.forgetVar(key)
+
+	err := cb.budget.spendCallback()
+	if err != nil {
+		return err
+	}
+	if cb.AssignToVarContext != nil {
+		err = cb.AssignToVarContext(cb.context(), key, value)
+	} else {
+		err = cb.AssignToVar(key, value)
+	}
+	if err != nil {
+		return err
+	}
+	cb.stats.inc(statAssignments)
+
+	// with `set -a`, everything that we assign to is exported
+	if cb.shellOpts().AllExport && cb.ExportVar != nil {
+		err = cb.budget.spendCallback()
+		if err != nil {
+			return err
+		}
+		return cb.ExportVar(key)
+	}
+
+	return nil
+}
+
+func (cb ExpansionCallbacks) lookupVar(key string) (string, bool) {
+	// we are not allowed to see this variable
+	if !cb.nameAllowed(key) {
+		return "", false
+	}
+
+	// the caller finds out that we ran out of budget via
+	// cb.budget.exceeded()
+	if cb.budget.spend(BudgetLookups) != nil {
+		return "", false
+	}
+
+	retval, ok, found := cb.cache.lookupVar(key)
+	if found {
+		cb.stats.inc(statLookupCacheHits)
+		cb.rememberSecret(key, retval)
+		return retval, ok
+	}
+	if cb.cache != nil {
+		cb.stats.inc(statLookupCacheMisses)
+	}
+
+	retval, ok = cb.callLookupVar(key)
+
+	// on Windows, variable names are not case-sensitive
+	if !ok && cb.windows() {
+		retval, ok = cb.lookupVarIgnoringCase(key)
+	}
+
+	// a shell always knows how many positional parameters there are;
+	// if the caller has not told us, there aren't any
+	if !ok && key == "$#" {
+		retval, ok = "0", true
+	}
+
+	cb.cache.rememberVar(key, retval, ok)
+	cb.rememberSecret(key, retval)
+	return retval, ok
+}
+
+// callLookupVar calls whichever LookupVar callback we have
+func (cb ExpansionCallbacks) callLookupVar(key string) (string, bool) {
+	if cb.budget.spendCallback() != nil {
+		return "", false
+	}
+	if cb.LookupVarContext != nil {
+		return cb.LookupVarContext(cb.context(), key)
+	}
+
+	return cb.LookupVar(key)
+}
+
+// lookupVarIgnoringCase finds a variable whose name is the same as
+// `key`, apart from upper / lower case
+//
+// it needs a MatchVarNames callback to find out which variables exist
+func (cb ExpansionCallbacks) lookupVarIgnoringCase(key string) (string, bool) {
+	for _, name := range cb.callMatchVarNames("") {
+		if name != key && strings.EqualFold(name, key) {
+			return cb.callLookupVar(name)
+		}
+	}
+
+	return "", false
+}
+
+func (cb ExpansionCallbacks) lookupHomeDir(key string) (string, bool) {
+	// looking up a user means reading the password database
+	if cb.sandboxed() {
+		return "", false
+	}
+
+	if cb.budget.spendCallback() != nil {
+		return "", false
+	}
+	if cb.LookupHomeDirContext != nil {
+		return cb.LookupHomeDirContext(cb.context(), key)
+	}
+	if cb.LookupHomeDir == nil {
+		return "", false
+	}
+
+	return cb.LookupHomeDir(key)
+}
+
+func (cb ExpansionCallbacks) matchVarNames(prefix string) []string {
+	if cb.budget.spend(BudgetLookups) != nil {
+		return nil
+	}
+
+	// on Windows, variable names are not case-sensitive
+	var names []string
+	if cb.windows() {
+		for _, name := range cb.callMatchVarNames("") {
+			if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				names = append(names, name)
+			}
+		}
+	} else {
+		names = cb.callMatchVarNames(prefix)
+	}
+
+	// we do not tell anyone about variables that they cannot see
+	if cb.opts == nil || cb.opts.nameFilter == nil {
+		return names
+	}
+	retval := names[:0:0]
+	for _, name := range names {
+		if cb.nameAllowed(name) {
+			retval = append(retval, name)
+		}
+	}
+
+	return retval
+}
+
+// callMatchVarNames calls whichever MatchVarNames callback we have
+func (cb ExpansionCallbacks) callMatchVarNames(prefix string) []string {
+	if cb.budget.spendCallback() != nil {
+		return nil
+	}
+	if cb.MatchVarNamesContext != nil {
+		return cb.MatchVarNamesContext(cb.context(), prefix)
+	}
+	if cb.MatchVarNames != nil {
+		return cb.MatchVarNames(prefix)
+	}
+
+	return nil
 }
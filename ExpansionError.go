@@ -0,0 +1,214 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ExpansionPhase identifies one of the steps of UNIX shell expansion
+type ExpansionPhase int
+
+// these are the phases of expansion, in the order that they happen
+const (
+	PhaseHistoryExpansion ExpansionPhase = iota + 1
+	PhaseBraceExpansion
+	PhaseTildeExpansion
+	PhaseParameterExpansion
+	PhaseCommandSubstitution
+	PhaseArithmeticExpansion
+	PhaseWordSplitting
+)
+
+func (p ExpansionPhase) String() string {
+	switch p {
+	case PhaseHistoryExpansion:
+		return "history expansion"
+	case PhaseBraceExpansion:
+		return "brace expansion"
+	case PhaseTildeExpansion:
+		return "tilde expansion"
+	case PhaseParameterExpansion:
+		return "parameter expansion"
+	case PhaseCommandSubstitution:
+		return "command substitution"
+	case PhaseArithmeticExpansion:
+		return "arithmetic expansion"
+	case PhaseWordSplitting:
+		return "word splitting"
+	default:
+		return "unknown phase"
+	}
+}
+
+// ExpansionError is returned when part of the input could not be
+// expanded. It tells you where in the input the problem is, so that
+// you can show it to your users.
+//
+// Its Error() method returns the message of the underlying error,
+// unchanged.
+type ExpansionError struct {
+	// Phase is the step of expansion that failed
+	Phase ExpansionPhase
+
+	// Offset is the byte offset into the input where the problem starts
+	Offset int
+
+	// Line is the line of the input where the problem starts. The
+	// first line is line 1.
+	Line int
+
+	// Column is the character (not byte) offset into Line where the
+	// problem starts. The first column is column 1.
+	Column int
+
+	// Substring is the part of the input that could not be expanded
+	Substring string
+
+	// Err is the underlying error
+	Err error
+}
+
+func (e ExpansionError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e ExpansionError) Unwrap() error {
+	return e.Err
+}
+
+// Caret returns the line of the input that the problem is on, followed
+// by a second line that points at the problem:
+//
+//	echo ${PARAM1#abc[}
+//	     ^~~~~~~~~~~~~~
+//
+// `input` must be the same string that you asked us to expand.
+func (e ExpansionError) Caret(input string) string {
+	if e.Offset < 0 || e.Offset > len(input) {
+		return ""
+	}
+
+	lineStart := strings.LastIndexByte(input[:e.Offset], '\n') + 1
+	lineEnd := strings.IndexByte(input[e.Offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(input)
+	} else {
+		lineEnd += e.Offset
+	}
+
+	var buf strings.Builder
+	buf.WriteString(input[lineStart:lineEnd])
+	buf.WriteByte('\n')
+
+	// keep any tabs, so that the caret lines up with the problem
+	for _, c := range input[lineStart:e.Offset] {
+		if c == '\t' {
+			buf.WriteRune(c)
+		} else {
+			buf.WriteByte(' ')
+		}
+	}
+	buf.WriteByte('^')
+
+	// underline the rest of the substring, as long as it is on this line
+	substringEnd := e.Offset + len(e.Substring)
+	if substringEnd > lineEnd {
+		substringEnd = lineEnd
+	}
+	if substringEnd > e.Offset {
+		buf.WriteString(strings.Repeat("~", utf8.RuneCountInString(input[e.Offset:substringEnd])-1))
+	}
+
+	return buf.String()
+}
+
+// newExpansionError creates an ExpansionError that points at
+// input[start:end]
+//
+// If err is already an ExpansionError (e.g. it happened inside a word
+// that we were expanding), the new error points at the outer part of
+// the input instead. Its position inside the word is no longer useful
+// to anyone.
+func newExpansionError(phase ExpansionPhase, input string, start, end int, err error) error {
+	inner, ok := err.(ExpansionError)
+	if ok {
+		err = inner.Err
+	}
+
+	return ExpansionError{
+		Phase:     phase,
+		Offset:    start,
+		Substring: input[start:end],
+		Err:       err,
+	}
+}
+
+// locateExpansionError works out where an ExpansionError is in the
+// original input that the caller gave us. Any other kind of error is
+// returned untouched.
+//
+// `phaseInput` is what the failing phase was working on, and `base` is
+// where `phaseInput` starts in `original`. Earlier phases may have
+// changed `phaseInput`; if they have, we look for the Substring instead.
+func locateExpansionError(err error, original, phaseInput string, base int) error {
+	e, ok := err.(ExpansionError)
+	if !ok {
+		return err
+	}
+
+	if strings.HasPrefix(original[base:], phaseInput) {
+		e.Offset += base
+	} else if i := strings.Index(original[base:], e.Substring); i >= 0 {
+		e.Offset = base + i
+	} else {
+		e.Offset = base
+	}
+
+	// an unterminated ${ reports its own position, which must match ours
+	_, ok = e.Err.(ErrMismatchedBrace)
+	if ok && strings.HasPrefix(e.Substring, "${") {
+		e.Err = NewErrMismatchedBrace(original, e.Offset+1)
+	}
+
+	e.Line = strings.Count(original[:e.Offset], "\n") + 1
+	lineStart := strings.LastIndexByte(original[:e.Offset], '\n') + 1
+	e.Column = utf8.RuneCountInString(original[lineStart:e.Offset]) + 1
+
+	return e
+}
@@ -0,0 +1,149 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpansionErrorReportsPositionOfBadParameter(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "abcdef", true
+		},
+	}
+	input := "first line\nsécond ${PARAM1#abc[} line"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Expand(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err, &expErr))
+	assert.Equal(t, PhaseParameterExpansion, expErr.Phase)
+	assert.Equal(t, 19, expErr.Offset)
+	assert.Equal(t, 2, expErr.Line)
+	assert.Equal(t, 8, expErr.Column)
+	assert.Equal(t, "${PARAM1#abc[}", expErr.Substring)
+	assert.Equal(t, "sécond ${PARAM1#abc[} line\n       ^~~~~~~~~~~~~~", expErr.Caret(input))
+
+	// the message has not changed
+	assert.Equal(t, expErr.Err.Error(), err.Error())
+}
+
+func TestExpansionErrorPointsAtOuterParameter(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			if key == "PARAM1" {
+				return "", false
+			}
+			return "abcdef", true
+		},
+		LookupHomeDir: func(user string) (string, bool) {
+			return "/home/" + user, true
+		},
+	}
+	input := "a{b,c} ~ ${PARAM1:-${PARAM2%%[}}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Expand(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err, &expErr))
+	assert.Equal(t, 9, expErr.Offset)
+	assert.Equal(t, 1, expErr.Line)
+	assert.Equal(t, 10, expErr.Column)
+	assert.Equal(t, "${PARAM1:-${PARAM2%%[}}", expErr.Substring)
+}
+
+func TestExpansionErrorFromExpandArgs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "abcdef", true
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err1 := ExpandArgs("echo 'hello' ${PARAM1#[}", cb)
+	_, err2 := ExpandArgs("echo 'hello", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err1, &expErr))
+	assert.Equal(t, PhaseParameterExpansion, expErr.Phase)
+	assert.Equal(t, 13, expErr.Offset)
+	assert.Equal(t, "${PARAM1#[}", expErr.Substring)
+
+	assert.True(t, errors.As(err2, &expErr))
+	assert.Equal(t, PhaseWordSplitting, expErr.Phase)
+	assert.Equal(t, 5, expErr.Offset)
+	assert.Equal(t, 6, expErr.Column)
+	assert.Equal(t, "'hello", expErr.Substring)
+
+	var quoteErr ErrUnterminatedQuote
+	assert.True(t, errors.As(err2, &quoteErr))
+}
@@ -0,0 +1,75 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "sort"
+
+// WithAllErrors makes the Expander's Expand() and ExpandContext()
+// report every problem in the input, instead of stopping at the first
+// one. Use it when you want your users to be able to fix all of the
+// mistakes in a template in one go.
+//
+// If only one part of the input cannot be expanded, you get back its
+// ExpansionError, just like you do without this option. If several
+// parts cannot be expanded, you get back an ErrExpansionErrors that
+// holds all of them.
+func WithAllErrors() Option {
+	return func(opts *options) {
+		opts.allErrors = true
+	}
+}
+
+// joinDiagnostics turns the results of ExpandBestEffort() into the
+// results of Expand()
+func joinDiagnostics(expanded string, diagnostics []ExpansionError, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+
+	switch len(diagnostics) {
+	case 0:
+		return expanded, nil
+	case 1:
+		return "", diagnostics[0]
+	}
+
+	// a strict-mode brace problem is found before anything else, no
+	// matter where it is in the input
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Offset < diagnostics[j].Offset
+	})
+	return "", ErrExpansionErrors{diagnostics}
+}
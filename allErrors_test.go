@@ -0,0 +1,150 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAllErrorsReportsEveryProblem(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"A": "abc", "B": "def"})
+	unit := NewExpander(cb, WithAllErrors())
+	input := "${A#[} $A\n${B%[}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var actualErr ErrExpansionErrors
+	assert.True(t, errors.As(err, &actualErr))
+	assert.Len(t, actualErr.Errors, 2)
+	assert.Equal(t, "${A#[}", actualErr.Errors[0].Substring)
+	assert.Equal(t, 1, actualErr.Errors[0].Line)
+	assert.Equal(t, "${B%[}", actualErr.Errors[1].Substring)
+	assert.Equal(t, 2, actualErr.Errors[1].Line)
+	assert.True(t, errors.Is(err, ErrBadPattern{}))
+	assert.Contains(t, err.Error(), "unable to expand 2 parts of the input: 1:1: ")
+}
+
+func TestWithAllErrorsReturnsASingleProblemAsItIs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"A": "abc"})
+	unit := NewExpander(cb, WithAllErrors())
+	input := "$A ${UNSET:?must be set}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	expErr, ok := err.(ExpansionError)
+	assert.True(t, ok)
+	assert.Equal(t, 3, expErr.Offset)
+	assert.Equal(t, "UNSET: must be set", err.Error())
+}
+
+func TestWithAllErrorsDoesNotChangeSuccessfulExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"A": "abc"})
+	unit := NewExpander(cb, WithAllErrors())
+	expectedResult := "abc/bin"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("$A/bin")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrExpansionErrorsMatchesEachProblem(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// we call Is() and As() directly, because Go 1.20 and later would
+	// find the problems through Unwrap() anyway
+	unit := ErrExpansionErrors{
+		Errors: []ExpansionError{
+			{Phase: PhaseParameterExpansion, Err: ErrBadPattern{}},
+			{Phase: PhaseParameterExpansion, Err: ErrUnboundVariable{"MISSING"}},
+		},
+	}
+	var unboundErr ErrUnboundVariable
+	var expErr ExpansionError
+
+	// ----------------------------------------------------------------
+	// perform the change and test the results
+
+	assert.True(t, unit.Is(ErrExpansionErrors{}))
+	assert.True(t, unit.Is(ErrBadPattern{}))
+	assert.True(t, unit.Is(ErrUnboundVariable{}))
+	assert.False(t, unit.Is(ErrVarRequired{}))
+
+	assert.True(t, unit.As(&unboundErr))
+	assert.Equal(t, "MISSING", unboundErr.Name)
+	assert.True(t, unit.As(&expErr))
+	assert.Equal(t, ErrBadPattern{}, expErr.Err)
+	assert.False(t, unit.As(new(ErrVarRequired)))
+}
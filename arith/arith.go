@@ -0,0 +1,748 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package arith evaluates UNIX shell arithmetic expressions, such as
+// the ones inside $(( ... )).
+//
+// It is the evaluator that shellexpand uses for arithmetic expansion.
+// Use it directly when you need to evaluate shell arithmetic yourself,
+// for example to emulate `if (( ... ))`.
+//
+// Just like bash, all arithmetic is done using 64-bit signed integers,
+// and overflow wraps around without an error. Numbers can be decimal
+// (`42`), octal (`052`), hexadecimal (`0x2a`), or written in any base
+// from 2 to 64 (`2#101010`). The supported operators are the same as
+// C's, and have the same precedence. From highest to lowest, they are:
+//
+//	( )               grouping
+//	x++ x--           post-increment and post-decrement
+//	++x --x           pre-increment and pre-decrement
+//	+ - ! ~           unary plus and minus, logical and bitwise NOT
+//	**                exponentiation
+//	* / %             multiplication, division and remainder
+//	+ -               addition and subtraction
+//	<< >>             left and right shift
+//	< <= > >=         comparison
+//	== !=             equality
+//	&                 bitwise AND
+//	^                 bitwise exclusive OR
+//	|                 bitwise OR
+//	&&                logical AND
+//	||                logical OR
+//	cond ? a : b      conditional
+//	= += -= *= /= %=  assignment
+//	<<= >>= &= ^= |=
+//	,                 comma; the value is the right-hand side
+//
+// A name in the expression is the value of that shell variable. The
+// value is itself evaluated as an arithmetic expression, and a variable
+// that is not set (or is empty) is 0.
+//
+// Assignments, and the increment and decrement operators, change the
+// shell variable's value, just like they do in the shell.
+package arith
+
+import (
+	"math"
+	"strings"
+)
+
+// Vars gives the evaluator access to the shell's variables
+type Vars struct {
+	// LookupVar returns the value of the given variable, and `true` if
+	// the variable is set. It may be nil, in which case every variable
+	// is 0.
+	LookupVar func(name string) (string, bool)
+
+	// AssignToVar sets the given variable to a new value. It may be nil,
+	// in which case assignments are evaluated, but nothing is changed.
+	AssignToVar func(name, value string) error
+
+	// UnsetVar is called when the expression reads a variable that is
+	// not set (e.g. `N` in `N + 1`). If it returns an error, the
+	// expression fails with that error. It may be nil, in which case
+	// every variable that is not set is 0.
+	UnsetVar func(name string) error
+}
+
+// maxRecursion is how deeply variables can refer to other variables,
+// just like in bash
+const maxRecursion = 1024
+
+// binaryOp describes one of the binary operators
+type binaryOp struct {
+	// higher numbers bind more tightly
+	precedence int
+
+	// true if a op b op c means a op (b op c)
+	rightAssoc bool
+}
+
+// binaryOps holds the precedence of each of the binary operators
+var binaryOps = map[string]binaryOp{
+	"||": {1, false},
+	"&&": {2, false},
+	"|":  {3, false},
+	"^":  {4, false},
+	"&":  {5, false},
+	"==": {6, false},
+	"!=": {6, false},
+	"<":  {7, false},
+	"<=": {7, false},
+	">":  {7, false},
+	">=": {7, false},
+	"<<": {8, false},
+	">>": {8, false},
+	"+":  {9, false},
+	"-":  {9, false},
+	"*":  {10, false},
+	"/":  {10, false},
+	"%":  {10, false},
+	"**": {11, true},
+}
+
+// assignOps maps each assignment operator onto the binary operator
+// that works out the new value
+var assignOps = map[string]string{
+	"=":   "",
+	"+=":  "+",
+	"-=":  "-",
+	"*=":  "*",
+	"/=":  "/",
+	"%=":  "%",
+	"<<=": "<<",
+	">>=": ">>",
+	"&=":  "&",
+	"^=":  "^",
+	"|=":  "|",
+}
+
+// Eval evaluates the given arithmetic expression, and returns its value
+//
+// An empty expression has the value 0, just like $(( )) does in bash.
+func Eval(expr string, vars Vars) (int64, error) {
+	retval, err := eval(expr, vars, 0, false)
+	return retval.Int(), err
+}
+
+// EvalFloat evaluates the given arithmetic expression, with support for
+// floating-point numbers, just like zsh does
+//
+// A number with a decimal point or an exponent (such as `1.5` or `1e3`)
+// is a float. Any operator with a float on either side gives you a
+// float, except for the bitwise operators, which always work on
+// integers. `%` gives you the floating-point remainder.
+//
+// When both sides are integers, you get integer arithmetic, so `7 / 2`
+// is 3, but `7 / 2.0` is 3.5.
+func EvalFloat(expr string, vars Vars) (Number, error) {
+	return eval(expr, vars, 0, true)
+}
+
+// Names returns the names of the variables that the given arithmetic
+// expression refers to, in the order that they appear. It does not
+// evaluate anything, so it does not look inside the variables' values.
+//
+// Floating-point numbers are accepted, so that you can use it on any
+// expression that Eval() or EvalFloat() understands.
+func Names(expr string) ([]string, error) {
+	tokens, err := tokenize(expr, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var retval []string
+	for _, tok := range tokens {
+		if tok.kind == tokenName {
+			retval = append(retval, tok.text)
+		}
+	}
+
+	return retval, nil
+}
+
+// evaluator holds the state of a single expression that we are part way
+// through evaluating
+type evaluator struct {
+	expr   string
+	tokens []token
+	pos    int
+	vars   Vars
+
+	// how deeply nested we are in the values of variables
+	depth int
+
+	// if this is more than zero, we are parsing a part of the expression
+	// that is not used (such as the right-hand side of `0 && x`), and
+	// it must not have any side effects or errors
+	noeval int
+
+	// if true, we support floating-point numbers
+	float bool
+}
+
+func eval(expr string, vars Vars, depth int, float bool) (Number, error) {
+	tokens, err := tokenize(expr, float)
+	if err != nil {
+		return Number{}, err
+	}
+
+	e := &evaluator{
+		expr:   expr,
+		tokens: tokens,
+		vars:   vars,
+		depth:  depth,
+		float:  float,
+	}
+
+	// special case: an empty expression
+	if e.peek().kind == tokenEOF {
+		return intNumber(0), nil
+	}
+
+	retval, err := e.parseExpr()
+	if err != nil {
+		return Number{}, err
+	}
+
+	// did we use up the whole expression?
+	if e.peek().kind != tokenEOF {
+		return Number{}, e.syntaxError("syntax error in expression", e.peek())
+	}
+
+	return retval, nil
+}
+
+// peek returns the next token, without using it up
+func (e *evaluator) peek() token {
+	return e.tokens[e.pos]
+}
+
+// next uses up the next token, and returns it
+func (e *evaluator) next() token {
+	retval := e.tokens[e.pos]
+	if retval.kind != tokenEOF {
+		e.pos++
+	}
+
+	return retval
+}
+
+// errorToken returns the text that bash would report for an error at
+// the given token
+func (e *evaluator) errorToken(tok token) string {
+	return strings.TrimSpace(e.expr[tok.start:])
+}
+
+func (e *evaluator) syntaxError(reason string, tok token) error {
+	return ErrSyntax{e.expr, reason, e.errorToken(tok)}
+}
+
+// peekOperator returns true if the next token is the given operator
+func (e *evaluator) peekOperator(op string) bool {
+	tok := e.peek()
+	return tok.kind == tokenOperator && tok.text == op
+}
+
+// reportToken returns the token that bash blames for an error at the
+// current position
+//
+// at the end of the expression, that is the last token that we used
+func (e *evaluator) reportToken() token {
+	tok := e.peek()
+	if tok.kind == tokenEOF && e.pos > 0 {
+		return e.tokens[e.pos-1]
+	}
+
+	return tok
+}
+
+// parseExpr evaluates a whole expression, including any commas
+func (e *evaluator) parseExpr() (Number, error) {
+	retval, err := e.parseAssign()
+	if err != nil {
+		return Number{}, err
+	}
+
+	// the value of a, b is b
+	for e.peekOperator(",") {
+		e.next()
+		retval, err = e.parseAssign()
+		if err != nil {
+			return Number{}, err
+		}
+	}
+
+	return retval, nil
+}
+
+// parseAssign evaluates an assignment, or an expression that does not
+// assign anything
+func (e *evaluator) parseAssign() (Number, error) {
+	// only a variable can be assigned to
+	name := e.peek()
+	if name.kind != tokenName || !isAssignOp(e.tokens[e.pos+1]) {
+		retval, err := e.parseConditional()
+		if err != nil {
+			return Number{}, err
+		}
+		if isAssignOp(e.peek()) {
+			return Number{}, e.syntaxError("attempted assignment to non-variable", e.peek())
+		}
+		return retval, nil
+	}
+	e.next()
+	tok := e.next()
+
+	// just like bash, we read the variable before we evaluate the
+	// right-hand side, which may change it
+	var lhs Number
+	var err error
+	binaryOp := assignOps[tok.text]
+	if binaryOp != "" {
+		lhs, err = e.varValue(name.text)
+		if err != nil {
+			return Number{}, err
+		}
+	}
+
+	// assignment is right-associative: x = y = 3 sets both x and y
+	retval, err := e.parseAssign()
+	if err != nil {
+		return Number{}, err
+	}
+	if binaryOp != "" {
+		retval, err = e.applyBinary(token{tokenOperator, binaryOp, tok.start}, lhs, retval)
+		if err != nil {
+			return Number{}, err
+		}
+	}
+
+	return retval, e.assign(name.text, retval)
+}
+
+// parseConditional evaluates cond ? a : b, or any expression that does
+// not contain one
+func (e *evaluator) parseConditional() (Number, error) {
+	cond, err := e.parseBinary(1)
+	if err != nil || !e.peekOperator("?") {
+		return cond, err
+	}
+	e.next()
+
+	// just like && and ||, only one of the two branches is evaluated
+	if e.peekOperator(":") || e.peek().kind == tokenEOF {
+		return Number{}, e.syntaxError("expression expected", e.reportToken())
+	}
+	if cond.isZero() {
+		e.noeval++
+	}
+	ifTrue, err := e.parseExpr()
+	if cond.isZero() {
+		e.noeval--
+	}
+	if err != nil {
+		return Number{}, err
+	}
+
+	if !e.peekOperator(":") {
+		return Number{}, e.syntaxError("`:' expected for conditional expression", e.reportToken())
+	}
+	e.next()
+
+	if e.peek().kind == tokenEOF {
+		return Number{}, e.syntaxError("expression expected", e.reportToken())
+	}
+	if !cond.isZero() {
+		e.noeval++
+	}
+	ifFalse, err := e.parseConditional()
+	if !cond.isZero() {
+		e.noeval--
+	}
+	if err != nil {
+		return Number{}, err
+	}
+
+	if !cond.isZero() {
+		return ifTrue, nil
+	}
+	return ifFalse, nil
+}
+
+// parseBinary evaluates a sequence of binary operators, whose
+// precedence is at least minPrecedence
+func (e *evaluator) parseBinary(minPrecedence int) (Number, error) {
+	lhs, err := e.parseUnary()
+	if err != nil {
+		return Number{}, err
+	}
+
+	for {
+		tok := e.peek()
+		op, ok := binaryOps[tok.text]
+		if tok.kind != tokenOperator || !ok || op.precedence < minPrecedence {
+			return lhs, nil
+		}
+		e.next()
+
+		nextPrecedence := op.precedence + 1
+		if op.rightAssoc {
+			nextPrecedence = op.precedence
+		}
+
+		// && and || do not evaluate their right-hand side if they
+		// already know the answer
+		skip := (tok.text == "&&" && lhs.isZero()) || (tok.text == "||" && !lhs.isZero())
+		if skip {
+			e.noeval++
+		}
+		rhs, err := e.parseBinary(nextPrecedence)
+		if skip {
+			e.noeval--
+		}
+		if err != nil {
+			return Number{}, err
+		}
+
+		lhs, err = e.applyBinary(tok, lhs, rhs)
+		if err != nil {
+			return Number{}, err
+		}
+	}
+}
+
+// applyBinary works out the result of a binary operator
+//
+// `tok` is the operator
+func (e *evaluator) applyBinary(tok token, lhs, rhs Number) (Number, error) {
+	// if either side is a float, so is the result
+	float := lhs.isFloat || rhs.isFloat
+
+	switch tok.text {
+	case "||":
+		return boolNumber(!lhs.isZero() || !rhs.isZero()), nil
+	case "&&":
+		return boolNumber(!lhs.isZero() && !rhs.isZero()), nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		return boolNumber(compare(tok.text, lhs, rhs)), nil
+	case "+":
+		if float {
+			return floatNumber(lhs.Float() + rhs.Float()), nil
+		}
+		return intNumber(lhs.i + rhs.i), nil
+	case "-":
+		if float {
+			return floatNumber(lhs.Float() - rhs.Float()), nil
+		}
+		return intNumber(lhs.i - rhs.i), nil
+	case "*":
+		if float {
+			return floatNumber(lhs.Float() * rhs.Float()), nil
+		}
+		return intNumber(lhs.i * rhs.i), nil
+	case "/", "%":
+		if rhs.isZero() {
+			if e.noeval > 0 {
+				return Number{}, nil
+			}
+			return Number{}, ErrDivisionByZero{e.expr, e.errorToken(e.tokens[e.pos-1])}
+		}
+		switch {
+		case float && tok.text == "/":
+			return floatNumber(lhs.Float() / rhs.Float()), nil
+		case float:
+			return floatNumber(math.Mod(lhs.Float(), rhs.Float())), nil
+		case tok.text == "/":
+			return intNumber(lhs.i / rhs.i), nil
+		}
+		return intNumber(lhs.i % rhs.i), nil
+	case "<<":
+		// just like bash on x86, only the bottom 6 bits of the shift
+		// count are used
+		return intNumber(lhs.Int() << (uint64(rhs.Int()) & 63)), nil
+	case ">>":
+		return intNumber(lhs.Int() >> (uint64(rhs.Int()) & 63)), nil
+	case "&":
+		return intNumber(lhs.Int() & rhs.Int()), nil
+	case "^":
+		return intNumber(lhs.Int() ^ rhs.Int()), nil
+	case "|":
+		return intNumber(lhs.Int() | rhs.Int()), nil
+	case "**":
+		// like zsh, a negative exponent gives you a fraction, if floats
+		// are allowed
+		if float || (e.float && rhs.i < 0) {
+			return floatNumber(math.Pow(lhs.Float(), rhs.Float())), nil
+		}
+		if rhs.i < 0 {
+			if e.noeval > 0 {
+				return Number{}, nil
+			}
+			return Number{}, ErrNegativeExponent{e.expr, e.errorToken(e.tokens[e.pos-1])}
+		}
+		return intNumber(power(lhs.i, rhs.i)), nil
+	}
+
+	// we should never get here
+	return Number{}, e.syntaxError("syntax error: invalid arithmetic operator", tok)
+}
+
+// parseUnary evaluates any unary operators, and the operand that they
+// apply to
+func (e *evaluator) parseUnary() (Number, error) {
+	tok := e.peek()
+	if tok.kind != tokenOperator {
+		return e.parsePrimary()
+	}
+
+	switch tok.text {
+	case "++", "--":
+		// the lexer makes sure that a variable name comes next
+		e.next()
+		name := e.next()
+		if name.kind != tokenName {
+			return Number{}, e.syntaxError("syntax error: operand expected", name)
+		}
+		value, err := e.varValue(name.text)
+		if err != nil {
+			return Number{}, err
+		}
+		value = increment(value, tok.text)
+		return value, e.assign(name.text, value)
+
+	case "+", "-", "!", "~":
+		e.next()
+		value, err := e.parseUnary()
+		if err != nil {
+			return Number{}, err
+		}
+		switch tok.text {
+		case "-":
+			if value.isFloat {
+				return floatNumber(-value.f), nil
+			}
+			return intNumber(-value.i), nil
+		case "!":
+			return boolNumber(value.isZero()), nil
+		case "~":
+			return intNumber(^value.Int()), nil
+		}
+		return value, nil
+	}
+
+	return e.parsePrimary()
+}
+
+// parsePrimary evaluates a number, a variable, or an expression inside
+// parentheses
+func (e *evaluator) parsePrimary() (Number, error) {
+	tok := e.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		return e.parseNumber(tok)
+
+	case tokenName:
+		value, err := e.varValue(tok.text)
+		if err != nil {
+			return Number{}, err
+		}
+
+		// post-increment and post-decrement return the old value
+		next := e.peek()
+		if next.kind == tokenOperator && (next.text == "++" || next.text == "--") {
+			e.next()
+			err = e.assign(tok.text, increment(value, next.text))
+		}
+		return value, err
+
+	case tokenOperator:
+		if tok.text != "(" {
+			break
+		}
+		value, err := e.parseExpr()
+		if err != nil {
+			return Number{}, err
+		}
+		closing := e.next()
+		if closing.kind != tokenOperator || closing.text != ")" {
+			return Number{}, e.syntaxError("missing `)'", closing)
+		}
+		return value, nil
+
+	case tokenEOF:
+		// bash reports the token before the missing operand
+		if e.pos > 0 {
+			tok = e.tokens[e.pos-1]
+		}
+	}
+
+	return Number{}, e.syntaxError("syntax error: operand expected", tok)
+}
+
+// parseNumber returns the value of a number in the expression
+func (e *evaluator) parseNumber(tok token) (Number, error) {
+	if e.float && isFloatLiteral(tok.text) {
+		return parseFloat(e.expr, tok.text)
+	}
+
+	retval, err := parseInteger(e.expr, tok.text)
+	return intNumber(retval), err
+}
+
+// varValue returns the value of the given variable
+//
+// just like bash, the variable's value is itself an arithmetic
+// expression
+func (e *evaluator) varValue(name string) (Number, error) {
+	if e.vars.LookupVar == nil {
+		return intNumber(0), nil
+	}
+
+	value, ok := e.vars.LookupVar(name)
+
+	// just like bash, `set -u` does not apply to the parts of the
+	// expression that are not used, e.g. the `N` in `0 && N`
+	if !ok && e.noeval == 0 && e.vars.UnsetVar != nil {
+		err := e.vars.UnsetVar(name)
+		if err != nil {
+			return Number{}, err
+		}
+	}
+
+	value = strings.TrimSpace(value)
+	if !ok || value == "" {
+		return intNumber(0), nil
+	}
+
+	// most variables hold a plain number
+	if isDigit(value[0]) {
+		retval, err := parseInteger(value, value)
+		if err == nil {
+			return intNumber(retval), nil
+		}
+	}
+
+	if e.depth >= maxRecursion {
+		return Number{}, ErrRecursionLimit{name}
+	}
+
+	return eval(value, e.vars, e.depth+1, e.float)
+}
+
+// assign sets the given variable to its new value
+func (e *evaluator) assign(name string, value Number) error {
+	// we must not change anything in the parts of the expression that
+	// are not used
+	if e.noeval > 0 || e.vars.AssignToVar == nil {
+		return nil
+	}
+
+	return e.vars.AssignToVar(name, value.String())
+}
+
+// isAssignOp returns true if the token is one of the assignment
+// operators
+func isAssignOp(tok token) bool {
+	_, ok := assignOps[tok.text]
+	return tok.kind == tokenOperator && ok
+}
+
+// increment returns the new value of a variable after the ++ or --
+// operator
+func increment(value Number, op string) Number {
+	delta := int64(1)
+	if op == "--" {
+		delta = -1
+	}
+
+	if value.isFloat {
+		return floatNumber(value.f + float64(delta))
+	}
+	return intNumber(value.i + delta)
+}
+
+// compare works out the result of one of the comparison operators
+func compare(op string, lhs, rhs Number) bool {
+	// if either side is a float, we compare them as floats
+	if lhs.isFloat || rhs.isFloat {
+		a, b := lhs.Float(), rhs.Float()
+		switch op {
+		case "==":
+			return a == b
+		case "!=":
+			return a != b
+		case "<":
+			return a < b
+		case "<=":
+			return a <= b
+		case ">":
+			return a > b
+		}
+		return a >= b
+	}
+
+	a, b := lhs.i, rhs.i
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	}
+	return a >= b
+}
+
+// power returns base**exp, wrapping around on overflow
+//
+// exp must not be negative
+func power(base, exp int64) int64 {
+	var retval int64 = 1
+	for exp > 0 {
+		if exp&1 == 1 {
+			retval *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+
+	return retval
+}
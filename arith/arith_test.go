@@ -0,0 +1,653 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package arith
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+// arithTestVars are the variables that our test expressions use
+var arithTestVars = map[string]string{
+	"y": "1+2",
+	"z": " 4 ",
+	"e": "",
+	"o": "010",
+}
+
+// arithTestData holds expressions, and the results that bash gives us
+// for them
+var arithTestData = []struct {
+	expr     string
+	expected int64
+}{
+	{"1+2*3", 7},
+	{"(1+2)*3", 9},
+	{"2**3**2", 512},
+	{"-2**2", 4},
+	{"10/3", 3},
+	{"-10/3", -3},
+	{"-10%3", -1},
+	{"7%-3", 1},
+	{"!0", 1},
+	{"!5", 0},
+	{"1<2", 1},
+	{"2<=1", 0},
+	{"3>2", 1},
+	{"3>=3", 1},
+	{"1==1", 1},
+	{"1!=1", 0},
+	{"1&&0", 0},
+	{"0||3", 1},
+	{"0&&1/0", 0},
+	{"1||1/0", 1},
+	{"--5", 5},
+	{"+-+3", -3},
+	{" 1 + 2 ", 3},
+	{"1 - - 1", 2},
+	{"9223372036854775807+1", -9223372036854775808},
+	{"99999999999999999999", 7766279631452241919},
+	{"2**63", -9223372036854775808},
+	{"y*2", 6},
+	{"z+1", 5},
+	{"e", 0},
+	{"unset", 0},
+	{"0x10 + 010 + 2#11", 27},
+	{"o + 1", 9},
+	{"", 0},
+	{"~5", -6},
+	{"~0", -1},
+	{"-~1", 2},
+	{"~-1", 0},
+	{"!~-1", 1},
+	{"1 << 3", 8},
+	{"-1 >> 1", -1},
+	{"-16 >> 2", -4},
+	{"1 << 63", -9223372036854775808},
+	{"1 << 64", 1},
+	{"1 << -1", -9223372036854775808},
+	{"5 & 3", 1},
+	{"5 ^ 3", 6},
+	{"5 | 3", 7},
+	{"1 | 2 ^ 3 & 4", 3},
+	{"1 + 2 << 3", 24},
+	{"1 << 2 < 5", 1},
+	{"1 < 2 == 1", 1},
+	{"2 & 3 == 3", 0},
+	{"6 & 3 ^ 1", 3},
+	{"1 | 0 && 0", 0},
+	{"0 && 1 | 2", 0},
+	{"10 - 3 - 2", 5},
+	{"100 / 10 / 5", 2},
+	{"1 ? 2 : 3", 2},
+	{"0 ? 2 : 3", 3},
+	{"5 > 3 ? 10 : 20", 10},
+	{"1 ? 0 ? 5 : 6 : 7", 6},
+	{"0 ? 1 : 0 ? 2 : 3", 3},
+	{"1 ? 2 : 3 ? 4 : 5", 2},
+	{"1 || 0 ? 5 : 6", 5},
+	{"1 ? 2, 3 : 4", 3},
+	{"0 ? 1/0 : 4", 4},
+	{"1 ? 4 : 1/0", 4},
+	{"1, 2", 2},
+	{"(1, 2) + 1", 3},
+	{"1 + 2, 3 * 4, y", 3},
+}
+
+func lookupArithTestVar(name string) (string, bool) {
+	retval, ok := arithTestVars[name]
+	return retval, ok
+}
+
+func TestEvalMatchesBash(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range arithTestData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Eval(testCase.expr, Vars{LookupVar: lookupArithTestVar})
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testCase.expr)
+		assert.Equal(t, testCase.expected, actualResult, testCase.expr)
+	}
+}
+
+func TestEvalTestDataComesFromBash(t *testing.T) {
+	t.Parallel()
+
+	if !shelltest.Available("bash") {
+		t.Skip("bash is not available")
+	}
+
+	for _, testCase := range arithTestData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		shellCase := shelltest.Case{
+			Input: "$((" + testCase.expr + "))",
+			Vars:  arithTestVars,
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		shellResult, err := shelltest.Run("bash", &shellCase)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, strconv.FormatInt(testCase.expected, 10), shellResult, testCase.expr)
+	}
+}
+
+func TestEvalWithoutLookupVarTreatsVariablesAsZero(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Eval("x + 1", Vars{})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), actualResult)
+}
+
+func TestEvalCallsUnsetVarForVariablesThatAreNotSet(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedErr := errors.New("unbound")
+	var unsetVars []string
+	vars := Vars{
+		LookupVar: func(name string) (string, bool) {
+			return "2", name == "x"
+		},
+		UnsetVar: func(name string) error {
+			unsetVars = append(unsetVars, name)
+			return expectedErr
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	setResult, setErr := Eval("x + 1", vars)
+	unusedResult, unusedErr := Eval("0 && y", vars)
+	_, err := Eval("x + y", vars)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, setErr)
+	assert.Equal(t, int64(3), setResult)
+	assert.Nil(t, unusedErr)
+	assert.Equal(t, int64(0), unusedResult)
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, []string{"y"}, unsetVars)
+}
+
+func TestEvalReturnsErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := Vars{
+		LookupVar: func(name string) (string, bool) {
+			// `loop` refers to itself
+			if name == "loop" {
+				return name, true
+			}
+			return "7", true
+		},
+	}
+	testData := []struct {
+		expr        string
+		expectedErr error
+		expectedMsg string
+	}{
+		{"1 +", ErrSyntax{}, `1 +: syntax error: operand expected (error token is "+")`},
+		{"1 + * 2", ErrSyntax{}, `1 + * 2: syntax error: operand expected (error token is "* 2")`},
+		{"1 2", ErrSyntax{}, `1 2: syntax error in expression (error token is "2")`},
+		{"(1", ErrSyntax{}, "(1: missing `)' (error token is \"\")"},
+		{"1 @ 2", ErrSyntax{}, `1 @ 2: syntax error: invalid arithmetic operator (error token is "@ 2")`},
+		{"1/0", ErrDivisionByZero{}, `1/0: division by 0 (error token is "0")`},
+		{"5%0", ErrDivisionByZero{}, `5%0: division by 0 (error token is "0")`},
+		{"2**-1", ErrNegativeExponent{}, `2**-1: exponent less than 0 (error token is "1")`},
+		{"12abc", ErrInvalidNumber{}, `12abc: value too great for base (error token is "12abc")`},
+		{"loop", ErrRecursionLimit{}, `loop: expression recursion level exceeded (error token is "loop")`},
+		{"1 = 2", ErrSyntax{}, `1 = 2: attempted assignment to non-variable (error token is "= 2")`},
+		{"1 + x = 3", ErrSyntax{}, `1 + x = 3: attempted assignment to non-variable (error token is "= 3")`},
+		{"(x) = 2", ErrSyntax{}, `(x) = 2: attempted assignment to non-variable (error token is "= 2")`},
+		{"x **= 2", ErrSyntax{}, `x **= 2: syntax error: operand expected (error token is "= 2")`},
+		{"x--1", ErrSyntax{}, `x--1: syntax error in expression (error token is "1")`},
+		{"5++", ErrSyntax{}, `5++: syntax error: operand expected (error token is "+")`},
+		{"x /= 0", ErrDivisionByZero{}, `x /= 0: division by 0 (error token is "0")`},
+		{"1 ? 2", ErrSyntax{}, "1 ? 2: `:' expected for conditional expression (error token is \"2\")"},
+		{"1 ? : 3", ErrSyntax{}, `1 ? : 3: expression expected (error token is ": 3")`},
+		{"1 ? 2 :", ErrSyntax{}, `1 ? 2 :: expression expected (error token is ":")`},
+		{"0 ? 1 : x = 5", ErrSyntax{}, `0 ? 1 : x = 5: attempted assignment to non-variable (error token is "= 5")`},
+		{",1", ErrSyntax{}, `,1: syntax error: operand expected (error token is ",1")`},
+	}
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := Eval(testCase.expr, vars)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, testCase.expectedErr), testCase.expr)
+		assert.Equal(t, testCase.expectedMsg, err.Error())
+	}
+}
+
+// arithAssignTestData holds expressions that change variables, and the
+// results that bash gives us for them
+//
+// every test starts with x=7 and y=1+2
+var arithAssignTestData = []struct {
+	expr         string
+	expected     int64
+	expectedVars map[string]string
+}{
+	{"x = 5", 5, map[string]string{"x": "5"}},
+	{"x = y = 3", 3, map[string]string{"x": "3", "y": "3"}},
+	{"x = 1 + 2", 3, map[string]string{"x": "3"}},
+	{"x=+1", 1, map[string]string{"x": "1"}},
+	{"n = x", 7, map[string]string{"n": "7"}},
+	{"n = y", 3, map[string]string{"n": "3"}},
+	{"x += 2", 9, map[string]string{"x": "9"}},
+	{"x -= 1", 6, map[string]string{"x": "6"}},
+	{"x *= 3", 21, map[string]string{"x": "21"}},
+	{"x /= 2", 3, map[string]string{"x": "3"}},
+	{"x %= 3", 1, map[string]string{"x": "1"}},
+	{"x <<= 2", 28, map[string]string{"x": "28"}},
+	{"x >>= 1", 3, map[string]string{"x": "3"}},
+	{"x &= 6", 6, map[string]string{"x": "6"}},
+	{"x ^= 1", 6, map[string]string{"x": "6"}},
+	{"x |= 8", 15, map[string]string{"x": "15"}},
+	{"y += 1", 4, map[string]string{"y": "4"}},
+	{"n += 1", 1, map[string]string{"n": "1"}},
+	{"x += x = 2", 9, map[string]string{"x": "9"}},
+	{"x++", 7, map[string]string{"x": "8"}},
+	{"x--", 7, map[string]string{"x": "6"}},
+	{"++x", 8, map[string]string{"x": "8"}},
+	{"--x", 6, map[string]string{"x": "6"}},
+	{"x ++", 7, map[string]string{"x": "8"}},
+	{"-- x", 6, map[string]string{"x": "6"}},
+	{"x++ + x", 15, map[string]string{"x": "8"}},
+	{"++x + x", 16, map[string]string{"x": "8"}},
+	{"n = x++", 7, map[string]string{"n": "7", "x": "8"}},
+	{"++y", 4, map[string]string{"y": "4"}},
+	{"++n", 1, map[string]string{"n": "1"}},
+	{"1--1", 2, map[string]string{"x": "7"}},
+	{"++5", 5, map[string]string{"x": "7"}},
+	{"- -x", 7, map[string]string{"x": "7"}},
+	{"0 && (x = 1)", 0, map[string]string{"x": "7"}},
+	{"1 || x++", 1, map[string]string{"x": "7"}},
+	{"(x = 2) * x", 4, map[string]string{"x": "2"}},
+	{"x = 0 ? 1 : 2", 2, map[string]string{"x": "2"}},
+	{"1 ? x = 2 : 3", 2, map[string]string{"x": "2"}},
+	{"0 ? x++ : 3", 3, map[string]string{"x": "7"}},
+	{"1 ? 3 : x++", 3, map[string]string{"x": "7"}},
+	{"(x = 3) ? x : 0", 3, map[string]string{"x": "3"}},
+	{"x = 1, x + 1", 2, map[string]string{"x": "1"}},
+	{"x++, x++, x", 9, map[string]string{"x": "9"}},
+	{"x <<= 1, x |= 1", 15, map[string]string{"x": "15"}},
+}
+
+// newArithTestVars returns the variables that our assignment tests
+// start with
+func newArithTestVars() map[string]string {
+	return map[string]string{
+		"x": "7",
+		"y": "1+2",
+	}
+}
+
+func TestEvalAssignsToVariables(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range arithAssignTestData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		vars := newArithTestVars()
+		arithVars := Vars{
+			LookupVar: func(name string) (string, bool) {
+				retval, ok := vars[name]
+				return retval, ok
+			},
+			AssignToVar: func(name, value string) error {
+				vars[name] = value
+				return nil
+			},
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Eval(testCase.expr, arithVars)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testCase.expr)
+		assert.Equal(t, testCase.expected, actualResult, testCase.expr)
+		for name, expectedValue := range testCase.expectedVars {
+			assert.Equal(t, expectedValue, vars[name], testCase.expr+": "+name)
+		}
+	}
+}
+
+func TestEvalAssignTestDataComesFromBash(t *testing.T) {
+	t.Parallel()
+
+	if !shelltest.Available("bash") {
+		t.Skip("bash is not available")
+	}
+
+	for _, testCase := range arithAssignTestData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		// bash expands left to right, so we can see what the
+		// expression did to the variables afterwards
+		names := make([]string, 0, len(testCase.expectedVars))
+		for name := range testCase.expectedVars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		input := "$((" + testCase.expr + "))"
+		expected := strconv.FormatInt(testCase.expected, 10)
+		for _, name := range names {
+			input += " $" + name
+			expected += " " + testCase.expectedVars[name]
+		}
+		shellCase := shelltest.Case{
+			Input: input,
+			Vars:  newArithTestVars(),
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		shellResult, err := shelltest.Run("bash", &shellCase)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, expected, shellResult, testCase.expr)
+	}
+}
+
+func TestEvalWithoutAssignToVarChangesNothing(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := Vars{
+		LookupVar: func(name string) (string, bool) {
+			return "7", true
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Eval("x += 1", vars)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8), actualResult)
+}
+
+func TestEvalReturnsAssignToVarErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedErr := errors.New("x is read-only")
+	vars := Vars{
+		AssignToVar: func(name, value string) error {
+			return expectedErr
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Eval("x++", vars)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestEvalFloatSupportsFloatingPointNumbers(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []struct {
+		expr     string
+		expected string
+	}{
+		// integers on both sides give us integer arithmetic
+		{"7 / 2", "3"},
+		{"7 % 4", "3"},
+		{"7 / 2.0", "3.5"},
+		{"7. / 2", "3.5"},
+		{".5 * 4", "2."},
+		{"1.5 + 1.5", "3."},
+		{"1.0 / 3", "0.33333333333333331"},
+		{"1e3", "1000."},
+		{"2.5e1 - 5", "20."},
+		{"1e-2 * 100", "1."},
+		{"-1.5", "-1.5"},
+		{"7.5 % 2", "1.5"},
+		{"2 ** -1", "0.5"},
+		{"2 ** 0.5", "1.4142135623730951"},
+		{"1.5 ** 2", "2.25"},
+		{"1.5 < 2", "1"},
+		{"1.5 == 1.5", "1"},
+		{"0.0 || 0.5", "1"},
+		{"!0.5", "0"},
+		{"0.5 ? 2.5 : 3", "2.5"},
+		{"7.9 | 0", "7"},
+		{"~1.5", "-2"},
+		{"f + 1", "2.5"},
+		{"++f", "2.5"},
+		{"1e400", "Inf"},
+		{"-1e400", "-Inf"},
+	}
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := EvalFloat(testCase.expr, Vars{
+			LookupVar: func(name string) (string, bool) {
+				return "1.5", true
+			},
+		})
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testCase.expr)
+		assert.Equal(t, testCase.expected, actualResult.String(), testCase.expr)
+	}
+}
+
+func TestEvalFloatAssignsFloatsToVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{}
+	arithVars := Vars{
+		LookupVar: func(name string) (string, bool) {
+			retval, ok := vars[name]
+			return retval, ok
+		},
+		AssignToVar: func(name, value string) error {
+			vars[name] = value
+			return nil
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := EvalFloat("x = 2.5, y = x * 2, x++", arithVars)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2.5, actualResult.Float())
+	assert.Equal(t, map[string]string{"x": "3.5", "y": "5."}, vars)
+}
+
+func TestEvalFloatReturnsErrors(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		expr        string
+		expectedErr error
+		expectedMsg string
+	}{
+		{"1 / 0.0", ErrDivisionByZero{}, `1 / 0.0: division by 0 (error token is "0.0")`},
+		{"1.5 % 0", ErrDivisionByZero{}, `1.5 % 0: division by 0 (error token is "0")`},
+		{"1.5.2", ErrInvalidNumber{}, `1.5.2: value too great for base (error token is "1.5.2")`},
+		{"1.5abc", ErrInvalidNumber{}, `1.5abc: value too great for base (error token is "1.5abc")`},
+	}
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := EvalFloat(testCase.expr, Vars{})
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, testCase.expectedErr), testCase.expr)
+		assert.Equal(t, testCase.expectedMsg, err.Error())
+	}
+}
+
+func TestEvalDoesNotSupportFloats(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Eval("1.5 + 1", Vars{})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrSyntax{}))
+}
+
+func TestNamesReturnsTheVariablesInTheExpression(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "count++ + limit*2 - 0x1f + 1.5 ? count : other"
+	expectedResult := []string{"count", "limit", "count", "other"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Names(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestNamesReturnsErrorForInvalidExpressions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "count $ 2"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Names(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrSyntax{}))
+}
@@ -0,0 +1,156 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package arith
+
+import "fmt"
+
+// errorMessage builds an error message that looks like the one that
+// bash gives you
+func errorMessage(expr, reason, token string) string {
+	return fmt.Sprintf("%s: %s (error token is \"%s\")", expr, reason, token)
+}
+
+// ErrSyntax is returned if the expression is not a valid arithmetic
+// expression
+type ErrSyntax struct {
+	expr   string
+	reason string
+	token  string
+}
+
+func (e ErrSyntax) Error() string {
+	return errorMessage(e.expr, e.reason, e.token)
+}
+
+// Is returns true if the target is also an ErrSyntax. It lets you use
+// errors.Is(err, ErrSyntax{})
+func (e ErrSyntax) Is(target error) bool {
+	_, ok := target.(ErrSyntax)
+	return ok
+}
+
+// ErrDivisionByZero is returned if the expression divides by zero, or
+// uses zero as the right-hand side of the `%` operator
+type ErrDivisionByZero struct {
+	expr  string
+	token string
+}
+
+func (e ErrDivisionByZero) Error() string {
+	return errorMessage(e.expr, "division by 0", e.token)
+}
+
+// Is returns true if the target is also an ErrDivisionByZero. It lets
+// you use errors.Is(err, ErrDivisionByZero{})
+func (e ErrDivisionByZero) Is(target error) bool {
+	_, ok := target.(ErrDivisionByZero)
+	return ok
+}
+
+// ErrNegativeExponent is returned if the expression raises a number to
+// a negative power
+type ErrNegativeExponent struct {
+	expr  string
+	token string
+}
+
+func (e ErrNegativeExponent) Error() string {
+	return errorMessage(e.expr, "exponent less than 0", e.token)
+}
+
+// Is returns true if the target is also an ErrNegativeExponent. It lets
+// you use errors.Is(err, ErrNegativeExponent{})
+func (e ErrNegativeExponent) Is(target error) bool {
+	_, ok := target.(ErrNegativeExponent)
+	return ok
+}
+
+// ErrInvalidNumber is returned if the expression contains a number that
+// we cannot parse, such as `12abc`, or a digit that is too big for the
+// number's base, such as `08` or `2#12`
+type ErrInvalidNumber struct {
+	expr  string
+	token string
+}
+
+func (e ErrInvalidNumber) Error() string {
+	return errorMessage(e.expr, "value too great for base", e.token)
+}
+
+// Is returns true if the target is also an ErrInvalidNumber. It lets
+// you use errors.Is(err, ErrInvalidNumber{})
+func (e ErrInvalidNumber) Is(target error) bool {
+	_, ok := target.(ErrInvalidNumber)
+	return ok
+}
+
+// ErrInvalidBase is returned if the expression contains a number with
+// a base that is not between 2 and 64, such as `65#1`
+type ErrInvalidBase struct {
+	expr  string
+	token string
+}
+
+func (e ErrInvalidBase) Error() string {
+	return errorMessage(e.expr, "invalid arithmetic base", e.token)
+}
+
+// Is returns true if the target is also an ErrInvalidBase. It lets
+// you use errors.Is(err, ErrInvalidBase{})
+func (e ErrInvalidBase) Is(target error) bool {
+	_, ok := target.(ErrInvalidBase)
+	return ok
+}
+
+// ErrRecursionLimit is returned if a variable's value refers back to
+// the variable itself, e.g. when `x` is set to `x + 1`
+//
+// Just like in bash, the value of a variable is itself evaluated as an
+// arithmetic expression.
+type ErrRecursionLimit struct {
+	name string
+}
+
+func (e ErrRecursionLimit) Error() string {
+	return errorMessage(e.name, "expression recursion level exceeded", e.name)
+}
+
+// Is returns true if the target is also an ErrRecursionLimit. It lets
+// you use errors.Is(err, ErrRecursionLimit{})
+func (e ErrRecursionLimit) Is(target error) bool {
+	_, ok := target.(ErrRecursionLimit)
+	return ok
+}
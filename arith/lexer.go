@@ -0,0 +1,190 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package arith
+
+import "strings"
+
+// the kinds of token that an arithmetic expression is made of
+const (
+	tokenEOF = iota
+	tokenNumber
+	tokenName
+	tokenOperator
+)
+
+// token is a single number, name or operator in the expression
+type token struct {
+	kind  int
+	text  string
+	start int
+}
+
+// operators holds every operator that we understand
+//
+// longer operators come first, so that we always match the longest one
+// that we can
+var operators = []string{
+	"<<=", ">>=",
+	"**", "++", "--", "<<", ">>",
+	"<=", ">=", "==", "!=", "&&", "||",
+	"+=", "-=", "*=", "/=", "%=", "&=", "^=", "|=",
+	"+", "-", "*", "/", "%", "<", ">", "!", "~", "&", "^", "|",
+	"?", ":", ",", "(", ")", "=",
+}
+
+// tokenize breaks the expression up into tokens
+//
+// if `float` is true, numbers can have a decimal point and an exponent
+//
+// the last token is always a tokenEOF
+func tokenize(expr string, float bool) ([]token, error) {
+	var retval []token
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case isBlank(c):
+			i++
+
+		case isDigit(c) || (float && c == '.' && i+1 < len(expr) && isDigit(expr[i+1])):
+			end := scanNumber(expr, i, float)
+			retval = append(retval, token{tokenNumber, expr[i:end], i})
+			i = end
+
+		case isNameStart(c):
+			end := i + 1
+			for end < len(expr) && isNameChar(expr[end]) {
+				end++
+			}
+			retval = append(retval, token{tokenName, expr[i:end], i})
+			i = end
+
+		default:
+			op, ok := matchOperator(expr[i:])
+			if !ok {
+				return nil, ErrSyntax{expr, "syntax error: invalid arithmetic operator", strings.TrimSpace(expr[i:])}
+			}
+
+			// just like bash, ++ and -- are only increment and
+			// decrement operators if they come straight after a
+			// variable name, or before one; otherwise, `--5` is
+			// two unary minuses
+			if op == "++" || op == "--" {
+				afterName := len(retval) > 0 && retval[len(retval)-1].kind == tokenName
+				if !afterName && !nameFollows(expr[i+2:]) {
+					op = op[:1]
+				}
+			}
+			retval = append(retval, token{tokenOperator, op, i})
+			i += len(op)
+		}
+	}
+
+	return append(retval, token{tokenEOF, "", len(expr)}), nil
+}
+
+// scanNumber returns the end of the number that starts at expr[start]
+//
+// we pick up anything that looks like part of the number here, and find
+// out if it is valid when we parse it
+func scanNumber(expr string, start int, float bool) int {
+	end := start
+	for end < len(expr) {
+		c := expr[end]
+		switch {
+		case float && c == '.':
+			end++
+
+		case float && (c == 'e' || c == 'E') && isDecimal(expr[start:end]) && end+2 < len(expr) && (expr[end+1] == '+' || expr[end+1] == '-') && isDigit(expr[end+2]):
+			// the exponent of a float can have a sign, e.g. 1.5e-3
+			end += 2
+
+		case isNumberChar(c):
+			end++
+
+		default:
+			return end
+		}
+	}
+
+	return end
+}
+
+// isDecimal returns true if the text is made up of decimal digits and
+// decimal points
+func isDecimal(text string) bool {
+	return strings.Trim(text, "0123456789.") == ""
+}
+
+// matchOperator returns the operator at the start of the input
+func matchOperator(input string) (string, bool) {
+	for _, op := range operators {
+		if strings.HasPrefix(input, op) {
+			return op, true
+		}
+	}
+
+	return "", false
+}
+
+// nameFollows returns true if the input starts with a variable name,
+// after skipping any blanks
+func nameFollows(input string) bool {
+	input = strings.TrimLeft(input, " \t\n")
+	return len(input) > 0 && isNameStart(input[0])
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
+
+// isNumberChar returns true if the character can be part of a number,
+// such as 0x1f or 64#@_
+func isNumberChar(c byte) bool {
+	return isNameChar(c) || c == '#' || c == '@'
+}
@@ -0,0 +1,147 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package arith
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizeSplitsExpressionIntoTokens(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "count**2 >= (limit-10)"
+	expectedResult := []token{
+		{tokenName, "count", 0},
+		{tokenOperator, "**", 5},
+		{tokenNumber, "2", 7},
+		{tokenOperator, ">=", 9},
+		{tokenOperator, "(", 12},
+		{tokenName, "limit", 13},
+		{tokenOperator, "-", 18},
+		{tokenNumber, "10", 19},
+		{tokenOperator, ")", 21},
+		{tokenEOF, "", 22},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := tokenize(testData, false)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestTokenizeKeepsInvalidNumbersTogether(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "12abc+1"
+	expectedResult := []token{
+		{tokenNumber, "12abc", 0},
+		{tokenOperator, "+", 5},
+		{tokenNumber, "1", 6},
+		{tokenEOF, "", 7},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := tokenize(testData, false)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestTokenizeRejectsUnknownOperators(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := tokenize("1 $ 2", false)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.IsType(t, ErrSyntax{}, err)
+}
+
+func TestTokenizeOnlyIncrementsVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// ++ and -- are only operators when they are next to a name
+	testData := "x++ + --5 - -- y"
+	expectedResult := []token{
+		{tokenName, "x", 0},
+		{tokenOperator, "++", 1},
+		{tokenOperator, "+", 4},
+		{tokenOperator, "-", 6},
+		{tokenOperator, "-", 7},
+		{tokenNumber, "5", 8},
+		{tokenOperator, "-", 10},
+		{tokenOperator, "--", 12},
+		{tokenName, "y", 15},
+		{tokenEOF, "", 16},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := tokenize(testData, false)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
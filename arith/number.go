@@ -0,0 +1,226 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package arith
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseInteger returns the value of a number from the expression
+//
+// just like bash, the number can be:
+//
+//   - decimal, e.g. 42
+//   - octal, if it starts with a 0, e.g. 052
+//   - hexadecimal, if it starts with 0x or 0X, e.g. 0x2a
+//   - in any base from 2 to 64, using the base#digits notation,
+//     e.g. 2#101010
+//
+// a number that is too big wraps around
+func parseInteger(expr, text string) (int64, error) {
+	var base int64 = 10
+	digits := text
+
+	switch {
+	case strings.Contains(text, "#"):
+		i := strings.IndexByte(text, '#')
+		parsedBase, err := strconv.ParseInt(text[:i], 10, 64)
+		if err != nil || parsedBase < 2 || parsedBase > 64 {
+			return 0, ErrInvalidBase{expr, text}
+		}
+		base = parsedBase
+		digits = text[i+1:]
+		if digits == "" {
+			return 0, ErrSyntax{expr, "invalid integer constant", text}
+		}
+
+	case strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X"):
+		// just like bash, `0x` on its own is 0
+		base = 16
+		digits = text[2:]
+
+	case len(text) > 1 && text[0] == '0':
+		base = 8
+		digits = text[1:]
+	}
+
+	var retval int64
+	for i := 0; i < len(digits); i++ {
+		digit := digitValue(digits[i], base)
+		if digit < 0 || digit >= base {
+			return 0, ErrInvalidNumber{expr, text}
+		}
+		retval = retval*base + digit
+	}
+
+	return retval, nil
+}
+
+// digitValue returns the value of a single digit, or -1 if it is not
+// a digit at all
+//
+// up to base 36, upper and lower case letters mean the same thing;
+// above that, lower case letters come first, then upper case letters,
+// then @ and _
+func digitValue(c byte, base int64) int64 {
+	switch {
+	case isDigit(c):
+		return int64(c - '0')
+	case 'a' <= c && c <= 'z':
+		return int64(c-'a') + 10
+	case 'A' <= c && c <= 'Z' && base <= 36:
+		return int64(c-'A') + 10
+	case 'A' <= c && c <= 'Z':
+		return int64(c-'A') + 36
+	case c == '@':
+		return 62
+	case c == '_':
+		return 63
+	}
+
+	return -1
+}
+
+// Number is the value of an arithmetic expression. It holds either an
+// integer or, if you use EvalFloat(), a floating-point number.
+type Number struct {
+	i       int64
+	f       float64
+	isFloat bool
+}
+
+func intNumber(value int64) Number {
+	return Number{i: value}
+}
+
+func floatNumber(value float64) Number {
+	return Number{f: value, isFloat: true}
+}
+
+// boolNumber turns the result of a comparison into 1 or 0
+func boolNumber(value bool) Number {
+	if value {
+		return intNumber(1)
+	}
+
+	return intNumber(0)
+}
+
+// IsFloat returns true if the number is a floating-point number
+func (n Number) IsFloat() bool {
+	return n.isFloat
+}
+
+// Int returns the number as an integer. A float is truncated towards
+// zero.
+func (n Number) Int() int64 {
+	if n.isFloat {
+		return int64(n.f)
+	}
+
+	return n.i
+}
+
+// Float returns the number as a floating-point number
+func (n Number) Float() float64 {
+	if n.isFloat {
+		return n.f
+	}
+
+	return float64(n.i)
+}
+
+// String returns the number in the same format that zsh uses
+//
+// A float always has a decimal point or an exponent (e.g. `3.` or
+// `1e+20`), so that it is still a float if it is read back in.
+func (n Number) String() string {
+	if !n.isFloat {
+		return strconv.FormatInt(n.i, 10)
+	}
+
+	switch {
+	case math.IsInf(n.f, 1):
+		return "Inf"
+	case math.IsInf(n.f, -1):
+		return "-Inf"
+	case math.IsNaN(n.f):
+		return "NaN"
+	}
+
+	retval := strconv.FormatFloat(n.f, 'g', 17, 64)
+	if !strings.ContainsAny(retval, ".e") {
+		retval += "."
+	}
+	return retval
+}
+
+// isZero returns true if the number is zero (and so is false, as far as
+// the logical operators are concerned)
+func (n Number) isZero() bool {
+	if n.isFloat {
+		return n.f == 0
+	}
+
+	return n.i == 0
+}
+
+// isFloatLiteral returns true if the number from the expression is a
+// decimal number with a decimal point or an exponent, e.g. 1.5 or 1e3
+func isFloatLiteral(text string) bool {
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") || strings.Contains(text, "#") {
+		return false
+	}
+
+	return strings.ContainsAny(text, ".eE")
+}
+
+// parseFloat returns the value of a floating-point number from the
+// expression
+func parseFloat(expr, text string) (Number, error) {
+	retval, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		// just like zsh, a number that is too big is infinity
+		numErr, ok := err.(*strconv.NumError)
+		if !ok || numErr.Err != strconv.ErrRange {
+			return Number{}, ErrInvalidNumber{expr, text}
+		}
+	}
+
+	return floatNumber(retval), nil
+}
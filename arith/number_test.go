@@ -0,0 +1,202 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package arith
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+// numberTestData holds numbers, and the values that bash gives us for
+// them
+var numberTestData = []struct {
+	text     string
+	expected int64
+}{
+	{"42", 42},
+	{"0", 0},
+	{"010", 8},
+	{"0777", 511},
+	{"0x10", 16},
+	{"0X1f", 31},
+	{"0xFF", 255},
+	{"0x", 0},
+	{"2#1011", 11},
+	{"8#17", 15},
+	{"10#08", 8},
+	{"16#ff", 255},
+	{"16#FF", 255},
+	{"36#z", 35},
+	{"36#Z", 35},
+	{"37#z", 35},
+	{"64#A", 36},
+	{"64#@", 62},
+	{"64#_", 63},
+	{"64#10", 64},
+	{"0xffffffffffffffffff", -1},
+	{"0777777777777777777777777", -1},
+	{"99999999999999999999", 7766279631452241919},
+}
+
+func TestParseIntegerMatchesBash(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range numberTestData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := parseInteger(testCase.text, testCase.text)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testCase.text)
+		assert.Equal(t, testCase.expected, actualResult, testCase.text)
+	}
+}
+
+func TestParseIntegerTestDataComesFromBash(t *testing.T) {
+	t.Parallel()
+
+	if !shelltest.Available("bash") {
+		t.Skip("bash is not available")
+	}
+
+	for _, testCase := range numberTestData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		shellCase := shelltest.Case{
+			Input: "$((" + testCase.text + "))",
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		shellResult, err := shelltest.Run("bash", &shellCase)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, strconv.FormatInt(testCase.expected, 10), shellResult, testCase.text)
+	}
+}
+
+func TestParseIntegerReturnsErrors(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		text        string
+		expectedErr error
+		expectedMsg string
+	}{
+		{"08", ErrInvalidNumber{}, `08: value too great for base (error token is "08")`},
+		{"0xg", ErrInvalidNumber{}, `0xg: value too great for base (error token is "0xg")`},
+		{"2#2", ErrInvalidNumber{}, `2#2: value too great for base (error token is "2#2")`},
+		{"37#Z", ErrInvalidNumber{}, `37#Z: value too great for base (error token is "37#Z")`},
+		{"1@", ErrInvalidNumber{}, `1@: value too great for base (error token is "1@")`},
+		{"1#1", ErrInvalidBase{}, `1#1: invalid arithmetic base (error token is "1#1")`},
+		{"65#1", ErrInvalidBase{}, `65#1: invalid arithmetic base (error token is "65#1")`},
+		{"2#", ErrSyntax{}, `2#: invalid integer constant (error token is "2#")`},
+	}
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := parseInteger(testCase.text, testCase.text)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, testCase.expectedErr), testCase.text)
+		assert.Equal(t, testCase.expectedMsg, err.Error())
+	}
+}
+
+func TestEvalUsesTheBaseOfNumbersInVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := Vars{
+		LookupVar: func(name string) (string, bool) {
+			return "0755", true
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Eval("mode - 8#700", vars)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0755-0700), actualResult)
+}
+
+func TestNumberConvertsBetweenIntegersAndFloats(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	i := intNumber(-7)
+	f := floatNumber(-7.9)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.False(t, i.IsFloat())
+	assert.Equal(t, int64(-7), i.Int())
+	assert.Equal(t, -7.0, i.Float())
+	assert.Equal(t, "-7", i.String())
+
+	assert.True(t, f.IsFloat())
+	assert.Equal(t, int64(-7), f.Int())
+	assert.Equal(t, -7.9, f.Float())
+	assert.Equal(t, "-7.9000000000000004", f.String())
+	assert.Equal(t, "NaN", floatNumber(math.NaN()).String())
+}
@@ -0,0 +1,258 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ganbarodigital/go_shellexpand/arith"
+)
+
+// findArithmetic checks to see if the input string starts with a
+// $((...)) arithmetic expansion
+//
+// returns:
+//
+// - the position just after the closing ))
+// - `true` on success
+func findArithmetic(input string) (int, bool) {
+	if !strings.HasPrefix(input, "$((") {
+		return 0, false
+	}
+
+	depth := 0
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '$':
+			// a ${...} can contain anything, including parentheses
+			varEnd, err := findVar(input[i:])
+			if err == nil {
+				i += varEnd - 1
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth > 1 {
+				continue
+			}
+
+			// the first ( must be closed by the second-to-last ),
+			// otherwise this is a command substitution that starts
+			// with a subshell, e.g. $((cd /tmp) && ls)
+			if depth == 1 {
+				if i+1 < len(input) && input[i+1] == ')' {
+					return i + 2, true
+				}
+				return 0, false
+			}
+		}
+	}
+
+	// if we get here, the $(( was never closed
+	return 0, false
+}
+
+// findLegacyArithmetic checks to see if the input string starts with a
+// $[...] arithmetic expansion, which bash still supports for old scripts
+//
+// returns:
+//
+// - the position just after the closing ]
+// - `true` on success
+func findLegacyArithmetic(input string) (int, bool) {
+	if !strings.HasPrefix(input, "$[") {
+		return 0, false
+	}
+
+	depth := 0
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '$':
+			// a ${...} can contain anything, including brackets
+			varEnd, err := findVar(input[i:])
+			if err == nil {
+				i += varEnd - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		}
+	}
+
+	// if we get here, the $[ was never closed
+	return 0, false
+}
+
+// hasArithmeticPrefix returns true if the input starts with an
+// arithmetic expansion that the caller wants us to expand
+func hasArithmeticPrefix(input string, cb ExpansionCallbacks) bool {
+	return (cb.dialect().arithmetic && strings.HasPrefix(input, "$((")) ||
+		(cb.legacyArithmetic() && strings.HasPrefix(input, "$["))
+}
+
+// matchArithmetic checks to see if the input string starts with an
+// arithmetic expansion that the caller wants us to expand
+//
+// returns:
+//
+// - the position just after the end of the expansion
+// - `true` on success
+func matchArithmetic(input string, cb ExpansionCallbacks) (int, bool) {
+	if cb.dialect().arithmetic {
+		exprEnd, ok := findArithmetic(input)
+		if ok {
+			return exprEnd, true
+		}
+	}
+	if cb.legacyArithmetic() {
+		return findLegacyArithmetic(input)
+	}
+
+	return 0, false
+}
+
+// arithmeticPrefixLen returns the length of the $(( or $[ at the start
+// of an arithmetic expansion
+func arithmeticPrefixLen(text string) int {
+	if strings.HasPrefix(text, "$[") {
+		return 2
+	}
+
+	return 3
+}
+
+// arithmeticExpr returns the expression inside an arithmetic expansion
+func arithmeticExpr(text string) string {
+	if strings.HasPrefix(text, "$[") {
+		return text[2 : len(text)-1]
+	}
+
+	return text[3 : len(text)-2]
+}
+
+// WithLegacyArithmetic makes the Expander treat $[expr] as arithmetic
+// expansion too, just like $((expr)). bash still supports this old
+// syntax, because some scripts still use it.
+//
+// It only applies to the bash dialect.
+func WithLegacyArithmetic() Option {
+	return func(opts *options) {
+		opts.legacyArithmetic = true
+	}
+}
+
+// WithFloatArithmetic makes arithmetic expansion support floating-point
+// numbers, just like zsh does. `$((7 / 2))` is still 3, but
+// `$((7 / 2.0))` is 3.5.
+//
+// Floats are written out with up to 17 significant digits, and always
+// have a decimal point or an exponent, e.g. `$((1.5 * 2))` is `3.`.
+// EvalLet() still returns integers; it truncates any fractions.
+func WithFloatArithmetic() Option {
+	return func(opts *options) {
+		opts.floatArithmetic = true
+	}
+}
+
+// evalArithmetic evaluates the (already expanded) expression from
+// inside a $((...)), and returns the result as a string
+//
+// any assignments in the expression are written back to the caller's
+// variables
+func evalArithmetic(expr string, cb ExpansionCallbacks) (string, error) {
+	var retval string
+	if cb.floatArithmetic() {
+		value, err := arith.EvalFloat(expr, arithVars(cb))
+		if err != nil {
+			return "", err
+		}
+		retval = value.String()
+	} else {
+		value, err := arith.Eval(expr, arithVars(cb))
+		if err != nil {
+			return "", err
+		}
+		retval = strconv.FormatInt(value, 10)
+	}
+
+	traceArithmetic(cb, expr, retval)
+	return retval, nil
+}
+
+// arithVars gives the arithmetic evaluator access to the caller's
+// variables
+func arithVars(cb ExpansionCallbacks) arith.Vars {
+	retval := arith.Vars{
+		LookupVar:   cb.lookupVar,
+		AssignToVar: cb.assignToVar,
+	}
+
+	// let WithTrace() and ExpandDetailed() see what the expression
+	// reads and writes
+	if cb.tracing() {
+		retval.LookupVar = func(name string) (string, bool) {
+			value, ok := cb.lookupVar(name)
+			traceVar(cb, TraceVarRead, PhaseArithmeticExpansion, name, value)
+			return value, ok
+		}
+		retval.AssignToVar = func(name, value string) error {
+			err := cb.assignToVar(name, value)
+			if err == nil {
+				traceVar(cb, TraceAssignment, PhaseArithmeticExpansion, name, value)
+			}
+			return err
+		}
+	}
+
+	// with `set -u`, it is an error to read a variable that isn't set
+	if cb.shellOpts().NoUnset {
+		retval.UnsetVar = func(name string) error {
+			return ErrUnboundVariable{name}
+		}
+	}
+
+	return retval
+}
@@ -0,0 +1,384 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/arith"
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindArithmetic(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		input       string
+		expectedEnd int
+		expectedOk  bool
+	}{
+		{"$((1 + 2))", 10, true},
+		{"$((1 + 2)) and more", 10, true},
+		{"$(( (1 + 2) * 3 ))x", 18, true},
+		{"$((${PARAM1:-)} + 1))", 21, true},
+		{`$((1 \) 2))`, 11, true},
+		// command substitution that starts with a subshell
+		{"$((cd /tmp) && ls)", 0, false},
+		// command substitution
+		{"$(echo)", 0, false},
+		// unterminated
+		{"$((1 + 2)", 0, false},
+		{"$((1 + 2", 0, false},
+		// not an expansion at all
+		{"1 + 2", 0, false},
+	}
+
+	for _, testCase := range testCases {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualEnd, actualOk := findArithmetic(testCase.input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, testCase.expectedOk, actualOk, testCase.input)
+		assert.Equal(t, testCase.expectedEnd, actualEnd, testCase.input)
+	}
+}
+
+func TestExpandArithmeticUsesVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"WIDTH": "10",
+		"EXPR":  "WIDTH * 2",
+	}
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			retval, ok := vars[key]
+			return retval, ok
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("$(($WIDTH + 3)) $((WIDTH + 3)) $((EXPR + 1)) $((UNSET + 1))", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "13 13 21 1", actualResult)
+}
+
+func TestExpandArithmeticReportsErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "0", true
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Expand("x is $((10 / $x))", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var expansionErr ExpansionError
+	assert.True(t, errors.As(err, &expansionErr))
+	assert.Equal(t, PhaseArithmeticExpansion, expansionErr.Phase)
+	assert.Equal(t, 5, expansionErr.Offset)
+	assert.Equal(t, "$((10 / $x))", expansionErr.Substring)
+	assert.True(t, errors.Is(err, arith.ErrDivisionByZero{}))
+}
+
+func TestExpandArithmeticIsNotSupportedByTheComposeDialect(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := NewExpander(cb, WithDialect(DialectCompose)).Expand("$((1 + 2))")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "$((1 + 2))", actualResult)
+}
+
+func TestExpandArgsSplitsUnquotedArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"IFS": "0",
+	}
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			retval, ok := vars[key]
+			return retval, ok
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandArgs(`$((50 * 2 + 1)) "$((101))"`, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "1", "101"}, actualResult)
+}
+
+func TestExpandArithmeticHonoursReadOnlyVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"COUNT": "1",
+	}
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			retval, ok := vars[key]
+			return retval, ok
+		},
+		AssignToVar: func(key, value string) error {
+			vars[key] = value
+			return nil
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := NewExpander(cb, WithReadOnly("COUNT")).Expand("$((COUNT++))")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrReadOnlyVar{}))
+	assert.Equal(t, "1", vars["COUNT"])
+}
+
+func TestFindLegacyArithmetic(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		input       string
+		expectedEnd int
+		expectedOk  bool
+	}{
+		{"$[1 + 2]", 8, true},
+		{"$[1 + 2] and more", 8, true},
+		{"$[x[1] + 2]x", 11, true},
+		{"$[${PARAM1:-]} + 1]", 19, true},
+		{`$[1 \] 2]`, 9, true},
+		// unterminated
+		{"$[1 + 2", 0, false},
+		// not an expansion at all
+		{"$((1 + 2))", 0, false},
+	}
+
+	for _, testCase := range testCases {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualEnd, actualOk := findLegacyArithmetic(testCase.input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, testCase.expectedOk, actualOk, testCase.input)
+		assert.Equal(t, testCase.expectedEnd, actualEnd, testCase.input)
+	}
+}
+
+func TestExpandLegacyArithmeticMatchesBash(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	shellCase := shelltest.Case{
+		Input: "$[WIDTH + 3] x$[$WIDTH * (2 + 1)]y $(($[1 + 1] * 2))",
+		Vars: map[string]string{
+			"WIDTH": "10",
+		},
+	}
+	expander := NewExpander(newTestCallbacks(shellCase.Vars), WithLegacyArithmetic())
+
+	// ----------------------------------------------------------------
+	// perform the change and test the results
+
+	shelltest.AssertMatch(t, "bash", &shellCase, expander.Expand)
+}
+
+func TestExpandLegacyArithmeticIsOffByDefault(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expander *Expander
+	}{
+		{NewExpander(ExpansionCallbacks{})},
+		{NewExpander(ExpansionCallbacks{}, WithLegacyArithmetic(), WithDialect(DialectPOSIX))},
+		{NewExpander(ExpansionCallbacks{}, WithLegacyArithmetic(), WithDialect(DialectZsh))},
+	}
+
+	for _, testCase := range testCases {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := testCase.expander.Expand("$[1 + 2]")
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, "$[1 + 2]", actualResult)
+	}
+}
+
+func TestExpandLegacyArithmeticReportsErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expander := NewExpander(ExpansionCallbacks{}, WithLegacyArithmetic())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := expander.Expand("x is $[10 / 0]")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var expansionErr ExpansionError
+	assert.True(t, errors.As(err, &expansionErr))
+	assert.Equal(t, PhaseArithmeticExpansion, expansionErr.Phase)
+	assert.Equal(t, 5, expansionErr.Offset)
+	assert.Equal(t, "$[10 / 0]", expansionErr.Substring)
+}
+
+func TestExpandArgsSupportsLegacyArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expander := NewExpander(newTestCallbacks(map[string]string{}), WithLegacyArithmetic())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expander.ExpandArgs(`$[1 + 2] "$[3 * 4]"`)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "12"}, actualResult)
+}
+
+func TestExpandFloatArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"RATIO": "0.5",
+	}
+	expander := NewExpander(newTestCallbacks(vars), WithFloatArithmetic())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expander.Expand("$((7 / 2)) $((7 / 2.0)) $((RATIO * 6)) $((TOTAL = 10 * RATIO))")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "3 3.5 3. 5.", actualResult)
+	assert.Equal(t, "5.", vars["TOTAL"])
+}
+
+func TestExpandWithoutFloatArithmeticRejectsFloats(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expander := NewExpander(newTestCallbacks(map[string]string{}))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := expander.Expand("$((7 / 2.0))")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, arith.ErrSyntax{}))
+}
@@ -0,0 +1,95 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "context"
+
+// ExpandBestEffort replaces ${var} and $var in the input string, just
+// like Expand() does, but it does not give up at the first problem.
+//
+// Anything that cannot be expanded is left in the output exactly as it
+// appears in the input, and the rest of the input is expanded as
+// normal. You get back the partially-expanded string, and a list of
+// everything that went wrong, in the order it happened. Each problem is
+// an ExpansionError, so you know where in the input it is.
+//
+// Some problems stop the whole expansion (e.g. a history event that
+// does not exist). When that happens, you get the error back as well.
+func ExpandBestEffort(input string, cb ExpansionCallbacks) (string, []ExpansionError, error) {
+	return ExpandBestEffortContext(cb.context(), input, cb)
+}
+
+// ExpandBestEffortContext replaces ${var} and $var in the input string,
+// just like ExpandBestEffort() does. It uses the given context in the
+// same way that ExpandContext() does.
+func ExpandBestEffortContext(ctx context.Context, input string, cb ExpansionCallbacks) (string, []ExpansionError, error) {
+	diagnostics := []ExpansionError{}
+	cb.diagnostics = &diagnostics
+
+	retval, err := ExpandContext(ctx, input, cb)
+	return retval, diagnostics, err
+}
+
+// ExpandBestEffort replaces ${var} and $var in the input string, just
+// like the package-level ExpandBestEffort() does
+func (e *Expander) ExpandBestEffort(input string) (string, []ExpansionError, error) {
+	return e.ExpandBestEffortContext(context.Background(), input)
+}
+
+// ExpandBestEffortContext replaces ${var} and $var in the input string,
+// just like the package-level ExpandBestEffortContext() does
+func (e *Expander) ExpandBestEffortContext(ctx context.Context, input string) (string, []ExpansionError, error) {
+	ctx, done := e.withTimeout(ctx)
+	cb := e.callbacks()
+	retval, diagnostics, err := ExpandBestEffortContext(ctx, input, cb)
+	err = done(err)
+	for i := range diagnostics {
+		diagnostics[i] = cb.maskError(diagnostics[i]).(ExpansionError)
+		e.stats.countError(diagnostics[i])
+	}
+	err = cb.maskError(err)
+	e.stats.countError(err)
+	return retval, diagnostics, err
+}
+
+// recovered returns how many problems we have recovered from so far
+func (cb ExpansionCallbacks) recovered() int {
+	if cb.diagnostics == nil {
+		return 0
+	}
+
+	return len(*cb.diagnostics)
+}
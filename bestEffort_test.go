@@ -0,0 +1,169 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandBestEffortLeavesBrokenSpansAsTheyAre(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"A": "a", "B": "b"})
+	input := "x ${U:?unset} $A ${V:?also} ${B}"
+	expectedResult := "x ${U:?unset} a ${V:?also} b"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, diagnostics, err := ExpandBestEffort(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Len(t, diagnostics, 2)
+	assert.Equal(t, 2, diagnostics[0].Offset)
+	assert.Equal(t, "${U:?unset}", diagnostics[0].Substring)
+	assert.True(t, errors.Is(diagnostics[0], ErrVarRequired{}))
+	assert.Equal(t, "U: unset", diagnostics[0].Error())
+	assert.Equal(t, 17, diagnostics[1].Offset)
+	assert.Equal(t, "${V:?also}", diagnostics[1].Substring)
+}
+
+func TestExpandBestEffortReportsLinesAndColumns(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"A": "a"})
+	unit := NewExpander(cb, WithStrict())
+	input := "line1\n${U?} $A ${"
+	expectedResult := "line1\n${U?} a ${"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, diagnostics, err := unit.ExpandBestEffort(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Len(t, diagnostics, 2)
+	assert.Equal(t, 2, diagnostics[0].Line)
+	assert.Equal(t, 1, diagnostics[0].Column)
+	assert.Equal(t, 2, diagnostics[1].Line)
+	assert.Equal(t, 10, diagnostics[1].Column)
+	assert.IsType(t, ErrMismatchedBrace{}, diagnostics[1].Err)
+}
+
+func TestExpandBestEffortReturnsNoDiagnosticsWhenNothingGoesWrong(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"A": "a"})
+	input := "hello $A"
+	expectedResult := "hello a"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, diagnostics, err := ExpandBestEffort(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Empty(t, diagnostics)
+}
+
+func TestExpandBestEffortStillStopsWhenTheContextIsCancelled(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cb := NewMapCallbacks(map[string]string{"A": "a"})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, _, err := ExpandBestEffortContext(ctx, "$A", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestExpanderExpandBestEffortMasksSecrets(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"TOKEN": "hunter2"})
+	unit := NewExpander(cb, WithSecrets("TOKEN"))
+	input := "${TOKEN} ${U:?${TOKEN}}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, diagnostics, err := unit.ExpandBestEffort(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Len(t, diagnostics, 1)
+	assert.NotContains(t, diagnostics[0].Error(), "hunter2")
+}
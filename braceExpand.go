@@ -0,0 +1,96 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "context"
+
+// BraceExpand performs UNIX shell brace expansion on the input, and
+// nothing else. It returns the list of words that the input expands
+// into, e.g. `web{01..03}.example.com` gives you `web01.example.com`,
+// `web02.example.com` and `web03.example.com`.
+//
+// The input is split into words first, just like a command line, and
+// each word is brace expanded on its own. Quotes and backslashes stop
+// braces from being expanded, and are then removed, just like a UNIX
+// shell does. Variables such as ${var} are left as they are.
+//
+// BraceExpand() understands the same options as NewExpander(). For
+// example, WithStrict() makes it an error if the braces do not match
+// up, and WithShellOpts(ShellOpts{NoBraceExpand: true}) switches brace
+// expansion off.
+//
+// It returns ErrUnterminatedQuote if a quote is never closed.
+func BraceExpand(input string, opts ...Option) ([]string, error) {
+	e := NewExpander(ExpansionCallbacks{}, opts...)
+	ctx, done := e.withTimeout(context.Background())
+	retval, err := braceExpand(input, ctx, e.callbacks())
+	return retval, done(err)
+}
+
+func braceExpand(input string, ctx context.Context, cb ExpansionCallbacks) ([]string, error) {
+	enabled := cb.dialect().braceExpansion
+
+	if enabled && cb.strict() {
+		err := checkBraces(input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	words, err := splitWords(input)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := newBraceLimiter(ctx, cb.budget)
+	retval := []string{}
+	for _, word := range words {
+		expanded := []string{word.text}
+		if enabled {
+			expanded, err = expandBracesInWord(word.text, limits)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, text := range expanded {
+			text, _ = unquoteWord(text)
+			retval = append(retval, text)
+		}
+	}
+
+	return retval, nil
+}
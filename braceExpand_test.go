@@ -0,0 +1,133 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBraceExpandReturnsEachWord(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[string][]string{
+		"web{01..03}.example.com":   {"web01.example.com", "web02.example.com", "web03.example.com"},
+		"a{b,c} d{1..2}":            {"ab", "ac", "d1", "d2"},
+		"{x,y}{1,2}":                {"x1", "x2", "y1", "y2"},
+		"a{b,{c,d}}e":               {"abe", "ace", "ade"},
+		"no-braces":                 {"no-braces"},
+		"'{a,b}' \\{c,d} \"{e,f}\"": {"{a,b}", "{c,d}", "{e,f}"},
+		"${HOME}{1,2}":              {"${HOME}1", "${HOME}2"},
+		"{single}":                  {"{single}"},
+		"${'}":                      {"${'}"},
+		"*${))'#}":                  {"*${))'#}"},
+		"${\"}{1,2}":                {"${\"}1", "${\"}2"},
+		"{a,b}${X:-'}":              {"a${X:-'}", "b${X:-'}"},
+		"":                          {},
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := BraceExpand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestBraceExpandSupportsNoBraceExpand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedResult := []string{"a{b,c}", "d"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := BraceExpand("a{b,c} d", WithShellOpts(ShellOpts{NoBraceExpand: true}))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestBraceExpandReportsMismatchedBracesInStrictMode(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	lenientResult, lenientErr := BraceExpand("a{b,c")
+	_, strictErr := BraceExpand("a{b,c", WithStrict())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, lenientErr)
+	assert.Equal(t, []string{"a{b,c"}, lenientResult)
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(strictErr, &expErr))
+	assert.Equal(t, PhaseBraceExpansion, expErr.Phase)
+}
+
+func TestBraceExpandReportsUnterminatedQuotes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := BraceExpand("a{b,c} 'oops")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.IsType(t, ErrUnterminatedQuote{}, err)
+}
@@ -0,0 +1,206 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// posixCharClasses are the [:class:] names that can appear inside a
+// bracket expression, and what we hand to Golang's regexp package for
+// each one
+//
+// regexp's own [:alpha:] and friends only match ASCII characters, so we
+// swap the letter classes for their Unicode equivalents. The glob package
+// escapes any {, which is why [:upper:] and [:lower:] are spelled out
+// range by range instead of as \p{Lu} and \p{Ll}.
+var posixCharClasses = map[string]string{
+	"alnum":  `\pL\pN`,
+	"alpha":  `\pL`,
+	"ascii":  "[:ascii:]",
+	"blank":  "[:blank:]",
+	"cntrl":  "[:cntrl:]",
+	"digit":  "[:digit:]",
+	"graph":  "[:graph:]",
+	"lower":  unicodeClassMembers(unicode.Lower),
+	"print":  "[:print:]",
+	"punct":  "[:punct:]",
+	"space":  "[:space:]",
+	"upper":  unicodeClassMembers(unicode.Upper),
+	"word":   "[:word:]",
+	"xdigit": "[:xdigit:]",
+}
+
+// unicodeClassMembers returns the contents of a bracket expression that
+// matches every character in the given Unicode table
+func unicodeClassMembers(table *unicode.RangeTable) string {
+	var buf strings.Builder
+
+	addRange := func(lo, hi, stride rune) {
+		if stride == 1 {
+			buf.WriteRune(lo)
+			if hi > lo {
+				buf.WriteByte('-')
+				buf.WriteRune(hi)
+			}
+			return
+		}
+		for c := lo; c <= hi; c += stride {
+			buf.WriteRune(c)
+		}
+	}
+
+	for _, r := range table.R16 {
+		addRange(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range table.R32 {
+		addRange(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+
+	return buf.String()
+}
+
+// translateBracketExpressions rewrites any bracket expressions in the
+// given glob pattern, so that the glob package treats them the way that
+// UNIX shells do:
+//
+// - [!...] is a negated set, just like [^...]
+// - *, ? and \ inside a bracket expression are just characters
+// - [:class:] is a POSIX character class; one that does not exist
+// matches nothing
+//
+// a [ with no matching ] is left alone, for the glob package to reject
+func translateBracketExpressions(pattern string) string {
+	// nothing to do?
+	if strings.IndexByte(pattern, '[') < 0 {
+		return pattern
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			// escaped characters are copied across as they are
+			end := i + 1
+			if end < len(pattern) {
+				_, w := utf8.DecodeRuneInString(pattern[end:])
+				end += w
+			}
+			buf.WriteString(pattern[i:end])
+			i = end - 1
+		case '[':
+			class, end, ok := translateBracketExpression(pattern[i:])
+			if !ok {
+				buf.WriteByte('[')
+				continue
+			}
+			buf.WriteString(class)
+			i += end - 1
+		default:
+			buf.WriteByte(pattern[i])
+		}
+	}
+
+	return buf.String()
+}
+
+// translateBracketExpression rewrites the bracket expression at the
+// start of the input, and tells you how long it was
+//
+// it returns false if the [ is not the start of a bracket expression
+func translateBracketExpression(input string) (string, int, bool) {
+	var members strings.Builder
+	negated := false
+
+	i := 1
+	if i < len(input) && (input[i] == '!' || input[i] == '^') {
+		negated = true
+		i++
+	}
+
+	// a ] straight after the [ (or [!) is part of the set
+	first := i
+	for i < len(input) && (input[i] != ']' || i == first) {
+		// POSIX character class?
+		if strings.HasPrefix(input[i:], "[:") {
+			classEnd := strings.Index(input[i+2:], ":]")
+			if classEnd >= 0 {
+				name := input[i+2 : i+2+classEnd]
+				members.WriteString(posixCharClasses[name])
+				i += classEnd + 4
+				continue
+			}
+		}
+
+		c, w := utf8.DecodeRuneInString(input[i:])
+		escaped := c == '\\' && i+1 < len(input)
+		if escaped {
+			i++
+			c, w = utf8.DecodeRuneInString(input[i:])
+		}
+		switch {
+		case c == '*', c == '?', c == '\\', c == '[', c == ']', c == '^':
+			members.WriteByte('\\')
+		case c == '-' && escaped:
+			// \- is a character, not a range
+			members.WriteByte('\\')
+		}
+		// we copy the original bytes, in case they are not valid UTF-8
+		members.WriteString(input[i : i+w])
+		i += w
+	}
+
+	// no closing ]? then it isn't a bracket expression
+	if i >= len(input) {
+		return "", 0, false
+	}
+
+	// a set with nothing in it (because its only class does not exist)
+	// matches nothing, and its opposite matches anything
+	if members.Len() == 0 {
+		if negated {
+			return `[\s\S]`, i + 1, true
+		}
+		return `[^\s\S]`, i + 1, true
+	}
+
+	if negated {
+		return "[^" + members.String() + "]", i + 1, true
+	}
+	return "[" + members.String() + "]", i + 1, true
+}
@@ -0,0 +1,124 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateBracketExpressions(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := map[string]string{
+		"no brackets":        "no brackets",
+		"[[:alpha:]]*":       `[\pL]*`,
+		"[![:digit:]_]":      "[^[:digit:]_]",
+		"[*?]":               `[\*\?]`,
+		"[]a]":               `[\]a]`,
+		`[a\-z]`:             `[a\-z]`,
+		"[[:bogus:]]":        `[^\s\S]`,
+		"[![:bogus:]]":       `[\s\S]`,
+		"a[b":                "a[b",
+		`\[[:alpha:]]`:       `\[[:alpha:]]`,
+		"[[:digit:][:lower]": `[[:digit:]\[:lower]`,
+	}
+
+	for testData, expectedResult := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := translateBracketExpressions(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult, testData)
+	}
+}
+
+func TestPatternOperatorsSupportPOSIXCharacterClasses(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := []string{
+		"${PARAM1#[[:alpha:]]}",
+		"${PARAM1##*[[:digit:]]}",
+		"${PARAM1%[[:digit:]]*}",
+		"${PARAM1//[[:space:]]/_}",
+		"${PARAM1//[![:alnum:]]/}",
+		"${PARAM1//[[:punct:][:blank:]]/.}",
+		"${PARAM1//[a[:digit:]]/#}",
+		"${PARAM1//[[:bogus:]]/!}",
+		"${PARAM1^^[[:lower:]]}",
+		"${PARAM1,,[[:upper:]]}",
+		"${PARAM1//[*?]/+}",
+	}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		shellCase := shelltest.Case{
+			Vars: map[string]string{
+				"PARAM1": "ab1 C2-d*?",
+			},
+			Input: `"` + testData + `"`,
+		}
+		cb := ExpansionCallbacks{
+			LookupVar: shellCase.LookupVar,
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Expand(testData, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		shellActualResult, err := shelltest.Run("bash", &shellCase)
+		if err != nil {
+			t.Skip("bash is not available")
+		}
+		assert.Equal(t, shellActualResult, actualResult, shelltest.Script(&shellCase))
+	}
+}
@@ -0,0 +1,262 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// BudgetLimit identifies one of the limits that WithBudget() sets
+type BudgetLimit int
+
+// these are the limits that WithBudget() sets
+const (
+	// BudgetExpansions limits how many parameter, tilde and brace
+	// expansions we perform; each word that brace expansion generates
+	// counts as one expansion
+	BudgetExpansions BudgetLimit = iota + 1
+
+	// BudgetLookups limits how many times we ask LookupVar and
+	// MatchVarNames for variables
+	BudgetLookups
+
+	// BudgetGlobMatches limits how many times we match a glob pattern
+	// against a value
+	BudgetGlobMatches
+
+	// BudgetParams limits how many parameter expansions we perform
+	BudgetParams
+
+	// BudgetCallbacks limits how many times we call your callbacks
+	BudgetCallbacks
+)
+
+func (l BudgetLimit) String() string {
+	switch l {
+	case BudgetExpansions:
+		return "expansions"
+	case BudgetLookups:
+		return "lookups"
+	case BudgetGlobMatches:
+		return "glob matches"
+	case BudgetParams:
+		return "parameter expansions"
+	case BudgetCallbacks:
+		return "callback invocations"
+	default:
+		return "unknown limit"
+	}
+}
+
+// budgetLimits holds the limits that WithBudget(), WithMaxParamExpansions()
+// and WithMaxCallbacks() set
+//
+// zero means no limit
+type budgetLimits struct {
+	expansions  int
+	lookups     int
+	globMatches int
+	params      int
+	callbacks   int
+}
+
+// expansionBudget keeps track of how much of its budget a single call
+// to the Expander has spent
+//
+// the zero value (nil) is valid, and simply means "no limits"
+//
+// it is safe to share between goroutines, so that ExpandSlice() and
+// ExpandMap() can expand several entries at once
+type expansionBudget struct {
+	// these come first, so that they are 64-bit aligned for the
+	// sync/atomic package
+	expansions  int64
+	lookups     int64
+	globMatches int64
+	params      int64
+	callbacks   int64
+
+	limits budgetLimits
+
+	// the first limit that we went over
+	mu  sync.Mutex
+	err error
+}
+
+func newExpansionBudget(limits budgetLimits) *expansionBudget {
+	return &expansionBudget{limits: limits}
+}
+
+// spend uses up one unit of the given limit
+//
+// it returns ErrBudgetExceeded once the limit has been used up
+func (b *expansionBudget) spend(limit BudgetLimit) error {
+	// do we have a budget?
+	if b == nil {
+		return nil
+	}
+
+	var count *int64
+	var max int
+	switch limit {
+	case BudgetExpansions:
+		count, max = &b.expansions, b.limits.expansions
+	case BudgetLookups:
+		count, max = &b.lookups, b.limits.lookups
+	case BudgetGlobMatches:
+		count, max = &b.globMatches, b.limits.globMatches
+	case BudgetParams:
+		count, max = &b.params, b.limits.params
+	case BudgetCallbacks:
+		count, max = &b.callbacks, b.limits.callbacks
+	}
+	if max <= 0 || atomic.AddInt64(count, 1) <= int64(max) {
+		return nil
+	}
+
+	// some of the places that spend our budget have no way to return
+	// an error, so we remember it for later
+	err := ErrBudgetExceeded{Limit: limit, Max: max}
+	b.mu.Lock()
+	if b.err == nil {
+		b.err = err
+	}
+	b.mu.Unlock()
+
+	return err
+}
+
+// spendParam uses up one parameter expansion, which also counts as one
+// of our BudgetExpansions
+func (b *expansionBudget) spendParam() error {
+	err := b.spend(BudgetExpansions)
+	if err != nil {
+		return err
+	}
+
+	return b.spend(BudgetParams)
+}
+
+// spendCallback uses up one call to the caller's callbacks
+//
+// callbacks that cannot return an error should act as if they found
+// nothing; the caller finds out via exceeded()
+func (b *expansionBudget) spendCallback() error {
+	return b.spend(BudgetCallbacks)
+}
+
+// spendGlobMatch uses up one of our BudgetGlobMatches
+//
+// it also stops if the caller's context has run out: a single ${...},
+// such as ${var//pattern/x}, can match its pattern thousands of times
+func (cb ExpansionCallbacks) spendGlobMatch() error {
+	err := cb.context().Err()
+	if err != nil {
+		return err
+	}
+
+	return cb.budget.spend(BudgetGlobMatches)
+}
+
+// exceeded returns ErrBudgetExceeded if we have gone over any of our
+// limits
+func (b *expansionBudget) exceeded() error {
+	// do we have a budget?
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// WithBudget limits how much work the Expander will do for each call
+// to Expand() (or ExpandContext(), ExpandArgs(), ExpandSlice() and so
+// on), so that a single hostile template cannot use up unlimited CPU
+// time:
+//
+// - maxExpansions limits how many parameter, tilde and brace expansions
+// we perform; each word that brace expansion generates counts as one, so
+// that {1..10000000} cannot build millions of words
+// - maxLookups limits how many times we ask LookupVar and MatchVarNames
+// for variables
+// - maxGlobMatches limits how many times we match a glob pattern against
+// a value (for example, ${var^^[a-z]} matches the pattern against each
+// character of the value)
+//
+// Set any of these to zero for no limit. ExpandSlice() and ExpandMap()
+// share the budget across all of their entries.
+//
+// Once a limit has been used up, expansion stops, and you get back an
+// ErrBudgetExceeded that tells you which limit it was.
+func WithBudget(maxExpansions, maxLookups, maxGlobMatches int) Option {
+	return func(opts *options) {
+		opts.budget.expansions = maxExpansions
+		opts.budget.lookups = maxLookups
+		opts.budget.globMatches = maxGlobMatches
+	}
+}
+
+// WithMaxParamExpansions limits how many parameter expansions (such as
+// $var, ${var:-word} or %VAR%) the Expander will perform for each call,
+// in the same way that WithBudget() limits the other kinds of work.
+//
+// Parameter expansions also count towards WithBudget()'s maxExpansions.
+// Set it to zero for no limit. Once it has been used up, you get back
+// an ErrBudgetExceeded for BudgetParams.
+func WithMaxParamExpansions(max int) Option {
+	return func(opts *options) {
+		opts.budget.params = max
+	}
+}
+
+// WithMaxCallbacks limits how many times the Expander will call your
+// callbacks for each call, so that a template cannot hammer an expensive
+// backend (such as a remote secrets store) thousands of times.
+//
+// Every call to one of your ExpansionCallbacks counts, whether it is
+// LookupVar, MatchVarNames, AssignToVar or any of the others. Lookups
+// that WithMemoizedLookups() answers from its cache do not count.
+//
+// Set it to zero for no limit. Once it has been used up, you get back
+// an ErrBudgetExceeded for BudgetCallbacks.
+func WithMaxCallbacks(max int) Option {
+	return func(opts *options) {
+		opts.budget.callbacks = max
+	}
+}
@@ -0,0 +1,446 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func budgetTestVars() map[string]string {
+	return map[string]string{
+		"HOME":   "/home/me",
+		"PARAM1": "foo",
+		"PARAM2": "hello world",
+	}
+}
+
+func TestWithBudgetLetsExpansionFinishWithinBudget(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithBudget(5, 3, 11))
+	testData := "~/${PARAM1} x{a,b} ${PARAM2^^[a-z]}"
+	expectedResult := "/home/me/foo xa xb HELLO WORLD"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestWithBudgetGivesEachCallItsOwnBudget(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithBudget(2, 2, 0))
+	testData := "$PARAM1 $PARAM1"
+	expectedResult := "foo foo"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	for i := 0; i < 3; i++ {
+		actualResult, err := unit.Expand(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, expectedResult, actualResult)
+	}
+}
+
+func TestWithBudgetLimitsExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithBudget(2, 0, 0))
+	testData := "${UNSET:-${UNSET:-${PARAM1}}}"
+	expectedErr := ErrBudgetExceeded{Limit: BudgetExpansions, Max: 2}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrBudgetExceeded{}))
+	var budgetErr ErrBudgetExceeded
+	assert.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, expectedErr, budgetErr)
+	assert.Equal(t, "expansion budget exceeded: more than 2 expansions", err.Error())
+}
+
+func TestWithBudgetLimitsLookups(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithBudget(0, 2, 0))
+	testData := "$PARAM1 $PARAM2 ${!PARAM*}"
+	expectedErr := ErrBudgetExceeded{Limit: BudgetLookups, Max: 2}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var budgetErr ErrBudgetExceeded
+	assert.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, expectedErr, budgetErr)
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err, &expErr))
+	assert.Equal(t, 16, expErr.Offset)
+}
+
+func TestWithBudgetLimitsGlobMatches(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithBudget(0, 0, 10))
+	testData := "${PARAM2^^[a-z]}"
+	expectedErr := ErrBudgetExceeded{Limit: BudgetGlobMatches, Max: 10}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var budgetErr ErrBudgetExceeded
+	assert.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, expectedErr, budgetErr)
+}
+
+func TestWithBudgetStopsHugeBraceExpansions(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := []string{
+		"{1..1000000}",
+		"{1..200}{1..200}{1..200}",
+		"x{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}",
+	}
+	expectedErr := ErrBudgetExceeded{Limit: BudgetExpansions, Max: 100}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		unit := NewExpander(ExpansionCallbacks{}, WithBudget(100, 100, 100))
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, expandErr := unit.Expand(testData)
+		_, argsErr := unit.ExpandArgs(testData)
+		_, braceErr := BraceExpand(testData, WithBudget(100, 100, 100))
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		for _, err := range []error{expandErr, argsErr, braceErr} {
+			var budgetErr ErrBudgetExceeded
+			assert.True(t, errors.As(err, &budgetErr), testData)
+			assert.Equal(t, expectedErr, budgetErr, testData)
+		}
+	}
+}
+
+func TestWithBudgetChargesForEachBraceExpansionWord(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "{1..100}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, okErr := NewExpander(ExpansionCallbacks{}, WithBudget(100, 0, 0)).Expand(testData)
+	_, overErr := NewExpander(ExpansionCallbacks{}, WithBudget(99, 0, 0)).Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, okErr)
+	assert.True(t, errors.Is(overErr, ErrBudgetExceeded{}))
+}
+
+func TestWithBudgetAppliesToExpandArgs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithBudget(3, 0, 0))
+	testData := "~/x $PARAM1 {a,b} $PARAM2"
+	expectedErr := ErrBudgetExceeded{Limit: BudgetExpansions, Max: 3}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.ExpandArgs(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var budgetErr ErrBudgetExceeded
+	assert.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, expectedErr, budgetErr)
+}
+
+func TestWithBudgetIsSharedAcrossExpandSlice(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithBudget(0, 3, 0), WithWorkers(2))
+	testData := []string{"$PARAM1", "$PARAM1", "$PARAM1", "$PARAM1"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.ExpandSlice(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	sliceErr, ok := err.(ErrSliceExpansion)
+	assert.True(t, ok)
+	assert.NotEmpty(t, sliceErr.Errors)
+	for _, entryErr := range sliceErr.Errors {
+		assert.True(t, errors.Is(entryErr, ErrBudgetExceeded{}))
+	}
+}
+
+func TestWithMaxParamExpansionsLimitsParameterExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithMaxParamExpansions(2))
+	expectedErr := ErrBudgetExceeded{Limit: BudgetParams, Max: 2}
+
+	// ----------------------------------------------------------------
+	// perform the change
+	//
+	// brace and tilde expansions do not count
+
+	okResult, okErr := unit.Expand("~ {a,b} $PARAM1 ${PARAM2}")
+	_, err := unit.Expand("$PARAM1 ${PARAM2} ${PARAM3:-$PARAM1}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, okErr)
+	assert.Equal(t, "/home/me a b foo hello world", okResult)
+
+	var budgetErr ErrBudgetExceeded
+	assert.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, expectedErr, budgetErr)
+}
+
+func TestWithMaxParamExpansionsAppliesToExpandArgs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithMaxParamExpansions(1))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.ExpandArgs("$PARAM1 $PARAM2")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrBudgetExceeded{}))
+}
+
+func TestWithMaxCallbacksLimitsCallbackInvocations(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	calls := 0
+	cb := newTestCallbacks(budgetTestVars())
+	lookupVar := cb.LookupVar
+	cb.LookupVar = func(key string) (string, bool) {
+		calls++
+		return lookupVar(key)
+	}
+	unit := NewExpander(cb, WithMaxCallbacks(3))
+	expectedErr := ErrBudgetExceeded{Limit: BudgetCallbacks, Max: 3}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("$PARAM1 ~stuart $PARAM2 $PARAM1 $PARAM2")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var budgetErr ErrBudgetExceeded
+	assert.True(t, errors.As(err, &budgetErr))
+	assert.Equal(t, expectedErr, budgetErr)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWithMaxCallbacksDoesNotCountCachedLookups(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(budgetTestVars()), WithMaxCallbacks(2), WithMemoizedLookups())
+	expectedResult := "foo foo foo hello world hello world"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("$PARAM1 $PARAM1 $PARAM1 $PARAM2 $PARAM2")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestWithMaxCallbacksAppliesToArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(map[string]string{"a": "1"}), WithMaxCallbacks(2))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, exprErr := unit.Expand("$((a + a + a))")
+	_, letErr := unit.EvalLet([]string{"a + a + a"})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(exprErr, ErrBudgetExceeded{}))
+	assert.True(t, errors.Is(letErr, ErrBudgetExceeded{}))
+}
+
+func TestWithBudgetKeepsOtherLimits(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(
+		newTestCallbacks(budgetTestVars()),
+		WithMaxParamExpansions(1),
+		WithMaxCallbacks(1),
+		WithBudget(100, 100, 100),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("$PARAM1 $PARAM2")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrBudgetExceeded{}))
+}
+
+func TestBudgetLimitString(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[BudgetLimit]string{
+		BudgetExpansions:  "expansions",
+		BudgetLookups:     "lookups",
+		BudgetGlobMatches: "glob matches",
+		BudgetParams:      "parameter expansions",
+		BudgetCallbacks:   "callback invocations",
+		BudgetLimit(0):    "unknown limit",
+	}
+
+	for limit, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := limit.String()
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult)
+	}
+}
@@ -0,0 +1,250 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "strings"
+
+// SpanKind tells you what a Span is, for highlighting purposes
+type SpanKind int
+
+// these are the kinds of Span that Classify() produces
+const (
+	// SpanText is plain text, that expansion does not change
+	SpanText SpanKind = iota + 1
+
+	// SpanVarName is the name of a variable, such as HOME in ${HOME}
+	SpanVarName
+
+	// SpanOperator is the punctuation of an expansion, such as $, ${,
+	// :-, // and }
+	SpanOperator
+
+	// SpanWord is the word that an operator uses, such as the default
+	// value in ${HOME:-/root}, or the replacement string in
+	// ${PATH//:/ }
+	SpanWord
+
+	// SpanPattern is a glob pattern, such as *.txt in ${FILE%*.txt}
+	SpanPattern
+
+	// SpanArithmetic is an arithmetic expression, such as 1+2 in
+	// $((1+2)) or $[1+2]
+	SpanArithmetic
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanText:
+		return "text"
+	case SpanVarName:
+		return "var-name"
+	case SpanOperator:
+		return "operator"
+	case SpanWord:
+		return "word"
+	case SpanPattern:
+		return "pattern"
+	case SpanArithmetic:
+		return "arithmetic"
+	default:
+		return "unknown"
+	}
+}
+
+// Span is a classified part of the input string, as found by
+// Classify()
+type Span struct {
+	Kind SpanKind
+
+	// Text is the span, exactly as it appears in the input string
+	Text string
+
+	// Start and End are the byte offsets of the span in the input
+	// string
+	Start int
+	End   int
+}
+
+// Classify breaks the input string up into spans, and tells you what
+// each one is. It is built on top of Tokenize(), and is meant for
+// language servers and editors that want to highlight expansions
+// inside other files (such as YAML or JSON).
+//
+// Like Tokenize(), the spans cover the whole input string, in order,
+// with no gaps, and Classify() never fails.
+func Classify(input string) []Span {
+	sc := spanClassifier{}
+
+	for _, token := range Tokenize(input) {
+		switch token.Kind {
+		case TokenVarRef:
+			sc.classifyVarRef(token)
+		case TokenOperator:
+			sc.classifyOperator(token)
+		case TokenArithmetic:
+			sc.classifyArithmetic(token)
+		default:
+			sc.emit(sc.wordKind(), token.Text, token.Start)
+		}
+	}
+
+	return sc.spans
+}
+
+// spanClassifier holds the state that Classify() needs
+type spanClassifier struct {
+	spans []Span
+
+	// what kind of span each open ${...} contains at the moment
+	stack []SpanKind
+}
+
+// wordKind tells you how to classify text at the current position
+func (sc *spanClassifier) wordKind() SpanKind {
+	if len(sc.stack) == 0 {
+		return SpanText
+	}
+
+	return sc.stack[len(sc.stack)-1]
+}
+
+// classifyVarRef splits a variable reference into its punctuation and
+// the variable's name
+func (sc *spanClassifier) classifyVarRef(token Token) {
+	text := token.Text
+
+	// a var ref that ends in } is a complete ${...}; one that doesn't
+	// has an operator after it
+	closed := strings.HasSuffix(text, "}")
+	nameLimit := len(text)
+	if closed {
+		nameLimit--
+	}
+
+	// skip over the punctuation at the start
+	nameStart := 1
+	if strings.HasPrefix(text, "${") {
+		nameStart = 2
+
+		// ${#var} and ${!var}, but not ${#} or ${!}
+		if nameStart < nameLimit && (text[nameStart] == '#' || text[nameStart] == '!') {
+			_, _, ok := matchParam(text[:nameLimit], nameStart+1)
+			if ok {
+				nameStart++
+			}
+		}
+	}
+
+	_, nameEnd, ok := matchParam(text, nameStart)
+	if !ok {
+		sc.emit(SpanOperator, text, token.Start)
+		return
+	}
+
+	// inside braces, positional params can have more than one digit
+	if nameStart > 1 {
+		for nameEnd < nameLimit && isNumericChar(rune(text[nameEnd])) && isNumericChar(rune(text[nameStart])) {
+			nameEnd++
+		}
+	}
+
+	sc.emit(SpanOperator, text[:nameStart], token.Start)
+	sc.emit(SpanVarName, text[nameStart:nameEnd], token.Start+nameStart)
+	sc.emit(SpanOperator, text[nameEnd:], token.Start+nameEnd)
+
+	// is this the start of a ${...} with an operator?
+	if nameStart > 1 && !closed {
+		sc.stack = append(sc.stack, SpanWord)
+	}
+}
+
+// classifyOperator works out what the text after an operator is
+func (sc *spanClassifier) classifyOperator(token Token) {
+	sc.emit(SpanOperator, token.Text, token.Start)
+
+	if len(sc.stack) == 0 {
+		return
+	}
+	top := len(sc.stack) - 1
+
+	switch token.Text {
+	case "}":
+		sc.stack = sc.stack[:top]
+	case "#", "##", "%", "%%", "/", "//", "/#", "/%", "^", "^^", ",", ",,":
+		// the / between the pattern and the replacement string
+		if sc.stack[top] == SpanPattern && token.Text == "/" {
+			sc.stack[top] = SpanWord
+			return
+		}
+		sc.stack[top] = SpanPattern
+	default:
+		sc.stack[top] = SpanWord
+	}
+}
+
+// classifyArithmetic splits an arithmetic expansion into its
+// punctuation and the expression inside it
+func (sc *spanClassifier) classifyArithmetic(token Token) {
+	text := token.Text
+
+	// $[...] is the old form of $((...))
+	delimLen := 3
+	if strings.HasPrefix(text, "$[") {
+		delimLen = 2
+	}
+	closeLen := delimLen - 1
+
+	sc.emit(SpanOperator, text[:delimLen], token.Start)
+	sc.emit(SpanArithmetic, text[delimLen:len(text)-closeLen], token.Start+delimLen)
+	sc.emit(SpanOperator, text[len(text)-closeLen:], token.Start+len(text)-closeLen)
+}
+
+// emit adds a span, joining it onto the previous span if they are
+// the same kind of text
+func (sc *spanClassifier) emit(kind SpanKind, text string, start int) {
+	if text == "" {
+		return
+	}
+
+	last := len(sc.spans) - 1
+	if last >= 0 && kind != SpanOperator && sc.spans[last].Kind == kind && sc.spans[last].End == start {
+		sc.spans[last].Text += text
+		sc.spans[last].End += len(text)
+		return
+	}
+
+	sc.spans = append(sc.spans, Span{kind, text, start, start + len(text)})
+}
@@ -0,0 +1,250 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifySplitsDefaultValueExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "home: ${HOME:-/root}"
+	expectedResult := []Span{
+		{SpanText, "home: ", 0, 6},
+		{SpanOperator, "${", 6, 8},
+		{SpanVarName, "HOME", 8, 12},
+		{SpanOperator, ":-", 12, 14},
+		{SpanWord, "/root", 14, 19},
+		{SpanOperator, "}", 19, 20},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Classify(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestClassifyFindsGlobPatterns(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "${FILE%*.txt} ${PATH//:/ }"
+	expectedResult := []Span{
+		{SpanOperator, "${", 0, 2},
+		{SpanVarName, "FILE", 2, 6},
+		{SpanOperator, "%", 6, 7},
+		{SpanPattern, "*.txt", 7, 12},
+		{SpanOperator, "}", 12, 13},
+		{SpanText, " ", 13, 14},
+		{SpanOperator, "${", 14, 16},
+		{SpanVarName, "PATH", 16, 20},
+		{SpanOperator, "//", 20, 22},
+		{SpanPattern, ":", 22, 23},
+		{SpanOperator, "/", 23, 24},
+		{SpanWord, " ", 24, 25},
+		{SpanOperator, "}", 25, 26},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Classify(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestClassifyHandlesNestedExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "${A:-$B/${C:+x}}y"
+	expectedResult := []Span{
+		{SpanOperator, "${", 0, 2},
+		{SpanVarName, "A", 2, 3},
+		{SpanOperator, ":-", 3, 5},
+		{SpanOperator, "$", 5, 6},
+		{SpanVarName, "B", 6, 7},
+		{SpanWord, "/", 7, 8},
+		{SpanOperator, "${", 8, 10},
+		{SpanVarName, "C", 10, 11},
+		{SpanOperator, ":+", 11, 13},
+		{SpanWord, "x", 13, 14},
+		{SpanOperator, "}", 14, 15},
+		{SpanOperator, "}", 15, 16},
+		{SpanText, "y", 16, 17},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Classify(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestClassifyFindsVarNames(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[string]string{
+		"$HOME":       "HOME",
+		"${HOME}":     "HOME",
+		"${#HOME}":    "HOME",
+		"${!PREFIX*}": "PREFIX",
+		"${!ref:-x}":  "ref",
+		"$1":          "1",
+		"${10}":       "10",
+		"$@":          "@",
+		"${#}":        "#",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		var actualResult []string
+		for _, span := range Classify(input) {
+			if span.Kind == SpanVarName {
+				actualResult = append(actualResult, span.Text)
+			}
+		}
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, []string{expectedResult}, actualResult, input)
+	}
+}
+
+func TestClassifySplitsArithmeticExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "$(( 1+2 )) $[X*2]"
+	expectedResult := []Span{
+		{SpanOperator, "$((", 0, 3},
+		{SpanArithmetic, " 1+2 ", 3, 8},
+		{SpanOperator, "))", 8, 10},
+		{SpanText, " ", 10, 11},
+		{SpanOperator, "$[", 11, 13},
+		{SpanArithmetic, "X*2", 13, 16},
+		{SpanOperator, "]", 16, 17},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Classify(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestClassifyTreatsUnexpandableTextAsPlainText(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "'$HOME' ${HOME"
+	expectedResult := []Span{{SpanText, input, 0, len(input)}}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Classify(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestSpanKindString(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[SpanKind]string{
+		SpanText:       "text",
+		SpanVarName:    "var-name",
+		SpanOperator:   "operator",
+		SpanWord:       "word",
+		SpanPattern:    "pattern",
+		SpanArithmetic: "arithmetic",
+		SpanKind(99):   "unknown",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := input.String()
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult)
+	}
+}
@@ -0,0 +1,266 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Command shellexpand expands UNIX shell strings, using the variables in
+// your environment.
+//
+// Usage:
+//
+//	shellexpand [flags] [file ...]
+//
+// It reads each file in turn (or stdin, if you don't name any files),
+// expands it, and writes the result to stdout.
+//
+// The flags are:
+//
+//	-check      check the input for syntax errors, without expanding it;
+//	            exits with status 1 if there are any
+//	-list-vars  print the names of the variables that the input refers
+//	            to, one per line, without expanding it
+//	-explain    print each step of the expansion to stderr, as it happens
+//
+// Flags can start with - or --.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	shellexpand "github.com/ganbarodigital/go_shellexpand"
+)
+
+// these are the exit statuses that we use
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// stdinName is what we call stdin in error messages
+const stdinName = "<stdin>"
+
+// input is a single file that we have been asked to work on
+type input struct {
+	name string
+	text string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, shellexpand.NewOSCallbacks()))
+}
+
+// run does all the work, and returns the exit status
+//
+// it is separate from main(), so that we can test it
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, cb shellexpand.ExpansionCallbacks) int {
+	flags := flag.NewFlagSet("shellexpand", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: shellexpand [flags] [file ...]")
+		flags.PrintDefaults()
+	}
+	check := flags.Bool("check", false, "check the input for syntax errors, without expanding it")
+	listVars := flags.Bool("list-vars", false, "print the variables that the input refers to, without expanding it")
+	explain := flags.Bool("explain", false, "print each step of the expansion to stderr")
+	err := flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	inputs, err := readInputs(flags.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "shellexpand: %s\n", err)
+		return exitFailure
+	}
+
+	// the dry-run flags never expand anything
+	if *check || *listVars {
+		status := exitOK
+		if *check {
+			status = checkInputs(inputs, stderr)
+		}
+		if *listVars {
+			err = printVars(inputs, stdout)
+			if err != nil {
+				fmt.Fprintf(stderr, "shellexpand: %s\n", err)
+				return exitFailure
+			}
+		}
+		return status
+	}
+
+	var opts []shellexpand.Option
+	var ex *explainer
+	if *explain {
+		ex = &explainer{w: stderr}
+		opts = append(opts, shellexpand.WithTrace(ex.trace))
+	}
+	expander := shellexpand.NewExpander(cb, opts...)
+
+	for _, in := range inputs {
+		output, err := expander.Expand(in.text)
+		if err != nil {
+			printError(stderr, in, err)
+			return exitFailure
+		}
+
+		// there's no point carrying on if nobody can see the results
+		// (e.g. our stdout is a pipe that has been closed)
+		_, err = io.WriteString(stdout, output)
+		if err != nil {
+			fmt.Fprintf(stderr, "shellexpand: %s\n", err)
+			return exitFailure
+		}
+		if ex != nil && ex.err != nil {
+			return exitFailure
+		}
+	}
+
+	return exitOK
+}
+
+// readInputs reads the named files, or stdin if there are none
+func readInputs(filenames []string, stdin io.Reader) ([]input, error) {
+	if len(filenames) == 0 {
+		text, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		return []input{{stdinName, string(text)}}, nil
+	}
+
+	retval := make([]input, 0, len(filenames))
+	for _, filename := range filenames {
+		text, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		retval = append(retval, input{filename, string(text)})
+	}
+
+	return retval, nil
+}
+
+// checkInputs reports every syntax error in the inputs, and returns
+// the exit status to use
+func checkInputs(inputs []input, stderr io.Writer) int {
+	status := exitOK
+	for _, in := range inputs {
+		for _, e := range shellexpand.Validate(in.text) {
+			printError(stderr, in, e)
+			status = exitFailure
+		}
+	}
+
+	return status
+}
+
+// printVars prints the names of the variables that the inputs refer
+// to, sorted, one per line
+func printVars(inputs []input, stdout io.Writer) error {
+	// we use a map to remove any duplicates
+	seen := make(map[string]bool)
+	for _, in := range inputs {
+		for _, name := range shellexpand.ReferencedVars(in.text) {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, err := fmt.Fprintln(stdout, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// explainer prints each step of the expansion
+type explainer struct {
+	w io.Writer
+
+	// err is the first error that we got when writing to w
+	err error
+}
+
+// trace is our shellexpand.TraceFunc
+func (e *explainer) trace(event shellexpand.TraceEvent) {
+	// once writing has failed, there's nobody left to read what we say
+	if e.err != nil {
+		return
+	}
+
+	var err error
+	switch event.Kind {
+	case shellexpand.TracePhase:
+		_, err = fmt.Fprintf(e.w, "%s: %q -> %q\n", event.Phase, event.Input, event.Result)
+	case shellexpand.TraceParameter:
+		_, err = fmt.Fprintf(e.w, "  %s (%s): %q\n", event.Input, event.Operator, event.Result)
+	case shellexpand.TraceArithmetic:
+		_, err = fmt.Fprintf(e.w, "  $((%s)): %q\n", event.Input, event.Result)
+	case shellexpand.TraceVarRead:
+		_, err = fmt.Fprintf(e.w, "  %s (read): %q\n", event.Name, event.Result)
+	case shellexpand.TraceAssignment:
+		_, err = fmt.Fprintf(e.w, "  %s (assign): %q\n", event.Name, event.Result)
+	}
+	e.err = err
+}
+
+// printError tells the user what went wrong, and where
+func printError(stderr io.Writer, in input, err error) {
+	var expErr shellexpand.ExpansionError
+	if !errors.As(err, &expErr) {
+		fmt.Fprintf(stderr, "%s: %s\n", in.name, err)
+		return
+	}
+
+	fmt.Fprintf(stderr, "%s:%d:%d: %s\n", in.name, expErr.Line, expErr.Column, expErr.Err)
+	fmt.Fprintln(stderr, expErr.Caret(in.text))
+}
@@ -0,0 +1,293 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	shellexpand "github.com/ganbarodigital/go_shellexpand"
+	"github.com/stretchr/testify/assert"
+)
+
+var testVars = map[string]string{
+	"COUNT": "2",
+	"HOME":  "/home/alfred",
+	"USER":  "alfred",
+}
+
+func testCallbacks() shellexpand.ExpansionCallbacks {
+	return shellexpand.ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			retval, ok := testVars[key]
+			return retval, ok
+		},
+	}
+}
+
+func TestRunExpandsStdin(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	stdin := strings.NewReader("${USER} lives in $HOME\n")
+	var stdout, stderr bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	status := run(nil, stdin, &stdout, &stderr, testCallbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, exitOK, status)
+	assert.Equal(t, "alfred lives in /home/alfred\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunListVarsDoesNotExpandAnything(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	stdin := strings.NewReader("${USER:-$LOGNAME} lives in $HOME ${!SSH_*}\n")
+	var stdout, stderr bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	status := run([]string{"--list-vars"}, stdin, &stdout, &stderr, testCallbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, exitOK, status)
+	assert.Equal(t, "HOME\nLOGNAME\nSSH_*\nUSER\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunListVarsIncludesArithmeticVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	stdin := strings.NewReader("$((COUNT + 1)) of $LIMIT\n")
+	var stdout, stderr bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	status := run([]string{"--list-vars"}, stdin, &stdout, &stderr, testCallbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, exitOK, status)
+	assert.Equal(t, "COUNT\nLIMIT\n", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunCheckReportsSyntaxErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	stdin := strings.NewReader("fine\nbroken ${USER\n")
+	var stdout, stderr bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	status := run([]string{"--check"}, stdin, &stdout, &stderr, testCallbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, exitFailure, status)
+	assert.Empty(t, stdout.String())
+	assert.True(t, strings.HasPrefix(stderr.String(), "<stdin>:2:8: "), stderr.String())
+	assert.Contains(t, stderr.String(), "broken ${USER\n       ^")
+}
+
+func TestRunCheckAcceptsValidInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	stdin := strings.NewReader("${USER} lives in $HOME\n")
+	var stdout, stderr bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	status := run([]string{"-check"}, stdin, &stdout, &stderr, testCallbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, exitOK, status)
+	assert.Empty(t, stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+func TestRunExplainPrintsEachExpansion(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	stdin := strings.NewReader("${UNSET:-$USER}")
+	var stdout, stderr bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	status := run([]string{"--explain"}, stdin, &stdout, &stderr, testCallbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, exitOK, status)
+	assert.Equal(t, "alfred", stdout.String())
+	assert.Contains(t, stderr.String(), `  ${UNSET:-$USER} (expand-with-default-value): "alfred"`)
+	assert.Contains(t, stderr.String(), `parameter expansion: "${UNSET:-$USER}" -> "alfred"`)
+}
+
+func TestRunExplainPrintsArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	stdin := strings.NewReader("$(( COUNT * 2 ))")
+	var stdout, stderr bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	status := run([]string{"--explain"}, stdin, &stdout, &stderr, testCallbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, exitOK, status)
+	assert.Equal(t, "4", stdout.String())
+	assert.Contains(t, stderr.String(), `  COUNT (read): "2"`)
+	assert.Contains(t, stderr.String(), `  $(( COUNT * 2 )): "4"`)
+}
+
+// failingWriter is an io.Writer that behaves like a closed pipe
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestRunFailsIfItCannotWriteTheResults(t *testing.T) {
+	t.Parallel()
+
+	testData := [][]string{
+		nil,
+		{"--list-vars"},
+	}
+
+	for _, args := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		stdin := strings.NewReader("${USER} lives in $HOME\n")
+		var stderr bytes.Buffer
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		status := run(args, stdin, failingWriter{}, &stderr, testCallbacks())
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, exitFailure, status, "%v", args)
+		assert.Contains(t, stderr.String(), io.ErrClosedPipe.Error(), "%v", args)
+	}
+}
+
+func TestRunExplainFailsIfItCannotWrite(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	stdin := strings.NewReader("${USER}")
+	var stdout bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	status := run([]string{"--explain"}, stdin, &stdout, failingWriter{}, testCallbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, exitFailure, status)
+}
+
+func TestRunRejectsUnknownFlags(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var stdout, stderr bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	status := run([]string{"--no-such-flag"}, strings.NewReader(""), &stdout, &stderr, testCallbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, exitUsage, status)
+	assert.Contains(t, stderr.String(), "usage: shellexpand")
+}
@@ -0,0 +1,202 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// RunCommand runs a command for command substitution, and returns what
+// it wrote to its standard output.
+//
+// args[0] is the name of the command, and the rest are its arguments.
+// They have already been expanded and split into words, in the same way
+// that ExpandArgs() does it. Nothing is passed through a shell.
+type RunCommand func(ctx context.Context, args []string) (string, error)
+
+// findCommand returns the length of the $(...) at the start of the
+// input string
+//
+// it returns false if there isn't one, or if it is never closed
+func findCommand(input string) (int, bool) {
+	if len(input) < 3 || input[0] != '$' || input[1] != '(' {
+		return 0, false
+	}
+
+	// $((...)) is arithmetic expansion, not command substitution
+	if input[2] == '(' {
+		return 0, false
+	}
+
+	depth := 0
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '\'', '"':
+			quoteEnd, ok := matchQuotes(input[i:])
+			if !ok {
+				return 0, false
+			}
+			i += quoteEnd - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		}
+	}
+
+	// if we get here, the $( was never closed
+	return 0, false
+}
+
+// findSubstitution returns the length of the $((...)), $[...] or $(...)
+// at the start of the input string
+//
+// it returns false if there isn't one, or if it is never closed
+func findSubstitution(input string) (int, bool) {
+	spanEnd, ok := findArithmetic(input)
+	if !ok {
+		spanEnd, ok = findLegacyArithmetic(input)
+	}
+	if !ok {
+		spanEnd, ok = findCommand(input)
+	}
+
+	return spanEnd, ok
+}
+
+// expandCommand runs the $(...) command, and returns what it wrote to
+// its standard output
+//
+// just like a UNIX shell, we remove any trailing newlines from the output
+func expandCommand(text string, cb ExpansionCallbacks) (string, error) {
+	// $(< file) reads the file, instead of running a command
+	filename, ok := matchFileRead(text)
+	if ok {
+		return readFile(filename, cb)
+	}
+
+	if cb.sandboxed() {
+		return "", ErrSandboxed{"command substitution"}
+	}
+
+	// we are only looking for $(< file)
+	if cb.RunCommand == nil {
+		return text, nil
+	}
+
+	args, err := ExpandArgs(text[2:len(text)-1], cb)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	err = cb.budget.spendCallback()
+	if err != nil {
+		return "", err
+	}
+	output, err := cb.RunCommand(cb.context(), args)
+	if err != nil {
+		return "", ErrCommandFailed{Command: args[0], Err: err}
+	}
+
+	cb.stats.inc(statParamsExpanded)
+	return strings.TrimRight(output, "\n"), nil
+}
+
+// errAmbiguousRedirect is what bash reports if the filename in a
+// $(< file) does not expand to exactly one word
+var errAmbiguousRedirect = errors.New("ambiguous redirect")
+
+// matchFileRead checks to see if the $(...) command is nothing but a
+// `< file` redirection, which bash treats as "read the file"
+//
+// returns the (unexpanded) filename, and `true` if it is
+func matchFileRead(text string) (string, bool) {
+	body := strings.TrimLeft(text[2:len(text)-1], " \t\n")
+	if len(body) < 2 || body[0] != '<' || body[1] == '<' || body[1] == '(' {
+		return "", false
+	}
+
+	return body[1:], true
+}
+
+// readFile expands the filename in a $(< file) command substitution, and
+// returns what is in the file
+//
+// the file is read from the filesystem that WithFS() sets, and just like
+// a UNIX shell, we remove any trailing newlines
+//
+// in the sandbox, only the WithFS() filesystem can be read
+func readFile(filename string, cb ExpansionCallbacks) (string, error) {
+	if cb.sandboxed() && !cb.hasFS() {
+		return "", ErrSandboxed{"reading files"}
+	}
+
+	args, err := ExpandArgs(filename, cb)
+	if err != nil {
+		return "", err
+	}
+	if len(args) != 1 {
+		return "", ErrCommandFailed{Command: strings.TrimSpace(filename), Err: errAmbiguousRedirect}
+	}
+
+	// every filesystem must refuse paths such as /etc/passwd and
+	// ../secret in the same way
+	name := path.Clean(args[0])
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+		return "", ErrCommandFailed{Command: strings.TrimSpace(filename), Err: err}
+	}
+
+	data, err := fs.ReadFile(cb.fs(), name)
+	if err != nil {
+		return "", ErrCommandFailed{Command: strings.TrimSpace(filename), Err: err}
+	}
+
+	cb.stats.inc(statParamsExpanded)
+	return strings.TrimRight(string(data), "\n"), nil
+}
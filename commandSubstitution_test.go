@@ -0,0 +1,164 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCommandCallbacks(commands *[][]string) ExpansionCallbacks {
+	cb := NewMapCallbacks(map[string]string{
+		"PARAM1": "hello world",
+	})
+	cb.RunCommand = func(ctx context.Context, args []string) (string, error) {
+		*commands = append(*commands, args)
+		switch args[0] {
+		case "echo":
+			return strings.Join(args[1:], " ") + "\n\n", nil
+		default:
+			return "", errors.New("command not found")
+		}
+	}
+
+	return cb
+}
+
+func TestCommandSubstitutionRunsTheCommand(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		input            string
+		expectedResult   string
+		expectedCommands [][]string
+	}{
+		{
+			input:            "$(echo a  b)",
+			expectedResult:   "a b",
+			expectedCommands: [][]string{{"echo", "a", "b"}},
+		},
+		{
+			input:            `x$(echo "$PARAM1" ')')y`,
+			expectedResult:   "xhello world )y",
+			expectedCommands: [][]string{{"echo", "hello world", ")"}},
+		},
+		{
+			input:            "${UNSET:-$(echo default)}",
+			expectedResult:   "default",
+			expectedCommands: [][]string{{"echo", "default"}},
+		},
+		{
+			input:            "$( )",
+			expectedResult:   "",
+			expectedCommands: nil,
+		},
+		{
+			input:            "$(echo unterminated",
+			expectedResult:   "$(echo unterminated",
+			expectedCommands: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		var commands [][]string
+		cb := newTestCommandCallbacks(&commands)
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Expand(testCase.input, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testCase.input)
+		assert.Equal(t, testCase.expectedResult, actualResult, testCase.input)
+		assert.Equal(t, testCase.expectedCommands, commands, testCase.input)
+	}
+}
+
+func TestCommandSubstitutionIsOffByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "$(echo hello)"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand(testData, ExpansionCallbacks{})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, testData, actualResult)
+}
+
+func TestCommandSubstitutionReportsFailedCommands(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var commands [][]string
+	cb := newTestCommandCallbacks(&commands)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Expand("abc $(missing arg) def", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrCommandFailed{}))
+	assert.Equal(t, "missing: command not found", err.Error())
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err, &expErr))
+	assert.Equal(t, PhaseCommandSubstitution, expErr.Phase)
+	assert.Equal(t, "$(missing arg)", expErr.Substring)
+}
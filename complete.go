@@ -0,0 +1,222 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"sort"
+	"strings"
+)
+
+// CompletionKind tells you what a Completion would insert
+type CompletionKind int
+
+// these are the kinds of Completion that Complete() returns
+const (
+	// CompletionVarName is the name of a variable
+	CompletionVarName CompletionKind = iota + 1
+
+	// CompletionOperator is an operator that can go after the
+	// parameter's name in ${...}, such as :- or }
+	CompletionOperator
+)
+
+func (k CompletionKind) String() string {
+	switch k {
+	case CompletionVarName:
+		return "var-name"
+	case CompletionOperator:
+		return "operator"
+	default:
+		return "unknown"
+	}
+}
+
+// Completion is a suggestion for what to type next
+type Completion struct {
+	Kind CompletionKind
+
+	// Text replaces input[Start:End]
+	Text  string
+	Start int
+	End   int
+
+	// Description is a short, human-readable explanation of what an
+	// operator does. It is empty for variable names.
+	Description string
+}
+
+// paramOpCompletions are the operators that we suggest inside ${...},
+// in the order that we suggest them
+var paramOpCompletions = []Completion{
+	{Text: "}", Description: "end of expansion"},
+	{Text: ":-", Description: "use default value if unset or empty"},
+	{Text: "-", Description: "use default value if unset"},
+	{Text: ":=", Description: "assign default value if unset or empty"},
+	{Text: "=", Description: "assign default value if unset"},
+	{Text: ":?", Description: "error if unset or empty"},
+	{Text: "?", Description: "error if unset"},
+	{Text: ":+", Description: "use alternative value if set and not empty"},
+	{Text: "+", Description: "use alternative value if set"},
+	{Text: ":", Description: "substring"},
+	{Text: "#", Description: "remove shortest matching prefix"},
+	{Text: "##", Description: "remove longest matching prefix"},
+	{Text: "%", Description: "remove shortest matching suffix"},
+	{Text: "%%", Description: "remove longest matching suffix"},
+	{Text: "/", Description: "replace first match"},
+	{Text: "//", Description: "replace all matches"},
+	{Text: "/#", Description: "replace matching prefix"},
+	{Text: "/%", Description: "replace matching suffix"},
+	{Text: "^", Description: "uppercase first character"},
+	{Text: "^^", Description: "uppercase all characters"},
+	{Text: ",", Description: "lowercase first character"},
+	{Text: ",,", Description: "lowercase all characters"},
+	{Text: "@a", Description: "describe variable's attributes"},
+	{Text: "@A", Description: "describe variable as an assignment"},
+	{Text: "@U", Description: "uppercase all characters"},
+	{Text: "@u", Description: "uppercase first character"},
+	{Text: "@L", Description: "lowercase all characters"},
+}
+
+// Complete returns suggestions for what could be typed at `cursor`,
+// which is a byte offset into the input string. It is meant for
+// interactive tools that offer autocompletion inside $VAR and ${...}.
+//
+// After a $ or ${, Complete suggests the names of variables that
+// MatchVarNames() knows about. After the name of a variable in ${...},
+// it suggests the operators that can go there too.
+//
+// It returns nil if there is nothing to suggest.
+func Complete(input string, cursor int, cb ExpansionCallbacks) []Completion {
+	if cursor < 0 || cursor > len(input) {
+		return nil
+	}
+	before := input[:cursor]
+
+	// are we in a parameter expansion?
+	dollar := strings.LastIndexByte(before, '$')
+	if dollar < 0 || isEscaped(before, dollar) {
+		return nil
+	}
+	rest := before[dollar+1:]
+	braced := strings.HasPrefix(rest, "{")
+	if braced {
+		rest = rest[1:]
+		if strings.HasPrefix(rest, "#") || strings.HasPrefix(rest, "!") {
+			rest = rest[1:]
+		}
+	}
+
+	// how much of the variable's name has been typed?
+	nameLen := 0
+	if rest != "" {
+		_, nameEnd, ok := matchName(rest)
+		if ok {
+			nameLen = nameEnd
+		}
+	}
+
+	// still typing the name?
+	if nameLen == len(rest) {
+		retval := completeVarNames(rest, cursor, cb)
+		if braced && nameLen > 0 {
+			retval = append(retval, completeParamOps("", cursor)...)
+		}
+		return retval
+	}
+
+	// still typing the operator?
+	if braced && nameLen > 0 && !strings.Contains(rest, "}") {
+		return completeParamOps(rest[nameLen:], cursor)
+	}
+
+	return nil
+}
+
+// Complete returns suggestions for what could be typed at `cursor`,
+// just like Complete() does. It only suggests variables that the
+// Expander's options allow.
+func (e *Expander) Complete(input string, cursor int) []Completion {
+	return Complete(input, cursor, e.callbacks())
+}
+
+// completeVarNames returns the variables whose names start with the
+// given prefix
+func completeVarNames(prefix string, cursor int, cb ExpansionCallbacks) []Completion {
+	names := cb.matchVarNames(prefix)
+	sort.Strings(names)
+
+	retval := []Completion{}
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+		retval = append(retval, Completion{
+			Kind:  CompletionVarName,
+			Text:  name,
+			Start: cursor - len(prefix),
+			End:   cursor,
+		})
+	}
+
+	return retval
+}
+
+// completeParamOps returns the operators that start with the given
+// partial operator
+func completeParamOps(partialOp string, cursor int) []Completion {
+	var retval []Completion
+	for _, op := range paramOpCompletions {
+		if !strings.HasPrefix(op.Text, partialOp) {
+			continue
+		}
+		op.Kind = CompletionOperator
+		op.Start = cursor - len(partialOp)
+		op.End = cursor
+		retval = append(retval, op)
+	}
+
+	return retval
+}
+
+// isEscaped returns true if input[pos] has a backslash in front of it
+func isEscaped(input string, pos int) bool {
+	backslashes := 0
+	for i := pos - 1; i >= 0 && input[i] == '\\'; i-- {
+		backslashes++
+	}
+
+	return backslashes%2 == 1
+}
@@ -0,0 +1,201 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteSuggestsVarNames(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"HOME":     "/home/stuart",
+		"HOSTNAME": "example",
+		"PATH":     "/usr/bin",
+	})
+	input := "cd $HO/bin"
+	expectedResult := []Completion{
+		{Kind: CompletionVarName, Text: "HOME", Start: 4, End: 6},
+		{Kind: CompletionVarName, Text: "HOSTNAME", Start: 4, End: 6},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Complete(input, 6, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestCompleteSuggestsVarNamesAndOperatorsInsideBraces(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"PATH": "/usr/bin",
+	})
+	input := "${PATH"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Complete(input, len(input), cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, Completion{Kind: CompletionVarName, Text: "PATH", Start: 2, End: 6}, actualResult[0])
+	assert.Equal(t, Completion{Kind: CompletionOperator, Text: "}", Start: 6, End: 6, Description: "end of expansion"}, actualResult[1])
+	assert.Len(t, actualResult, len(paramOpCompletions)+1)
+}
+
+func TestCompleteSuggestsOperatorsThatMatchWhatHasBeenTyped(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(nil)
+	input := "${PATH:}"
+	expectedResult := []string{":-", ":=", ":?", ":+", ":"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	completions := Complete(input, 7, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var actualResult []string
+	for _, completion := range completions {
+		assert.Equal(t, CompletionOperator, completion.Kind)
+		assert.Equal(t, 6, completion.Start)
+		assert.Equal(t, 7, completion.End)
+		actualResult = append(actualResult, completion.Text)
+	}
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestCompleteReturnsNilWhenThereIsNothingToSuggest(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"HOME": "/home/stuart"})
+	testData := []string{
+		"plain text",
+		`\$HO`,
+		"${HOME}",
+		"${HOME:-/ro",
+		"$HOME/",
+	}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := Complete(input, len(input), cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, actualResult, input)
+	}
+}
+
+func TestExpanderCompleteOnlySuggestsAllowedVars(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"APP_NAME":   "demo",
+		"APP_SECRET": "hunter2",
+	})
+	unit := NewExpander(cb, WithNameFilter(AllowNames("APP_NAME")))
+	expectedResult := []Completion{
+		{Kind: CompletionVarName, Text: "APP_NAME", Start: 1, End: 5},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := unit.Complete("$APP_", 5)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestCompletionKindString(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[CompletionKind]string{
+		CompletionVarName:  "var-name",
+		CompletionOperator: "operator",
+		CompletionKind(99): "unknown",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := input.String()
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult)
+	}
+}
@@ -0,0 +1,214 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NewConfigCallbacks returns a set of ExpansionCallbacks that can look
+// variables up in structured config (such as the settings that Viper
+// or koanf load from a YAML or JSON file), as well as in the ambient
+// callbacks (usually NewOSCallbacks()).
+//
+// A variable name is treated as a path through the config, with `_`
+// between each key: ${DB_HOST} is the `host` key inside the `db` map.
+// Keys are matched case-insensitively, and any character in a key that
+// cannot appear in a variable name counts as a `_`; that means
+// ${DB_MAX_CONNS} finds both db.max_conns and db.max-conns. Use a
+// number to pick an entry from a list, e.g. ${SERVERS_0_HOST}.
+//
+// Only strings, numbers and booleans can be expanded. Maps, lists and
+// nil values are treated as unset.
+//
+// The ambient callbacks are asked first, so that (just like Viper's
+// AutomaticEnv()) an environment variable overrides the config. They
+// are also used to assign to variables, and to find home directories.
+func NewConfigCallbacks(config map[string]interface{}, ambient ExpansionCallbacks) ExpansionCallbacks {
+	return ExpansionCallbacks{
+		AssignToVar:        ambient.AssignToVar,
+		AssignToVarContext: ambient.AssignToVarContext,
+		LookupVarContext: func(ctx context.Context, name string) (string, bool) {
+			var value string
+			var ok bool
+			if ambient.LookupVarContext != nil {
+				value, ok = ambient.LookupVarContext(ctx, name)
+			} else if ambient.LookupVar != nil {
+				value, ok = ambient.LookupVar(name)
+			}
+			if ok {
+				return value, true
+			}
+
+			return lookupConfigVar(config, name)
+		},
+		LookupHomeDir:        ambient.LookupHomeDir,
+		LookupHomeDirContext: ambient.LookupHomeDirContext,
+		MatchVarNamesContext: func(ctx context.Context, prefix string) []string {
+			var retval []string
+			if ambient.MatchVarNamesContext != nil {
+				retval = ambient.MatchVarNamesContext(ctx, prefix)
+			} else if ambient.MatchVarNames != nil {
+				retval = ambient.MatchVarNames(prefix)
+			}
+
+			// we use a map to remove any duplicates
+			seen := make(map[string]bool, len(retval))
+			for _, name := range retval {
+				seen[name] = true
+			}
+			for _, name := range configVarNames(config, "") {
+				if strings.HasPrefix(name, prefix) && !seen[name] {
+					retval = append(retval, name)
+				}
+			}
+
+			return retval
+		},
+	}
+}
+
+// lookupConfigVar finds the value of the named variable in the config
+func lookupConfigVar(config map[string]interface{}, name string) (string, bool) {
+	if !isName(name) {
+		return "", false
+	}
+
+	node, ok := findConfigPath(config, strings.Split(strings.ToUpper(name), "_"))
+	if !ok {
+		return "", false
+	}
+
+	return configScalar(node)
+}
+
+// findConfigPath follows the path through the config
+//
+// each key in the config can match one or more segments of the path,
+// because keys can contain underscores of their own
+func findConfigPath(node interface{}, segments []string) (interface{}, bool) {
+	if len(segments) == 0 {
+		return node, true
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		// we sort the keys, so that we always find the same value
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for i := 1; i <= len(segments); i++ {
+			want := strings.Join(segments[:i], "_")
+			for _, key := range keys {
+				if configKeyName(key) != want {
+					continue
+				}
+				retval, ok := findConfigPath(n[key], segments[i:])
+				if ok {
+					return retval, true
+				}
+			}
+		}
+	case []interface{}:
+		i, err := strconv.Atoi(segments[0])
+		if err == nil && i >= 0 && i < len(n) && strconv.Itoa(i) == segments[0] {
+			return findConfigPath(n[i], segments[1:])
+		}
+	}
+
+	return nil, false
+}
+
+// configScalar turns a value from the config into a string
+//
+// it returns false if the value is not a string, number or boolean
+func configScalar(node interface{}) (string, bool) {
+	switch n := node.(type) {
+	case string:
+		return n, true
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(n), true
+	default:
+		return "", false
+	}
+}
+
+// configVarNames returns the names of all the variables in the config,
+// sorted
+func configVarNames(node interface{}, prefix string) []string {
+	var retval []string
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, child := range n {
+			retval = append(retval, configVarNames(child, prefix+configKeyName(key)+"_")...)
+		}
+	case []interface{}:
+		for i, child := range n {
+			retval = append(retval, configVarNames(child, prefix+strconv.Itoa(i)+"_")...)
+		}
+	default:
+		name := strings.TrimSuffix(prefix, "_")
+		_, ok := configScalar(node)
+		if ok && isName(name) {
+			retval = append(retval, name)
+		}
+	}
+
+	sort.Strings(retval)
+	return retval
+}
+
+// configKeyName turns a key from the config into the form that it has
+// in a variable name
+func configKeyName(key string) string {
+	return strings.Map(
+		func(c rune) rune {
+			if isNameBodyChar(c) {
+				return c
+			}
+			return '_'
+		},
+		strings.ToUpper(key),
+	)
+}
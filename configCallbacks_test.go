@@ -0,0 +1,146 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"db": map[string]interface{}{
+			"host":      "db.example.com",
+			"port":      5432,
+			"max-conns": 10.5,
+			"ssl":       true,
+			"replicas":  []interface{}{"r1.example.com", "r2.example.com"},
+			"options":   nil,
+		},
+		"app.name": "shellexpand",
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a.example.com"},
+			map[string]interface{}{"host": "b.example.com"},
+		},
+	}
+}
+
+func TestNewConfigCallbacksLooksUpNestedKeys(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := map[string]string{
+		"${DB_HOST}":            "db.example.com",
+		"${db_host}":            "db.example.com",
+		"${DB_PORT}":            "5432",
+		"${DB_MAX_CONNS}":       "10.5",
+		"${DB_SSL}":             "true",
+		"${DB_REPLICAS_1}":      "r2.example.com",
+		"${APP_NAME}":           "shellexpand",
+		"${SERVERS_0_HOST}":     "a.example.com",
+		"${SERVERS_1_HOST}":     "b.example.com",
+		"${DB:-map}":            "map",
+		"${DB_REPLICAS:-list}":  "list",
+		"${DB_OPTIONS:-nil}":    "nil",
+		"${SERVERS_2_HOST:-no}": "no",
+		"${SERVERS_01_HOST:-x}": "x",
+		"${DB_USER:-postgres}":  "postgres",
+	}
+
+	for input, expectedResult := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		cb := NewConfigCallbacks(testConfig(), ExpansionCallbacks{})
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Expand(input, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestNewConfigCallbacksPrefersTheAmbientCallbacks(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("DB_HOST", "localhost")
+	cb := NewConfigCallbacks(testConfig(), env.Callbacks())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${DB_HOST}:${DB_PORT}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost:5432", actualResult)
+}
+
+func TestNewConfigCallbacksMatchesConfigVarNames(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("DB_HOST", "localhost")
+	env.Set("DB_USER", "alfred")
+	cb := NewConfigCallbacks(testConfig(), env.Callbacks())
+	expectedResult := "DB_HOST DB_MAX_CONNS DB_PORT DB_REPLICAS_0 DB_REPLICAS_1 DB_SSL DB_USER"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${!DB_*}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
@@ -0,0 +1,332 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ganbarodigital/go_shellexpand/arith"
+)
+
+// DependencyGraph describes how a set of named templates refer to each
+// other. Use it to work out which order to expand the templates in.
+//
+// A template depends on another template if it refers to it by name,
+// e.g. "${HOME}/bin" depends on HOME. References to variables that are
+// not in the set, and references from a template to itself (such as
+// PATH=${PATH}:/usr/local/bin), are ignored; they refer to whatever value
+// the variable had before.
+//
+// The graph only knows about references that it can see in the text of
+// the templates. ${!ref} refers to whichever variable `ref` names, and
+// that is not known until it is expanded; the graph records that the
+// template depends on `ref`, but not on the variable that `ref` points
+// at. Use IndirectRefs() to find the templates where Order() may not
+// be enough. (ExpandAll() copes with them for you.)
+type DependencyGraph struct {
+	// all of the template names, sorted
+	names []string
+
+	// the names that each template refers to, sorted
+	deps map[string][]string
+
+	// the names that each template uses in ${!ref}, sorted
+	indirect map[string][]string
+}
+
+// NewDependencyGraph works out which of the given templates refer to
+// each other.
+func NewDependencyGraph(templates map[string]string) *DependencyGraph {
+	retval := DependencyGraph{
+		names:    make([]string, 0, len(templates)),
+		deps:     make(map[string][]string, len(templates)),
+		indirect: make(map[string][]string),
+	}
+
+	for name := range templates {
+		retval.names = append(retval.names, name)
+	}
+	sort.Strings(retval.names)
+
+	for _, name := range retval.names {
+		refs, prefixes, indirect := findVarRefs(templates[name])
+
+		// we use a map to remove any duplicates
+		deps := make(map[string]bool)
+		for _, ref := range refs {
+			_, ok := templates[ref]
+			if ok {
+				deps[ref] = true
+			}
+		}
+		for _, prefix := range prefixes {
+			for _, other := range retval.names {
+				if strings.HasPrefix(other, prefix) {
+					deps[other] = true
+				}
+			}
+		}
+		delete(deps, name)
+
+		for dep := range deps {
+			retval.deps[name] = append(retval.deps[name], dep)
+		}
+		sort.Strings(retval.deps[name])
+
+		if len(indirect) > 0 {
+			retval.indirect[name] = uniqueSorted(indirect)
+		}
+	}
+
+	return &retval
+}
+
+// Names returns the names of all the templates in the graph, sorted
+func (g *DependencyGraph) Names() []string {
+	retval := make([]string, len(g.names))
+	copy(retval, g.names)
+	return retval
+}
+
+// DependsOn returns the names of the templates that the named template
+// refers to, sorted
+func (g *DependencyGraph) DependsOn(name string) []string {
+	retval := make([]string, len(g.deps[name]))
+	copy(retval, g.deps[name])
+	return retval
+}
+
+// IndirectRefs returns the names that the named template uses in
+// ${!ref}, sorted. The template also depends on whichever variables
+// those names point at, which the graph does not know about.
+func (g *DependencyGraph) IndirectRefs(name string) []string {
+	retval := make([]string, len(g.indirect[name]))
+	copy(retval, g.indirect[name])
+	return retval
+}
+
+// Order returns the names of all the templates, in an order where every
+// template comes after all of the templates that it depends on.
+//
+// Templates that do not depend on each other are returned in sorted
+// order, so the results are always the same for the same input.
+//
+// If the templates refer to each other in a loop, you get an
+// ErrDependencyCycle that tells you which templates are involved.
+func (g *DependencyGraph) Order() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	retval := make([]string, 0, len(g.names))
+	state := make(map[string]int, len(g.names))
+
+	// the templates that we are part-way through visiting
+	var stack []string
+
+	var visit func(string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			// we have been here before, so the stack contains a loop
+			for i := range stack {
+				if stack[i] == name {
+					cycle := append([]string{}, stack[i:]...)
+					return ErrDependencyCycle{append(cycle, name)}
+				}
+			}
+		}
+
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range g.deps[name] {
+			err := visit(dep)
+			if err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+
+		retval = append(retval, name)
+		return nil
+	}
+
+	for _, name := range g.names {
+		err := visit(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return retval, nil
+}
+
+// findVarRefs returns the names of all the variables that the input
+// refers to, any prefixes used in ${!prefix*} / ${!prefix@}, and the
+// names used in ${!ref}
+//
+// Shell special parameters and positional parameters are not included.
+func findVarRefs(input string) ([]string, []string, []string) {
+	var names []string
+	var prefixes []string
+	var indirect []string
+
+	inEscape := false
+	var c rune
+	w := 0
+	for i := 0; i < len(input); i += w {
+		c, w = utf8.DecodeRuneInString(input[i:])
+		if inEscape {
+			inEscape = false
+			continue
+		}
+		if c == '\\' {
+			inEscape = true
+			continue
+		}
+		if c != '$' {
+			continue
+		}
+
+		// names inside $((...)) and $[...] are variables too
+		exprEnd, ok := findArithmetic(input[i:])
+		if !ok {
+			exprEnd, ok = findLegacyArithmetic(input[i:])
+		}
+		if ok {
+			exprNames, exprPrefixes, exprIndirect := findArithmeticRefs(arithmeticExpr(input[i : i+exprEnd]))
+			names = append(names, exprNames...)
+			prefixes = append(prefixes, exprPrefixes...)
+			indirect = append(indirect, exprIndirect...)
+			w = exprEnd
+			continue
+		}
+
+		varEnd, ok := matchVar(input[i:])
+		if !ok {
+			continue
+		}
+		paramDesc, ok := parseParameter(input[i : i+varEnd])
+		if !ok {
+			continue
+		}
+
+		switch {
+		case paramDesc.kind == paramExpandPrefixNames || paramDesc.kind == paramExpandPrefixNamesDoubleQuoted:
+			prefixes = append(prefixes, paramDesc.parts[0])
+		case !strings.HasPrefix(paramDesc.parts[0], "$"):
+			names = append(names, paramDesc.parts[0])
+			if paramDesc.indirect {
+				indirect = append(indirect, paramDesc.parts[0])
+			}
+		}
+
+		// any words inside the parameter can refer to variables too
+		for _, part := range paramDesc.parts[1:] {
+			partNames, partPrefixes, partIndirect := findVarRefs(part)
+			names = append(names, partNames...)
+			prefixes = append(prefixes, partPrefixes...)
+			indirect = append(indirect, partIndirect...)
+		}
+
+		w = varEnd
+	}
+
+	return names, prefixes, indirect
+}
+
+// findArithmeticRefs returns the names of all the variables that an
+// arithmetic expression refers to, just like findVarRefs() does
+//
+// the expression can use variables by name (e.g. `B + 1`), or through
+// an expansion (e.g. `$B + 1`)
+func findArithmeticRefs(expr string) ([]string, []string, []string) {
+	names, prefixes, indirect := findVarRefs(expr)
+
+	// the arithmetic lexer doesn't understand expansions, so we swap
+	// them for a number before we look for the bare names
+	var buf strings.Builder
+	for i := 0; i < len(expr); i++ {
+		if expr[i] != '$' {
+			buf.WriteByte(expr[i])
+			continue
+		}
+
+		exprEnd, ok := findSubstitution(expr[i:])
+		if !ok {
+			exprEnd, ok = matchVar(expr[i:])
+		}
+		if !ok {
+			buf.WriteByte(expr[i])
+			continue
+		}
+
+		buf.WriteString(" 0 ")
+		i += exprEnd - 1
+	}
+
+	// if it isn't valid arithmetic, the names in it are not variables
+	exprNames, err := arith.Names(buf.String())
+	if err == nil {
+		names = append(names, exprNames...)
+	}
+
+	return names, prefixes, indirect
+}
+
+// uniqueSorted returns the given names, sorted, with any duplicates
+// removed
+func uniqueSorted(names []string) []string {
+	retval := append([]string(nil), names...)
+	sort.Strings(retval)
+
+	j := 0
+	for i, name := range retval {
+		if i == 0 || name != retval[j-1] {
+			retval[j] = name
+			j++
+		}
+	}
+
+	return retval[:j]
+}
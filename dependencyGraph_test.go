@@ -0,0 +1,179 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyGraphFindsReferences(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"APP_DIR":  "${HOME}/${APP_NAME}",
+		"APP_NAME": "myapp",
+		"APP_LOG":  "${APP_LOG_DIR:-$APP_DIR/log}/app.log $1 \\$APP_NAME",
+		"APP_ALL":  "${!APP_*}",
+		"PATH":     "${PATH}:${APP_DIR}/bin",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	graph := NewDependencyGraph(templates)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"APP_ALL", "APP_DIR", "APP_LOG", "APP_NAME", "PATH"}, graph.Names())
+	assert.Equal(t, []string{"APP_NAME"}, graph.DependsOn("APP_DIR"))
+	assert.Equal(t, []string{"APP_DIR"}, graph.DependsOn("APP_LOG"))
+	assert.Equal(t, []string{"APP_DIR", "APP_LOG", "APP_NAME"}, graph.DependsOn("APP_ALL"))
+	assert.Equal(t, []string{"APP_DIR"}, graph.DependsOn("PATH"))
+	assert.Equal(t, []string{}, graph.DependsOn("APP_NAME"))
+}
+
+func TestDependencyGraphOrder(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"D": "${C}${B}",
+		"C": "$A",
+		"B": "${A:+$C}",
+		"A": "a",
+		"E": "e",
+	}
+	expectedResult := []string{"A", "C", "B", "D", "E"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := NewDependencyGraph(templates).Order()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDependencyGraphOrderDetectsCycles(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"A": "${B}",
+		"B": "${C#x}",
+		"C": "${D:-$A}",
+		"D": "d",
+	}
+	expectedError := "dependency cycle: A -> B -> C -> A"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := NewDependencyGraph(templates).Order()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+	assert.Error(t, err)
+	assert.Equal(t, expectedError, err.Error())
+	assert.Equal(t, ErrDependencyCycle{[]string{"A", "B", "C", "A"}}, err)
+}
+
+func TestDependencyGraphFindsReferencesInsideArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"A": "$((B+1))",
+		"B": "5",
+		"C": "${D:-$((A*B))}",
+		"D": "$((D+1))",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	graph := NewDependencyGraph(templates)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"B"}, graph.DependsOn("A"))
+	assert.Equal(t, []string{"A", "B", "D"}, graph.DependsOn("C"))
+	assert.Equal(t, []string{}, graph.DependsOn("D"))
+}
+
+func TestDependencyGraphReportsIndirectReferences(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"A":   "${!REF} ${X:-${!OTHER}} ${!REF}",
+		"B":   "bee",
+		"REF": "B",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	graph := NewDependencyGraph(templates)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	// A really depends on B too, but the graph cannot see that
+	assert.Equal(t, []string{"REF"}, graph.DependsOn("A"))
+	assert.Equal(t, []string{"OTHER", "REF"}, graph.IndirectRefs("A"))
+	assert.Equal(t, []string{}, graph.IndirectRefs("B"))
+}
@@ -0,0 +1,222 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "strings"
+
+// Dialect is the UNIX shell whose behaviour we copy
+type Dialect int
+
+// these are the shell dialects that we support
+const (
+	// DialectBash copies GNU bash. It is the default.
+	DialectBash Dialect = iota
+
+	// DialectPOSIX copies a strict POSIX sh, such as dash. Bashisms
+	// (such as ${var^^} and ${!var}) are rejected with
+	// ErrBadSubstitution, and there is no brace expansion.
+	DialectPOSIX
+
+	// DialectZsh copies zsh. The bash-only operators are rejected with
+	// ErrBadSubstitution, and ExpandArgs() does not split the results
+	// of unquoted expansions into separate words.
+	//
+	// The most common zsh parameter expansion flags are supported:
+	// ${(U)var}, ${(L)var}, ${(C)var}, ${(P)var}, ${(s:sep:)var} and
+	// ${(j:sep:)var}.
+	DialectZsh
+
+	// DialectCompose copies the variable interpolation in Docker
+	// Compose files. Only $VAR, ${VAR}, ${VAR:-word}, ${VAR-word},
+	// ${VAR:?word}, ${VAR?word}, ${VAR:+word} and ${VAR+word} are
+	// expanded, and $$ is a literal $.
+	//
+	// Backslashes and tildes are not special, and there is no brace
+	// expansion. $1, $? and the other special parameters are left as
+	// they are. Any other ${...} is rejected with ErrBadSubstitution,
+	// even when strict mode is off.
+	DialectCompose
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case DialectBash:
+		return "bash"
+	case DialectPOSIX:
+		return "posix"
+	case DialectZsh:
+		return "zsh"
+	case DialectCompose:
+		return "compose"
+	default:
+		return "unknown dialect"
+	}
+}
+
+// WithDialect makes the Expander copy the given shell dialect
+func WithDialect(dialect Dialect) Option {
+	return func(opts *options) {
+		opts.dialect = dialect
+	}
+}
+
+// dialectFeatures describes what a shell dialect supports
+type dialectFeatures struct {
+	// do we perform brace expansion?
+	braceExpansion bool
+
+	// do we split the results of unquoted expansions into words?
+	wordSplitting bool
+
+	// do we perform tilde expansion?
+	tildeExpansion bool
+
+	// does a backslash escape the character that comes after it?
+	backslashEscapes bool
+
+	// is $$ a literal $, instead of the shell's process ID?
+	dollarEscapes bool
+
+	// are named variables the only parameters that we support?
+	namesOnly bool
+
+	// do we refuse to expand a ${...} that we do not understand, even
+	// when strict mode is off?
+	rejectBadSubstitutions bool
+
+	// do we support ${!var}?
+	indirection bool
+
+	// do we support zsh's ${(flags)var}?
+	zshFlags bool
+
+	// do we translate $"..." strings?
+	localeStrings bool
+
+	// do we perform arithmetic expansion on $((...))?
+	arithmetic bool
+
+	// the kinds of parameter expansion that we support
+	//
+	// nil means that we support all of them
+	paramKinds map[int]bool
+}
+
+// dialects holds the features of each shell dialect
+var dialects = map[Dialect]dialectFeatures{
+	DialectBash: {
+		braceExpansion:   true,
+		wordSplitting:    true,
+		tildeExpansion:   true,
+		backslashEscapes: true,
+		indirection:      true,
+		localeStrings:    true,
+		arithmetic:       true,
+	},
+	DialectPOSIX: {
+		paramKinds: map[int]bool{
+			paramExpandToValue:                   true,
+			paramExpandWithDefaultValue:          true,
+			paramExpandSetDefaultValue:           true,
+			paramExpandWriteError:                true,
+			paramExpandAlternativeValue:          true,
+			paramExpandParamLength:               true,
+			paramExpandNoOfPositionalParams:      true,
+			paramExpandRemovePrefixShortestMatch: true,
+			paramExpandRemovePrefixLongestMatch:  true,
+			paramExpandRemoveSuffixShortestMatch: true,
+			paramExpandRemoveSuffixLongestMatch:  true,
+		},
+		wordSplitting:    true,
+		tildeExpansion:   true,
+		backslashEscapes: true,
+		arithmetic:       true,
+	},
+	DialectZsh: {
+		braceExpansion:   true,
+		tildeExpansion:   true,
+		backslashEscapes: true,
+		zshFlags:         true,
+		arithmetic:       true,
+		paramKinds: map[int]bool{
+			paramExpandToValue:                          true,
+			paramExpandWithDefaultValue:                 true,
+			paramExpandSetDefaultValue:                  true,
+			paramExpandWriteError:                       true,
+			paramExpandAlternativeValue:                 true,
+			paramExpandSubstring:                        true,
+			paramExpandSubstringLength:                  true,
+			paramExpandParamLength:                      true,
+			paramExpandNoOfPositionalParams:             true,
+			paramExpandRemovePrefixShortestMatch:        true,
+			paramExpandRemovePrefixLongestMatch:         true,
+			paramExpandRemoveSuffixShortestMatch:        true,
+			paramExpandRemoveSuffixLongestMatch:         true,
+			paramExpandSearchReplaceLongestFirstMatch:   true,
+			paramExpandSearchReplaceLongestAllMatches:   true,
+			paramExpandSearchReplaceLongestPrefix:       true,
+			paramExpandSearchReplaceLongestSuffix:       true,
+			paramExpandAllPositionalParamsSearchReplace: true,
+		},
+	},
+	DialectCompose: {
+		dollarEscapes:          true,
+		namesOnly:              true,
+		rejectBadSubstitutions: true,
+		paramKinds: map[int]bool{
+			paramExpandToValue:          true,
+			paramExpandWithDefaultValue: true,
+			paramExpandWriteError:       true,
+			paramExpandAlternativeValue: true,
+		},
+	},
+}
+
+// supportsParam returns true if the dialect understands the given
+// parameter expansion
+func (f dialectFeatures) supportsParam(paramDesc paramDesc) bool {
+	// zsh uses ${(P)var} for indirection, instead of ${!var}
+	if paramDesc.indirect && !f.indirection && paramDesc.flags == nil {
+		return false
+	}
+
+	// Docker Compose only expands named variables
+	if f.namesOnly && strings.HasPrefix(paramDesc.parts[0], "$") {
+		return false
+	}
+
+	return f.paramKinds == nil || f.paramKinds[paramDesc.kind]
+}
@@ -0,0 +1,328 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDialectExpander(dialect Dialect) *Expander {
+	vars := map[string]string{
+		"PARAM1": "hello world",
+		"PARAM2": "PARAM1",
+	}
+
+	return NewExpander(
+		ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				retval, ok := vars[key]
+				return retval, ok
+			},
+			MatchVarNames: func(prefix string) []string {
+				return []string{"PARAM1", "PARAM2"}
+			},
+		},
+		WithDialect(dialect),
+	)
+}
+
+func TestDialectPOSIXRejectsBashisms(t *testing.T) {
+	t.Parallel()
+
+	unit := newTestDialectExpander(DialectPOSIX)
+	testCases := []string{
+		"${PARAM1^^}",
+		"${PARAM1,}",
+		"${!PARAM2}",
+		"${!PARAM*}",
+		"${PARAM1:1}",
+		"${PARAM1:1:2}",
+		"${PARAM3:-${PARAM1^}}",
+	}
+
+	for _, testData := range testCases {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrBadSubstitution{}), testData)
+	}
+}
+
+func TestDialectPOSIXSupportsPOSIXExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestDialectExpander(DialectPOSIX)
+	testData := "$PARAM1 ${PARAM3:-default} ${#PARAM1} ${PARAM1#* } ${PARAM1%% *} {a,b}"
+	expectedResult := "hello world default 11 world hello {a,b}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDialectZshRejectsBashOnlyOperators(t *testing.T) {
+	t.Parallel()
+
+	unit := newTestDialectExpander(DialectZsh)
+	testCases := []string{
+		"${PARAM1^^}",
+		"${!PARAM2}",
+		"${!PARAM*}",
+		"${PARAM1@Q}",
+	}
+
+	for _, testData := range testCases {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrBadSubstitution{}), testData)
+	}
+}
+
+func TestDialectZshDoesNotSplitWords(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestDialectExpander(DialectZsh)
+	expectedResult := []string{"echo", "hello world", "a1", "a2", "hello"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandArgs("echo $PARAM1 a{1,2} ${PARAM1:0:5}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDialectBashIsTheDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestDialectExpander(DialectBash)
+	testData := "${PARAM1^^} ${!PARAM2} ${!PARAM*} {a,b}"
+	expectedResult, expectedErr := Expand(testData, unit.cb)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, expectedErr)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, "HELLO WORLD hello world PARAM1 PARAM2 a b", actualResult)
+}
+
+func TestDialectComposeSupportsComposeExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestDialectExpander(DialectCompose)
+	testData := `$PARAM1 ${PARAM1} ${PARAM3:-default} ${PARAM3-default} ${PARAM2:+alt} ${PARAM3+alt}`
+	expectedResult := "hello world hello world default default alt "
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDialectComposeTreatsDoubleDollarAsLiteral(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestDialectExpander(DialectCompose)
+	testData := `$$PARAM1 $${PARAM1} $$ cost: 5$`
+	expectedResult := `$PARAM1 ${PARAM1} $ cost: 5$`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDialectComposeHasNoShellSyntax(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestDialectExpander(DialectCompose)
+	testData := `~/data \$PARAM1 C:\temp {a,b} $1 $?`
+	expectedResult := `~/data \hello world C:\temp {a,b} $1 $?`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestDialectComposeRejectsOtherExpansions(t *testing.T) {
+	t.Parallel()
+
+	unit := newTestDialectExpander(DialectCompose)
+	testCases := []string{
+		"${PARAM1:=default}",
+		"${PARAM1^^}",
+		"${PARAM1#hello}",
+		"${#PARAM1}",
+		"${!PARAM2}",
+		"${!PARAM*}",
+		"${1}",
+		"${PARAM3:-${PARAM1%world}}",
+	}
+
+	for _, testData := range testCases {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrBadSubstitution{}), testData)
+	}
+}
+
+func TestDialectComposeRejectsUnterminatedBraces(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestDialectExpander(DialectCompose)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${PARAM1")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.As(err, &ErrMismatchedBrace{}))
+}
+
+func TestDialectComposeWritesErrorForRequiredVars(t *testing.T) {
+	t.Parallel()
+
+	unit := newTestDialectExpander(DialectCompose)
+	testCases := []string{
+		"${PARAM3:?is required}",
+		"${PARAM3?is required}",
+	}
+
+	for _, testData := range testCases {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrVarRequired{}), testData)
+	}
+}
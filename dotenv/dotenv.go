@@ -0,0 +1,210 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package dotenv loads .env files, using shellexpand to expand the values.
+//
+// Each value is expanded against the keys that were defined earlier in
+// the same file, and then against an ambient set of variables (usually
+// your program's environment). This is how tools such as docker-compose
+// and direnv treat .env files.
+//
+// Only parameters (such as $KEY and ${KEY:-default}) are expanded. Braces
+// and tildes are left as they are, so KEY={a,b} and KEY=~/dir mean what
+// they say.
+//
+// The supported syntax is:
+//
+//	# comments, and blank lines, are ignored
+//	KEY=value                 # values are expanded by shellexpand.ExpandParamsOnly()
+//	export KEY=value          # the 'export' keyword is optional
+//	KEY="value with \"escapes\" and ${VARS}"
+//	KEY='value with no expansion at all'
+//
+// Single-quoted and double-quoted values may span multiple lines.
+package dotenv
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	shellexpand "github.com/ganbarodigital/go_shellexpand"
+)
+
+// Load reads a .env file from the given reader, and expands each of
+// its values in turn.
+//
+// Only parameters are expanded; see shellexpand.ExpandParamsOnly().
+// Variables are looked up in the keys defined so far, and then in the
+// ambient callbacks. Assignments (such as ${KEY:=default}) are made to
+// the returned Env; they never change the ambient variables.
+//
+// The returned Env only contains the keys defined in the .env file, in
+// the order they were first defined in.
+func Load(r io.Reader, ambient shellexpand.ExpansionCallbacks) (*shellexpand.Env, error) {
+	entries, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	env := shellexpand.NewEnv()
+	cb := newCallbacks(env, ambient)
+
+	for _, entry := range entries {
+		value := entry.Value
+		if entry.Quote != '\'' {
+			if entry.Quote == '"' {
+				value = unescapeDoubleQuoted(value)
+			}
+
+			value, err = shellexpand.ExpandParamsOnly(value, cb)
+			if err != nil {
+				return nil, ErrExpansionFailed{line: entry.Line, key: entry.Key, err: err}
+			}
+		}
+
+		env.Set(entry.Key, value)
+	}
+
+	return env, nil
+}
+
+// LoadFile opens the given .env file, and passes it to Load()
+func LoadFile(filename string, ambient shellexpand.ExpansionCallbacks) (*shellexpand.Env, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f, ambient)
+}
+
+// LoadFS opens the given .env file from the given filesystem, and passes
+// it to Load()
+//
+// Use it to load .env files from a virtual filesystem (such as
+// fstest.MapFS or an embed.FS).
+func LoadFS(fsys fs.FS, filename string, ambient shellexpand.ExpansionCallbacks) (*shellexpand.Env, error) {
+	f, err := fsys.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f, ambient)
+}
+
+// newCallbacks layers the .env file's own keys over the ambient callbacks
+func newCallbacks(env *shellexpand.Env, ambient shellexpand.ExpansionCallbacks) shellexpand.ExpansionCallbacks {
+	return shellexpand.ExpansionCallbacks{
+		AssignToVar: env.Set,
+		LookupVarContext: func(ctx context.Context, name string) (string, bool) {
+			value, ok := env.Lookup(name)
+			if ok {
+				return value, true
+			}
+
+			if ambient.LookupVarContext != nil {
+				return ambient.LookupVarContext(ctx, name)
+			}
+			if ambient.LookupVar != nil {
+				return ambient.LookupVar(name)
+			}
+
+			return "", false
+		},
+		LookupHomeDir:        ambient.LookupHomeDir,
+		LookupHomeDirContext: ambient.LookupHomeDirContext,
+		MatchVarNamesContext: func(ctx context.Context, prefix string) []string {
+			retval := env.MatchVarNames(prefix)
+
+			var ambientNames []string
+			if ambient.MatchVarNamesContext != nil {
+				ambientNames = ambient.MatchVarNamesContext(ctx, prefix)
+			} else if ambient.MatchVarNames != nil {
+				ambientNames = ambient.MatchVarNames(prefix)
+			}
+
+			for _, name := range ambientNames {
+				_, ok := env.Lookup(name)
+				if !ok {
+					retval = append(retval, name)
+				}
+			}
+
+			return retval
+		},
+	}
+}
+
+// unescapeDoubleQuoted turns the escape sequences that are special to
+// .env files into the characters they stand for.
+//
+// Escape sequences that shellexpand understands (\\ and \$) are left
+// alone. Any other backslash is kept as a literal backslash, just like
+// the UNIX shell does inside double quotes.
+func unescapeDoubleQuoted(input string) string {
+	var buf strings.Builder
+
+	for i := 0; i < len(input); i++ {
+		if input[i] != '\\' || i+1 == len(input) {
+			buf.WriteByte(input[i])
+			continue
+		}
+
+		i++
+		switch input[i] {
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 't':
+			buf.WriteByte('\t')
+		case '"':
+			buf.WriteByte('"')
+		case '\\', '$':
+			buf.WriteByte('\\')
+			buf.WriteByte(input[i])
+		default:
+			buf.WriteString(`\\`)
+			buf.WriteByte(input[i])
+		}
+	}
+
+	return buf.String()
+}
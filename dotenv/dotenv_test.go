@@ -0,0 +1,247 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package dotenv
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	shellexpand "github.com/ganbarodigital/go_shellexpand"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadExpandsAgainstEarlierKeysThenAmbient(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ambient := shellexpand.NewEnv()
+	ambient.Set("HOME", "/home/stuart")
+	ambient.Set("APP_NAME", "ambient")
+
+	testData := strings.Join([]string{
+		"APP_NAME=myapp",
+		"APP_DIR=${HOME}/$APP_NAME",
+		"APP_LOG='${APP_DIR}/log'",
+		`APP_GREETING="hello\tfrom\n${APP_NAME}, costs \$5"`,
+		"APP_PORT=${APP_PORT:=8080}",
+	}, "\n")
+	expectedResult := []string{
+		"APP_NAME=myapp",
+		"APP_DIR=/home/stuart/myapp",
+		"APP_LOG=${APP_DIR}/log",
+		"APP_GREETING=hello\tfrom\nmyapp, costs $5",
+		"APP_PORT=8080",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env, err := Load(strings.NewReader(testData), ambient.Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, env.Environ())
+
+	// the ambient variables must not have changed
+	assert.Equal(t, "ambient", ambient.Get("APP_NAME"))
+	_, ok := ambient.Lookup("APP_PORT")
+	assert.False(t, ok)
+}
+
+func TestLoadDoesNotSeeLaterKeys(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "PARAM1=${PARAM2:-unset}\nPARAM2=foo\n"
+	expectedResult := []string{"PARAM1=unset", "PARAM2=foo"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env, err := Load(strings.NewReader(testData), shellexpand.NewEnv().Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, env.Environ())
+}
+
+func TestLoadOnlyExpandsParameters(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ambient := shellexpand.NewEnv()
+	ambient.Set("HOME", "/home/stuart")
+	cb := ambient.Callbacks()
+	cb.LookupHomeDir = func(name string) (string, bool) {
+		return "/home/stuart", true
+	}
+
+	testData := strings.Join([]string{
+		"SIZES={small,large}",
+		"RANGE={1..3}",
+		"CACHE=~/.cache",
+		"OTHER=~root/bin",
+		`QUOTED="~/{a,b}"`,
+		"MIXED=${HOME}/{x,y}",
+	}, "\n")
+	expectedResult := []string{
+		"SIZES={small,large}",
+		"RANGE={1..3}",
+		"CACHE=~/.cache",
+		"OTHER=~root/bin",
+		"QUOTED=~/{a,b}",
+		"MIXED=/home/stuart/{x,y}",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env, err := Load(strings.NewReader(testData), cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, env.Environ())
+}
+
+func TestLoadReportsExpansionErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "PARAM1=foo\nPARAM2=${PARAM1#[}\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env, err := Load(strings.NewReader(testData), shellexpand.NewEnv().Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, env)
+	assert.Error(t, err)
+
+	var expandErr ErrExpansionFailed
+	assert.True(t, errors.As(err, &expandErr))
+	assert.Equal(t, 2, expandErr.line)
+	assert.Equal(t, "PARAM2", expandErr.key)
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir, err := ioutil.TempDir("", "dotenv")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, ".env")
+	err = ioutil.WriteFile(filename, []byte("PARAM1=foo\nPARAM2=${PARAM1}bar\n"), 0644)
+	assert.Nil(t, err)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env, err := LoadFile(filename, shellexpand.NewEnv().Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PARAM1=foo", "PARAM2=foobar"}, env.Environ())
+}
+
+func TestLoadFS(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	fsys := fstest.MapFS{
+		"app/.env": &fstest.MapFile{Data: []byte("PARAM1=foo\nPARAM2=${PARAM1}bar\n")},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env, err := LoadFS(fsys, "app/.env", shellexpand.NewEnv().Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PARAM1=foo", "PARAM2=foobar"}, env.Environ())
+}
+
+func TestLoadFSReportsMissingFiles(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	fsys := fstest.MapFS{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := LoadFS(fsys, ".env", shellexpand.NewEnv().Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
@@ -0,0 +1,98 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package dotenv
+
+import "fmt"
+
+// ErrMissingEquals is returned if a line in a .env file is not a
+// KEY=value assignment
+type ErrMissingEquals struct {
+	line int
+}
+
+func (e ErrMissingEquals) Error() string {
+	return fmt.Sprintf("line %d: expected KEY=value", e.line)
+}
+
+// ErrInvalidKey is returned if a .env file tries to set a variable whose
+// name is not a valid UNIX shell variable name
+type ErrInvalidKey struct {
+	line int
+	key  string
+}
+
+func (e ErrInvalidKey) Error() string {
+	return fmt.Sprintf("line %d: invalid variable name '%s'", e.line, e.key)
+}
+
+// ErrUnterminatedQuote is returned if a value in a .env file has an
+// opening quote without a matching closing quote
+type ErrUnterminatedQuote struct {
+	line  int
+	quote rune
+}
+
+func (e ErrUnterminatedQuote) Error() string {
+	return fmt.Sprintf("line %d: unterminated %c", e.line, e.quote)
+}
+
+// ErrUnexpectedText is returned if there is anything other than a
+// comment after a quoted value in a .env file
+type ErrUnexpectedText struct {
+	line int
+	text string
+}
+
+func (e ErrUnexpectedText) Error() string {
+	return fmt.Sprintf("line %d: unexpected text after closing quote: %s", e.line, e.text)
+}
+
+// ErrExpansionFailed is returned if shellexpand was unable to expand a
+// value in a .env file
+type ErrExpansionFailed struct {
+	line int
+	key  string
+	err  error
+}
+
+func (e ErrExpansionFailed) Error() string {
+	return fmt.Sprintf("line %d: unable to expand %s: %s", e.line, e.key, e.err)
+}
+
+// Unwrap returns the error that shellexpand reported
+func (e ErrExpansionFailed) Unwrap() error {
+	return e.err
+}
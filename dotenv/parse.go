@@ -0,0 +1,250 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package dotenv
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+)
+
+// Entry is a single KEY=value assignment from a .env file
+type Entry struct {
+	// Key is the name of the variable
+	Key string
+
+	// Value is the text of the value, with any surrounding quotes
+	// removed. It has not been expanded.
+	Value string
+
+	// Quote is the quote character that surrounded the value, or
+	// 0 if the value was not quoted
+	Quote rune
+
+	// Line is the line number (starting from 1) that the entry
+	// begins on
+	Line int
+}
+
+// Parse reads a .env file from the given reader, and returns the
+// assignments that it contains, in the order they appear in the file.
+//
+// The values are not expanded. Use Load() if you want that.
+func Parse(r io.Reader) ([]Entry, error) {
+	input, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{input: string(input), line: 1}
+	return p.parse()
+}
+
+// parser keeps track of where we are in a .env file
+type parser struct {
+	input string
+	pos   int
+	line  int
+}
+
+func (p *parser) parse() ([]Entry, error) {
+	var retval []Entry
+
+	for p.pos < len(p.input) {
+		line := strings.TrimSpace(p.nextLine())
+
+		// skip blank lines and comments
+		if len(line) == 0 || line[0] == '#' {
+			p.endLine()
+			continue
+		}
+
+		entry, err := p.parseEntry()
+		if err != nil {
+			return nil, err
+		}
+		retval = append(retval, entry)
+	}
+
+	return retval, nil
+}
+
+// parseEntry parses the KEY=value assignment that starts on the
+// current line
+func (p *parser) parseEntry() (Entry, error) {
+	retval := Entry{Line: p.line}
+
+	line := strings.TrimLeftFunc(p.nextLine(), unicode.IsSpace)
+	p.pos += len(p.nextLine()) - len(line)
+
+	// the export keyword is there for the benefit of the UNIX shell
+	if strings.HasPrefix(line, "export") && len(line) > 6 && (line[6] == ' ' || line[6] == '\t') {
+		line = strings.TrimLeft(line[6:], " \t")
+		p.pos += len(p.nextLine()) - len(line)
+	}
+
+	equals := strings.IndexByte(line, '=')
+	if equals < 0 {
+		return retval, ErrMissingEquals{line: p.line}
+	}
+
+	retval.Key = strings.TrimRight(line[:equals], " \t")
+	if !isValidKey(retval.Key) {
+		return retval, ErrInvalidKey{line: p.line, key: retval.Key}
+	}
+
+	// move past the '=', and any whitespace that follows it
+	p.pos += equals + 1
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+
+	if p.pos < len(p.input) && (p.input[p.pos] == '\'' || p.input[p.pos] == '"') {
+		return p.parseQuotedValue(retval)
+	}
+
+	retval.Value = stripComment(p.nextLine())
+	p.endLine()
+
+	return retval, nil
+}
+
+// parseQuotedValue parses a value that starts with a quote. The value
+// can run over several lines.
+func (p *parser) parseQuotedValue(entry Entry) (Entry, error) {
+	quote := p.input[p.pos]
+	entry.Quote = rune(quote)
+	startLine := p.line
+
+	var buf strings.Builder
+	for i := p.pos + 1; i < len(p.input); i++ {
+		c := p.input[i]
+		if c == '\n' {
+			p.line++
+		}
+
+		// inside double quotes, backslash escapes the next character;
+		// we keep the escape sequence, for Load() to deal with
+		if quote == '"' && c == '\\' && i+1 < len(p.input) {
+			buf.WriteByte(c)
+			i++
+			c = p.input[i]
+			if c == '\n' {
+				p.line++
+			}
+			buf.WriteByte(c)
+			continue
+		}
+
+		if c == quote {
+			entry.Value = buf.String()
+			p.pos = i + 1
+
+			// the only thing allowed after the closing quote is
+			// a comment
+			rest := strings.TrimSpace(p.nextLine())
+			if len(rest) > 0 && rest[0] != '#' {
+				return entry, ErrUnexpectedText{line: p.line, text: rest}
+			}
+			p.endLine()
+
+			return entry, nil
+		}
+
+		buf.WriteByte(c)
+	}
+
+	return entry, ErrUnterminatedQuote{line: startLine, quote: entry.Quote}
+}
+
+// nextLine returns the rest of the current line, without the newline
+func (p *parser) nextLine() string {
+	retval := p.input[p.pos:]
+	end := strings.IndexByte(retval, '\n')
+	if end >= 0 {
+		retval = retval[:end]
+	}
+
+	return strings.TrimSuffix(retval, "\r")
+}
+
+// endLine moves us to the start of the next line
+func (p *parser) endLine() {
+	end := strings.IndexByte(p.input[p.pos:], '\n')
+	if end < 0 {
+		p.pos = len(p.input)
+		return
+	}
+
+	p.pos += end + 1
+	p.line++
+}
+
+// stripComment removes any trailing comment from an unquoted value
+//
+// A comment starts with a '#' that follows whitespace
+func stripComment(input string) string {
+	for i := 1; i < len(input); i++ {
+		if input[i] == '#' && (input[i-1] == ' ' || input[i-1] == '\t') {
+			input = input[:i]
+			break
+		}
+	}
+
+	return strings.TrimSpace(input)
+}
+
+// isValidKey returns true if the given key is a valid UNIX shell
+// variable name
+func isValidKey(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+
+	for i, c := range key {
+		if c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+			continue
+		}
+		if i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,121 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package dotenv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUnquotedValues(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "# a comment\n\nPARAM1=foo\n  export PARAM2 = bar baz  # trailing comment\nPARAM3=a#b\nPARAM4=\r\n"
+	expectedResult := []Entry{
+		{Key: "PARAM1", Value: "foo", Line: 3},
+		{Key: "PARAM2", Value: "bar baz", Line: 4},
+		{Key: "PARAM3", Value: "a#b", Line: 5},
+		{Key: "PARAM4", Value: "", Line: 6},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Parse(strings.NewReader(testData))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestParseQuotedValues(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "PARAM1='foo # bar'\nPARAM2=\"say \\\"hello\\\"\" # comment\nPARAM3=\"line 1\nline 2\"\nPARAM4=after\n"
+	expectedResult := []Entry{
+		{Key: "PARAM1", Value: "foo # bar", Quote: '\'', Line: 1},
+		{Key: "PARAM2", Value: "say \\\"hello\\\"", Quote: '"', Line: 2},
+		{Key: "PARAM3", Value: "line 1\nline 2", Quote: '"', Line: 3},
+		{Key: "PARAM4", Value: "after", Line: 5},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Parse(strings.NewReader(testData))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestParseReportsSyntaxErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"PARAM1=foo\nnot an assignment\n": "line 2: expected KEY=value",
+		"1PARAM=foo\n":                    "line 1: invalid variable name '1PARAM'",
+		"PARAM1=foo\nPARAM2=\"foo\n\n":    "line 2: unterminated \"",
+		"PARAM1='foo' bar\n":              "line 1: unexpected text after closing quote: bar",
+	}
+
+	for testData, expectedError := range testCases {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := Parse(strings.NewReader(testData))
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.EqualError(t, err, expectedError, testData)
+	}
+}
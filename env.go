@@ -0,0 +1,291 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"sync"
+)
+
+// Env is a ready-made, in-memory variable backing store.
+//
+// It remembers the order that variables were first set in, so that
+// Environ() can export them in a predictable order.
+//
+// Env is safe to use from multiple goroutines. Use Clone() if you need
+// a private copy (for example, one per request) that you can change
+// without affecting anyone else.
+//
+// The zero value is an empty Env, with case-sensitive variable names,
+// that is ready to use.
+type Env struct {
+	mu sync.RWMutex
+
+	// the variables, keyed by their (possibly case-folded) name
+	vars map[string]envVar
+
+	// the keys of `vars`, in the order they were first set
+	keys []string
+
+	// the keys of the variables that have been exported
+	exported map[string]bool
+
+	// if true, variable names are case-insensitive (like on Windows)
+	caseInsensitive bool
+}
+
+type envVar struct {
+	// the name, exactly as it was first set
+	name string
+
+	value string
+}
+
+// NewEnv creates an empty Env. Variable names are case-sensitive, just
+// like they are on UNIX.
+func NewEnv() *Env {
+	return &Env{
+		vars:     make(map[string]envVar),
+		exported: make(map[string]bool),
+	}
+}
+
+// NewCaseInsensitiveEnv creates an empty Env, where variable names are
+// case-insensitive, just like they are on Windows.
+func NewCaseInsensitiveEnv() *Env {
+	retval := NewEnv()
+	retval.caseInsensitive = true
+	return retval
+}
+
+// Import copies a list of "key=value" pairs into the Env, such as the
+// list returned by os.Environ().
+//
+// Entries that are not in "key=value" form are ignored.
+func (e *Env) Import(pairs []string) {
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			continue
+		}
+
+		e.Set(parts[0], parts[1])
+	}
+}
+
+// Environ returns a copy of the Env as a list of "key=value" pairs, in
+// the order that the variables were first set in. It is suitable for
+// passing to exec.Cmd.
+func (e *Env) Environ() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	retval := make([]string, 0, len(e.keys))
+	for _, key := range e.keys {
+		entry := e.vars[key]
+		retval = append(retval, entry.name+"="+entry.value)
+	}
+
+	return retval
+}
+
+// Clone returns a copy of the Env, which can be changed without
+// affecting the original.
+func (e *Env) Clone() *Env {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	retval := &Env{
+		vars:            make(map[string]envVar, len(e.vars)),
+		keys:            make([]string, len(e.keys)),
+		exported:        make(map[string]bool, len(e.exported)),
+		caseInsensitive: e.caseInsensitive,
+	}
+	for key, entry := range e.vars {
+		retval.vars[key] = entry
+	}
+	for key := range e.exported {
+		retval.exported[key] = true
+	}
+	copy(retval.keys, e.keys)
+
+	return retval
+}
+
+// Get returns the value of the given variable, or an empty string if
+// the variable is not set
+func (e *Env) Get(name string) string {
+	retval, _ := e.Lookup(name)
+	return retval
+}
+
+// Lookup returns the value of the given variable, and whether or not
+// the variable is set. It can be used as a LookupVar callback.
+func (e *Env) Lookup(name string) (string, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	entry, ok := e.vars[e.key(name)]
+	return entry.value, ok
+}
+
+// Set sets a variable to the given value. It can be used as an
+// AssignVar callback.
+func (e *Env) Set(name, value string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// the zero value of Env has no map yet
+	if e.vars == nil {
+		e.vars = make(map[string]envVar)
+	}
+
+	key := e.key(name)
+	entry, ok := e.vars[key]
+	if !ok {
+		e.keys = append(e.keys, key)
+		entry.name = name
+	}
+	entry.value = value
+	e.vars[key] = entry
+
+	return nil
+}
+
+// Unset removes a variable from the Env
+func (e *Env) Unset(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := e.key(name)
+	delete(e.exported, key)
+	if _, ok := e.vars[key]; !ok {
+		return
+	}
+	delete(e.vars, key)
+
+	for i := range e.keys {
+		if e.keys[i] == key {
+			e.keys = append(e.keys[:i], e.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Export marks a variable as exported, just like the `export` builtin
+// in a UNIX shell does. The variable does not have to be set yet. It
+// can be used as an ExportVar callback.
+func (e *Env) Export(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// the zero value of Env has no map yet
+	if e.exported == nil {
+		e.exported = make(map[string]bool)
+	}
+
+	e.exported[e.key(name)] = true
+	return nil
+}
+
+// IsExported returns true if the given variable has been exported. It
+// can be used as an IsExported callback.
+func (e *Env) IsExported(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.exported[e.key(name)]
+}
+
+// Exports returns the exported variables that are set, as a list of
+// "key=value" pairs, in the order that the variables were first set in
+func (e *Env) Exports() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var retval []string
+	for _, key := range e.keys {
+		if e.exported[key] {
+			entry := e.vars[key]
+			retval = append(retval, entry.name+"="+entry.value)
+		}
+	}
+
+	return retval
+}
+
+// MatchVarNames returns the names of all the variables that start with
+// the given prefix. It can be used as a MatchVarNames callback.
+func (e *Env) MatchVarNames(prefix string) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	prefix = e.key(prefix)
+
+	var retval []string
+	for _, key := range e.keys {
+		if strings.HasPrefix(key, prefix) {
+			retval = append(retval, e.vars[key].name)
+		}
+	}
+
+	return retval
+}
+
+// Callbacks returns a set of ExpansionCallbacks that use the Env as
+// their backing store.
+//
+// Home directories are not variables, so LookupHomeDir calls
+// LookupOSHomeDir(), just like NewOSCallbacks() does.
+func (e *Env) Callbacks() ExpansionCallbacks {
+	return ExpansionCallbacks{
+		AssignToVar:   e.Set,
+		LookupVar:     e.Lookup,
+		LookupHomeDir: LookupOSHomeDir,
+		MatchVarNames: e.MatchVarNames,
+		IsExported:    e.IsExported,
+		ExportVar:     e.Export,
+	}
+}
+
+// key returns the name that we store the given variable under
+func (e *Env) key(name string) string {
+	if e.caseInsensitive {
+		return strings.ToUpper(name)
+	}
+
+	return name
+}
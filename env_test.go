@@ -0,0 +1,256 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvSetAndLookup(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := env.Set("PARAM1", "foo")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+
+	actualResult, ok := env.Lookup("PARAM1")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", actualResult)
+
+	_, ok = env.Lookup("param1")
+	assert.False(t, ok)
+}
+
+func TestEnvIsCaseInsensitiveIfAskedTo(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewCaseInsensitiveEnv()
+	env.Set("Path", "/usr/bin")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env.Set("PATH", "/bin")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "/bin", env.Get("path"))
+	assert.Equal(t, []string{"Path=/bin"}, env.Environ())
+	assert.Equal(t, []string{"Path"}, env.MatchVarNames("pa"))
+}
+
+func TestEnvUnset(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("PARAM1", "foo")
+	env.Set("PARAM2", "bar")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env.Unset("PARAM1")
+	env.Unset("NOT_SET")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	_, ok := env.Lookup("PARAM1")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"PARAM2=bar"}, env.Environ())
+}
+
+func TestEnvImportAndExportKeepOrder(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	testData := []string{
+		"PATH=/usr/bin:/bin",
+		"HOME=/home/stuart",
+		"not a pair",
+		"=C:=C:\\",
+		"EQUALS=a=b",
+		"EMPTY=",
+	}
+	expectedResult := []string{
+		"PATH=/usr/bin:/bin",
+		"HOME=/home/stuart",
+		"EQUALS=a=b",
+		"EMPTY=",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env.Import(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, env.Environ())
+}
+
+func TestEnvCloneIsIndependent(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("PARAM1", "foo")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	clone := env.Clone()
+	clone.Set("PARAM1", "bar")
+	clone.Set("PARAM2", "baz")
+	env.Unset("PARAM1")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{}, env.Environ())
+	assert.Equal(t, []string{"PARAM1=bar", "PARAM2=baz"}, clone.Environ())
+}
+
+func TestEnvCallbacks(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("PARAM1", "foo")
+	env.Set("PARAM2", "bar")
+	env.Set("OTHER", "baz")
+	cb := env.Callbacks()
+	expectedResult := "foo PARAM1 PARAM2 default"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${PARAM1} ${!PARAM*} ${PARAM3:=default}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, "default", env.Get("PARAM3"))
+}
+
+func TestEnvTracksExports(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("PARAM1", "foo")
+	env.Set("PARAM2", "bar")
+	env.Set("PARAM3", "baz")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	env.Export("PARAM3")
+	env.Export("PARAM1")
+	env.Export("PARAM4")
+	clone := env.Clone()
+	env.Unset("PARAM1")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []string{"PARAM3=baz"}, env.Exports())
+	assert.False(t, env.IsExported("PARAM1"))
+	assert.False(t, env.IsExported("PARAM2"))
+	assert.True(t, env.IsExported("PARAM4"))
+	assert.Equal(t, []string{"PARAM1=foo", "PARAM3=baz"}, clone.Exports())
+}
+
+func TestZeroValueEnvIsUsable(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var env Env
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	setErr := env.Set("PARAM1", "foo")
+	exportErr := env.Export("PARAM1")
+	env.Set("PARAM2", "bar")
+	clone := env.Clone()
+	env.Unset("PARAM2")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, setErr)
+	assert.Nil(t, exportErr)
+	assert.Equal(t, "foo", env.Get("PARAM1"))
+	assert.True(t, env.IsExported("PARAM1"))
+	assert.Equal(t, []string{"PARAM1=foo"}, env.Environ())
+	assert.Equal(t, []string{"PARAM1=foo"}, env.Exports())
+	assert.Equal(t, []string{"PARAM1=foo", "PARAM2=bar"}, clone.Environ())
+}
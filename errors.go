@@ -35,24 +35,748 @@
 
 package shellexpand
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
 
 // ErrMismatchedBrace is returned if a string has more opening '{'
 // than closing '}'
 type ErrMismatchedBrace struct {
-	startIndex int
+	// Index is the byte offset of the unmatched '{' in the input
+	Index int
+
+	// Snippet is the part of the input around the unmatched '{'
+	Snippet string
+
+	// Hint suggests how to fix the input
+	Hint string
+}
+
+// NewErrMismatchedBrace creates an ErrMismatchedBrace for the '{' at
+// input[index]
+func NewErrMismatchedBrace(input string, index int) ErrMismatchedBrace {
+	retval := ErrMismatchedBrace{
+		Index:   index,
+		Snippet: snippetAround(input, index),
+		Hint:    "did you mean to escape this brace?",
+	}
+	if index > 0 && index <= len(input) && input[index-1] == '$' {
+		retval.Hint = "did you forget the closing '}'?"
+	}
+
+	return retval
 }
 
 func (e ErrMismatchedBrace) Error() string {
-	return fmt.Sprintf("unmatched '{' at position %d", e.startIndex)
+	return fmt.Sprintf("unmatched '{' at position %d", e.Index)
+}
+
+// Is returns true if the target is also an ErrMismatchedBrace, so that
+// you can use errors.Is() without knowing where the brace is
+func (e ErrMismatchedBrace) Is(target error) bool {
+	_, ok := target.(ErrMismatchedBrace)
+	return ok
 }
 
 // ErrMismatchedClosingBrace is returned if a string has more closing '}'
 // than opening '{'
 type ErrMismatchedClosingBrace struct {
-	index int
+	// Position is where the unmatched '}' is in the input, counting
+	// from 1 (i.e. its byte offset + 1)
+	Position int
+
+	// Snippet is the part of the input around the unmatched '}'
+	Snippet string
+
+	// Hint suggests how to fix the input
+	Hint string
+}
+
+// NewErrMismatchedClosingBrace creates an ErrMismatchedClosingBrace for
+// the '}' at input[index]
+func NewErrMismatchedClosingBrace(input string, index int) ErrMismatchedClosingBrace {
+	return ErrMismatchedClosingBrace{
+		Position: index + 1,
+		Snippet:  snippetAround(input, index),
+		Hint:     "did you mean to escape this brace?",
+	}
 }
 
 func (e ErrMismatchedClosingBrace) Error() string {
-	return fmt.Sprintf("unmatched '}' at position %d", e.index)
+	return fmt.Sprintf("unmatched '}' at position %d", e.Position)
+}
+
+// Is returns true if the target is also an ErrMismatchedClosingBrace, so
+// that you can use errors.Is() without knowing where the brace is
+func (e ErrMismatchedClosingBrace) Is(target error) bool {
+	_, ok := target.(ErrMismatchedClosingBrace)
+	return ok
+}
+
+// snippetRadius is how many bytes of input either side of a problem we
+// put into an error's Snippet
+const snippetRadius = 10
+
+// snippetAround returns the part of the input around input[index],
+// without splitting any multi-byte characters
+func snippetAround(input string, index int) string {
+	start := index - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := index + snippetRadius + 1
+	if end > len(input) {
+		end = len(input)
+	}
+	if start > end {
+		return ""
+	}
+
+	for start < end && !utf8.RuneStart(input[start]) {
+		start++
+	}
+	for end < len(input) && !utf8.RuneStart(input[end]) {
+		end++
+	}
+
+	return input[start:end]
+}
+
+// ErrUnterminatedQuote is returned if a string has an opening quote
+// without a matching closing quote
+type ErrUnterminatedQuote struct {
+	quote rune
+	index int
+}
+
+func (e ErrUnterminatedQuote) Error() string {
+	return fmt.Sprintf("unterminated %c at position %d", e.quote, e.index)
+}
+
+// ErrBadSubstitution is returned if the input contains a ${...} that
+// we do not know how to expand. UNIX shells refuse to run a command that
+// contains one.
+//
+// It is returned in strict mode, or if the ${...} is not supported by
+// the shell dialect that we are copying.
+type ErrBadSubstitution struct {
+	param string
+}
+
+func (e ErrBadSubstitution) Error() string {
+	return fmt.Sprintf("%s: bad substitution", e.param)
+}
+
+// Is returns true if the target is also an ErrBadSubstitution. It lets you
+// use errors.Is(err, ErrBadSubstitution{})
+func (e ErrBadSubstitution) Is(target error) bool {
+	_, ok := target.(ErrBadSubstitution)
+	return ok
+}
+
+// ErrUnsupportedOperator is returned in strict mode if the input contains
+// a ${...} that we understand, but do not support yet
+type ErrUnsupportedOperator struct {
+	param string
+}
+
+func (e ErrUnsupportedOperator) Error() string {
+	return fmt.Sprintf("%s: unsupported operator", e.param)
+}
+
+// Is returns true if the target is also an ErrUnsupportedOperator. It lets
+// you use errors.Is(err, ErrUnsupportedOperator{})
+func (e ErrUnsupportedOperator) Is(target error) bool {
+	_, ok := target.(ErrUnsupportedOperator)
+	return ok
+}
+
+// ErrBadPattern is returned if the input contains a glob pattern that
+// we cannot use
+type ErrBadPattern struct {
+	pattern string
+	err     error
+}
+
+func (e ErrBadPattern) Error() string {
+	return e.err.Error()
+}
+
+// Is returns true if the target is also an ErrBadPattern. It lets you
+// use errors.Is(err, ErrBadPattern{})
+func (e ErrBadPattern) Is(target error) bool {
+	_, ok := target.(ErrBadPattern)
+	return ok
+}
+
+// Unwrap returns the error that the glob package reported
+func (e ErrBadPattern) Unwrap() error {
+	return e.err
+}
+
+// ErrVarRequired is returned by ${var:?message} when var is unset or
+// empty
+type ErrVarRequired struct {
+	name    string
+	message string
+}
+
+func (e ErrVarRequired) Error() string {
+	if len(e.message) == 0 {
+		return fmt.Sprintf("%s: parameter null or not set", e.name)
+	}
+
+	return fmt.Sprintf("%s: %s", e.name, e.message)
+}
+
+// Is returns true if the target is also an ErrVarRequired. It lets you
+// use errors.Is(err, ErrVarRequired{})
+func (e ErrVarRequired) Is(target error) bool {
+	_, ok := target.(ErrVarRequired)
+	return ok
+}
+
+// ErrSubstringExpression is returned if ${var:offset:length} has a
+// negative length that ends before the substring starts
+type ErrSubstringExpression struct {
+	length string
+}
+
+func (e ErrSubstringExpression) Error() string {
+	return fmt.Sprintf("%s: substring expression < 0", e.length)
+}
+
+// Is returns true if the target is also an ErrSubstringExpression. It
+// lets you use errors.Is(err, ErrSubstringExpression{})
+func (e ErrSubstringExpression) Is(target error) bool {
+	_, ok := target.(ErrSubstringExpression)
+	return ok
+}
+
+// ErrBudgetExceeded is returned if expansion goes over one of the limits
+// set by WithBudget()
+//
+// Limit tells you which limit it was, and Max tells you what it was set
+// to
+type ErrBudgetExceeded struct {
+	Limit BudgetLimit
+	Max   int
+}
+
+func (e ErrBudgetExceeded) Error() string {
+	return fmt.Sprintf("expansion budget exceeded: more than %d %s", e.Max, e.Limit)
+}
+
+// Is returns true if the target is also an ErrBudgetExceeded. It lets
+// you use errors.Is(err, ErrBudgetExceeded{})
+func (e ErrBudgetExceeded) Is(target error) bool {
+	_, ok := target.(ErrBudgetExceeded)
+	return ok
+}
+
+// ErrNameNotAllowed is returned in strict mode if the input refers to
+// a variable that the WithNameFilter() option does not allow
+type ErrNameNotAllowed struct {
+	Name string
+}
+
+func (e ErrNameNotAllowed) Error() string {
+	return fmt.Sprintf("%s: variable not allowed", e.Name)
+}
+
+// Is returns true if the target is also an ErrNameNotAllowed. It lets
+// you use errors.Is(err, ErrNameNotAllowed{})
+func (e ErrNameNotAllowed) Is(target error) bool {
+	_, ok := target.(ErrNameNotAllowed)
+	return ok
+}
+
+// ErrNotAnAssignment is returned by ExpandAssignment() if the input
+// does not start with a valid `NAME=`
+type ErrNotAnAssignment struct {
+	Input string
+}
+
+func (e ErrNotAnAssignment) Error() string {
+	return fmt.Sprintf("%s: not a valid assignment", e.Input)
+}
+
+// Is returns true if the target is also an ErrNotAnAssignment. It lets
+// you use errors.Is(err, ErrNotAnAssignment{})
+func (e ErrNotAnAssignment) Is(target error) bool {
+	_, ok := target.(ErrNotAnAssignment)
+	return ok
+}
+
+// ErrDependencyCycle is returned by DependencyGraph.Order() if some of
+// the templates refer to each other in a loop
+//
+// Cycle lists the templates in the loop, starting and ending with the
+// same template
+type ErrDependencyCycle struct {
+	Cycle []string
+}
+
+func (e ErrDependencyCycle) Error() string {
+	return fmt.Sprintf("dependency cycle: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// Is returns true if the target is an ErrDependencyCycle or an
+// ErrCircularReference. It lets you use errors.Is(err, ErrDependencyCycle{})
+func (e ErrDependencyCycle) Is(target error) bool {
+	switch target.(type) {
+	case ErrDependencyCycle, ErrCircularReference:
+		return true
+	default:
+		return false
+	}
+}
+
+// As lets errors.As() turn an ErrDependencyCycle into an
+// ErrCircularReference
+func (e ErrDependencyCycle) As(target interface{}) bool {
+	circErr, ok := target.(*ErrCircularReference)
+	if ok {
+		*circErr = ErrCircularReference(e)
+	}
+	return ok
+}
+
+// ErrSliceExpansion is returned by ExpandSlice() if one or more entries
+// could not be expanded
+//
+// Errors holds the error for each failed entry, keyed by the entry's
+// index in the input slice
+type ErrSliceExpansion struct {
+	Errors map[int]error
+}
+
+func (e ErrSliceExpansion) Error() string {
+	indexes := e.indexes()
+
+	msgs := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		msgs = append(msgs, fmt.Sprintf("[%d]: %s", i, e.Errors[i]))
+	}
+
+	return fmt.Sprintf("unable to expand %d entries: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Is returns true if the target is also an ErrSliceExpansion, or if any
+// of the failed entries' errors match the target. It lets you use
+// errors.Is(err, ErrVarRequired{}) on the result of ExpandSlice()
+func (e ErrSliceExpansion) Is(target error) bool {
+	_, ok := target.(ErrSliceExpansion)
+	if ok {
+		return true
+	}
+
+	for _, i := range e.indexes() {
+		if errors.Is(e.Errors[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first failed entry (in index order) whose error matches
+// the target, and sets the target to that error
+func (e ErrSliceExpansion) As(target interface{}) bool {
+	for _, i := range e.indexes() {
+		if errors.As(e.Errors[i], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexes returns the indexes of the failed entries, sorted
+func (e ErrSliceExpansion) indexes() []int {
+	retval := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		retval = append(retval, i)
+	}
+	sort.Ints(retval)
+
+	return retval
+}
+
+// ErrMapExpansion is returned by ExpandMap() if one or more entries
+// could not be expanded
+//
+// Errors holds the error for each failed entry, keyed by the entry's
+// key in the input map
+type ErrMapExpansion struct {
+	Errors map[string]error
+}
+
+func (e ErrMapExpansion) Error() string {
+	keys := e.keys()
+
+	msgs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", key, e.Errors[key]))
+	}
+
+	return fmt.Sprintf("unable to expand %d entries: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Is returns true if the target is also an ErrMapExpansion, or if any
+// of the failed entries' errors match the target. It lets you use
+// errors.Is(err, ErrVarRequired{}) on the result of ExpandMap() and
+// ExpandAll()
+func (e ErrMapExpansion) Is(target error) bool {
+	_, ok := target.(ErrMapExpansion)
+	if ok {
+		return true
+	}
+
+	for _, key := range e.keys() {
+		if errors.Is(e.Errors[key], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first failed entry (in key order) whose error matches
+// the target, and sets the target to that error
+func (e ErrMapExpansion) As(target interface{}) bool {
+	for _, key := range e.keys() {
+		if errors.As(e.Errors[key], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// keys returns the keys of the failed entries, sorted
+func (e ErrMapExpansion) keys() []string {
+	retval := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		retval = append(retval, key)
+	}
+	sort.Strings(retval)
+
+	return retval
+}
+
+// ErrUnknownSpecifier is returned if the input contains a %x specifier
+// that the WithSpecifiers() lookup function does not know
+type ErrUnknownSpecifier struct {
+	Specifier rune
+}
+
+func (e ErrUnknownSpecifier) Error() string {
+	return fmt.Sprintf("%%%c: unknown specifier", e.Specifier)
+}
+
+// Is returns true if the target is also an ErrUnknownSpecifier. It lets
+// you use errors.Is(err, ErrUnknownSpecifier{})
+func (e ErrUnknownSpecifier) Is(target error) bool {
+	_, ok := target.(ErrUnknownSpecifier)
+	return ok
+}
+
+// ErrCommandFailed is returned if the command in a $(...) command
+// substitution could not be run, or reported an error
+type ErrCommandFailed struct {
+	Command string
+	Err     error
+}
+
+func (e ErrCommandFailed) Error() string {
+	return fmt.Sprintf("%s: %s", e.Command, e.Err)
+}
+
+// Is returns true if the target is also an ErrCommandFailed. It lets
+// you use errors.Is(err, ErrCommandFailed{})
+func (e ErrCommandFailed) Is(target error) bool {
+	_, ok := target.(ErrCommandFailed)
+	return ok
+}
+
+// Unwrap returns the error that the RunCommand callback reported
+func (e ErrCommandFailed) Unwrap() error {
+	return e.Err
+}
+
+// ErrOutputTooLarge is returned by the NewExecRunner() RunCommand if a
+// command writes more than the allowed amount of output
+type ErrOutputTooLarge struct {
+	Limit int
+}
+
+func (e ErrOutputTooLarge) Error() string {
+	return fmt.Sprintf("command output is larger than the limit of %d bytes", e.Limit)
+}
+
+// Is returns true if the target is also an ErrOutputTooLarge. It lets
+// you use errors.Is(err, ErrOutputTooLarge{})
+func (e ErrOutputTooLarge) Is(target error) bool {
+	_, ok := target.(ErrOutputTooLarge)
+	return ok
+}
+
+// ErrEmptyCommand is returned by the NewExecRunner() RunCommand if it
+// is not given a command to run
+type ErrEmptyCommand struct{}
+
+func (e ErrEmptyCommand) Error() string {
+	return "no command to run"
+}
+
+// Is returns true if the target is also an ErrEmptyCommand. It lets
+// you use errors.Is(err, ErrEmptyCommand{})
+func (e ErrEmptyCommand) Is(target error) bool {
+	_, ok := target.(ErrEmptyCommand)
+	return ok
+}
+
+// ErrSandboxed is returned if the input tries to do something that the
+// WithSandbox() option does not allow
+type ErrSandboxed struct {
+	Feature string
+}
+
+func (e ErrSandboxed) Error() string {
+	return fmt.Sprintf("%s is not allowed in the sandbox", e.Feature)
+}
+
+// Is returns true if the target is also an ErrSandboxed. It lets you
+// use errors.Is(err, ErrSandboxed{})
+func (e ErrSandboxed) Is(target error) bool {
+	_, ok := target.(ErrSandboxed)
+	return ok
+}
+
+// ErrReadOnlyVar is returned if the input tries to assign to a variable
+// that the WithReadOnly() option has marked as read-only, or if there is
+// no AssignToVar callback to assign to it with
+type ErrReadOnlyVar struct {
+	Name string
+}
+
+func (e ErrReadOnlyVar) Error() string {
+	return fmt.Sprintf("%s: readonly variable", e.Name)
+}
+
+// Is returns true if the target is also an ErrReadOnlyVar. It lets you
+// use errors.Is(err, ErrReadOnlyVar{})
+func (e ErrReadOnlyVar) Is(target error) bool {
+	_, ok := target.(ErrReadOnlyVar)
+	return ok
+}
+
+// ErrUnboundVariable is returned if ShellOpts.NoUnset is set, and the
+// input expands a variable that is not set
+type ErrUnboundVariable struct {
+	Name string
+}
+
+func (e ErrUnboundVariable) Error() string {
+	return fmt.Sprintf("%s: unbound variable", e.Name)
+}
+
+// Is returns true if the target is also an ErrUnboundVariable. It lets
+// you use errors.Is(err, ErrUnboundVariable{})
+func (e ErrUnboundVariable) Is(target error) bool {
+	_, ok := target.(ErrUnboundVariable)
+	return ok
+}
+
+// ErrEventNotFound is returned by history expansion if the history
+// entry that the input refers to does not exist
+type ErrEventNotFound struct {
+	Event string
+}
+
+func (e ErrEventNotFound) Error() string {
+	return fmt.Sprintf("%s: event not found", e.Event)
+}
+
+// Is returns true if the target is also an ErrEventNotFound. It lets
+// you use errors.Is(err, ErrEventNotFound{})
+func (e ErrEventNotFound) Is(target error) bool {
+	_, ok := target.(ErrEventNotFound)
+	return ok
+}
+
+// ErrBadWordSpecifier is returned by history expansion if the input
+// refers to a word that the history entry does not have
+type ErrBadWordSpecifier struct {
+	Designator string
+}
+
+func (e ErrBadWordSpecifier) Error() string {
+	return fmt.Sprintf("%s: bad word specifier", e.Designator)
+}
+
+// Is returns true if the target is also an ErrBadWordSpecifier. It lets
+// you use errors.Is(err, ErrBadWordSpecifier{})
+func (e ErrBadWordSpecifier) Is(target error) bool {
+	_, ok := target.(ErrBadWordSpecifier)
+	return ok
+}
+
+// ErrExpansionErrors is returned by an Expander that has the
+// WithAllErrors() option set, when more than one part of the input
+// could not be expanded
+//
+// Errors holds each problem, in the order that they appear in the
+// input
+type ErrExpansionErrors struct {
+	Errors []ExpansionError
+}
+
+func (e ErrExpansionErrors) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%d:%d: %s", err.Line, err.Column, err))
+	}
+
+	return fmt.Sprintf("unable to expand %d parts of the input: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Is returns true if the target is also an ErrExpansionErrors, or if
+// any of the problems match the target. It lets you use
+// errors.Is(err, ErrBadPattern{}) to look for a particular problem.
+func (e ErrExpansionErrors) Is(target error) bool {
+	_, ok := target.(ErrExpansionErrors)
+	if ok {
+		return true
+	}
+
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first problem that matches the target, and sets the
+// target to it
+func (e ErrExpansionErrors) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns each of the problems, just like the errors that
+// errors.Join() returns. Only Go 1.20 and later look inside them; Is()
+// and As() work on older versions too.
+func (e ErrExpansionErrors) Unwrap() []error {
+	retval := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		retval = append(retval, err)
+	}
+
+	return retval
+}
+
+// ErrCircularReference describes variables or templates that refer to
+// each other in a loop
+//
+// DependencyGraph.Order() and ExpandAll() report loops as an
+// ErrDependencyCycle. errors.Is() and errors.As() treat the two types as
+// the same error, so you can check for either one.
+//
+// Cycle lists the variables in the loop, starting and ending with the
+// same variable
+type ErrCircularReference struct {
+	Cycle []string
+}
+
+func (e ErrCircularReference) Error() string {
+	return fmt.Sprintf("circular reference: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// Is returns true if the target is an ErrCircularReference or an
+// ErrDependencyCycle. It lets you use errors.Is(err, ErrCircularReference{})
+func (e ErrCircularReference) Is(target error) bool {
+	switch target.(type) {
+	case ErrCircularReference, ErrDependencyCycle:
+		return true
+	default:
+		return false
+	}
+}
+
+// As lets errors.As() turn an ErrCircularReference into an
+// ErrDependencyCycle
+func (e ErrCircularReference) As(target interface{}) bool {
+	cycleErr, ok := target.(*ErrDependencyCycle)
+	if ok {
+		*cycleErr = ErrDependencyCycle(e)
+	}
+	return ok
+}
+
+// ErrDeadlineExceeded is returned by an Expander that has the
+// WithTimeout() option set, when a call takes longer than Timeout
+//
+// It also matches context.DeadlineExceeded when you use errors.Is()
+type ErrDeadlineExceeded struct {
+	Timeout time.Duration
+}
+
+func (e ErrDeadlineExceeded) Error() string {
+	return fmt.Sprintf("expansion did not finish within %s", e.Timeout)
+}
+
+func (e ErrDeadlineExceeded) Is(target error) bool {
+	_, ok := target.(ErrDeadlineExceeded)
+	return ok
+}
+
+func (e ErrDeadlineExceeded) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// ErrInvalidInput is returned by an Expander that has the
+// WithInvalidInput(InvalidInputReject) option set, when the input
+// contains a NUL byte or invalid UTF-8
+//
+// Offset is where the first bad byte is, and Byte is its value
+type ErrInvalidInput struct {
+	Offset int
+	Byte   byte
+}
+
+func (e ErrInvalidInput) Error() string {
+	if e.Byte == 0 {
+		return fmt.Sprintf("NUL byte at offset %d", e.Offset)
+	}
+
+	return fmt.Sprintf("invalid UTF-8 byte 0x%02x at offset %d", e.Byte, e.Offset)
+}
+
+func (e ErrInvalidInput) Is(target error) bool {
+	_, ok := target.(ErrInvalidInput)
+	return ok
+}
+
+// ErrTooManyPasses is returned by ExpandFully() when its input is still
+// changing after Max passes of expansion
+type ErrTooManyPasses struct {
+	Max int
+}
+
+func (e ErrTooManyPasses) Error() string {
+	return fmt.Sprintf("input still changing after %d passes of expansion", e.Max)
+}
+
+func (e ErrTooManyPasses) Is(target error) bool {
+	_, ok := target.(ErrTooManyPasses)
+	return ok
 }
@@ -36,6 +36,7 @@
 package shellexpand
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,7 +48,7 @@ func TestErrMismatchedBrace(t *testing.T) {
 	// ----------------------------------------------------------------
 	// setup your test
 
-	testData := ErrMismatchedBrace{10}
+	testData := ErrMismatchedBrace{Index: 10}
 	expectedResult := "unmatched '{' at position 10"
 
 	// ----------------------------------------------------------------
@@ -59,6 +60,8 @@ func TestErrMismatchedBrace(t *testing.T) {
 	// test the results
 
 	assert.Equal(t, expectedResult, actualResult)
+	assert.True(t, errors.Is(testData, ErrMismatchedBrace{}))
+	assert.False(t, errors.Is(testData, ErrMismatchedClosingBrace{}))
 }
 
 func TestErrMismatchedClosingBrace(t *testing.T) {
@@ -67,7 +70,7 @@ func TestErrMismatchedClosingBrace(t *testing.T) {
 	// ----------------------------------------------------------------
 	// setup your test
 
-	testData := ErrMismatchedClosingBrace{10}
+	testData := ErrMismatchedClosingBrace{Position: 10}
 	expectedResult := "unmatched '}' at position 10"
 
 	// ----------------------------------------------------------------
@@ -79,4 +82,256 @@ func TestErrMismatchedClosingBrace(t *testing.T) {
 	// test the results
 
 	assert.Equal(t, expectedResult, actualResult)
+	assert.True(t, errors.Is(testData, ErrMismatchedClosingBrace{}))
+	assert.False(t, errors.Is(testData, ErrMismatchedBrace{}))
+}
+
+func TestExpandReturnsErrorsThatMatchMismatchedBraces(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(ExpansionCallbacks{}, WithStrict())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, openErr := unit.Expand("a { b")
+	_, closeErr := unit.Expand("a } b")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(openErr, ErrMismatchedBrace{}), "%v", openErr)
+	assert.True(t, errors.Is(closeErr, ErrMismatchedClosingBrace{}), "%v", closeErr)
+}
+
+func TestNewErrMismatchedBraceAddsContext(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "echo hello x{a,b world and goodbye"
+	expectedResult := ErrMismatchedBrace{
+		Index:   12,
+		Snippet: "ho hello x{a,b world ",
+		Hint:    "did you mean to escape this brace?",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := NewErrMismatchedBrace(testData, 12)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestNewErrMismatchedBraceSpotsUnterminatedParams(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "${PARAM1"
+	expectedResult := ErrMismatchedBrace{
+		Index:   1,
+		Snippet: "${PARAM1",
+		Hint:    "did you forget the closing '}'?",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := NewErrMismatchedBrace(testData, 1)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestNewErrMismatchedClosingBraceAddsContext(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "héllo wörld}"
+	expectedResult := ErrMismatchedClosingBrace{
+		Position: 14,
+		Snippet:  "llo wörld}",
+		Hint:     "did you mean to escape this brace?",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := NewErrMismatchedClosingBrace(testData, 13)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrSliceExpansion(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := ErrSliceExpansion{
+		Errors: map[int]error{
+			3: errors.New("bad pattern"),
+			1: errors.New("no such variable"),
+		},
+	}
+	expectedResult := "unable to expand 2 entries: [1]: no such variable; [3]: bad pattern"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrMapExpansion(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := ErrMapExpansion{
+		Errors: map[string]error{
+			"PATH": errors.New("bad pattern"),
+			"HOME": errors.New("no such variable"),
+		},
+	}
+	expectedResult := "unable to expand 2 entries: HOME: no such variable; PATH: bad pattern"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestErrBadSubstitution(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := ErrBadSubstitution{"${++}"}
+	expectedResult := "${++}: bad substitution"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+	assert.True(t, errors.Is(testData, ErrBadSubstitution{}))
+	assert.False(t, errors.Is(testData, ErrVarRequired{}))
+}
+
+func TestErrVarRequired(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []ErrVarRequired{
+		{"PARAM1", "must be set"},
+		{"PARAM1", ""},
+	}
+	expectedResult := []string{
+		"PARAM1: must be set",
+		"PARAM1: parameter null or not set",
+	}
+
+	for i := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := testData[i].Error()
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult[i], actualResult)
+	}
+}
+
+func TestExpandReturnsErrVarRequired(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "", false
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Expand("${PARAM1:?}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrVarRequired{}))
+	assert.False(t, errors.Is(err, ErrBadPattern{}))
+	assert.Equal(t, "PARAM1: parameter null or not set", err.Error())
+
+	var reqErr ErrVarRequired
+	assert.True(t, errors.As(err, &reqErr))
+	assert.Equal(t, "PARAM1", reqErr.name)
+}
+
+func TestExpandReturnsErrBadPattern(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "abc", true
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Expand("${PARAM1^^[}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrBadPattern{}))
+
+	var patternErr ErrBadPattern
+	assert.True(t, errors.As(err, &patternErr))
+	assert.Equal(t, "[", patternErr.pattern)
+	assert.Equal(t, patternErr.err.Error(), err.Error())
 }
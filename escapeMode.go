@@ -0,0 +1,139 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+// EscapeMode controls what Expand() does with backslashes
+type EscapeMode int
+
+// these are the ways that we can handle backslashes
+const (
+	// EscapeAll makes a backslash escape whatever character comes after
+	// it. The backslash is removed. It is the default.
+	//
+	// Quotes are ignored; a backslash inside quotes is treated the same
+	// as a backslash anywhere else.
+	EscapeAll EscapeMode = iota
+
+	// EscapeBash copies how bash treats backslashes in a command:
+	//
+	// - outside of quotes, a backslash escapes whatever comes after it,
+	//   and is removed
+	// - inside double quotes, a backslash only escapes $, `, ", \ and
+	//   newline; before anything else, it is kept
+	// - inside single quotes, a backslash is just a backslash. Nothing
+	//   inside single quotes is expanded.
+	//
+	// An escaped newline is a line continuation, and is removed.
+	EscapeBash
+
+	// EscapePOSIX copies how POSIX shells treat backslashes in a
+	// here-document. A backslash only escapes $, `, \ and newline (and
+	// } inside ${...}), and is removed. Before anything else, it is
+	// kept. Quotes are ignored.
+	//
+	// An escaped newline is a line continuation, and is removed.
+	EscapePOSIX
+
+	// EscapePreserve keeps every backslash in the output. A backslash
+	// still stops the character after it from being expanded.
+	EscapePreserve
+)
+
+func (m EscapeMode) String() string {
+	switch m {
+	case EscapeAll:
+		return "all"
+	case EscapeBash:
+		return "bash"
+	case EscapePOSIX:
+		return "posix"
+	case EscapePreserve:
+		return "preserve"
+	default:
+		return "unknown escape mode"
+	}
+}
+
+// WithEscapeMode changes what the Expander does with backslashes
+//
+// It does not change ExpandArgs(), which always follows the shell's rules
+// for quotes and backslashes.
+func WithEscapeMode(mode EscapeMode) Option {
+	return func(opts *options) {
+		opts.escapeMode = mode
+	}
+}
+
+// unescape returns what an escape (a backslash, and the character that
+// it escapes) expands to
+//
+// set `inWord` if the escape is in the word after a parameter
+// expansion's operator, where \} is an escape too
+func (m EscapeMode) unescape(text string, inWord bool) string {
+	// a backslash at the end of the input has nothing to escape
+	if len(text) < 2 {
+		if m == EscapeAll {
+			return ""
+		}
+		return text
+	}
+
+	switch m {
+	case EscapePreserve:
+		return text
+	case EscapeBash:
+		// scanExpansions() has already worked out whether or not the
+		// backslash is inside quotes
+		if text[1] == '\n' {
+			return ""
+		}
+		return text[1:]
+	case EscapePOSIX:
+		switch text[1] {
+		case '\n':
+			return ""
+		case '$', '`', '\\':
+			return text[1:]
+		case '}':
+			if inWord {
+				return text[1:]
+			}
+		}
+		return text
+	default:
+		return text[1:]
+	}
+}
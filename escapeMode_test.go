@@ -0,0 +1,145 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+type escapeModeTestData struct {
+	input          string
+	expectedResult string
+}
+
+func newEscapeModeTestCase() shelltest.Case {
+	return shelltest.Case{
+		Vars: map[string]string{
+			"HOME":   "/home/me",
+			"PARAM1": "foo",
+		},
+	}
+}
+
+func TestEscapeAllRemovesEveryBackslash(t *testing.T) {
+	testData := []escapeModeTestData{
+		{`a\b \$PARAM1 \\`, `ab $PARAM1 \`},
+		{`'\$PARAM1' "\a"`, `'$PARAM1' "a"`},
+		{`${UNSET:-a\b}`, `ab`},
+	}
+	testEscapeModeTestCases(t, EscapeAll, testData)
+}
+
+func TestEscapeBashFollowsQuotingRules(t *testing.T) {
+	// these are the results that bash gives us, without quote removal:
+	//
+	//	printf '[%s]' "\$PARAM1 \a $PARAM1"
+	testData := []escapeModeTestData{
+		{`a\b \$PARAM1 \\ a\ b`, `ab $PARAM1 \ a b`},
+		{`"\$PARAM1 \a \" $PARAM1"`, `"$PARAM1 \a " foo"`},
+		{`'\$PARAM1 $PARAM1 ~'`, `'\$PARAM1 $PARAM1 ~'`},
+		{`"{a,b} ~" {a,b} ~`, `"{a,b} ~" a b /home/me`},
+		{`\'$PARAM1'`, `'foo'`},
+		{`"'$PARAM1'"`, `"'foo'"`},
+		{"a\\\nb", "ab"},
+		{`${UNSET:-a\b}`, `ab`},
+	}
+	testEscapeModeTestCases(t, EscapeBash, testData)
+}
+
+func TestEscapePOSIXMatchesHereDocuments(t *testing.T) {
+	testData := []escapeModeTestData{
+		{`a\b \$PARAM1 \\ a\ b`, `a\b $PARAM1 \ a\ b`},
+		{`'\$PARAM1 $PARAM1'`, `'$PARAM1 foo'`},
+		{`"\$PARAM1 \a $PARAM1"`, `"$PARAM1 \a foo"`},
+		{`\{a,b} \~`, `\{a,b} \~`},
+		{`${UNSET:-\}} ${UNSET:-a\b} ${UNSET:-\$PARAM1}`, `} a\b $PARAM1`},
+		{"a\\\nb", "ab"},
+	}
+	testEscapeModeTestCases(t, EscapePOSIX, testData)
+
+	for _, testCase := range testData {
+		shellCase := newEscapeModeTestCase()
+		shellCase.Commands = []string{
+			"cat <<EOF",
+			testCase.input,
+			"EOF",
+		}
+		shellActualResult, err := shelltest.Run("dash", &shellCase)
+		if err != nil {
+			t.Skip("dash is not available")
+		}
+		assert.Equal(t, testCase.expectedResult, shellActualResult, shelltest.Script(&shellCase))
+	}
+}
+
+func TestEscapePreserveKeepsEveryBackslash(t *testing.T) {
+	testData := []escapeModeTestData{
+		{`a\b \$PARAM1 \\`, `a\b \$PARAM1 \\`},
+		{`\{a,b} \~ \${PARAM1}`, `\{a,b} \~ \${PARAM1}`},
+		{`${UNSET:-\$PARAM1}`, `\$PARAM1`},
+	}
+	testEscapeModeTestCases(t, EscapePreserve, testData)
+}
+
+func testEscapeModeTestCases(t *testing.T, mode EscapeMode, testData []escapeModeTestData) {
+	t.Parallel()
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		shellCase := newEscapeModeTestCase()
+		cb := ExpansionCallbacks{
+			LookupVar:     shellCase.LookupVar,
+			LookupHomeDir: shellCase.LookupHomeDir,
+		}
+		unit := NewExpander(cb, WithEscapeMode(mode))
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(testCase.input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.expectedResult, actualResult, "%s: %s", mode, testCase.input)
+	}
+}
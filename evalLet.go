@@ -0,0 +1,142 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+
+	"github.com/ganbarodigital/go_shellexpand/arith"
+)
+
+// EvalLet evaluates each of the arithmetic expressions in turn, just
+// like bash's `let` builtin does, and returns their values
+//
+// Parameters inside each expression are expanded first, just like they
+// are inside $((...)) and (( ... )). (With `let`, the shell expands them
+// before `let` sees them.) Assignments are written back through your
+// AssignToVar callback, so each expression sees the changes made by the
+// ones before it.
+//
+// `let` (and `(( ... ))`) succeed if the last value is not zero.
+//
+// If an expression cannot be evaluated, you get an ExpansionError, and
+// the expressions after it are not evaluated.
+func EvalLet(exprs []string, cb ExpansionCallbacks) ([]int64, error) {
+	return EvalLetContext(cb.context(), exprs, cb)
+}
+
+// EvalLetContext evaluates each of the arithmetic expressions in turn,
+// just like EvalLet() does. It uses the given context in the same way
+// that ExpandContext() does.
+func EvalLetContext(ctx context.Context, exprs []string, cb ExpansionCallbacks) ([]int64, error) {
+	cb.ctx = ctx
+
+	retval := make([]int64, 0, len(exprs))
+	for _, expr := range exprs {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := evalLetExpr(expr, cb)
+
+		// don't hide the caller's own error from them
+		ctxErr := ctx.Err()
+		if ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		retval = append(retval, value)
+	}
+
+	return retval, nil
+}
+
+// EvalLet evaluates each of the arithmetic expressions in turn, just
+// like the package-level EvalLet() does
+func (e *Expander) EvalLet(exprs []string) ([]int64, error) {
+	return e.EvalLetContext(context.Background(), exprs)
+}
+
+// EvalLetContext evaluates each of the arithmetic expressions in turn,
+// just like the package-level EvalLetContext() does
+func (e *Expander) EvalLetContext(ctx context.Context, exprs []string) ([]int64, error) {
+	ctx, done := e.withTimeout(ctx)
+	cb := e.callbacks()
+	retval, err := EvalLetContext(ctx, exprs, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
+
+// evalLetExpr expands and then evaluates a single expression
+func evalLetExpr(expr string, cb ExpansionCallbacks) (int64, error) {
+	expr, err := checkInput(expr, cb)
+	if err != nil {
+		return 0, err
+	}
+
+	err = cb.budget.spend(BudgetExpansions)
+	if err != nil {
+		return 0, newExpansionError(PhaseArithmeticExpansion, expr, 0, len(expr), err)
+	}
+
+	expanded, err := expandParameters(expr, cb)
+	if err != nil {
+		return 0, locateExpansionError(err, expr, expr, 0)
+	}
+
+	var retval int64
+	if cb.floatArithmetic() {
+		var value arith.Number
+		value, err = arith.EvalFloat(expanded, arithVars(cb))
+		retval = value.Int()
+	} else {
+		retval, err = arith.Eval(expanded, arithVars(cb))
+	}
+	if err == nil {
+		// looking up the expression's variables may have used up
+		// our budget
+		err = cb.budget.exceeded()
+	}
+	if err != nil {
+		return 0, newExpansionError(PhaseArithmeticExpansion, expr, 0, len(expr), err)
+	}
+
+	return retval, nil
+}
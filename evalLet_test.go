@@ -0,0 +1,184 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/arith"
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalLetSharesVariablesBetweenExpressions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{}
+	cb := newTestCallbacks(vars)
+	exprs := []string{"x = 5", "y = x * 2", "x++", "y - x"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := EvalLet(exprs, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{5, 10, 5, 4}, actualResult)
+	assert.Equal(t, map[string]string{"x": "6", "y": "10"}, vars)
+}
+
+func TestEvalLetMatchesBash(t *testing.T) {
+	t.Parallel()
+
+	if !shelltest.Available("bash") {
+		t.Skip("bash is not available")
+	}
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"step": "3",
+	}
+	cb := newTestCallbacks(vars)
+	exprs := []string{"total = 10", "total += $step", "total <<= 1", "total % 2"}
+	shellCase := shelltest.Case{
+		Vars: map[string]string{
+			"step": "3",
+		},
+		Commands: []string{
+			`let 'total = 10' "total += $step" 'total <<= 1' 'total % 2'`,
+			`echo $? $total`,
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := EvalLet(exprs, cb)
+	shellResult, shellErr := shelltest.Run("bash", &shellCase)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Nil(t, shellErr)
+	assert.Equal(t, []int64{10, 13, 26, 0}, actualResult)
+
+	// let fails if the last value is 0
+	assert.Equal(t, "1 "+vars["total"], shellResult)
+}
+
+func TestEvalLetStopsAtTheFirstError(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{}
+	cb := newTestCallbacks(vars)
+	exprs := []string{"x = 1", "x / 0", "x = 2"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := EvalLet(exprs, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+	assert.True(t, errors.Is(err, arith.ErrDivisionByZero{}))
+
+	var expansionErr ExpansionError
+	assert.True(t, errors.As(err, &expansionErr))
+	assert.Equal(t, PhaseArithmeticExpansion, expansionErr.Phase)
+	assert.Equal(t, "x / 0", expansionErr.Substring)
+	assert.Equal(t, "1", vars["x"])
+}
+
+func TestExpanderEvalLetUsesTheExpandersOptions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"COUNT": "1",
+	}
+	cb := newTestCallbacks(vars)
+	expander := NewExpander(cb, WithReadOnly("COUNT"))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := expander.EvalLet([]string{"COUNT++"})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrReadOnlyVar{}))
+	assert.Equal(t, "1", vars["COUNT"])
+}
+
+func TestEvalLetTruncatesFloats(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{}
+	expander := NewExpander(newTestCallbacks(vars), WithFloatArithmetic())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expander.EvalLet([]string{"x = 7 / 2.0", "-x"})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{3, -3}, actualResult)
+	assert.Equal(t, "3.5", vars["x"])
+}
@@ -0,0 +1,168 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"time"
+)
+
+// these are the limits that NewExecRunner() uses, if you do not set
+// your own
+const (
+	// DefaultExecTimeout is how long a command can run for
+	DefaultExecTimeout = 10 * time.Second
+
+	// DefaultExecMaxOutput is how many bytes of output a command can
+	// write
+	DefaultExecMaxOutput = 1024 * 1024
+)
+
+// ExecOptions controls how the RunCommand from NewExecRunner() runs
+// each command
+type ExecOptions struct {
+	// Timeout is how long each command can run for, before it is
+	// killed. Zero means DefaultExecTimeout.
+	Timeout time.Duration
+
+	// Env is the environment that each command runs with, in the same
+	// "KEY=value" form as os.Environ(). Nil means an empty environment;
+	// the command does NOT see your program's environment unless you
+	// pass it in.
+	Env []string
+
+	// Dir is the working directory that each command runs in. An
+	// empty string means your program's current working directory.
+	Dir string
+
+	// MaxOutput is how many bytes each command can write to its
+	// standard output, before it is killed. Zero means
+	// DefaultExecMaxOutput.
+	MaxOutput int
+}
+
+// NewExecRunner returns a RunCommand that uses os/exec to run each
+// command directly (not via a shell), with the given limits.
+//
+// Anything that the command writes to its standard error is thrown
+// away. A command that exits with a non-zero status is an error, and so
+// is an empty list of args (ErrEmptyCommand).
+//
+// On UNIX-like systems, each command runs in its own process group. When
+// it runs out of time (or writes too much), we kill the whole group, so
+// that a pipeline such as `sh -c "sleep 60 | cat"` is stopped too.
+// Elsewhere, only the command itself is killed.
+func NewExecRunner(opts ExecOptions) RunCommand {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultExecTimeout
+	}
+	if opts.MaxOutput <= 0 {
+		opts.MaxOutput = DefaultExecMaxOutput
+	}
+	env := opts.Env
+	if env == nil {
+		env = []string{}
+	}
+
+	return func(ctx context.Context, args []string) (string, error) {
+		if len(args) == 0 {
+			return "", ErrEmptyCommand{}
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+
+		stdout := limitedBuffer{limit: opts.MaxOutput, cancel: cancel}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Env = env
+		cmd.Dir = opts.Dir
+		cmd.Stdout = &stdout
+		startProcessGroup(cmd)
+
+		err := cmd.Start()
+		if err != nil {
+			return "", err
+		}
+
+		// exec.CommandContext() only kills the command itself; anything
+		// that it has started keeps our stdout pipe open, and cmd.Wait()
+		// would wait for that too
+		finished := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				killProcessGroup(cmd.Process)
+			case <-finished:
+			}
+		}()
+
+		err = cmd.Wait()
+		close(finished)
+		switch {
+		case stdout.exceeded:
+			return "", ErrOutputTooLarge{opts.MaxOutput}
+		case ctx.Err() != nil:
+			return "", ctx.Err()
+		case err != nil:
+			return "", err
+		}
+
+		return stdout.buf.String(), nil
+	}
+}
+
+// limitedBuffer collects a command's output, and stops the command if
+// it writes too much
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	exceeded bool
+
+	// stops the command
+	cancel context.CancelFunc
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.limit {
+		b.exceeded = true
+		b.cancel()
+		return 0, ErrOutputTooLarge{b.limit}
+	}
+
+	return b.buf.Write(p)
+}
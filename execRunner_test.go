@@ -0,0 +1,236 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func skipIfNoCommand(t *testing.T, name string) {
+	_, err := exec.LookPath(name)
+	if err != nil {
+		t.Skip("unable to find command:", name)
+	}
+}
+
+func TestExecRunnerRunsCommands(t *testing.T) {
+	t.Parallel()
+	skipIfNoCommand(t, "echo")
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewEnv().Callbacks()
+	cb.RunCommand = NewExecRunner(ExecOptions{})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("[$(echo hello   world)]", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "[hello world]", actualResult)
+}
+
+func TestExecRunnerUsesOnlyTheGivenEnvironmentAndDirectory(t *testing.T) {
+	t.Parallel()
+	skipIfNoCommand(t, "sh")
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	dir := t.TempDir()
+	unit := NewExecRunner(ExecOptions{
+		Env: []string{"GREETING=hi"},
+		Dir: dir,
+	})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit(context.Background(), []string{"sh", "-c", `echo "$GREETING:$HOME:$(pwd)"`})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "hi::"+dir+"\n", actualResult)
+}
+
+func TestExecRunnerStopsCommandsThatRunTooLong(t *testing.T) {
+	t.Parallel()
+	skipIfNoCommand(t, "sleep")
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExecRunner(ExecOptions{Timeout: 50 * time.Millisecond})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit(context.Background(), []string{"sleep", "5"})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestExecRunnerStopsCommandsThatStartOtherCommands(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not supported on windows")
+	}
+	skipIfNoCommand(t, "sh")
+	skipIfNoCommand(t, "sleep")
+	skipIfNoCommand(t, "cat")
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// sleep and cat keep our stdout pipe open after sh has been killed
+	unit := NewExecRunner(ExecOptions{Timeout: 50 * time.Millisecond})
+	start := time.Now()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit(context.Background(), []string{"sh", "-c", "sleep 5 | cat"})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+}
+
+func TestExecRunnerStopsCommandsThatWriteTooMuch(t *testing.T) {
+	t.Parallel()
+	skipIfNoCommand(t, "yes")
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExecRunner(ExecOptions{MaxOutput: 1024})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit(context.Background(), []string{"yes"})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrOutputTooLarge{}))
+}
+
+func TestExecRunnerReportsFailedCommands(t *testing.T) {
+	t.Parallel()
+	skipIfNoCommand(t, "false")
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewEnv().Callbacks()
+	cb.RunCommand = NewExecRunner(ExecOptions{})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Expand("$(false)", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var exitErr *exec.ExitError
+	assert.True(t, errors.Is(err, ErrCommandFailed{}))
+	assert.True(t, errors.As(err, &exitErr))
+}
+
+func TestExecRunnerReturnsErrorForEmptyCommands(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExecRunner(ExecOptions{})
+	testData := [][]string{nil, {}}
+
+	for _, args := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit(context.Background(), args)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, "", actualResult)
+		assert.True(t, errors.Is(err, ErrEmptyCommand{}))
+	}
+}
+
+func TestExecRunnerExpandsBlankCommandsToNothing(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewEnv().Callbacks()
+	cb.RunCommand = NewExecRunner(ExecOptions{})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("[$( )] [$(   )] [$($EMPTY)]", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "[] [] []", actualResult)
+}
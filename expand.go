@@ -35,30 +35,177 @@
 
 package shellexpand
 
+import (
+	"context"
+	"strings"
+)
+
 // Expand replaces ${var} and $var in the input string. Variable values
 // are found by calling the supplied mapping function.
 //
 // This is a replacement for Golang's `os.Expand()` that supports full
 // UNIX shell string expansion. It is not a drop-in replacement, but it
 // should be straight-forward to migrate from `os.Expand()`
+//
+// If expanding the input does not change it, you get back the input
+// string itself, not a copy. Use ExpandResult() if you need to know
+// whether anything changed.
 func Expand(input string, cb ExpansionCallbacks) (string, error) {
+	return ExpandContext(cb.context(), input, cb)
+}
+
+// ExpandContext replaces ${var} and $var in the input string, just like
+// Expand() does.
+//
+// The given context is passed to any context-aware callbacks that you
+// have set in the ExpansionCallbacks. If the context is cancelled or its
+// deadline passes, expansion stops and the context's error is returned.
+func ExpandContext(ctx context.Context, input string, cb ExpansionCallbacks) (string, error) {
+	cb.ctx = ctx
+
+	// NUL bytes and invalid UTF-8 are dealt with before anything else
+	input, err := checkInput(input, cb)
+	if err != nil {
+		return "", err
+	}
+
+	// history expansion happens next, and only if it has been switched
+	// on
+	input, err = expandHistory(input, cb)
+	if err != nil {
+		return "", err
+	}
+
+	// strict mode: braces that do not match up
+	if cb.checksBraces() {
+		err = checkBraces(input)
+		if err != nil {
+			err = locateExpansionError(err, input, input, 0)
+
+			// in best-effort mode, the rest of the input can still be
+			// expanded
+			expErr, ok := err.(ExpansionError)
+			if !ok || cb.diagnostics == nil {
+				return "", err
+			}
+			*cb.diagnostics = append(*cb.diagnostics, expErr)
+		}
+	}
+
+	// fast path: most strings (especially in config files) have nothing
+	// in them to expand
+	if !hasExpansionChars(input) && !hasPercentVars(input, cb) && !hasSpecifiers(input, cb) && !cb.tracing() {
+		err = ctx.Err()
+		if err != nil {
+			return "", err
+		}
+		return input, nil
+	}
+
+	// tracing reports on each phase of expansion, from start to finish
+	//
+	// a source map can only be built in a single pass
+	if cb.tracing() && cb.sourceMap == nil {
+		return expandInPhases(ctx, input, cb)
+	}
+
+	err = ctx.Err()
+	if err != nil {
+		return "", err
+	}
+
+	// steps 1-4: brace, tilde, parameter and arithmetic expansion
+	//
+	// these all happen in a single pass
+	phases := scanParams
+	if cb.dialect().braceExpansion {
+		phases |= scanBraces
+	}
+	if cb.dialect().tildeExpansion {
+		phases |= scanTilde
+	}
+	if cb.varSyntax() == VarSyntaxPercent {
+		phases = scanParams
+	}
+	expanded, err := expandSpans(input, cb, phases)
+	if err != nil {
+		return "", locateExpansionError(err, input, input, 0)
+	}
+
+	// step 5: quote removal
+	expanded = expandQuoteRemoval(expanded)
+
+	// if nothing changed, give the caller back their own string, so
+	// that they are not holding onto two copies of it
+	if expanded == input {
+		return input, nil
+	}
+
+	// all done
+	return expanded, nil
+}
+
+// expandInPhases applies each phase of expansion to the whole input
+// string, one after the other
+//
+// it gives the same results as the single pass in ExpandContext(), but
+// lets us report what each phase did
+func expandInPhases(ctx context.Context, input string, cb ExpansionCallbacks) (string, error) {
+	// we need this to report where any errors are
+	original := input
+
 	// step 1: brace expansion
-	input = expandBraces(input)
+	if cb.dialect().braceExpansion && cb.varSyntax() != VarSyntaxPercent {
+		expanded, err := expandBraces(input, newBraceLimiter(ctx, cb.budget))
+		if err != nil {
+			return "", err
+		}
+		tracePhase(cb, PhaseBraceExpansion, input, expanded)
+		input = expanded
+	}
 
 	// step 2: tilde expansion
-	input = ExpandTilde(input, cb)
+	err := ctx.Err()
+	if err != nil {
+		return "", err
+	}
+	if cb.dialect().tildeExpansion {
+		expanded := ExpandTilde(input, cb)
+		tracePhase(cb, PhaseTildeExpansion, input, expanded)
+		input = expanded
+	}
 
-	// step 3: parameter & variable expansion
-	var err error
-	input, err = expandParameters(input, cb)
+	// steps 3-4: parameter & variable expansion, and arithmetic expansion
+	//
+	// bash expands these left to right, in a single pass
+	err = ctx.Err()
 	if err != nil {
 		return "", err
 	}
+	recovered := cb.recovered()
+	expanded, err := expandParameters(input, cb)
+	if err != nil {
+		return "", locateExpansionError(err, original, input, 0)
+	}
+	for i := recovered; i < cb.recovered(); i++ {
+		(*cb.diagnostics)[i] = locateExpansionError((*cb.diagnostics)[i], original, input, 0).(ExpansionError)
+	}
+	tracePhase(cb, PhaseParameterExpansion, input, expanded)
+	input = expanded
 
-	// step 4: arithmetic expansion
 	// step 5: quote removal
 	input = expandQuoteRemoval(input)
 
 	// all done
 	return input, nil
 }
+
+// expansionChars are the characters that Expand() might change the
+// input around
+const expansionChars = "${~\\"
+
+// hasExpansionChars returns false if the input definitely has nothing
+// in it that Expand() can change
+func hasExpansionChars(input string) bool {
+	return strings.ContainsAny(input, expansionChars)
+}
@@ -0,0 +1,183 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+// ExpandAll expands a set of named templates that refer to each other,
+// such as the entries in a .env file or a map of config settings:
+//
+//	HOME=/home/stuart
+//	APP_DIR=${HOME}/app
+//	LOG_DIR=${APP_DIR}/logs
+//
+// It uses a DependencyGraph to work out which order to expand the
+// templates in, so that every template sees the expanded value of the
+// templates that it refers to. Anything that is not in the map is
+// looked up using your callbacks. A template that refers to itself
+// (such as PATH=${PATH}:/usr/local/bin) sees the value from your
+// callbacks too.
+//
+// The DependencyGraph cannot see where ${!ref} points. If a template is
+// looked up that way before its turn, it is expanded there and then, so
+// the result does not depend on the templates' names.
+//
+// Any assignments that the templates make (e.g. ${var:=word}) can be
+// seen by the templates that are expanded after them, but they do not
+// change your callbacks' variables.
+//
+// If the templates refer to each other in a loop, you get an
+// ErrDependencyCycle. If a template cannot be expanded, you get an
+// ErrMapExpansion that tells you which one; none of the templates that
+// come after it are expanded.
+func ExpandAll(templates map[string]string, cb ExpansionCallbacks) (map[string]string, error) {
+	return expandAll(templates, NewScope(cb), Expand)
+}
+
+// ExpandAll expands a set of named templates that refer to each other,
+// just like the package-level ExpandAll() does
+func (e *Expander) ExpandAll(templates map[string]string) (map[string]string, error) {
+	return expandAll(templates, NewScope(e.cb), func(input string, cb ExpansionCallbacks) (string, error) {
+		scoped := *e
+		scoped.cb = cb
+		return scoped.Expand(input)
+	})
+}
+
+// expandAll does the work for ExpandAll(), using the given function to
+// expand each template. Each template's result goes into the scope, so
+// that the templates that come after it can see it.
+func expandAll(templates map[string]string, scope *Scope, expand func(string, ExpansionCallbacks) (string, error)) (map[string]string, error) {
+	order, err := NewDependencyGraph(templates).Order()
+	if err != nil {
+		return nil, err
+	}
+
+	r := templateResolver{
+		templates: templates,
+		scope:     scope,
+		expand:    expand,
+		results:   make(map[string]string, len(templates)),
+	}
+	r.cb = FirstOf(ExpansionCallbacks{LookupVar: r.lookupVar}, scope.Callbacks())
+
+	for _, name := range order {
+		err := r.resolve(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r.results, nil
+}
+
+// templateResolver expands the templates for ExpandAll()
+//
+// the DependencyGraph cannot see where ${!ref} points, so a template can
+// be looked up before its turn comes. When that happens, we expand it
+// there and then.
+type templateResolver struct {
+	templates map[string]string
+	scope     *Scope
+	expand    func(string, ExpansionCallbacks) (string, error)
+
+	// the callbacks that every template is expanded with
+	cb ExpansionCallbacks
+
+	// the templates that we have expanded so far
+	results map[string]string
+
+	// the templates that we are part-way through expanding
+	stack []string
+
+	// the first error from a template that we expanded during a lookup
+	err error
+}
+
+// resolve expands the named template, if we have not already done so
+func (r *templateResolver) resolve(name string) error {
+	_, done := r.results[name]
+	if done {
+		return nil
+	}
+
+	r.stack = append(r.stack, name)
+	value, err := r.expand(r.templates[name], r.cb)
+	r.stack = r.stack[:len(r.stack)-1]
+
+	// a lookup may have failed, even if the expansion did not
+	if r.err != nil {
+		return r.err
+	}
+	if err != nil {
+		return ErrMapExpansion{map[string]error{name: err}}
+	}
+
+	r.results[name] = value
+	r.scope.Locals().Set(name, value)
+	return nil
+}
+
+// lookupVar expands the named template if it is looked up before its
+// turn. It never has a value of its own; once the template has been
+// expanded, the lookup finds it in the scope.
+func (r *templateResolver) lookupVar(name string) (string, bool) {
+	_, isTemplate := r.templates[name]
+	if !isTemplate || r.err != nil {
+		return "", false
+	}
+
+	for i, expanding := range r.stack {
+		if expanding != name {
+			continue
+		}
+
+		// a template that refers to itself sees the value from the
+		// callbacks underneath
+		if i == len(r.stack)-1 {
+			return "", false
+		}
+
+		cycle := append([]string{}, r.stack[i:]...)
+		r.err = ErrDependencyCycle{append(cycle, name)}
+		return "", false
+	}
+
+	err := r.resolve(name)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+
+	return "", false
+}
@@ -0,0 +1,347 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandAllExpandsTemplatesInDependencyOrder(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"USER": "stuart",
+		"PATH": "/usr/bin",
+	})
+	templates := map[string]string{
+		"LOG_DIR": "${APP_DIR}/logs",
+		"APP_DIR": "${HOME}/app",
+		"HOME":    "/home/$USER",
+		"PATH":    "${PATH}:${APP_DIR}/bin",
+	}
+	expectedResult := map[string]string{
+		"LOG_DIR": "/home/stuart/app/logs",
+		"APP_DIR": "/home/stuart/app",
+		"HOME":    "/home/stuart",
+		"PATH":    "/usr/bin:/home/stuart/app/bin",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandAll(templates, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandAllReturnsErrorForCycles(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"A": "${B}",
+		"B": "${A}",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandAll(templates, NewMapCallbacks(nil))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+	assert.Equal(t, ErrDependencyCycle{[]string{"A", "B", "A"}}, err)
+}
+
+func TestExpandAllCycleErrorsMatchErrCircularReference(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"A": "${B}",
+		"B": "${A}",
+	}
+	var circularErr ErrCircularReference
+	var cycleErr ErrDependencyCycle
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandAll(templates, NewMapCallbacks(nil))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrCircularReference{}))
+	assert.True(t, errors.Is(err, ErrDependencyCycle{}))
+	assert.True(t, errors.As(err, &circularErr))
+	assert.Equal(t, []string{"A", "B", "A"}, circularErr.Cycle)
+	assert.True(t, errors.As(ErrCircularReference{[]string{"X", "X"}}, &cycleErr))
+	assert.Equal(t, []string{"X", "X"}, cycleErr.Cycle)
+}
+
+func TestExpandAllTellsYouWhichTemplateFailed(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"A": "ok",
+		"B": "${UNSET:?must be set}",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandAll(templates, NewMapCallbacks(nil))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+	mapErr, ok := err.(ErrMapExpansion)
+	assert.True(t, ok)
+	assert.Len(t, mapErr.Errors, 1)
+	assert.Error(t, mapErr.Errors["B"])
+}
+
+func TestExpanderExpandAllDoesNotChangeTheCallbacks(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("HOME", "/root")
+	unit := NewExpander(env.Callbacks(), WithStrict())
+	templates := map[string]string{
+		"HOME":  "/home/stuart",
+		"CACHE": "${HOME}/.cache ${MODE:=fast}",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandAll(templates)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "/home/stuart/.cache fast", actualResult["CACHE"])
+	home, _ := env.Lookup("HOME")
+	assert.Equal(t, "/root", home)
+	_, ok := env.Lookup("MODE")
+	assert.False(t, ok)
+}
+
+func TestExpandAllFindsReferencesInsideArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"COUNT": "2",
+	})
+	templates := map[string]string{
+		"A":     "$((B+1))",
+		"B":     "5",
+		"COUNT": "$((COUNT+1))",
+	}
+	expectedResult := map[string]string{
+		"A":     "6",
+		"B":     "5",
+		"COUNT": "3",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandAll(templates, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandAllReturnsErrorForCyclesInsideArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"A": "$((B+1))",
+		"B": "$[A*2]",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandAll(templates, NewMapCallbacks(nil))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+	assert.Equal(t, ErrDependencyCycle{[]string{"A", "B", "A"}}, err)
+}
+
+func TestExpandAllErrorsMatchTheTemplatesError(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(nil)
+	unit := NewExpander(cb, WithShellOpts(ShellOpts{NoUnset: true}))
+	templates := map[string]string{
+		"A": "$MISSING",
+	}
+	var unboundErr ErrUnboundVariable
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.ExpandAll(templates)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrUnboundVariable{}))
+	assert.True(t, errors.As(err, &unboundErr))
+	assert.Equal(t, "MISSING", unboundErr.Name)
+}
+
+func TestExpandAllFollowsIndirectionWhateverTheKeyNames(t *testing.T) {
+	t.Parallel()
+
+	// the graph cannot see where ${!REF} points, so we try names that
+	// sort before and after the templates that it refers to
+	testData := []struct {
+		templates map[string]string
+		name      string
+	}{
+		{map[string]string{"A": "${!REF}", "REF": "B", "B": "bee"}, "A"},
+		{map[string]string{"Z": "${!REF}", "REF": "B", "B": "bee"}, "Z"},
+		{map[string]string{"A": "${!REF}", "REF": "Z", "Z": "bee"}, "A"},
+		{map[string]string{"Z": "${!A}", "A": "B", "B": "bee"}, "Z"},
+	}
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := ExpandAll(testCase.templates, NewMapCallbacks(nil))
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testCase.templates)
+		assert.Equal(t, "bee", actualResult[testCase.name], testCase.templates)
+	}
+}
+
+func TestExpandAllReturnsErrorForCyclesThroughIndirection(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"A":   "${!REF}",
+		"REF": "B",
+		"B":   "$A",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandAll(templates, NewMapCallbacks(nil))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+	assert.True(t, errors.Is(err, ErrDependencyCycle{}))
+}
+
+func TestExpandAllReportsErrorsFromTemplatesReachedThroughIndirection(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	templates := map[string]string{
+		"A":   "${!REF}",
+		"REF": "Z",
+		"Z":   "${MISSING:?must be set}",
+	}
+	var mapErr ErrMapExpansion
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandAll(templates, NewMapCallbacks(nil))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+	assert.True(t, errors.As(err, &mapErr))
+	assert.Contains(t, mapErr.Errors, "Z")
+	assert.True(t, errors.Is(err, ErrVarRequired{}))
+}
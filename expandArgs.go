@@ -0,0 +1,742 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ExpandArgs expands the input string, and splits the result up into
+// a list of words, in the same way that a UNIX shell builds the argv
+// list for a command.
+//
+// The input string is split up into words first, and then each word
+// is expanded in turn:
+//
+// - brace expansion, which can turn one word into several
+// - tilde expansion
+// - parameter & variable expansion
+// - word splitting, on the results of any unquoted expansions
+// - quote removal
+//
+// Quoting follows the usual shell rules. Text inside single quotes is
+// left untouched. Text inside double quotes is still subject to
+// parameter expansion, but the results are never split into separate
+// words. The word after an operator, such as the default value in
+// ${var:-"two words"}, follows the same rules.
+//
+// Words that expand to nothing are dropped, unless they were quoted
+// (so "" gives you an empty argument).
+func ExpandArgs(input string, cb ExpansionCallbacks) ([]string, error) {
+	var retval []string
+	err := expandArgWords(input, cb, wordSplitFields, func(word rawWord, fb *fieldBuilder) {
+		retval = append(retval, fb.finish()...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// all done
+	if retval == nil {
+		retval = []string{}
+	}
+	return retval, nil
+}
+
+// expandArgWords does the work for ExpandArgs(). It calls `add` with
+// each word that brace expansion gives us, once that word has been
+// expanded.
+func expandArgWords(input string, cb ExpansionCallbacks, flags int, add func(rawWord, *fieldBuilder)) error {
+	// NUL bytes and invalid UTF-8 are dealt with before anything else
+	input, err := checkInput(input, cb)
+	if err != nil {
+		return err
+	}
+
+	// history expansion happens next, and only if it has been switched
+	// on
+	input, err = expandHistory(input, cb)
+	if err != nil {
+		return err
+	}
+
+	// step 1: break up the input into words
+	words, err := splitWords(input)
+	if err != nil {
+		quoteErr, ok := err.(ErrUnterminatedQuote)
+		if ok {
+			err = newExpansionError(PhaseWordSplitting, input, quoteErr.index, len(input), err)
+		}
+		return locateExpansionError(err, input, input, 0)
+	}
+
+	limits := newBraceLimiter(cb.context(), cb.budget)
+	for _, word := range words {
+		// step 2: brace expansion
+		bracedWords := []string{word.text}
+		if cb.dialect().braceExpansion {
+			bracedWords, err = expandBracesInWord(word.text, limits)
+			if err != nil {
+				return err
+			}
+		}
+		for _, bracedWord := range bracedWords {
+			// step 3: everything else
+			fb := fieldBuilder{}
+			err := fb.expandWord(bracedWord, cb, flags)
+			if err != nil {
+				return locateExpansionError(err, input, bracedWord, word.start)
+			}
+			add(word, &fb)
+		}
+	}
+
+	// a callback may have given up part-way through, because the
+	// caller's context ran out
+	err = cb.context().Err()
+	if err != nil {
+		return err
+	}
+
+	// or because we ran out of budget
+	return cb.budget.exceeded()
+}
+
+// these flags change how expandWordToFields() expands a word
+const (
+	// split the results of unquoted expansions on IFS
+	wordSplitFields = 1 << iota
+
+	// the word is the value in a NAME=value assignment: tilde expansion
+	// also happens after each unquoted ':', and expansions that give
+	// several words are joined back together
+	wordAssignment
+
+	// remember which expansions each field came from
+	wordTrackSources
+)
+
+// expandWordToFields expands a single word (that has already been through
+// brace expansion), and splits the results into fields
+//
+// without the `wordSplitFields` flag, there is no word splitting; you'll
+// then get back at most one field, unless the word contains "$@"
+func expandWordToFields(word string, cb ExpansionCallbacks, flags int) ([]string, error) {
+	fb := fieldBuilder{}
+	err := fb.expandWord(word, cb, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return fb.finish(), nil
+}
+
+// expandWord expands a single word (that has already been through
+// brace expansion), and adds the results to our fields
+func (fb *fieldBuilder) expandWord(word string, cb ExpansionCallbacks, flags int) error {
+	fb.trackSources = flags&wordTrackSources != 0
+	if flags&wordSplitFields != 0 && cb.dialect().wordSplitting {
+		fb.ifs = lookupIFS(cb)
+	}
+
+	return fb.expandText(word, cb, flags, false)
+}
+
+// expandText does the work for expandWord(). We also use it to expand
+// the word after a parameter's operator, such as the default value in
+// ${var:-word}.
+//
+// set `quoted` if the text is inside double quotes. Its own double
+// quotes are then simply removed, and single quotes are just
+// characters.
+func (fb *fieldBuilder) expandText(word string, cb ExpansionCallbacks, flags int, quoted bool) error {
+	assignment := flags&wordAssignment != 0
+
+	// where are we in the word?
+	i := 0
+
+	// tilde expansion happens at the start of a word
+	if !quoted && len(word) > 0 && word[0] == '~' {
+		prefixEnd, err := fb.writeTilde(word, cb, assignment)
+		if err != nil {
+			return err
+		}
+		i = prefixEnd
+	}
+
+	inDoubleQuotes := quoted
+
+	// where the current double quotes started, and whether they have
+	// contained a "$@" that expanded to nothing
+	var quoteStart fieldMark
+	quotedNothing := false
+
+	var c rune
+	w := 0
+	for ; i < len(word); i += w {
+		c, w = utf8.DecodeRuneInString(word[i:])
+
+		switch {
+		case c == '\\':
+			// what have we escaped?
+			if i+w == len(word) {
+				fb.writeRune(c)
+				continue
+			}
+			escC, escW := utf8.DecodeRuneInString(word[i+w:])
+
+			// inside double quotes, only a few characters can be escaped
+			if inDoubleQuotes && !isDoubleQuoteEscapeChar(escC) {
+				fb.writeRune(c)
+				continue
+			}
+
+			// an escaped newline is a line continuation
+			//
+			// we copy the escaped bytes as they are, so that invalid
+			// UTF-8 is not turned into utf8.RuneError
+			if escC != '\n' {
+				fb.writeString(word[i+w : i+w+escW])
+			}
+			w += escW
+
+		case c == '\'' && !inDoubleQuotes:
+			quoteEnd, ok := matchQuotes(word[i:])
+			if !ok {
+				return ErrUnterminatedQuote{c, i}
+			}
+			fb.markQuoted()
+			fb.writeString(word[i+1 : i+quoteEnd-1])
+			w = quoteEnd
+
+		case c == '"' && quoted:
+			fb.markQuoted()
+
+		case c == '"' && !inDoubleQuotes:
+			inDoubleQuotes = true
+			quoteStart = fb.mark()
+			quotedNothing = false
+			fb.markQuoted()
+
+		case c == '"':
+			inDoubleQuotes = false
+
+			// "$@" with no positional parameters does not create an
+			// empty word
+			if quotedNothing && fb.addedNothingSince(quoteStart) {
+				fb.inField = quoteStart.inField
+				continue
+			}
+			fb.markQuoted()
+
+		case c == ':' && assignment && !inDoubleQuotes:
+			// in an assignment, tilde expansion happens after each ':'
+			fb.writeRune(c)
+			if i+w < len(word) && word[i+w] == '~' {
+				prefixEnd, err := fb.writeTilde(word[i+w:], cb, assignment)
+				if err != nil {
+					return err
+				}
+				w += prefixEnd
+			}
+
+		case c == '$' && !inDoubleQuotes && cb.dialect().localeStrings && strings.HasPrefix(word[i:], `$"`):
+			// $"..." is translated first, and then expanded just like
+			// any other double-quoted string
+			translated, localeEnd, ok := translateLocaleString(word[i:], cb)
+			if !ok {
+				fb.writeRune(c)
+				continue
+			}
+			word = word[:i] + translated + word[i+localeEnd:]
+			w = 0
+
+		case c == '$' && hasArithmeticPrefix(word[i:], cb):
+			exprEnd, ok := matchArithmetic(word[i:], cb)
+			if !ok {
+				fb.writeRune(c)
+				continue
+			}
+			err := cb.budget.spend(BudgetExpansions)
+			if err != nil {
+				return err
+			}
+
+			// the expression may contain parameters that we need to
+			// expand before we can evaluate it
+			expr := arithmeticExpr(word[i : i+exprEnd])
+			exprStart := i + arithmeticPrefixLen(word[i:])
+			expanded, err := expandSpans(expr, cb, scanParams)
+			if err != nil {
+				return locateExpansionError(err, word, expr, exprStart)
+			}
+			value, err := evalArithmetic(expanded, cb)
+			if err != nil {
+				return newExpansionError(PhaseArithmeticExpansion, word, i, i+exprEnd, err)
+			}
+
+			fb.setSource(word[i : i+exprEnd])
+			if inDoubleQuotes {
+				fb.writeString(value)
+			} else {
+				fb.writeSplit(value)
+			}
+			fb.setSource("")
+			w = exprEnd
+
+		case c == '`' || (c == '$' && strings.HasPrefix(word[i:], "$(") && cb.varSyntax() != VarSyntaxShellAndMake):
+			// ExpandArgs() does not support command substitution, so we
+			// leave the command exactly as it is
+			cmdEnd, ok := matchCommandOrBracedVar(word[i:])
+			if !ok {
+				fb.writeRune(c)
+				continue
+			}
+			fb.writeString(word[i : i+cmdEnd])
+			w = cmdEnd
+
+		case c == '$':
+			varEnd, err := findVar(word[i:])
+			if err != nil {
+				_, unterminated := err.(ErrMismatchedBrace)
+				if unterminated && cb.strict() {
+					return newExpansionError(
+						PhaseParameterExpansion,
+						word,
+						i,
+						len(word),
+						NewErrMismatchedBrace(word, i+1),
+					)
+				}
+				fb.writeRune(c)
+				continue
+			}
+			err = cb.budget.spendParam()
+			if err != nil {
+				return err
+			}
+			paramDesc, ok := cb.parseParameter(word[i : i+varEnd])
+			if !ok {
+				if cb.strict() && strings.HasPrefix(word[i:i+varEnd], "${") {
+					return newExpansionError(
+						PhaseParameterExpansion,
+						word,
+						i,
+						i+varEnd,
+						ErrBadSubstitution{word[i : i+varEnd]},
+					)
+				}
+				fb.writeRune(c)
+				continue
+			}
+
+			fb.setSource(word[i : i+varEnd])
+			arg := argOperand{fb: fb, flags: flags, quoted: inDoubleQuotes}
+			values, allParams, err := expandParameterToFields(word[i:i+varEnd], paramDesc, cb, &arg)
+			if err != nil {
+				ctxErr := cb.context().Err()
+				if ctxErr != nil {
+					return ctxErr
+				}
+				return newExpansionError(PhaseParameterExpansion, word, i, i+varEnd, err)
+			}
+
+			switch {
+			case arg.written:
+				// the word after the operator is the result, and it
+				// has already been added to our fields
+			case allParams == "$@" && assignment:
+				// in an assignment, "$@" is joined up with spaces ...
+				fb.writeString(strings.Join(values, " "))
+			case allParams == "$*" && assignment:
+				// ... and "$*" is joined up with IFS, quoted or not
+				fb.writeString(strings.Join(values, ifsJoiner(cb)))
+			case allParams == "$@" && inDoubleQuotes:
+				// "$@" expands to one word per positional parameter, and
+				// "${!prefix@}" expands to one word per variable name
+				fb.writeFields(values)
+				if len(values) == 0 {
+					quotedNothing = true
+				}
+			case allParams == "$*" && inDoubleQuotes:
+				// "$*" and "${!prefix*}" expand to a single word
+				fb.writeString(strings.Join(values, ifsJoiner(cb)))
+			case allParams != "":
+				// unquoted, each value is split on its own
+				for j, value := range values {
+					if j > 0 {
+						fb.breakField()
+					}
+					fb.writeSplit(value)
+				}
+			case inDoubleQuotes:
+				// results of quoted expansions are never split
+				fb.writeString(values[0])
+			default:
+				fb.writeSplit(values[0])
+			}
+			fb.setSource("")
+			w = varEnd
+
+		case !inDoubleQuotes && fb.operandDepth > 0:
+			// the unquoted text in ${var:-word} is part of the
+			// expansion's result, so it is split too
+			fb.writeSplit(word[i : i+w])
+
+		default:
+			// invalid UTF-8 must come through untouched
+			fb.writeString(word[i : i+w])
+		}
+	}
+
+	// all done
+	return nil
+}
+
+// argOperand expands the word after a parameter's operator (e.g. the
+// default value in ${var:-word}), when the parameter is part of a
+// command-line argument
+//
+// the word follows the same quoting rules as the rest of the argument.
+// When the parameter expands to the word, the word's expansion goes
+// straight into our fields, so that quoted parts of it are never split.
+type argOperand struct {
+	fb     *fieldBuilder
+	flags  int
+	quoted bool
+
+	// true once the word's expansion has been added to `fb`
+	written bool
+}
+
+// lazyWord returns the word after the given parameter's operator, ready
+// to be expanded if it is needed
+//
+// a nil argOperand expands the word just like Expand() does
+func (a *argOperand) lazyWord(param *paramExpansion) *lazyWord {
+	retval := newLazyWord(param.desc.word())
+	if a == nil {
+		return retval
+	}
+
+	expandsToWord := param.expandsToWord()
+	retval.expandFunc = func(word string, cb ExpansionCallbacks) (string, error) {
+		// sometimes, we only need the text that the word expands to
+		if !expandsToWord {
+			fb := fieldBuilder{tee: &strings.Builder{}}
+			err := fb.expandText(word, cb, a.flags, a.quoted)
+			return fb.tee.String(), err
+		}
+
+		// the text is part of the parameter's expansion, as far as
+		// anyone tracking sources is concerned
+		var text strings.Builder
+		outerTee := a.fb.tee
+		a.fb.tee = &text
+		a.fb.operandDepth++
+		err := a.fb.expandText(word, cb, a.flags, a.quoted)
+		a.fb.operandDepth--
+		a.fb.tee = outerTee
+		if outerTee != nil {
+			outerTee.WriteString(text.String())
+		}
+
+		a.written = true
+		return text.String(), err
+	}
+
+	return retval
+}
+
+// writeTilde does tilde expansion on the start of the input, and tells
+// you how much of the input has been used up
+//
+// if the tilde prefix cannot be expanded, nothing is written, and we
+// use up none of the input
+func (fb *fieldBuilder) writeTilde(input string, cb ExpansionCallbacks, assignment bool) (int, error) {
+	err := cb.budget.spend(BudgetExpansions)
+	if err != nil {
+		return 0, err
+	}
+
+	// in an assignment, a ':' also ends the tilde prefix
+	if assignment {
+		colon := strings.IndexByte(input, ':')
+		if colon >= 0 {
+			input = input[:colon]
+		}
+	}
+	repl, prefixEnd, ok := expandTildePrefix(input, cb)
+
+	// on Windows, the prefix can end in a path separator
+	prefix := input[:prefixEnd]
+	if cb.windows() {
+		prefix = strings.TrimSuffix(prefix, `\`)
+	}
+	if !ok || strings.ContainsAny(prefix, "'\"\\") {
+		return 0, nil
+	}
+
+	fb.setSource(prefix)
+	fb.writeString(repl)
+	fb.setSource("")
+	return prefixEnd, nil
+}
+
+// isDoubleQuoteEscapeChar returns true if the given character can be
+// escaped inside double quotes
+func isDoubleQuoteEscapeChar(c rune) bool {
+	return c == '$' || c == '`' || c == '"' || c == '\\' || c == '\n'
+}
+
+// ifsJoiner returns the separator that "$*" puts between each
+// positional parameter: the first character of IFS
+func ifsJoiner(cb ExpansionCallbacks) string {
+	ifs := lookupIFS(cb)
+	if ifs == "" {
+		return ""
+	}
+
+	_, w := utf8.DecodeRuneInString(ifs)
+	return ifs[:w]
+}
+
+// lookupIFS returns the characters that we split words on
+func lookupIFS(cb ExpansionCallbacks) string {
+	ifs, ok := cb.lookupVar("IFS")
+	if !ok {
+		return " \t\n"
+	}
+
+	return ifs
+}
+
+// fieldBuilder builds up the list of fields that a single word expands
+// into
+type fieldBuilder struct {
+	// the fields we have finished
+	fields []string
+
+	// the field we are currently building
+	buf strings.Builder
+
+	// true if the current field exists, even if it is empty
+	inField bool
+
+	// true if we have just ended a field because of IFS whitespace
+	afterIFSSpace bool
+
+	// the characters that we split expansions on
+	//
+	// if empty, no splitting is done
+	ifs string
+
+	// if true, we remember which expansions each field came from
+	trackSources bool
+
+	// the expansion that we are currently writing, if any
+	source string
+
+	// the expansions that each finished field came from
+	fieldSources [][]string
+
+	// the expansions that the current field came from
+	sources []string
+
+	// how many operator words (e.g. ${var:-word}) we are in the middle
+	// of expanding; their text belongs to the outermost expansion
+	operandDepth int
+
+	// if set, we copy everything that we write into here too, without
+	// splitting it up
+	tee *strings.Builder
+}
+
+// setSource tells us which expansion the text that we are about to
+// write came from; use "" for text that came from the input
+func (fb *fieldBuilder) setSource(source string) {
+	if fb.trackSources && fb.operandDepth == 0 {
+		fb.source = source
+	}
+}
+
+// addSource adds the current expansion to the current field's list
+func (fb *fieldBuilder) addSource() {
+	if fb.source == "" {
+		return
+	}
+	if len(fb.sources) > 0 && fb.sources[len(fb.sources)-1] == fb.source {
+		return
+	}
+	fb.sources = append(fb.sources, fb.source)
+}
+
+// writeString adds text to the current field; it is never split
+func (fb *fieldBuilder) writeString(text string) {
+	fb.writeTee(text)
+	fb.appendString(text)
+}
+
+// appendString adds text to the current field, without copying it
+// to our tee
+func (fb *fieldBuilder) appendString(text string) {
+	fb.addSource()
+	fb.buf.WriteString(text)
+	fb.inField = true
+	fb.afterIFSSpace = false
+}
+
+// writeRune adds a single character to the current field
+func (fb *fieldBuilder) writeRune(c rune) {
+	if fb.tee != nil {
+		fb.tee.WriteRune(c)
+	}
+	fb.addSource()
+	fb.buf.WriteRune(c)
+	fb.inField = true
+	fb.afterIFSSpace = false
+}
+
+// markQuoted makes sure the current field exists, even if nothing is
+// ever added to it
+func (fb *fieldBuilder) markQuoted() {
+	fb.inField = true
+	fb.afterIFSSpace = false
+}
+
+// writeSplit adds the result of an unquoted expansion, splitting it on
+// the characters in IFS as we go
+func (fb *fieldBuilder) writeSplit(text string) {
+	fb.writeTee(text)
+
+	for i, w := 0, 0; i < len(text); i += w {
+		var c rune
+		c, w = utf8.DecodeRuneInString(text[i:])
+		if c == utf8.RuneError || !strings.ContainsRune(fb.ifs, c) {
+			// invalid UTF-8 must come through untouched
+			fb.appendString(text[i : i+w])
+			continue
+		}
+
+		// IFS whitespace ends the current field, and runs of IFS
+		// whitespace are treated as a single separator
+		if isBlankChar(c) {
+			if fb.inField {
+				fb.endField()
+				fb.afterIFSSpace = true
+			}
+			continue
+		}
+
+		// any other IFS character always ends the current field,
+		// even if that leaves an empty field behind ... unless we've
+		// only just ended a field because of IFS whitespace
+		if fb.afterIFSSpace {
+			fb.afterIFSSpace = false
+			continue
+		}
+		fb.endField()
+	}
+}
+
+// writeFields adds several words: the first is added to the current
+// field, and each of the others starts a new field
+func (fb *fieldBuilder) writeFields(words []string) {
+	for i, word := range words {
+		if i > 0 {
+			fb.endField()
+			fb.writeTee(" ")
+		}
+		fb.writeString(word)
+	}
+}
+
+// breakField ends the current field (if there is one), just like IFS
+// whitespace does
+func (fb *fieldBuilder) breakField() {
+	fb.writeTee(" ")
+	if fb.inField {
+		fb.endField()
+		fb.afterIFSSpace = true
+	}
+}
+
+// writeTee copies text to our tee (if we have one), without adding it
+// to any field
+func (fb *fieldBuilder) writeTee(text string) {
+	if fb.tee != nil {
+		fb.tee.WriteString(text)
+	}
+}
+
+// fieldMark records how far the fieldBuilder has got
+type fieldMark struct {
+	fields  int
+	bufLen  int
+	inField bool
+}
+
+// mark returns how far we have got
+func (fb *fieldBuilder) mark() fieldMark {
+	return fieldMark{len(fb.fields), fb.buf.Len(), fb.inField}
+}
+
+// addedNothingSince returns true if no text has been added since the
+// given mark was made
+func (fb *fieldBuilder) addedNothingSince(mark fieldMark) bool {
+	return len(fb.fields) == mark.fields && fb.buf.Len() == mark.bufLen
+}
+
+func (fb *fieldBuilder) endField() {
+	fb.fields = append(fb.fields, fb.buf.String())
+	fb.buf.Reset()
+	fb.inField = false
+
+	if fb.trackSources {
+		fb.fieldSources = append(fb.fieldSources, fb.sources)
+		fb.sources = nil
+	}
+}
+
+// finish returns the complete list of fields
+func (fb *fieldBuilder) finish() []string {
+	if fb.inField {
+		fb.endField()
+	}
+
+	return fb.fields
+}
@@ -0,0 +1,93 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "context"
+
+// Arg is a single argument, as returned by ExpandArgsDetailed()
+type Arg struct {
+	// Value is the argument, after expansion
+	Value string
+
+	// Start and End are the byte offsets of the word in the input
+	// string that this argument came from. Brace expansion and word
+	// splitting can turn one word into several arguments; they all
+	// have the same Start and End.
+	Start int
+	End   int
+
+	// Expansions lists the parameter expansions and tilde prefixes
+	// (e.g. "$EXTRA_FLAGS" or "~") whose results ended up in this
+	// argument, in the order they appear in the word. It is empty if
+	// the argument came straight from the input string.
+	Expansions []string
+}
+
+// ExpandArgsDetailed expands the input string into a list of arguments,
+// just like ExpandArgs() does. Each argument also tells you where in
+// the input it came from, and which expansions produced it, so that
+// you can give your users precise error messages (e.g. "argument 3
+// came from $EXTRA_FLAGS").
+func ExpandArgsDetailed(input string, cb ExpansionCallbacks) ([]Arg, error) {
+	retval := []Arg{}
+	err := expandArgWords(input, cb, wordSplitFields|wordTrackSources, func(word rawWord, fb *fieldBuilder) {
+		for i, field := range fb.finish() {
+			retval = append(retval, Arg{
+				Value:      field,
+				Start:      word.start,
+				End:        word.start + len(word.text),
+				Expansions: fb.fieldSources[i],
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return retval, nil
+}
+
+// ExpandArgsDetailed expands the input string into a list of arguments,
+// just like the package-level ExpandArgsDetailed() does
+func (e *Expander) ExpandArgsDetailed(input string) ([]Arg, error) {
+	ctx, done := e.withTimeout(context.Background())
+	cb := e.callbacks()
+	cb.ctx = ctx
+	retval, err := ExpandArgsDetailed(input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
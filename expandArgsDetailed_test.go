@@ -0,0 +1,125 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandArgsDetailedTellsYouWhereEachArgCameFrom(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"EXTRA_FLAGS": "-v --debug",
+		"NAME":        "world",
+		"HOME":        "/home/stuart",
+	})
+	input := `run $EXTRA_FLAGS "hello $NAME" ~/bin`
+	expectedResult := []Arg{
+		{Value: "run", Start: 0, End: 3},
+		{Value: "-v", Start: 4, End: 16, Expansions: []string{"$EXTRA_FLAGS"}},
+		{Value: "--debug", Start: 4, End: 16, Expansions: []string{"$EXTRA_FLAGS"}},
+		{Value: "hello world", Start: 17, End: 30, Expansions: []string{"$NAME"}},
+		{Value: "/home/stuart/bin", Start: 31, End: 36, Expansions: []string{"~"}},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandArgsDetailed(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandArgsDetailedListsEveryExpansionInAnArg(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"A": "a",
+		"B": "b",
+	})
+	input := "x-{1,2}-$A${B}"
+	expectedResult := []Arg{
+		{Value: "x-1-ab", Start: 0, End: 14, Expansions: []string{"$A", "${B}"}},
+		{Value: "x-2-ab", Start: 0, End: 14, Expansions: []string{"$A", "${B}"}},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandArgsDetailed(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandArgsDetailedReturnsTheSameErrorsAsExpandArgs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(nil)
+	input := `echo "unterminated`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, expectedErr := ExpandArgs(input, cb)
+	actualResult, actualErr := ExpandArgsDetailed(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+	assert.Error(t, actualErr)
+	assert.Equal(t, expectedErr, actualErr)
+}
@@ -0,0 +1,471 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+type expandArgsTestData struct {
+	vars             map[string]string
+	positionalParams []string
+	homedirs         map[string]string
+	input            string
+	expectedResult   []string
+}
+
+func TestExpandArgsSplitsOnBlanks(t *testing.T) {
+	testData := expandArgsTestData{
+		input:          "ls -l  /tmp\t/var",
+		expectedResult: []string{"ls", "-l", "/tmp", "/var"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsKeepsQuotedBlanks(t *testing.T) {
+	testData := expandArgsTestData{
+		input:          `echo 'hello world' "goodbye  world" a\ b`,
+		expectedResult: []string{"echo", "hello world", "goodbye  world", "a b"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsKeepsEmptyQuotedWords(t *testing.T) {
+	testData := expandArgsTestData{
+		input:          `a "" '' b`,
+		expectedResult: []string{"a", "", "", "b"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsSplitsUnquotedExpansions(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"FLAGS": "-a  -b -c",
+		},
+		input:          "cmd $FLAGS x${FLAGS}y",
+		expectedResult: []string{"cmd", "-a", "-b", "-c", "x-a", "-b", "-cy"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsDoesNotSplitQuotedExpansions(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"FLAGS": "-a  -b -c",
+		},
+		input:          `cmd "$FLAGS" "x${FLAGS}y"`,
+		expectedResult: []string{"cmd", "-a  -b -c", "x-a  -b -cy"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsDropsEmptyUnquotedExpansions(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"EMPTY": "",
+		},
+		input:          `cmd $EMPTY $UNSET "$EMPTY" x$EMPTY`,
+		expectedResult: []string{"cmd", "", "x"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsSplitsOnCustomIFS(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"IFS":  ":",
+			"PATH": "/usr/bin::/bin:",
+		},
+		input:          "$PATH",
+		expectedResult: []string{"/usr/bin", "", "/bin"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsSplitsOnMixedIFS(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"IFS":   " :",
+			"VALUE": "a : b:c  d",
+		},
+		input:          "$VALUE",
+		expectedResult: []string{"a", "b", "c", "d"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsDoesNotExpandSingleQuotes(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"PARAM1": "foo",
+		},
+		input:          `'$PARAM1' "$PARAM1" $PARAM1`,
+		expectedResult: []string{"$PARAM1", "foo", "foo"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsHandlesEscapesInDoubleQuotes(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"PARAM1": "foo",
+		},
+		input:          `"\$PARAM1 \"quoted\" \a \\"`,
+		expectedResult: []string{`$PARAM1 "quoted" \a \`},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsBraces(t *testing.T) {
+	testData := expandArgsTestData{
+		input:          "cp file.{txt,bak} web{1..3} '{a,b}'",
+		expectedResult: []string{"cp", "file.txt", "file.bak", "web1", "web2", "web3", "{a,b}"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsNestedBraces(t *testing.T) {
+	testData := expandArgsTestData{
+		input:          "a{b,c{d,e}}f",
+		expectedResult: []string{"abf", "acdf", "acef"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsTildes(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"HOME": "/home/me",
+		},
+		input:          `~/bin "~/bin" a~/bin`,
+		expectedResult: []string{"/home/me/bin", "~/bin", "a~/bin"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsKeepsBlanksInsideParameterExpansions(t *testing.T) {
+	testData := expandArgsTestData{
+		input:          "echo ${UNSET:-two words}",
+		expectedResult: []string{"echo", "two", "words"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsQuotedAtSignToSeparateWords(t *testing.T) {
+	testData := expandArgsTestData{
+		positionalParams: []string{"a b", "", "c"},
+		input:            `cmd x"$@"y`,
+		expectedResult:   []string{"cmd", "xa b", "", "cy"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsQuotedAtSignToNothingWithoutPositionalParams(t *testing.T) {
+	testData := expandArgsTestData{
+		input:          `cmd "$@" "${@}" ""$@`,
+		expectedResult: []string{"cmd", ""},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsQuotedStarToASingleWord(t *testing.T) {
+	testData := expandArgsTestData{
+		positionalParams: []string{"a b", "", "c"},
+		input:            `cmd "$*"`,
+		expectedResult:   []string{"cmd", "a b  c"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsJoinsQuotedStarWithFirstCharOfIFS(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"IFS": ":-",
+		},
+		positionalParams: []string{"a b", "c"},
+		input:            `cmd "$*" "${*%c}"`,
+		expectedResult:   []string{"cmd", "a b:c", "a b:"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsAppliesOperatorsToEachWordOfQuotedAtSign(t *testing.T) {
+	testData := expandArgsTestData{
+		positionalParams: []string{"one.doc", "two.txt"},
+		input:            `cmd "${@%.*}"`,
+		expectedResult:   []string{"cmd", "one", "two"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsAppliesPatternOperatorsToEachWordOfAtSign(t *testing.T) {
+	testData := expandArgsTestData{
+		positionalParams: []string{"ax b", "", "axx"},
+		input:            `cmd "${@#a}" "${@//x/y z}" ${@/x/y}`,
+		expectedResult:   []string{"cmd", "x b", "", "xx", "ay z b", "", "ay zy z", "ay", "b", "ayx"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsSplitsEachUnquotedPositionalParam(t *testing.T) {
+	testData := expandArgsTestData{
+		positionalParams: []string{"a b", "c"},
+		input:            `cmd $@ $*`,
+		expectedResult:   []string{"cmd", "a", "b", "c", "a", "b", "c"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsSlicesQuotedAtSign(t *testing.T) {
+	testData := expandArgsTestData{
+		positionalParams: []string{"one", "two", "three"},
+		input:            `cmd "${@:2}" "${@: -1}" "${@:1:2}" "${*:2}" ${@:4}`,
+		expectedResult:   []string{"cmd", "two", "three", "three", "one", "two", "two three"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsRemovesQuotesInsideOperatorWords(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"X": "x y",
+		},
+		input:          `cmd ${Z:-"a b"} ${Z:-'c d'} ${Z:-e\ f} ${X:+"g h"} ${Z:-"i j"k l}`,
+		expectedResult: []string{"cmd", "a b", "c d", "e f", "g h", "i jk", "l"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsKeepsOperatorWordsInsideDoubleQuotes(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"X": "x y",
+		},
+		input:          `cmd "${Z:-"a b"}" "${Z:-'c d'}" "${Z:-$X}" "${Z:-\"}" "${X:+"$X"}"`,
+		expectedResult: []string{"cmd", "a b", "'c d'", "x y", `"`, "x y"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsSkipsQuotedBracesInOperatorWords(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"X": "x y",
+		},
+		input:          `cmd ${N:-"}"} ${N:-'}'} "${N:-"}"}" ${N:-a"}"b} ${X:+"}"} "${N:-'}'}"`,
+		expectedResult: []string{"cmd", "}", "}", "}", "a}b", "}", "'}'"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsSplitsUnquotedExpansionsInsideOperatorWords(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"X":     "x y",
+			"EMPTY": "",
+		},
+		input:          `cmd ${Z:-$X} ${Z:-"$X"} a${Z:-$X}b ${Z:-""} ${Z:-$EMPTY}`,
+		expectedResult: []string{"cmd", "x", "y", "x y", "ax", "yb", ""},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsAtSignInsideOperatorWords(t *testing.T) {
+	testData := expandArgsTestData{
+		positionalParams: []string{"a b", "c"},
+		input:            `cmd ${Z:-"$@"} "${Z:-$@}" ${Z:-x"$@"y}`,
+		expectedResult:   []string{"cmd", "a b", "c", "a b", "c", "xa b", "cy"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsSearchReplaceReplacements(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"P": "/usr/local/bin",
+			"X": "abcabc",
+			"N": "7",
+		},
+		input:          `cmd "${P/local/"a b"}" ${P/local/"a b"} ${X/a/'q/r'} ${X/a/$N} "${X//b/\/}" ${P/local/a/b} ${P//\//_}`,
+		expectedResult: []string{"cmd", "/usr/a b/bin", "/usr/a", "b/bin", "q/rbcabc", "7bcabc", "a/ca/c", "/usr/a/b/bin", "_usr_local_bin"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsQuotedPrefixAtSignToSeparateWords(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"PREFIX_A": "a",
+			"PREFIX_B": "b",
+		},
+		input:          `cmd "${!PREFIX_@}" "${!NOMATCH_@}"`,
+		expectedResult: []string{"cmd", "PREFIX_A", "PREFIX_B"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsQuotedPrefixStarToASingleWord(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"IFS":      ":",
+			"PREFIX_A": "a",
+			"PREFIX_B": "b",
+		},
+		input:          `cmd "${!PREFIX_*}" "${!NOMATCH_*}"`,
+		expectedResult: []string{"cmd", "PREFIX_A:PREFIX_B", ""},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsExpandsLocaleStringsWithoutATranslation(t *testing.T) {
+	testData := expandArgsTestData{
+		vars: map[string]string{
+			"PARAM1": "a  b",
+		},
+		input:          `$"hello $PARAM1" x$"" "$"y`,
+		expectedResult: []string{"hello a  b", "x", "$y"},
+	}
+	testExpandArgsTestCase(t, testData)
+}
+
+func TestExpandArgsReturnsErrorForUnterminatedQuotes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "", false
+		},
+	}
+	expectedError := "unterminated \" at position 5"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandArgs(`echo "hello`, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, actualResult)
+	assert.Error(t, err)
+	assert.Equal(t, expectedError, err.Error())
+}
+
+func TestExpandArgsLeavesCommandSubstitutionsInASingleWord(t *testing.T) {
+	t.Parallel()
+
+	// ExpandArgs() does not run commands; these are the words that bash
+	// would split the command line into, before it ran them
+	testDataSet := map[string][]string{
+		"a $(echo b c) d":          {"a", "$(echo b c)", "d"},
+		"x`echo y z`w q":           {"x`echo y z`w", "q"},
+		`"$(echo ")")" z`:          {`$(echo ")")`, "z"},
+		`$(echo "a b") c`:          {`$(echo "a b")`, "c"},
+		"\"a `echo \"b c\"` d\" e": {"a `echo \"b c\"` d", "e"},
+		"${UNSET:-$(echo a b)}":    {"$(echo a b)"},
+	}
+
+	for input, expectedResult := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		cb := ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				return "", false
+			},
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := ExpandArgs(input, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func testExpandArgsTestCase(t *testing.T, testData expandArgsTestData) {
+	// ----------------------------------------------------------------
+	// create the shell script we'll run
+
+	shellCase := shelltest.Case{
+		Vars:             testData.vars,
+		PositionalParams: testData.positionalParams,
+		Commands: []string{
+			"printf '[%s]\\n' " + testData.input,
+		},
+	}
+
+	cb := ExpansionCallbacks{
+		LookupVar:     shellCase.LookupVar,
+		MatchVarNames: shellCase.MatchVarNames,
+		LookupHomeDir: func(key string) (string, bool) {
+			retval, ok := testData.homedirs[key]
+			return retval, ok
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	shellActualResult, _ := shelltest.Run("bash", &shellCase)
+
+	internalActualResult, internalActualError := ExpandArgs(testData.input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var expectedShellResult strings.Builder
+	for _, arg := range testData.expectedResult {
+		expectedShellResult.WriteString("[" + arg + "]\n")
+	}
+
+	assert.Nil(t, internalActualError)
+	assert.Equal(t, strings.TrimSpace(expectedShellResult.String()), shellActualResult, shelltest.Script(&shellCase))
+	assert.Equal(t, testData.expectedResult, internalActualResult)
+}
@@ -0,0 +1,92 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "strings"
+
+// ExpandAssignment expands a `NAME=value` string, the same way that a
+// UNIX shell expands a variable assignment, and returns the name and the
+// expanded value.
+//
+// The value goes through:
+//
+// - tilde expansion, at the start of the value and after each unquoted ':'
+// - parameter & variable expansion
+// - quote removal
+//
+// Just like in a shell assignment, there is no brace expansion, no word
+// splitting, and no pathname expansion; `PATH=~/bin:$PATH` does what
+// you expect, and `X=$Y` keeps any spaces in Y as they are.
+//
+// Use it when you are processing shell-style `export` blocks, or any
+// other list of assignments.
+func ExpandAssignment(input string, cb ExpansionCallbacks) (string, string, error) {
+	input, err := checkInput(input, cb)
+	if err != nil {
+		return "", "", err
+	}
+
+	// step 1: find the name
+	nameEnd := strings.IndexByte(input, '=')
+	if nameEnd < 0 || !isName(input[:nameEnd]) {
+		return "", "", ErrNotAnAssignment{input}
+	}
+	name := input[:nameEnd]
+	value := input[nameEnd+1:]
+
+	// step 2: make sure the quotes all match up
+	_, err = splitWords(value)
+	if err != nil {
+		quoteErr, ok := err.(ErrUnterminatedQuote)
+		if ok {
+			err = newExpansionError(PhaseWordSplitting, value, quoteErr.index, len(value), err)
+		}
+		return "", "", locateExpansionError(err, input, value, nameEnd+1)
+	}
+
+	// step 3: everything else
+	fields, err := expandWordToFields(value, cb, wordAssignment)
+	if err != nil {
+		return "", "", locateExpansionError(err, input, value, nameEnd+1)
+	}
+
+	// an empty value gives us no fields at all
+	if len(fields) == 0 {
+		return name, "", nil
+	}
+
+	return name, fields[0], nil
+}
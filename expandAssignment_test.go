@@ -0,0 +1,218 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+type expandAssignmentTestData struct {
+	vars             map[string]string
+	positionalParams []string
+	input            string
+	expectedName     string
+	expectedValue    string
+}
+
+func TestExpandAssignmentExpandsTheValue(t *testing.T) {
+	testData := expandAssignmentTestData{
+		vars: map[string]string{
+			"PARAM1": "foo",
+		},
+		input:         "GREETING=hello-${PARAM1}-$PARAM1",
+		expectedName:  "GREETING",
+		expectedValue: "hello-foo-foo",
+	}
+	testExpandAssignmentTestCase(t, testData)
+}
+
+func TestExpandAssignmentExpandsTildesAfterColons(t *testing.T) {
+	testData := expandAssignmentTestData{
+		vars: map[string]string{
+			"HOME": "/home/alfred",
+			"PATH": "/usr/bin:/bin",
+		},
+		input:         `PATH=~/bin:~:a~:"~"/x:\~/y:$PATH`,
+		expectedName:  "PATH",
+		expectedValue: "/home/alfred/bin:/home/alfred:a~:~/x:~/y:/usr/bin:/bin",
+	}
+	testExpandAssignmentTestCase(t, testData)
+}
+
+func TestExpandAssignmentDoesNotSplitWords(t *testing.T) {
+	testData := expandAssignmentTestData{
+		vars: map[string]string{
+			"PARAM1": "a  b\tc",
+		},
+		input:         "X=$PARAM1",
+		expectedName:  "X",
+		expectedValue: "a  b\tc",
+	}
+	testExpandAssignmentTestCase(t, testData)
+}
+
+func TestExpandAssignmentDoesNotExpandBracesOrGlobs(t *testing.T) {
+	testData := expandAssignmentTestData{
+		input:         "X={a,b}*",
+		expectedName:  "X",
+		expectedValue: "{a,b}*",
+	}
+	testExpandAssignmentTestCase(t, testData)
+}
+
+func TestExpandAssignmentRemovesQuotes(t *testing.T) {
+	testData := expandAssignmentTestData{
+		vars: map[string]string{
+			"PARAM1": "foo",
+		},
+		input:         `X="hello  $PARAM1"' $PARAM1 'world\ !`,
+		expectedName:  "X",
+		expectedValue: "hello  foo $PARAM1 world !",
+	}
+	testExpandAssignmentTestCase(t, testData)
+}
+
+func TestExpandAssignmentJoinsPositionalParams(t *testing.T) {
+	testData := expandAssignmentTestData{
+		vars: map[string]string{
+			"IFS": ":",
+		},
+		positionalParams: []string{"a", "b c"},
+		input:            `X=$@/"$@"/$*/"$*"`,
+		expectedName:     "X",
+		expectedValue:    "a b c/a b c/a:b c/a:b c",
+	}
+	testExpandAssignmentTestCase(t, testData)
+}
+
+func TestExpandAssignmentSupportsEmptyValues(t *testing.T) {
+	testData := expandAssignmentTestData{
+		input:         "X=",
+		expectedName:  "X",
+		expectedValue: "",
+	}
+	testExpandAssignmentTestCase(t, testData)
+}
+
+func TestExpandAssignmentRejectsInvalidNames(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := []string{
+		"",
+		"no equals sign",
+		"=value",
+		"1X=value",
+		"X-Y=value",
+		"X+=value",
+	}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		cb := ExpansionCallbacks{}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, _, err := ExpandAssignment(testData, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrNotAnAssignment{}), testData)
+	}
+}
+
+func TestExpandAssignmentReportsWhereTheErrorIs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, _, err := ExpandAssignment(`X=abc"def`, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err, &expErr))
+	assert.Equal(t, 5, expErr.Offset)
+	var quoteErr ErrUnterminatedQuote
+	assert.True(t, errors.As(err, &quoteErr))
+}
+
+func testExpandAssignmentTestCase(t *testing.T, testData expandAssignmentTestData) {
+	// ----------------------------------------------------------------
+	// create the shell script we'll run
+
+	shellCase := shelltest.Case{
+		Vars:             testData.vars,
+		PositionalParams: testData.positionalParams,
+		Commands: []string{
+			testData.input,
+			"printf '[%s]\\n' \"$" + testData.expectedName + "\"",
+		},
+	}
+
+	cb := ExpansionCallbacks{
+		LookupVar: shellCase.LookupVar,
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	shellActualResult, _ := shelltest.Run("bash", &shellCase)
+
+	actualName, actualValue, err := ExpandAssignment(testData.input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "["+testData.expectedValue+"]", shellActualResult, shelltest.Script(&shellCase))
+	assert.Equal(t, testData.expectedName, actualName)
+	assert.Equal(t, testData.expectedValue, actualValue)
+}
@@ -0,0 +1,216 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// ExpandSlice expands each entry in the input slice, and returns the
+// results in the same order.
+//
+// All of the entries share a single cache of parsed parameters and
+// variable lookups. LookupVar is only called once per variable name,
+// unless that variable is assigned to during the expansion (for example,
+// by ${var:=word}).
+//
+// If any entries fail to expand, you get back an ErrSliceExpansion that
+// tells you which ones. The results for the failed entries are empty
+// strings; all the other entries are still expanded.
+func ExpandSlice(input []string, cb ExpansionCallbacks) ([]string, error) {
+	retval, errs := expandEntries(input, cb)
+	if len(errs) > 0 {
+		return retval, ErrSliceExpansion{errs}
+	}
+
+	// all done
+	return retval, nil
+}
+
+// ExpandMap expands each value in the input map, and returns the results
+// in a new map with the same keys. The keys themselves are not expanded.
+//
+// All of the entries share a single cache of parsed parameters and
+// variable lookups. LookupVar is only called once per variable name,
+// unless that variable is assigned to during the expansion (for example,
+// by ${var:=word}).
+//
+// The values are expanded in the sorted order of their keys, so that
+// any assignments happen in a predictable order.
+//
+// If any entries fail to expand, you get back an ErrMapExpansion that
+// tells you which ones. The results for the failed entries are empty
+// strings; all the other entries are still expanded.
+func ExpandMap(input map[string]string, cb ExpansionCallbacks) (map[string]string, error) {
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = input[key]
+	}
+
+	results, errs := expandEntries(values, cb)
+
+	retval := make(map[string]string, len(input))
+	for i, key := range keys {
+		retval[key] = results[i]
+	}
+
+	if len(errs) > 0 {
+		keyedErrs := make(map[string]error, len(errs))
+		for i, err := range errs {
+			keyedErrs[keys[i]] = err
+		}
+		return retval, ErrMapExpansion{keyedErrs}
+	}
+
+	// all done
+	return retval, nil
+}
+
+// ExpandSlice expands each entry in the input slice, just like the
+// package-level ExpandSlice() does
+//
+// Use the WithWorkers() option to expand the entries concurrently.
+func (e *Expander) ExpandSlice(input []string) ([]string, error) {
+	ctx, done := e.withTimeout(context.Background())
+	cb := e.callbacks()
+	cb.ctx = ctx
+	retval, err := ExpandSlice(input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
+
+// ExpandMap expands each value in the input map, just like the
+// package-level ExpandMap() does
+//
+// Use the WithWorkers() option to expand the entries concurrently.
+func (e *Expander) ExpandMap(input map[string]string) (map[string]string, error) {
+	ctx, done := e.withTimeout(context.Background())
+	cb := e.callbacks()
+	cb.ctx = ctx
+	retval, err := ExpandMap(input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
+
+// WithWorkers makes the Expander's ExpandSlice() and ExpandMap() expand
+// up to `workers` entries at the same time.
+//
+// Only use this when the entries are independent of each other. The
+// entries are no longer expanded in order, so an entry may not see
+// assignments (e.g. ${var:=word}) made by other entries. Your callbacks
+// (and your TraceFunc, if you use WithTrace()) must be safe to call from
+// several goroutines at once.
+//
+// The entries still share a single cache of variable lookups, and the
+// Expander's cache of compiled glob patterns.
+//
+// By default, entries are expanded one at a time.
+func WithWorkers(workers int) Option {
+	return func(opts *options) {
+		opts.workers = workers
+	}
+}
+
+// expandEntries expands each of the input strings, using a single
+// cache for all of them
+//
+// it returns the results in the same order, plus any errors keyed by
+// their index in the input slice
+func expandEntries(input []string, cb ExpansionCallbacks) ([]string, map[int]error) {
+	cb.cache = newExpansionCache()
+
+	retval := make([]string, len(input))
+	errs := make([]error, len(input))
+
+	workers := 1
+	if cb.opts != nil && cb.opts.workers > 1 {
+		workers = cb.opts.workers
+	}
+	if workers > len(input) {
+		workers = len(input)
+	}
+
+	if workers <= 1 {
+		for i, entry := range input {
+			retval[i], errs[i] = ExpandContext(cb.context(), entry, cb)
+		}
+	} else {
+		// our worker pool pulls the index of the next entry to
+		// expand from this channel
+		next := make(chan int)
+
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for w := 0; w < workers; w++ {
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					retval[i], errs[i] = ExpandContext(cb.context(), input[i], cb)
+				}
+			}()
+		}
+
+		for i := range input {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+	}
+
+	// what went wrong?
+	var failed map[int]error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if failed == nil {
+			failed = make(map[int]error)
+		}
+		failed[i] = err
+	}
+
+	return retval, failed
+}
@@ -0,0 +1,453 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandSliceExpandsEachEntry(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo",
+		"PARAM2": "bar",
+	}
+	cb := newTestCallbacks(vars)
+	testData := []string{
+		"${PARAM1}",
+		"${PARAM2}",
+		"a{b,c}d",
+		"${PARAM1}/${PARAM2}",
+	}
+	expectedResult := []string{
+		"foo",
+		"bar",
+		"abd acd",
+		"foo/bar",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandSlice(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandSliceLooksUpEachVariableOnce(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo",
+		"PARAM2": "bar",
+	}
+	lookups := map[string]int{}
+	cb := countLookups(newTestCallbacks(vars), lookups)
+	testData := []string{
+		"${PARAM1}",
+		"${PARAM1} ${PARAM2}",
+		"${PARAM2:-default} ${PARAM1}",
+		"${UNSET} ${UNSET}",
+	}
+	expectedLookups := map[string]int{
+		"PARAM1": 1,
+		"PARAM2": 1,
+		"UNSET":  1,
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandSlice(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedLookups, lookups)
+}
+
+func TestExpandSliceSeesAssignmentsMadeByEarlierEntries(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{}
+	cb := newTestCallbacks(vars)
+	testData := []string{
+		"${PARAM1}",
+		"${PARAM1:=foo}",
+		"${PARAM1}",
+	}
+	expectedResult := []string{
+		"",
+		"foo",
+		"foo",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandSlice(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandSliceReturnsErrorsKeyedByIndex(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo",
+	}
+	cb := newTestCallbacks(vars)
+	testData := []string{
+		"${PARAM1##abc[}",
+		"${PARAM1}",
+		"${PARAM1%%[}",
+	}
+	expectedResult := []string{
+		"",
+		"foo",
+		"",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandSlice(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+
+	sliceErr, ok := err.(ErrSliceExpansion)
+	assert.True(t, ok)
+	assert.Len(t, sliceErr.Errors, 2)
+	assert.Error(t, sliceErr.Errors[0])
+	assert.Error(t, sliceErr.Errors[2])
+}
+
+func TestExpandMapExpandsEachValue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"HOME": "/home/stuart",
+		"USER": "stuart",
+	}
+	cb := newTestCallbacks(vars)
+	testData := map[string]string{
+		"CONFIG_DIR": "${HOME}/.config",
+		"GREETING":   "hello ${USER}",
+		"${USER}":    "keys are not expanded",
+	}
+	expectedResult := map[string]string{
+		"CONFIG_DIR": "/home/stuart/.config",
+		"GREETING":   "hello stuart",
+		"${USER}":    "keys are not expanded",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandMap(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandMapLooksUpEachVariableOnce(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"HOME": "/home/stuart",
+	}
+	lookups := map[string]int{}
+	cb := countLookups(newTestCallbacks(vars), lookups)
+	testData := map[string]string{
+		"CONFIG_DIR": "${HOME}/.config",
+		"CACHE_DIR":  "${HOME}/.cache",
+		"DATA_DIR":   "${HOME}/.local/share",
+	}
+	expectedLookups := map[string]int{
+		"HOME": 1,
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandMap(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedLookups, lookups)
+}
+
+func TestExpandMapReturnsErrorsKeyedByName(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo",
+	}
+	cb := newTestCallbacks(vars)
+	testData := map[string]string{
+		"GOOD": "${PARAM1}",
+		"BAD":  "${PARAM1##abc[}",
+	}
+	expectedResult := map[string]string{
+		"GOOD": "foo",
+		"BAD":  "",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandMap(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+
+	mapErr, ok := err.(ErrMapExpansion)
+	assert.True(t, ok)
+	assert.Len(t, mapErr.Errors, 1)
+	assert.Error(t, mapErr.Errors["BAD"])
+}
+
+func TestExpanderExpandSliceWithWorkersExpandsEachEntry(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo.tar.gz",
+		"PARAM2": "bar",
+	}
+	lookups := map[string]int{}
+	unit := NewExpander(countLookups(newTestCallbacks(vars), lookups), WithWorkers(4))
+
+	var testData []string
+	var expectedResult []string
+	for i := 0; i < 100; i++ {
+		testData = append(testData, fmt.Sprintf("%d:${PARAM1%%%%.*}/${PARAM2^^}/a{b,c}", i))
+		expectedResult = append(expectedResult, fmt.Sprintf("%d:foo/BAR/ab %d:foo/BAR/ac", i, i))
+	}
+	expectedLookups := map[string]int{
+		"PARAM1": 1,
+		"PARAM2": 1,
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandSlice(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, expectedLookups, lookups)
+}
+
+func TestExpanderExpandSliceWithWorkersReturnsErrorsKeyedByIndex(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo",
+	}
+	unit := NewExpander(newTestCallbacks(vars), WithWorkers(3))
+	testData := []string{
+		"${PARAM1}",
+		"${PARAM1##abc[}",
+		"${PARAM1}",
+		"${PARAM1%%abc[}",
+	}
+	expectedResult := []string{
+		"foo",
+		"",
+		"foo",
+		"",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandSlice(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+
+	sliceErr, ok := err.(ErrSliceExpansion)
+	assert.True(t, ok)
+	assert.Len(t, sliceErr.Errors, 2)
+	assert.Error(t, sliceErr.Errors[1])
+	assert.Error(t, sliceErr.Errors[3])
+}
+
+func TestExpanderExpandMapWithWorkersExpandsEachValue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo",
+	}
+	unit := NewExpander(newTestCallbacks(vars), WithWorkers(8))
+
+	testData := map[string]string{}
+	expectedResult := map[string]string{}
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("KEY%d", i)
+		testData[key] = fmt.Sprintf("${PARAM1}-%d", i)
+		expectedResult[key] = fmt.Sprintf("foo-%d", i)
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandMap(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandSliceErrorsMatchEachEntrysError(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(nil)
+	testData := []string{
+		"fine",
+		"${PARAM1:?must be set}",
+	}
+	var varErr ErrVarRequired
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandSlice(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrSliceExpansion{}))
+	assert.True(t, errors.Is(err, ErrVarRequired{}))
+	assert.False(t, errors.Is(err, ErrBadPattern{}))
+	assert.True(t, errors.As(err, &varErr))
+	assert.Equal(t, "PARAM1", varErr.name)
+}
+
+func TestExpandMapErrorsMatchEachEntrysError(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(nil)
+	testData := map[string]string{
+		"A": "fine",
+		"B": "${PARAM1:?must be set}",
+	}
+	var varErr ErrVarRequired
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandMap(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrMapExpansion{}))
+	assert.True(t, errors.Is(err, ErrVarRequired{}))
+	assert.False(t, errors.Is(err, ErrBadPattern{}))
+	assert.True(t, errors.As(err, &varErr))
+	assert.Equal(t, "PARAM1", varErr.name)
+}
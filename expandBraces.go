@@ -36,36 +36,49 @@
 package shellexpand
 
 import (
+	"context"
+	"fmt"
 	"strconv"
 	"strings"
 	"unicode/utf8"
 )
 
 // expandBraces performs UNIX shell brace expansion on the input string
-func expandBraces(input string) string {
-	// this is what we're assessing
-	var r rune
-
-	// this is always how many bytes 'r' is
-	w := 0
-
-	// this is true when we are skipping over escaped characters
-	inEscape := false
+func expandBraces(input string, limits *braceLimiter) (string, error) {
+	// this is where the current word starts; brace expansion applies
+	// to the whole word
+	wordStart := 0
 
 	// we expand in a strictly left-to-right manner
 	for i := 0; i < len(input); {
-		r, w = utf8.DecodeRuneInString(input[i:])
+		// jump straight to the next character that we are interested
+		// in; they are all ASCII, so we can never land in the middle of
+		// a multi-byte character
+		next := strings.IndexAny(input[i:], blankChars+"\\${")
+		if next < 0 {
+			break
+		}
+		i += next
 
 		// what are we looking at?
-		if inEscape {
-			// skip over escaped character
-			inEscape = false
-			i += w
-		} else if r == '\\' && !inEscape {
-			// next character is escaped
-			inEscape = true
-			i += w
-		} else if r == '$' {
+		switch input[i] {
+		case '\\':
+			// skip over the escaped character
+			i++
+			if i < len(input) {
+				_, escW := utf8.DecodeRuneInString(input[i:])
+				i += escW
+			}
+		case '$':
+			// arithmetic expansions and command substitutions are
+			// immune to brace expansion, and their spaces do not end
+			// the word
+			spanEnd, ok := findSubstitution(input[i:])
+			if ok {
+				i += spanEnd
+				continue
+			}
+
 			// possible variable?
 			//
 			// variables are immune to brace expansion
@@ -73,79 +86,240 @@ func expandBraces(input string) string {
 			if ok {
 				i += varEnd - 1
 			} else {
-				i += w
+				i++
 			}
-		} else if r == '{' {
+		case '{':
 			// probably the start of something we can expand
 			var ok bool
-			input, ok = matchAndExpandBraceSequence(input, i)
+			var err error
+			input, ok, err = matchAndExpandBraceSequence(input, wordStart, i, limits)
+			if err != nil {
+				return "", err
+			}
 			if !ok {
-				input, ok = matchAndExpandBracePattern(input, i)
+				input, _, err = matchAndExpandBracePattern(input, wordStart, i, limits)
+				if err != nil {
+					return "", err
+				}
 			}
-			i += w
-		} else {
-			// just another character, nothing for us to do with it
-			i += w
+			i++
+		case ' ', '\t', '\n':
+			// we have reached the end of the current word
+			i++
+			wordStart = i
 		}
 	}
 
 	// all done
-	return input
+	return input, nil
 }
 
-func expandBracePattern(preamble, part, postscript string) string {
-	// we'll build our substitution here
-	var buf strings.Builder
+// checkBraces returns an ExpansionError if the braces in the input do
+// not match up
+//
+// an unterminated ${ is not reported here; parameter expansion reports
+// that itself
+func checkBraces(input string) error {
+	_, err := matchBraces(input)
+	if err == nil {
+		return nil
+	}
 
-	// may be empty
-	if len(preamble) > 0 {
-		buf.WriteString(preamble)
+	braceErr, ok := err.(ErrMismatchedBrace)
+	if ok && braceErr.Index > 0 && input[braceErr.Index-1] == '$' {
+		return nil
 	}
 
-	// we always have a pattern part to add
-	buf.WriteString(part)
+	return newBraceExpansionError(input, err)
+}
 
-	// may also be empty
-	if len(postscript) > 0 {
-		buf.WriteString(postscript)
+// newBraceExpansionError turns an error from matchBraces() into an
+// ExpansionError that points at the brace that does not match
+func newBraceExpansionError(input string, err error) error {
+	switch braceErr := err.(type) {
+	case ErrMismatchedBrace:
+		return newExpansionError(PhaseBraceExpansion, input, braceErr.Index, len(input), err)
+	case ErrMismatchedClosingBrace:
+		return newExpansionError(PhaseBraceExpansion, input, braceErr.Position-1, braceErr.Position, err)
 	}
 
-	// all done
-	return buf.String()
+	return err
+}
+
+// expandBracesInWord performs UNIX shell brace expansion on a single
+// word, and returns the list of words that it expands into
+//
+// unlike expandBraces(), it understands quoting: braces inside quotes
+// are not expanded
+func expandBracesInWord(word string, limits *braceLimiter) ([]string, error) {
+	var r rune
+	w := 0
+
+	for i := 0; i < len(word); i += w {
+		r, w = utf8.DecodeRuneInString(word[i:])
+
+		switch r {
+		case '\\':
+			// skip over the escaped character
+			if i+w < len(word) {
+				_, escW := utf8.DecodeRuneInString(word[i+w:])
+				w += escW
+			}
+		case '\'', '"':
+			// quoted strings are immune to brace expansion
+			quoteEnd, ok := matchQuotes(word[i:])
+			if ok {
+				w = quoteEnd
+			}
+		case '$':
+			// variables are immune to brace expansion
+			varEnd, ok := matchVar(word[i:])
+			if ok {
+				w = varEnd
+			}
+		case '{':
+			parts, partsEnd, ok, err := matchAndParseBraces(word[i:], limits)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			// there may be more braces to expand in each of the parts,
+			// and in the rest of the word
+			var retval []string
+			for _, part := range parts {
+				words, err := expandBracesInWord(word[:i]+part+word[i+partsEnd:], limits)
+				if err != nil {
+					return nil, err
+				}
+				retval = append(retval, words...)
+			}
+			return retval, nil
+		}
+	}
+
+	// if we get here, there was nothing to expand
+	return []string{word}, nil
+}
+
+// matchBraceExpansion checks to see if the input string starts with
+// either a brace sequence or a brace pattern, without expanding it
+//
+// returns:
+//
+// - the position just after the closing brace
+// - `true` on success
+func matchBraceExpansion(input string) (int, bool) {
+	// are we looking at a sequence?
+	seqEnd, ok := matchBraceSequence(input)
+	if ok {
+		_, ok = parseBraceSequence(input[:seqEnd])
+		if ok {
+			return seqEnd, true
+		}
+	}
+
+	// are we looking at a pattern?
+	patternEnd, ok := matchBracePattern(input)
+	if ok && !hasUnescapedSpace(input[:patternEnd]) {
+		_, ok = parseBracePattern(input[:patternEnd])
+		if ok {
+			return patternEnd, true
+		}
+	}
+
+	// no, we are not
+	return 0, false
+}
+
+// matchAndParseBraces checks to see if the input string starts with
+// either a brace sequence or a brace pattern
+//
+// returns:
+//
+// - the list of entries that the braces expand into
+// - the position just after the closing brace
+// - `true` on success
+// - an error if we have run out of budget or time
+func matchAndParseBraces(input string, limits *braceLimiter) ([]string, int, bool, error) {
+	// are we looking at a sequence?
+	seqEnd, ok := matchBraceSequence(input)
+	if ok {
+		braceSeq, ok := parseBraceSequence(input[:seqEnd])
+		if ok {
+			entries, err := expandBraceSequenceEntries(braceSeq, limits)
+			return entries, seqEnd, err == nil, err
+		}
+	}
+
+	// are we looking at a pattern?
+	patternEnd, ok := matchBracePattern(input)
+	if ok {
+		patternParts, ok := parseBracePattern(input[:patternEnd])
+		if ok {
+			for range patternParts {
+				err := limits.addWord()
+				if err != nil {
+					return nil, 0, false, err
+				}
+			}
+			return patternParts, patternEnd, true, nil
+		}
+	}
+
+	// no, we are not
+	return nil, 0, false, nil
 }
 
-func expandBraceSequence(entry int, isChars bool, preamble, postscript string) string {
+func expandBracePattern(preamble, part, postscript string) string {
 	// we'll build our substitution here
 	var buf strings.Builder
+	buf.Grow(len(preamble) + len(part) + len(postscript))
 
 	// may be empty
 	if len(preamble) > 0 {
 		buf.WriteString(preamble)
 	}
 
-	// we always have a sequence entry to add
-	if isChars {
-		buf.WriteString(string(entry))
-	} else {
-		buf.WriteString(strconv.Itoa(entry))
-	}
+	// we always have a pattern part to add
+	buf.WriteString(part)
 
 	// may also be empty
 	if len(postscript) > 0 {
 		buf.WriteString(postscript)
 	}
 
+	// all done
 	return buf.String()
 }
 
-func findPreambleStart(input string, preambleStart int) int {
-	for ; preambleStart > 0; preambleStart-- {
-		if input[preambleStart] == ' ' {
-			return preambleStart + 1
-		}
+func expandBraceSequence(entry int, braceSeq braceSequence, preamble, postscript string) string {
+	// what does this entry look like?
+	var part string
+	switch {
+	case braceSeq.chars:
+		part = string(rune(entry))
+	case braceSeq.width > 0:
+		part = fmt.Sprintf("%0*d", braceSeq.width, entry)
+	default:
+		part = strconv.Itoa(entry)
+	}
+
+	return expandBracePattern(preamble, part, postscript)
+}
+
+func expandBraceSequenceEntries(braceSeq braceSequence, limits *braceLimiter) ([]string, error) {
+	retval := make([]string, 0, braceSeq.capacity())
+	err := braceSeq.each(limits, func(entry int) {
+		retval = append(retval, expandBraceSequence(entry, braceSeq, "", ""))
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return 0
+	return retval, nil
 }
 
 func findPostscriptEnd(input string, postscriptEnd int) int {
@@ -153,7 +327,20 @@ func findPostscriptEnd(input string, postscriptEnd int) int {
 	w := 0
 	for postscriptEnd < len(input) {
 		r, w = utf8.DecodeRuneInString(input[postscriptEnd:])
-		if r == ' ' {
+		if r == '\\' && postscriptEnd+w < len(input) {
+			// escaped spaces do not end the word
+			_, escW := utf8.DecodeRuneInString(input[postscriptEnd+w:])
+			w += escW
+		} else if r == '$' {
+			// neither do spaces inside an expansion
+			spanEnd, ok := findSubstitution(input[postscriptEnd:])
+			if !ok {
+				spanEnd, ok = matchVar(input[postscriptEnd:])
+			}
+			if ok {
+				w = spanEnd
+			}
+		} else if isBlankChar(r) {
 			return postscriptEnd
 		}
 		postscriptEnd += w
@@ -162,33 +349,58 @@ func findPostscriptEnd(input string, postscriptEnd int) int {
 	return postscriptEnd
 }
 
-func matchAndExpandBracePattern(input string, i int) (string, bool) {
+// endsInEscape returns true if the input string ends in a backslash
+// that has nothing left to escape
+//
+// we cannot expand braces in a word like that: the backslash would
+// escape the space that separates the expanded words
+func endsInEscape(input string) bool {
+	escapes := 0
+	for i := len(input) - 1; i >= 0 && input[i] == '\\'; i-- {
+		escapes++
+	}
+
+	return escapes%2 == 1
+}
+
+func matchAndExpandBracePattern(input string, preambleStart, i int, limits *braceLimiter) (string, bool, error) {
 	// are we looking at a pattern?
 	patternEnd, ok := matchBracePattern(input[i:])
 	if !ok {
-		return input, false
+		return input, false, nil
+	}
+
+	// brace expansion never goes past the end of the current word
+	if hasUnescapedSpace(input[i : i+patternEnd]) {
+		return input, false, nil
 	}
 
 	// is it really a pattern though?
 	patternParts, ok := parseBracePattern(input[i : i+patternEnd])
 	if !ok {
-		return input, false
+		return input, false, nil
 	}
 
 	// if we get here, then yes it is
 	preamble := ""
-	preambleStart := findPreambleStart(input, i)
 	if preambleStart < i {
 		preamble = input[preambleStart:i]
 	}
 	postscript := ""
 	postscriptEnd := findPostscriptEnd(input, i+patternEnd)
+	if endsInEscape(input[:postscriptEnd]) {
+		return input, false, nil
+	}
 	if postscriptEnd > i+patternEnd {
 		postscript = input[i+patternEnd : postscriptEnd]
 	}
 
-	var exp []string
+	exp := make([]string, 0, len(patternParts))
 	for _, part := range patternParts {
+		err := limits.addWord()
+		if err != nil {
+			return input, false, err
+		}
 		exp = append(exp, expandBracePattern(preamble, part, postscript))
 	}
 
@@ -197,48 +409,46 @@ func matchAndExpandBracePattern(input string, i int) (string, bool) {
 		buf.WriteString(input[:preambleStart])
 	}
 	buf.WriteString(strings.Join(exp, " "))
-	if postscriptEnd+1 < len(input) {
-		buf.WriteRune(' ')
-		buf.WriteString(input[postscriptEnd+1:])
-	}
+	// keep whichever blank ended the word, so that newlines and tabs
+	// stay where they were
+	buf.WriteString(input[postscriptEnd:])
 
-	return buf.String(), true
+	return buf.String(), true, nil
 }
 
-func matchAndExpandBraceSequence(input string, i int) (string, bool) {
+func matchAndExpandBraceSequence(input string, preambleStart, i int, limits *braceLimiter) (string, bool, error) {
 	// are we looking at a sequence?
 	seqEnd, ok := matchBraceSequence(input[i:])
 	if !ok {
-		return input, false
+		return input, false, nil
 	}
 
 	// but is it really a sequence?
 	braceSeq, ok := parseBraceSequence(input[i : i+seqEnd])
 	if !ok {
-		return input, false
+		return input, false, nil
 	}
 
 	// if we get here, then yes it is
 	preamble := ""
-	preambleStart := findPreambleStart(input, i)
 	if preambleStart < i {
 		preamble = input[preambleStart:i]
 	}
 	postscript := ""
 	postscriptEnd := findPostscriptEnd(input, i+seqEnd)
+	if endsInEscape(input[:postscriptEnd]) {
+		return input, false, nil
+	}
 	if postscriptEnd > i+seqEnd {
 		postscript = input[i+seqEnd : postscriptEnd]
 	}
 
-	var exp []string
-	if braceSeq.incr > 0 {
-		for j := braceSeq.start; j <= braceSeq.end; j += braceSeq.incr {
-			exp = append(exp, expandBraceSequence(j, braceSeq.chars, preamble, postscript))
-		}
-	} else {
-		for j := braceSeq.start; j >= braceSeq.end; j += braceSeq.incr {
-			exp = append(exp, expandBraceSequence(j, braceSeq.chars, preamble, postscript))
-		}
+	exp := make([]string, 0, braceSeq.capacity())
+	err := braceSeq.each(limits, func(entry int) {
+		exp = append(exp, expandBraceSequence(entry, braceSeq, preamble, postscript))
+	})
+	if err != nil {
+		return input, false, err
 	}
 
 	var buf strings.Builder
@@ -246,13 +456,27 @@ func matchAndExpandBraceSequence(input string, i int) (string, bool) {
 		buf.WriteString(input[:preambleStart])
 	}
 	buf.WriteString(strings.Join(exp, " "))
-	if postscriptEnd < len(input) {
-		buf.WriteRune(' ')
-		buf.WriteString(input[postscriptEnd+1:])
-	}
+	// keep whichever blank ended the word, so that newlines and tabs
+	// stay where they were
+	buf.WriteString(input[postscriptEnd:])
 
 	// all done
-	return buf.String(), true
+	return buf.String(), true, nil
+}
+
+// hasUnescapedSpace returns true if the input string contains a space,
+// tab or newline that has not been escaped with a backslash
+func hasUnescapedSpace(input string) bool {
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case ' ', '\t', '\n':
+			return true
+		}
+	}
+
+	return false
 }
 
 func matchBracePattern(input string) (int, bool) {
@@ -431,8 +655,69 @@ type braceSequence struct {
 
 	// are we going up or down, and by how much?
 	incr int
+
+	// how many digits to pad each number out to, using leading zeros
+	//
+	// zero means no padding
+	width int
+}
+
+// len returns how many entries the sequence expands to
+func (s braceSequence) len() int {
+	switch {
+	case s.incr > 0 && s.end >= s.start:
+		return (s.end-s.start)/s.incr + 1
+	case s.incr < 0 && s.start >= s.end:
+		return (s.start-s.end)/-s.incr + 1
+	default:
+		return 0
+	}
 }
 
+// each calls fn for every entry in the sequence, in order
+//
+// it stops early if the limiter says that we have generated too many
+// words, or run out of time
+func (s braceSequence) each(limits *braceLimiter, fn func(entry int)) error {
+	if s.incr > 0 {
+		for j := s.start; j <= s.end; j += s.incr {
+			err := limits.addWord()
+			if err != nil {
+				return err
+			}
+			fn(j)
+		}
+	} else {
+		for j := s.start; j >= s.end; j += s.incr {
+			err := limits.addWord()
+			if err != nil {
+				return err
+			}
+			fn(j)
+		}
+	}
+
+	return nil
+}
+
+// capacity returns how many entries to make room for, before we start
+// generating the sequence
+//
+// a sequence can be far larger than we are allowed to generate, so we
+// don't trust len() on its own
+func (s braceSequence) capacity() int {
+	retval := s.len()
+	if retval > braceSequenceMaxCapacity {
+		return braceSequenceMaxCapacity
+	}
+
+	return retval
+}
+
+// braceSequenceMaxCapacity is the most entries that we make room for up
+// front; larger sequences grow as they are generated
+const braceSequenceMaxCapacity = 1024
+
 func parseBraceSequence(pattern string) (braceSequence, bool) {
 	var retval braceSequence
 
@@ -455,6 +740,15 @@ func parseBraceSequence(pattern string) (braceSequence, bool) {
 		// all numbers
 		retval.start, _ = strconv.Atoi(parts[0])
 		retval.end, _ = strconv.Atoi(parts[1])
+
+		// just like bash, a leading zero on either end (e.g. {01..20})
+		// pads every number to the same width
+		if hasLeadingZero(parts[0]) || hasLeadingZero(parts[1]) {
+			retval.width = len(parts[0])
+			if len(parts[1]) > retval.width {
+				retval.width = len(parts[1])
+			}
+		}
 	} else if isNumericStart != isNumericEnd {
 		return braceSequence{}, false
 	} else {
@@ -488,3 +782,54 @@ func parseBraceSequence(pattern string) (braceSequence, bool) {
 	// all done
 	return retval, true
 }
+
+// hasLeadingZero returns true if the number has a zero in front of it,
+// e.g. `01`
+func hasLeadingZero(number string) bool {
+	return len(number) > 1 && number[0] == '0'
+}
+
+// braceLimiter stops brace expansion from running away with itself
+//
+// a short input such as {1..10000000} or {1..200}{1..200}{1..200} can
+// generate millions of words, so we charge the caller's budget for each
+// word that we generate, and keep checking that the caller is still
+// waiting for us
+//
+// a nil braceLimiter has no limits
+type braceLimiter struct {
+	ctx    context.Context
+	budget *expansionBudget
+
+	// how many words we have generated so far
+	words int
+}
+
+// braceLimiterCheckInterval is how many words we generate between each
+// check of the caller's context
+const braceLimiterCheckInterval = 1024
+
+func newBraceLimiter(ctx context.Context, budget *expansionBudget) *braceLimiter {
+	return &braceLimiter{ctx: ctx, budget: budget}
+}
+
+// addWord charges for one more word of brace expansion
+//
+// it returns ErrBudgetExceeded if the caller's budget has been used up,
+// or the context's error if the caller has stopped waiting
+func (l *braceLimiter) addWord() error {
+	// do we have any limits?
+	if l == nil {
+		return nil
+	}
+
+	l.words++
+	if l.words%braceLimiterCheckInterval == 0 {
+		err := l.ctx.Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	return l.budget.spend(BudgetExpansions)
+}
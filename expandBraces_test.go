@@ -36,6 +36,8 @@
 package shellexpand
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -53,11 +55,12 @@ func TestExpandBracesSingleSet(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := expandBraces(testData)
+	actualResult, err := expandBraces(testData, nil)
 
 	// ----------------------------------------------------------------
 	// test the results
 
+	assert.Nil(t, err)
 	assert.Equal(t, expectedResult, actualResult)
 }
 
@@ -73,11 +76,12 @@ func TestExpandBracesSingleSetWithEmptyPart(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := expandBraces(testData)
+	actualResult, err := expandBraces(testData, nil)
 
 	// ----------------------------------------------------------------
 	// test the results
 
+	assert.Nil(t, err)
 	assert.Equal(t, expectedResult, actualResult)
 }
 
@@ -93,11 +97,12 @@ func TestExpandBracesNestedSet(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := expandBraces(testData)
+	actualResult, err := expandBraces(testData, nil)
 
 	// ----------------------------------------------------------------
 	// test the results
 
+	assert.Nil(t, err)
 	assert.Equal(t, expectedResult, actualResult)
 }
 
@@ -113,11 +118,12 @@ func TestExpandBracesSingleSequence(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := expandBraces(testData)
+	actualResult, err := expandBraces(testData, nil)
 
 	// ----------------------------------------------------------------
 	// test the results
 
+	assert.Nil(t, err)
 	assert.Equal(t, expectedResult, actualResult)
 }
 
@@ -133,11 +139,12 @@ func TestExpandBracesMalformedVariable(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := expandBraces(testData)
+	actualResult, err := expandBraces(testData, nil)
 
 	// ----------------------------------------------------------------
 	// test the results
 
+	assert.Nil(t, err)
 	assert.Equal(t, expectedResult, actualResult)
 }
 
@@ -153,11 +160,12 @@ func TestExpandBracesMalformedVariableInsidePattern(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := expandBraces(testData)
+	actualResult, err := expandBraces(testData, nil)
 
 	// ----------------------------------------------------------------
 	// test the results
 
+	assert.Nil(t, err)
 	assert.Equal(t, expectedResult, actualResult)
 }
 
@@ -173,14 +181,247 @@ func TestExpandBracesPatternAndSequence(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := expandBraces(testData)
+	actualResult, err := expandBraces(testData, nil)
 
 	// ----------------------------------------------------------------
 	// test the results
 
+	assert.Nil(t, err)
 	assert.Equal(t, expectedResult, actualResult)
 }
 
+func TestExpandBracesPatternStopsAtEndOfWord(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "x{a, b}y"
+	expectedResult := "x{a, b}y"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expandBraces(testData, nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandBracesPatternAfterEscapedBraces(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := `\{a,b}{a,b}{1`
+	expectedResult := `\{a,b}a{1 \{a,b}b{1`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expandBraces(testData, nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandBracesKeepsEscapedSpacesInPostscript(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := `{a,b}x\ y`
+	expectedResult := `ax\ y bx\ y`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expandBraces(testData, nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandBracesIgnoresWordEndingInBackslash(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := `{a,b}x\`
+	expectedResult := `{a,b}x\`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expandBraces(testData, nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandBracesPatternStopsAtEndOfLine(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "a{b,c}\nd{e,f}\n"
+	expectedResult := "ab ac\nde df\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expandBraces(testData, nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandBracesSequenceStopsAtTab(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "a{1..3}\t\tb"
+	expectedResult := "a1 a2 a3\t\tb"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expandBraces(testData, nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandBracesPatternCannotSpanLines(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "x{a,\nb}y"
+	expectedResult := "x{a,\nb}y"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expandBraces(testData, nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandBracesKeepsSubstitutionsInTheSameWord(t *testing.T) {
+	t.Parallel()
+
+	testData := map[string]string{
+		"{b,c}$((1 + 2))":       "b$((1 + 2)) c$((1 + 2))",
+		"$((1 + 2)){b,c}":       "$((1 + 2))b $((1 + 2))c",
+		"$[1 + 1]{b,c}":         "$[1 + 1]b $[1 + 1]c",
+		"{b,c}$[1 + 1]":         "b$[1 + 1] c$[1 + 1]",
+		"{b,c}$(echo x  y) z":   "b$(echo x  y) c$(echo x  y) z",
+		"$(echo x  y){b,c} z":   "$(echo x  y)b $(echo x  y)c z",
+		"a{b,c}${X:-1 2} {d,e}": "ab${X:-1 2} ac${X:-1 2} d e",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := expandBraces(input, nil)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestExpandBracesNextToArithmeticWithSpaces(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// these results match bash
+	unit := NewExpander(NewEnv().Callbacks(), WithLegacyArithmetic())
+	testData := map[string]string{
+		"{b,c}$((1 + 2))":   "b3 c3",
+		"$((1 + 2)){b,c}":   "3b 3c",
+		"$[1 + 1]{b,c}":     "2b 2c",
+		"x {b,c}$[1 + 1] y": "x b2 c2 y",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestExpandBracesNextToCommandSubstitutionWithSpaces(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewEnv().Callbacks()
+	cb.RunCommand = func(ctx context.Context, args []string) (string, error) {
+		return strings.Join(args, "-"), nil
+	}
+	testData := map[string]string{
+		"{b,c}$(echo x  y)": "becho-x-y cecho-x-y",
+		"$(echo x  y){b,c}": "echo-x-yb echo-x-yc",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Expand(input, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
 func TestMatchPatternSingleSet(t *testing.T) {
 	t.Parallel()
 
@@ -617,7 +858,7 @@ func TestParseSequenceSingleSetWithLowerCaseChars(t *testing.T) {
 	// setup your test
 
 	testData := "{a..z}"
-	expectedResult := braceSequence{true, 97, 122, 1}
+	expectedResult := braceSequence{true, 97, 122, 1, 0}
 
 	// ----------------------------------------------------------------
 	// perform the change
@@ -638,7 +879,7 @@ func TestParseSequenceSingleSetWithUpperCaseChars(t *testing.T) {
 	// setup your test
 
 	testData := "{A..Z}"
-	expectedResult := braceSequence{true, 65, 90, 1}
+	expectedResult := braceSequence{true, 65, 90, 1, 0}
 
 	// ----------------------------------------------------------------
 	// perform the change
@@ -659,7 +900,7 @@ func TestParseSequenceSingleSetWithNumbers(t *testing.T) {
 	// setup your test
 
 	testData := "{1..99}"
-	expectedResult := braceSequence{false, 1, 99, 1}
+	expectedResult := braceSequence{false, 1, 99, 1, 0}
 
 	// ----------------------------------------------------------------
 	// perform the change
@@ -680,7 +921,7 @@ func TestParseSequenceSingleSetWithIterator(t *testing.T) {
 	// setup your test
 
 	testData := "{1..99..3}"
-	expectedResult := braceSequence{false, 1, 99, 3}
+	expectedResult := braceSequence{false, 1, 99, 3, 0}
 
 	// ----------------------------------------------------------------
 	// perform the change
@@ -701,7 +942,7 @@ func TestParseSequenceSingleSetWithNegativeIterator(t *testing.T) {
 	// setup your test
 
 	testData := "{99..1..-3}"
-	expectedResult := braceSequence{false, 99, 1, -3}
+	expectedResult := braceSequence{false, 99, 1, -3, 0}
 
 	// ----------------------------------------------------------------
 	// perform the change
@@ -722,7 +963,7 @@ func TestParseSequenceSingleSetHighToLow(t *testing.T) {
 	// setup your test
 
 	testData := "{99..1}"
-	expectedResult := braceSequence{false, 99, 1, -1}
+	expectedResult := braceSequence{false, 99, 1, -1, 0}
 
 	// ----------------------------------------------------------------
 	// perform the change
@@ -743,7 +984,28 @@ func TestParseSequenceSingleSetHighToLowWithIncrement(t *testing.T) {
 	// setup your test
 
 	testData := "{99..1..2}"
-	expectedResult := braceSequence{false, 99, 1, -2}
+	expectedResult := braceSequence{false, 99, 1, -2, 0}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, ok := parseBraceSequence(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, ok)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestParseSequencePadsNumbersWithLeadingZeros(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "{1..010..2}"
+	expectedResult := braceSequence{false, 1, 10, 2, 3}
 
 	// ----------------------------------------------------------------
 	// perform the change
@@ -0,0 +1,77 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+)
+
+// ExpandBytes replaces ${var} and $var in the input, just like Expand()
+// does, for when you already have the input as a []byte (for example,
+// the contents of a file).
+//
+// If expanding the input does not change it, you get the input back as
+// it is, without it being copied. Don't modify the returned slice unless
+// you are happy to modify the input too.
+func ExpandBytes(input []byte, cb ExpansionCallbacks) ([]byte, error) {
+	// Expand() decides if there is anything to do: checking the input,
+	// history expansion and strict mode can all change it (or reject
+	// it), even when there is nothing else to expand
+	original := string(input)
+	expanded, err := Expand(original, cb)
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing changed? then there's nothing to convert
+	if expanded == original {
+		return input, nil
+	}
+
+	return []byte(expanded), nil
+}
+
+// ExpandBytes replaces ${var} and $var in the input, just like the
+// package-level ExpandBytes() does
+func (e *Expander) ExpandBytes(input []byte) ([]byte, error) {
+	ctx, done := e.withTimeout(context.Background())
+	cb := e.callbacks()
+	cb.ctx = ctx
+	retval, err := ExpandBytes(input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
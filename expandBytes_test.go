@@ -0,0 +1,173 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandBytesGivesSameResultsAsExpand(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range streamTestInputs {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		expectedResult, expectedErr := Expand(input, newTestCallbacks(streamTestVars()))
+		assert.Nil(t, expectedErr, input)
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := ExpandBytes([]byte(input), newTestCallbacks(streamTestVars()))
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, string(actualResult), input)
+	}
+}
+
+func TestExpandBytesReturnsInputWhenThereIsNothingToExpand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []byte("nothing to expand here at all")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandBytes(testData, newTestCallbacks(streamTestVars()))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, len(testData), len(actualResult))
+	assert.True(t, &testData[0] == &actualResult[0])
+}
+
+func TestExpandBytesReturnsErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []byte("hello ${UNSET:?not set}")
+	_, expectedErr := Expand(string(testData), newTestCallbacks(streamTestVars()))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandBytes(testData, newTestCallbacks(streamTestVars()))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.NotNil(t, expectedErr)
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, actualResult)
+}
+
+func TestExpandBytesAppliesEveryOption(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := []struct {
+		input          string
+		opts           []Option
+		expectedResult string
+		expectedErr    bool
+	}{
+		{"sudo !!", []Option{WithHistory(testHistory("make install"))}, "sudo make install", false},
+		{"a\x00b", []Option{WithInvalidInput(InvalidInputReject)}, "", true},
+		{"a\xffb", []Option{WithInvalidInput(InvalidInputReject)}, "", true},
+		{"a\x00b", []Option{WithInvalidInput(InvalidInputReplace)}, "a\uFFFDb", false},
+		{"a\xffb", []Option{WithInvalidInput(InvalidInputReplace)}, "a\uFFFDb", false},
+		{"a } b", []Option{WithStrict()}, "", true},
+	}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		unit := NewExpander(newTestCallbacks(streamTestVars()), testData.opts...)
+		_, expectedErr := unit.Expand(testData.input)
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.ExpandBytes([]byte(testData.input))
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		if testData.expectedErr {
+			assert.NotNil(t, err, "%q", testData.input)
+			assert.Equal(t, expectedErr, err, "%q", testData.input)
+			continue
+		}
+		assert.Nil(t, err, "%q", testData.input)
+		assert.Equal(t, testData.expectedResult, string(actualResult), "%q", testData.input)
+	}
+}
+
+func TestExpanderExpandBytes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(streamTestVars()), WithStrict())
+	testData := []byte("${PARAM1}")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.ExpandBytes([]byte("${PARAM1"))
+	actualResult, err2 := unit.ExpandBytes(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.NotNil(t, err)
+	assert.Nil(t, err2)
+	assert.Equal(t, "foo", string(actualResult))
+}
@@ -0,0 +1,119 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "context"
+
+// DefaultMaxPasses is how many times ExpandFully() will expand its
+// input, unless you use the WithMaxPasses() option
+const DefaultMaxPasses = 10
+
+// ExpandFully expands the input string again and again, until the result
+// stops changing. Use it when your variables hold values that contain
+// expansions of their own, such as:
+//
+//	LOG_DIR='${APP_DIR}/logs'
+//	APP_DIR='${HOME}/app'
+//
+// where a single call to Expand("$LOG_DIR") would only give you
+// `${APP_DIR}/logs`.
+//
+// Each pass is a full expansion, including quote removal. Any quotes
+// and backslashes that are left after one pass are removed by the next.
+//
+// If the result is still changing after DefaultMaxPasses passes (for
+// example, because two variables refer to each other), you get an
+// ErrTooManyPasses.
+func ExpandFully(input string, cb ExpansionCallbacks) (string, error) {
+	return ExpandFullyContext(cb.context(), input, cb)
+}
+
+// ExpandFullyContext expands the input string until the result stops
+// changing, just like ExpandFully() does. The given context is used
+// for every pass.
+func ExpandFullyContext(ctx context.Context, input string, cb ExpansionCallbacks) (string, error) {
+	return expandFully(input, cb.maxPasses(), func(input string) (string, error) {
+		return ExpandContext(ctx, input, cb)
+	})
+}
+
+// ExpandFully expands the input string until the result stops changing,
+// just like the package-level ExpandFully() does
+//
+// Every pass shares the same budget (see WithBudget()).
+func (e *Expander) ExpandFully(input string) (string, error) {
+	return e.ExpandFullyContext(context.Background(), input)
+}
+
+// ExpandFullyContext expands the input string until the result stops
+// changing, just like the package-level ExpandFullyContext() does
+func (e *Expander) ExpandFullyContext(ctx context.Context, input string) (string, error) {
+	ctx, done := e.withTimeout(ctx)
+	cb := e.callbacks()
+	retval, err := ExpandFullyContext(ctx, input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
+
+// WithMaxPasses sets how many times ExpandFully() will expand its input
+// before it gives up with an ErrTooManyPasses.
+//
+// By default, it is DefaultMaxPasses.
+func WithMaxPasses(passes int) Option {
+	return func(opts *options) {
+		opts.maxPasses = passes
+	}
+}
+
+// expandFully does the work for ExpandFully(), using the given function
+// for each pass
+func expandFully(input string, maxPasses int, expand func(string) (string, error)) (string, error) {
+	for i := 0; i < maxPasses; i++ {
+		expanded, err := expand(input)
+		if err != nil {
+			return "", err
+		}
+
+		// have we reached the fixpoint?
+		if expanded == input {
+			return expanded, nil
+		}
+		input = expanded
+	}
+
+	return "", ErrTooManyPasses{Max: maxPasses}
+}
@@ -0,0 +1,185 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandFullyExpandsUntilTheResultStopsChanging(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"HOME":    "/home/stuart",
+		"APP_DIR": "${HOME}/app",
+		"LOG_DIR": "${APP_DIR}/logs",
+	})
+	expectedResult := "/home/stuart/app/logs/app.log"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandFully("$LOG_DIR/app.log", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandFullyReturnsInputThatHasNothingToExpand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{})
+	expectedResult := "nothing to see here"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandFully(expectedResult, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandFullyReturnsErrTooManyPassesForCycles(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"A": "$B",
+		"B": "$A",
+	})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandFully("$A", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrTooManyPasses{}))
+	assert.Equal(t, ErrTooManyPasses{Max: DefaultMaxPasses}, err)
+}
+
+func TestExpandFullyReturnsExpansionErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"TEMPLATE": "${REQUIRED:?must be set}",
+	})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandFully("$TEMPLATE", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrVarRequired{}))
+}
+
+func TestExpanderExpandFullyUsesMaxPassesOption(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"HOME":    "/home/stuart",
+		"APP_DIR": "${HOME}/app",
+		"LOG_DIR": "${APP_DIR}/logs",
+	})
+	shallow := NewExpander(cb, WithMaxPasses(2))
+	deep := NewExpander(cb, WithMaxPasses(4))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, shallowErr := shallow.ExpandFully("$LOG_DIR")
+	deepResult, deepErr := deep.ExpandFully("$LOG_DIR")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, ErrTooManyPasses{Max: 2}, shallowErr)
+	assert.Nil(t, deepErr)
+	assert.Equal(t, "/home/stuart/app/logs", deepResult)
+}
+
+func TestErrTooManyPasses(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := ErrTooManyPasses{Max: 3}
+	expectedResult := "input still changing after 3 passes of expansion"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+	assert.True(t, errors.Is(testData, ErrTooManyPasses{}))
+	assert.False(t, errors.Is(testData, ErrVarRequired{}))
+}
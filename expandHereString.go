@@ -0,0 +1,83 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "strings"
+
+// ExpandHereString expands the word in a `<<<word` here-string, the
+// same way that bash does, and returns the text that the command reads
+// on its stdin.
+//
+// The word goes through:
+//
+// - tilde expansion, at the start of the word and after each unquoted ':'
+// - parameter & variable expansion
+// - command substitution and arithmetic expansion
+// - quote removal
+//
+// There is no brace expansion, no word splitting, and no pathname
+// expansion; `<<<$X` keeps any spaces and newlines in X as they are, and
+// `<<<"$@"` joins the positional parameters up with spaces.
+//
+// Just like bash, we add a newline to the end of the result.
+func ExpandHereString(input string, cb ExpansionCallbacks) (string, error) {
+	input, err := checkInput(input, cb)
+	if err != nil {
+		return "", err
+	}
+
+	// step 1: make sure the quotes all match up
+	_, err = splitWords(input)
+	if err != nil {
+		quoteErr, ok := err.(ErrUnterminatedQuote)
+		if ok {
+			err = newExpansionError(PhaseWordSplitting, input, quoteErr.index, len(input), err)
+		}
+		return "", locateExpansionError(err, input, input, 0)
+	}
+
+	// step 2: everything else
+	//
+	// bash expands a here-string the same way that it expands the value
+	// in an assignment
+	fields, err := expandWordToFields(input, cb, wordAssignment)
+	if err != nil {
+		return "", locateExpansionError(err, input, input, 0)
+	}
+
+	// "$@" can still give us several fields
+	return strings.Join(fields, " ") + "\n", nil
+}
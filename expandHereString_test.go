@@ -0,0 +1,180 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+type expandHereStringTestData struct {
+	vars             map[string]string
+	positionalParams []string
+	input            string
+	expectedResult   string
+}
+
+func TestExpandHereStringExpandsTheWord(t *testing.T) {
+	testData := expandHereStringTestData{
+		vars: map[string]string{
+			"PARAM1": "foo",
+		},
+		input:          "hello-${PARAM1}-$PARAM1-$((1 + 2))",
+		expectedResult: "hello-foo-foo-3\n",
+	}
+	testExpandHereStringTestCase(t, testData)
+}
+
+func TestExpandHereStringExpandsTildes(t *testing.T) {
+	testData := expandHereStringTestData{
+		vars: map[string]string{
+			"HOME": "/home/alfred",
+		},
+		input:          `~/bin:~:a~:"~"/x:\~/y`,
+		expectedResult: "/home/alfred/bin:/home/alfred:a~:~/x:~/y\n",
+	}
+	testExpandHereStringTestCase(t, testData)
+}
+
+func TestExpandHereStringDoesNotSplitWords(t *testing.T) {
+	testData := expandHereStringTestData{
+		vars: map[string]string{
+			"PARAM1": "a  b\tc\n\nd",
+		},
+		input:          "$PARAM1",
+		expectedResult: "a  b\tc\n\nd\n",
+	}
+	testExpandHereStringTestCase(t, testData)
+}
+
+func TestExpandHereStringDoesNotExpandBracesOrGlobs(t *testing.T) {
+	testData := expandHereStringTestData{
+		input:          "{a,b}*",
+		expectedResult: "{a,b}*\n",
+	}
+	testExpandHereStringTestCase(t, testData)
+}
+
+func TestExpandHereStringRemovesQuotes(t *testing.T) {
+	testData := expandHereStringTestData{
+		vars: map[string]string{
+			"PARAM1": "foo",
+		},
+		input:          `"hello  $PARAM1"' $PARAM1 'world\ !`,
+		expectedResult: "hello  foo $PARAM1 world !\n",
+	}
+	testExpandHereStringTestCase(t, testData)
+}
+
+func TestExpandHereStringJoinsPositionalParams(t *testing.T) {
+	testData := expandHereStringTestData{
+		vars: map[string]string{
+			"IFS": ":",
+		},
+		positionalParams: []string{"a", "b c"},
+		input:            `$@/"$@"/$*/"$*"`,
+		expectedResult:   "a b c/a b c/a:b c/a:b c\n",
+	}
+	testExpandHereStringTestCase(t, testData)
+}
+
+func TestExpandHereStringSupportsEmptyWords(t *testing.T) {
+	testData := expandHereStringTestData{
+		input:          `"$UNSET"`,
+		expectedResult: "\n",
+	}
+	testExpandHereStringTestCase(t, testData)
+}
+
+func TestExpandHereStringReportsWhereTheErrorIs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandHereString(`abc"def`, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err, &expErr))
+	assert.Equal(t, 3, expErr.Offset)
+	var quoteErr ErrUnterminatedQuote
+	assert.True(t, errors.As(err, &quoteErr))
+}
+
+func testExpandHereStringTestCase(t *testing.T, testData expandHereStringTestData) {
+	// ----------------------------------------------------------------
+	// create the shell script we'll run
+
+	// command substitution strips trailing newlines, so we add a marker
+	// to keep the newline that the here-string adds
+	shellCase := shelltest.Case{
+		Vars:             testData.vars,
+		PositionalParams: testData.positionalParams,
+		Commands: []string{
+			"X=$(cat <<<" + testData.input + "; echo .)",
+			"printf '[%s]\\n' \"${X%.}\"",
+		},
+	}
+
+	cb := ExpansionCallbacks{
+		LookupVar: shellCase.LookupVar,
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	shellActualResult, _ := shelltest.Run("bash", &shellCase)
+
+	actualResult, err := ExpandHereString(testData.input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "["+testData.expectedResult+"]", shellActualResult, shelltest.Script(&shellCase))
+	assert.Equal(t, testData.expectedResult, actualResult)
+}
@@ -51,7 +51,7 @@ import (
 // ${var} -> value of var
 // ${var:-word} -> value of var (if set); expansion of word otherwise
 // ${var:=word} -> value of var (if set); otherwise var is set to the expansion of word
-// ${var:?word} -> value of var (if set); otherwise error written to stderr
+// ${var:?word} -> value of var (if set); otherwise ErrVarRequired is returned
 // ${var:+word} -> empty string if var empty/unset; otherwise expansion of word
 // ${var:offset} -> substring of var (if set), starting from offset; otherwise empty string
 // ${var:offset:length} -> same as both, except also controlling length of substring
@@ -71,10 +71,14 @@ import (
 // ${var,pattern} -> value of var, with first char set to lowercase if they are in pattern
 // ${var,,pattern} -> value of var, with any char set to lowercase if they are in pattern
 // ${var@a} -> a set of flags describing var
-// ${var@A} -> not supported?
+// ${var@A} -> a declare statement that recreates var
 // ${var@E} -> escaped value of var - probably too dangerous to support
 // ${var@P} -> expanded prompt string - not supported
 // ${var@Q} -> quoted value of var - probably too dangerous to support
+// ${var@U} -> value of var, in uppercase (the same as ${var^^})
+// ${var@u} -> value of var, with the first char in uppercase (the same as ${var^})
+// ${var@L} -> value of var, in lowercase (the same as ${var,,})
+// ${var@K} / ${var@k} -> quoted value of var - not supported, like ${var@Q}
 //
 // traditional shell special parameters are treated as a special case:
 //
@@ -98,105 +102,187 @@ import (
 // it's up to the caller to ensure lookupVar() can provide a value for any
 // of these params
 func expandParameters(input string, cb ExpansionCallbacks) (string, error) {
-	// keep track of whether we're dealing with an escaped character
-	// or not
-	inEscape := false
+	return expandSpans(input, cb, scanParams)
+}
 
-	// keep track of the end of the last param we matched
-	varEnd := -1
+// handlesUnsetParams are the kinds of parameter expansion that do
+// something useful when the parameter is not set
+//
+// WithKeepUnset() does not apply to them
+var handlesUnsetParams = map[int]bool{
+	paramExpandWithDefaultValue:        true,
+	paramExpandSetDefaultValue:         true,
+	paramExpandWriteError:              true,
+	paramExpandAlternativeValue:        true,
+	paramExpandPrefixNames:             true,
+	paramExpandPrefixNamesDoubleQuoted: true,
+}
 
-	// and this will be where we build up our return value
-	var buf strings.Builder
+type paramExpandFunc func(string, string, paramDesc, ExpansionCallbacks) (string, bool, error)
 
-	// we expand in a strictly left-to-right manner
-	var c rune
-	w := 0
-	for i := 0; i < len(input); {
-		c, w = utf8.DecodeRuneInString(input[i:])
-		if inEscape {
-			// skip over escaped characters
-			inEscape = false
-			buf.WriteRune(c)
-			i += w
-		} else if c == '\\' && !inEscape {
-			// skip over escaped characters
-			inEscape = true
-			i += w
-		} else if c == '$' {
-			var ok bool
-			varEnd, ok = matchVar(input[i:])
-			if ok {
-				varEnd += i
-				paramDesc, ok := parseParameter(input[i:varEnd])
-				if !ok {
-					buf.WriteRune(c)
-					i += w
-					continue
-				}
-
-				replacement, err := expandParameter(input[i:varEnd], paramDesc, cb)
-				if err != nil {
-					return input, err
-				}
-
-				buf.WriteString(replacement)
-
-				i = varEnd
-			} else {
-				buf.WriteRune(c)
-				i += w
-			}
-		} else {
-			buf.WriteRune(c)
-			i += w
-		}
+// paramExpandFuncs holds the function that expands each kind of parameter
+//
+// it is filled in by init(), because some of these functions (such as
+// expandParamWithDefaultValue()) end up calling expandParameter() too
+var paramExpandFuncs map[int]paramExpandFunc
+
+func init() {
+	paramExpandFuncs = map[int]paramExpandFunc{
+		paramExpandToValue:                        expandParamToValue,
+		paramExpandWithDefaultValue:               expandParamWithDefaultValue,
+		paramExpandSetDefaultValue:                expandParamSetDefaultValue,
+		paramExpandWriteError:                     expandParamWriteError,
+		paramExpandAlternativeValue:               expandParamAlternativeValue,
+		paramExpandSubstring:                      expandParamSubstring,
+		paramExpandSubstringLength:                expandParamSubstringLength,
+		paramExpandPrefixNames:                    expandParamPrefixNames,
+		paramExpandPrefixNamesDoubleQuoted:        expandParamPrefixNames,
+		paramExpandParamLength:                    expandParamLength,
+		paramExpandRemovePrefixShortestMatch:      expandParamRemovePrefixShortestMatch,
+		paramExpandRemovePrefixLongestMatch:       expandParamRemovePrefixLongestMatch,
+		paramExpandRemoveSuffixShortestMatch:      expandParamRemoveSuffixShortestMatch,
+		paramExpandRemoveSuffixLongestMatch:       expandParamRemoveSuffixLongestMatch,
+		paramExpandSearchReplaceLongestFirstMatch: expandParamSearchReplaceFirstMatch,
+		paramExpandSearchReplaceLongestAllMatches: expandParamSearchReplaceAllMatches,
+		paramExpandSearchReplaceLongestPrefix:     expandParamSearchReplacePrefix,
+		paramExpandSearchReplaceLongestSuffix:     expandParamSearchReplaceSuffix,
+		paramExpandUppercaseFirstChar:             expandParamUppercaseFirstChar,
+		paramExpandUppercaseAllChars:              expandParamUppercaseAllChars,
+		paramExpandLowercaseFirstChar:             expandParamLowercaseFirstChar,
+		paramExpandLowercaseAllChars:              expandParamLowercaseAllChars,
+		paramExpandDescribeFlags:                  expandParamDescribeFlags,
+		paramExpandAsDeclare:                      expandParamAsDeclare,
 	}
+}
 
-	return buf.String(), nil
+func expandParameter(original string, paramDesc paramDesc, cb ExpansionCallbacks) (string, error) {
+	param, err := startParamExpansion(original, paramDesc, cb)
+	if err != nil {
+		return "", err
+	}
+
+	// the expansion functions will expand the word after the operator
+	// themselves, if they need it
+	if len(paramDesc.parts) > 1 {
+		param.desc.operand = newLazyWord(paramDesc.word())
+	}
+	retval, err := param.finish(cb)
+	param.release()
+
+	return retval, err
 }
 
-type paramExpandFunc func(string, string, paramDesc, ExpansionCallbacks) (string, bool, error)
+// expandParameterToFields expands the given parameter, just like
+// expandParameter() does
+//
+// if the parameter is $@ or $*, you get back the expansion of each
+// positional parameter separately (and the name of the parameter), so
+// that you can turn them into separate words. ${!prefix@} and
+// ${!prefix*} give you back each matching variable name, along with "$@"
+// or "$*" for the name, because they are quoted the same way. Otherwise,
+// you get back a single value, and an empty name.
+//
+// `arg` expands the word after the operator, if it is needed.
+func expandParameterToFields(original string, paramDesc paramDesc, cb ExpansionCallbacks, arg *argOperand) ([]string, string, error) {
+	param, err := startParamExpansion(original, paramDesc, cb)
+	if err != nil {
+		return nil, "", err
+	}
 
-func expandParameter(original string, paramDesc paramDesc, cb ExpansionCallbacks) (string, error) {
-	paramExpandFuncs := map[int]paramExpandFunc{
-		paramExpandToValue:                   expandParamToValue,
-		paramExpandWithDefaultValue:          expandParamWithDefaultValue,
-		paramExpandSetDefaultValue:           expandParamSetDefaultValue,
-		paramExpandWriteError:                expandParamWriteError,
-		paramExpandAlternativeValue:          expandParamAlternativeValue,
-		paramExpandSubstring:                 expandParamSubstring,
-		paramExpandSubstringLength:           expandParamSubstringLength,
-		paramExpandPrefixNames:               expandParamPrefixNames,
-		paramExpandPrefixNamesDoubleQuoted:   expandParamPrefixNames,
-		paramExpandParamLength:               expandParamLength,
-		paramExpandRemovePrefixShortestMatch: expandParamRemovePrefixShortestMatch,
-		paramExpandRemovePrefixLongestMatch:  expandParamRemovePrefixLongestMatch,
-		paramExpandRemoveSuffixShortestMatch: expandParamRemoveSuffixShortestMatch,
-		paramExpandRemoveSuffixLongestMatch:  expandParamRemoveSuffixLongestMatch,
-		paramExpandUppercaseFirstChar:        expandParamUppercaseFirstChar,
-		paramExpandUppercaseAllChars:         expandParamUppercaseAllChars,
-		paramExpandLowercaseFirstChar:        expandParamLowercaseFirstChar,
-		paramExpandLowercaseAllChars:         expandParamLowercaseAllChars,
+	// the expansion functions will expand the word after the operator
+	// themselves, if they need it
+	if len(paramDesc.parts) > 1 {
+		param.desc.operand = arg.lazyWord(&param)
 	}
+	defer param.release()
 
-	// what we will (eventually) send back
-	var retval []string
+	if param.isAllPositionalParams() {
+		retval, err := param.finishFields(cb)
+		return retval, param.name, err
+	}
+	if !param.done {
+		switch paramDesc.kind {
+		case paramExpandPrefixNames:
+			retval, err := param.finishNames(cb)
+			return retval, "$*", err
+		case paramExpandPrefixNamesDoubleQuoted:
+			retval, err := param.finishNames(cb)
+			return retval, "$@", err
+		}
+	}
+
+	retval, err := param.finish(cb)
+	if err != nil {
+		return nil, "", err
+	}
+	return []string{retval}, "", nil
+}
+
+// paramExpansion is a parameter expansion that we are part-way through
+//
+// we split parameter expansion into two halves, so that expandSpans()
+// can expand the word after the operator (if it is needed) without
+// calling itself
+type paramExpansion struct {
+	// the parameter expansion, as it appeared in the input
+	original string
+
+	// what we parsed from `original`
+	desc paramDesc
 
-	// and, because we may be building it up bit by bit, we need somewhere
-	// to store it temporarily
-	var buf string
+	// the name of the parameter, after any indirection
+	name string
+
+	// the value(s) of the parameter
+	values []string
+
+	// how we expand each of those values
+	expandFunc paramExpandFunc
+
+	// true if we already know the result
+	done   bool
+	result string
+}
+
+// startParamExpansion looks up the value(s) of the given parameter
+func startParamExpansion(original string, paramDesc paramDesc, cb ExpansionCallbacks) (paramExpansion, error) {
+	// does the shell we are copying understand this parameter?
+	if !cb.dialect().supportsParam(paramDesc) {
+		return paramExpansion{}, ErrBadSubstitution{original}
+	}
+
+	retval := paramExpansion{
+		original: original,
+		desc:     paramDesc,
+	}
 
 	// step 1: we need to expand the paramName first, to support any
 	// possible use of indirection
-	paramName, ok := expandParamName(paramDesc, cb.LookupVar)
+	if paramDesc.indirect {
+		err := cb.checkName(paramDesc.parts[0])
+		if err != nil {
+			return paramExpansion{}, err
+		}
+	}
+	var ok bool
+	retval.name, ok = expandParamName(paramDesc, cb.lookupVar)
+	if paramDesc.indirect {
+		traceVar(cb, TraceVarRead, PhaseParameterExpansion, paramDesc.parts[0], retval.name)
+	}
 	if !ok {
-		return "", nil
+		retval.done = true
+		if cb.keepUnset() && !handlesUnsetParams[paramDesc.kind] {
+			retval.result = original
+		}
+		return retval, nil
 	}
 
 	// special case
 	if paramDesc.kind == paramExpandNoOfPositionalParams {
-		buf, ok = cb.LookupVar("$#")
-		return buf, nil
+		retval.result, _ = cb.lookupVar("$#")
+		retval.done = true
+		traceParameter(cb, original, "$#", paramDesc, []string{retval.result}, retval.result)
+		return retval, nil
 	}
 
 	// step 2: we need to feed that into all the different ways that
@@ -205,25 +291,269 @@ func expandParameter(original string, paramDesc paramDesc, cb ExpansionCallbacks
 	// this is complicated by some parameters ($*, $@, and arrays if we
 	// ever add support for them in the future) having the expansion applied
 	// to each part of their value
-	for paramValue := range expandParamValue(paramName, cb.LookupVar) {
-		expandFunc, ok := paramExpandFuncs[paramDesc.kind]
-		if !ok {
-			return "", nil
+	//
+	// we collect all the values before expanding any of them
+	if paramDesc.scratch != nil {
+		retval.values = paramDesc.scratch.values[:0]
+	}
+	sliced := false
+	if retval.name == "$@" || retval.name == "$*" {
+		retval.values = expandParamValues(retval.name, cb.lookupVar, retval.values)
+
+		// ${@:offset:length} picks out some of the positional
+		// parameters, instead of part of each one
+		switch paramDesc.kind {
+		case paramExpandSubstring, paramExpandSubstringLength:
+			var err error
+			retval.values, err = slicePositionalParams(retval.values, paramDesc, cb)
+			if err != nil {
+				return paramExpansion{}, err
+			}
+			sliced = true
 		}
+	} else {
+		err := cb.checkName(retval.name)
+		if err != nil {
+			return paramExpansion{}, err
+		}
+		paramValue, ok := cb.lookupVar(retval.name)
+		retval.desc.unset = !ok
+
+		// with `set -u`, it is an error to use a variable that isn't set
+		if !ok && cb.shellOpts().NoUnset && !handlesUnsetParams[paramDesc.kind] && !alwaysSetParams[retval.name] {
+			return paramExpansion{}, ErrUnboundVariable{retval.name}
+		}
+
+		// with WithKeepUnset(), we leave it for someone else to expand
+		if !ok && cb.keepUnset() && !handlesUnsetParams[paramDesc.kind] {
+			retval.result = original
+			retval.done = true
+			return retval, nil
+		}
+		retval.values = append(retval.values, paramValue)
+	}
+	retval.expandFunc, ok = paramExpandFuncs[paramDesc.kind]
+	if sliced {
+		retval.expandFunc = expandParamToValue
+	}
+	if !ok {
+		if cb.strict() {
+			return paramExpansion{}, ErrUnsupportedOperator{original}
+		}
+		retval.done = true
+		return retval, nil
+	}
 
-		var err error
-		buf, ok, err = expandFunc(paramName, paramValue, paramDesc, cb)
+	return retval, nil
+}
+
+// slicePositionalParams applies ${@:offset:length} to the list of
+// positional parameters
+//
+// just like bash, the list starts with $0, if it is set
+func slicePositionalParams(values []string, paramDesc paramDesc, cb ExpansionCallbacks) ([]string, error) {
+	offset, err := evalSubstringNumber(paramDesc.parts[1], cb)
+	if err != nil {
+		return nil, err
+	}
+
+	var length *int
+	if paramDesc.kind == paramExpandSubstringLength {
+		amount, err := evalSubstringNumber(paramDesc.parts[2], cb)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
+		if amount < 0 {
+			return nil, ErrSubstringExpression{strings.TrimSpace(paramDesc.parts[2])}
+		}
+		length = &amount
+	}
 
-		if len(buf) > 0 {
+	arg0, hasArg0 := cb.lookupVar("$0")
+	params := make([]string, 0, len(values)+1)
+	params = append(append(params, arg0), values...)
+
+	// range overflow?
+	start, end, ok := substringBounds(len(params), offset, length)
+	if !ok {
+		return values[:0], nil
+	}
+	if start == 0 && !hasArg0 {
+		start = 1
+		if end < start {
+			end = start
+		}
+	}
+
+	return append(values[:0], params[start:end]...), nil
+}
+
+// expandsToWord returns true if, whenever the word after the operator
+// is needed, the result of the whole expansion is that word
+func (p *paramExpansion) expandsToWord() bool {
+	if p.done || p.desc.flags != nil || p.name == "$@" || p.name == "$*" {
+		return false
+	}
+
+	switch p.desc.kind {
+	case paramExpandWithDefaultValue, paramExpandAlternativeValue:
+		return true
+	default:
+		return false
+	}
+}
+
+// needsWord returns true if we need the expansion of the word after
+// the operator, and we do not have it yet
+func (p *paramExpansion) needsWord() bool {
+	if p.done || len(p.desc.parts) < 2 {
+		return false
+	}
+	if p.desc.operand != nil && p.desc.operand.done {
+		return false
+	}
+
+	// the word is only used when the value is empty ...
+	needEmpty := true
+	switch p.desc.kind {
+	case paramExpandWithDefaultValue, paramExpandSetDefaultValue, paramExpandWriteError:
+	case paramExpandAlternativeValue:
+		// ... or, for ${var:+word}, when it is not
+		needEmpty = false
+	default:
+		return false
+	}
+
+	for _, value := range p.values {
+		if p.desc.isNull(value) == needEmpty {
+			return true
+		}
+	}
+
+	return false
+}
+
+// finish applies the parameter expansion to each of the values
+func (p *paramExpansion) finish(cb ExpansionCallbacks) (string, error) {
+	// did we run out of budget while we were looking up the parameter?
+	err := cb.budget.exceeded()
+	if err != nil {
+		return "", err
+	}
+
+	if p.done {
+		cb.stats.inc(statParamsExpanded)
+		return p.result, nil
+	}
+
+	// what we will (eventually) send back
+	var retval []string
+	if p.desc.scratch != nil {
+		retval = p.desc.scratch.results[:0]
+	}
+
+	retval, err = p.expandValues(cb, retval, false)
+	if err != nil {
+		return "", err
+	}
+
+	// zsh flags can change how the values are put back together
+	var result string
+	if p.desc.flags != nil {
+		result = p.desc.flags.apply(retval)
+	} else {
+		result = strings.Join(retval, " ")
+	}
+	cb.rememberDerivedSecret(p.name, p.desc, p.values, result)
+	traceParameter(cb, p.original, p.name, p.desc, p.values, result)
+
+	// hang onto anything that we had to grow, for next time
+	if p.desc.scratch != nil {
+		p.desc.scratch.results = retval
+	}
+
+	// if we get here, then yes, we are happy
+	cb.stats.inc(statParamsExpanded)
+	return result, nil
+}
+
+// finishFields is finish() for $@ and $*, when the caller wants to turn
+// each positional parameter into a separate word
+//
+// unlike finish(), it keeps any values that expand to empty strings
+func (p *paramExpansion) finishFields(cb ExpansionCallbacks) ([]string, error) {
+	// did we run out of budget while we were looking up the parameter?
+	err := cb.budget.exceeded()
+	if err != nil {
+		return nil, err
+	}
+
+	retval, err := p.expandValues(cb, make([]string, 0, len(p.values)), true)
+	if err != nil {
+		return nil, err
+	}
+	traceParameter(cb, p.original, p.name, p.desc, p.values, strings.Join(retval, " "))
+
+	// if we get here, then yes, we are happy
+	cb.stats.inc(statParamsExpanded)
+	return retval, nil
+}
+
+// expandValues applies our expansion to each of our values in turn,
+// and appends the results to `retval`
+//
+// set `keepEmpty` to true if you want results that are empty strings
+func (p *paramExpansion) expandValues(cb ExpansionCallbacks, retval []string, keepEmpty bool) ([]string, error) {
+	for _, paramValue := range p.values {
+		buf, _, err := p.expandFunc(p.name, paramValue, p.desc, cb)
+		if err != nil {
+			return retval, err
+		}
+
+		if len(buf) > 0 || keepEmpty {
 			retval = append(retval, buf)
 		}
 	}
 
+	return retval, nil
+}
+
+// finishNames is finish() for ${!prefix*} and ${!prefix@}, when the
+// caller wants to turn each matching variable name into a separate word
+func (p *paramExpansion) finishNames(cb ExpansionCallbacks) ([]string, error) {
+	retval := prefixNames(p.name, cb)
+
+	// did we run out of budget while we were looking up the names?
+	err := cb.budget.exceeded()
+	if err != nil {
+		return nil, err
+	}
+	traceParameter(cb, p.original, p.name, p.desc, p.values, strings.Join(retval, " "))
+
 	// if we get here, then yes, we are happy
-	return strings.Join(retval, " "), nil
+	cb.stats.inc(statParamsExpanded)
+	return retval, nil
+}
+
+// isAllPositionalParams returns true if we are expanding $@ or $*
+func (p *paramExpansion) isAllPositionalParams() bool {
+	return !p.done && p.desc.flags == nil && (p.name == "$@" || p.name == "$*")
+}
+
+// release hands our slices back to the pool, once we have finished
+// with this parameter expansion
+//
+// you must not use the paramExpansion after calling this
+func (p *paramExpansion) release() {
+	if p.desc.scratch == nil {
+		return
+	}
+
+	p.desc.scratch.parts = p.desc.parts
+	p.desc.scratch.values = p.values
+	putParamScratch(p.desc.scratch)
+	p.desc = paramDesc{}
+	p.values = nil
 }
 
 func expandParamName(paramDesc paramDesc, lookupVar LookupVar) (string, bool) {
@@ -243,56 +573,60 @@ func expandParamToValue(paramName, paramValue string, paramDesc paramDesc, cb Ex
 
 func expandParamWithDefaultValue(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
 	// do we need to return the default value?
-	if paramValue != "" {
+	if !paramDesc.isNull(paramValue) {
 		return paramValue, true, nil
 	}
 
-	retval, err := expandWord(paramDesc.parts[1], cb)
+	cb.stats.inc(statDefaultsUsed)
+	retval, err := paramDesc.expandWord(cb)
 	return retval, true, err
 }
 
 func expandParamSetDefaultValue(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
 	// do we need to do anything?
-	if paramValue != "" {
+	if !paramDesc.isNull(paramValue) {
 		return paramValue, true, nil
 	}
 
 	// at this point, we need to assign a new value
-	word, err := expandWord(paramDesc.parts[1], cb)
+	cb.stats.inc(statDefaultsUsed)
+	word, err := paramDesc.expandWord(cb)
 	if err != nil {
 		return "", false, err
 	}
-	err = cb.AssignToVar(paramName, word)
+	err = cb.assignToVar(paramName, word)
 	if err != nil {
 		return "", false, err
 	}
 
 	// all done
-	retval, success := cb.LookupVar(paramName)
+	retval, success := cb.lookupVar(paramName)
 	return retval, success, nil
 }
 
 func expandParamWriteError(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
 	// do we have a value?
-	if paramValue != "" {
+	if !paramDesc.isNull(paramValue) {
 		return paramValue, true, nil
 	}
 
-	word, err := expandWord(paramDesc.parts[1], cb)
+	word, err := paramDesc.expandWord(cb)
 	if err != nil {
 		return "", false, err
 	}
 
-	return paramName + ": " + word, true, nil
+	err = ErrVarRequired{paramName, word}
+	cb.writeError(err)
+	return "", false, err
 }
 
 func expandParamAlternativeValue(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
 	// do we need to return the alternative value?
-	if paramValue == "" {
+	if paramDesc.isNull(paramValue) {
 		return paramValue, true, nil
 	}
 
-	word, err := expandWord(paramDesc.parts[1], cb)
+	word, err := paramDesc.expandWord(cb)
 	if err != nil {
 		return "", false, err
 	}
@@ -301,62 +635,78 @@ func expandParamAlternativeValue(paramName, paramValue string, paramDesc paramDe
 }
 
 func expandParamSubstring(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	start, err := strconv.Atoi(paramDesc.parts[1])
+	offset, err := evalSubstringNumber(paramDesc.parts[1], cb)
 	if err != nil {
-		return paramValue, true, nil
+		return "", false, err
 	}
 
 	// range overflow?
-	if start > len(paramValue) {
+	start, end, ok := substringBounds(valueLength(paramValue, cb), offset, nil)
+	if !ok {
 		return "", true, nil
 	}
 
-	return paramValue[start:], true, nil
+	return substring(paramValue, start, end, cb), true, nil
 }
 
 func expandParamSubstringLength(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
 	// where do we start from?
-	start, err := strconv.Atoi(paramDesc.parts[1])
+	offset, err := evalSubstringNumber(paramDesc.parts[1], cb)
 	if err != nil {
-		return paramValue, true, nil
-	}
-	// range overflow?
-	if start > len(paramValue) {
-		return "", true, nil
+		return "", false, err
 	}
 
-	// and where do we end?
-	amount, err := strconv.Atoi(paramDesc.parts[2])
+	// and how much do we want?
+	amount, err := evalSubstringNumber(paramDesc.parts[2], cb)
 	if err != nil {
-		return "", false, nil
+		return "", false, err
+	}
+
+	// range overflow?
+	start, end, ok := substringBounds(valueLength(paramValue, cb), offset, &amount)
+	if !ok {
+		return "", true, nil
 	}
-	end := start + amount
 
-	// watch out for this range overflowing too!
-	if end > len(paramValue) {
-		end = len(paramValue)
+	// a negative length can end before we start
+	if end < start {
+		return "", false, ErrSubstringExpression{strings.TrimSpace(paramDesc.parts[2])}
 	}
 
-	return paramValue[start:end], true, nil
+	return substring(paramValue, start, end, cb), true, nil
 }
 
 func expandParamPrefixNames(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	varNames := cb.MatchVarNames(paramName)
+	return strings.Join(prefixNames(paramName, cb), " "), true, nil
+}
+
+// prefixNames returns the names of all the variables that start with
+// the given prefix, in sorted order
+func prefixNames(prefix string, cb ExpansionCallbacks) []string {
+	varNames := cb.matchVarNames(prefix)
 	sort.Strings(varNames)
-	return strings.Join(varNames, " "), true, nil
+	return varNames
 }
 
 func expandParamLength(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	return strconv.Itoa(len(paramValue)), true, nil
+	return strconv.Itoa(valueLength(paramValue, cb)), true, nil
 }
 
 func expandParamRemovePrefixShortestMatch(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	g := glob.NewGlob(paramDesc.parts[1])
+	g, err := cb.compileGlob(paramDesc.parts[1], globMatchShortestPrefix)
+	if err != nil {
+		return "", false, err
+	}
 
-	pos, success, err := g.MatchShortestPrefix(paramValue)
+	err = cb.spendGlobMatch()
 	if err != nil {
 		return "", false, err
 	}
+
+	pos, success, err := g.MatchShortestPrefix(paramValue)
+	if err != nil {
+		return "", false, ErrBadPattern{paramDesc.parts[1], err}
+	}
 	if success {
 		return paramValue[pos:], true, nil
 	}
@@ -365,12 +715,20 @@ func expandParamRemovePrefixShortestMatch(paramName, paramValue string, paramDes
 }
 
 func expandParamRemovePrefixLongestMatch(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	g := glob.NewGlob(paramDesc.parts[1])
+	g, err := cb.compileGlob(paramDesc.parts[1], globMatchLongestPrefix)
+	if err != nil {
+		return "", false, err
+	}
 
-	pos, success, err := g.MatchLongestPrefix(paramValue)
+	err = cb.spendGlobMatch()
 	if err != nil {
 		return "", false, err
 	}
+
+	pos, success, err := g.MatchLongestPrefix(paramValue)
+	if err != nil {
+		return "", false, ErrBadPattern{paramDesc.parts[1], err}
+	}
 	if success {
 		return paramValue[pos:], true, nil
 	}
@@ -379,12 +737,20 @@ func expandParamRemovePrefixLongestMatch(paramName, paramValue string, paramDesc
 }
 
 func expandParamRemoveSuffixShortestMatch(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	g := glob.NewGlob(paramDesc.parts[1])
+	g, err := cb.compileGlob(paramDesc.parts[1], globMatchShortestSuffix)
+	if err != nil {
+		return "", false, err
+	}
 
-	pos, success, err := g.MatchShortestSuffix(paramValue)
+	err = cb.spendGlobMatch()
 	if err != nil {
 		return "", false, err
 	}
+
+	pos, success, err := g.MatchShortestSuffix(paramValue)
+	if err != nil {
+		return "", false, ErrBadPattern{paramDesc.parts[1], err}
+	}
 	if success {
 		if pos < len(paramValue) {
 			return paramValue[:pos], true, nil
@@ -396,12 +762,20 @@ func expandParamRemoveSuffixShortestMatch(paramName, paramValue string, paramDes
 }
 
 func expandParamRemoveSuffixLongestMatch(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	g := glob.NewGlob(paramDesc.parts[1])
+	g, err := cb.compileGlob(paramDesc.parts[1], globMatchLongestSuffix)
+	if err != nil {
+		return "", false, err
+	}
 
-	pos, success, err := g.MatchLongestSuffix(paramValue)
+	err = cb.spendGlobMatch()
 	if err != nil {
 		return "", false, err
 	}
+
+	pos, success, err := g.MatchLongestSuffix(paramValue)
+	if err != nil {
+		return "", false, ErrBadPattern{paramDesc.parts[1], err}
+	}
 	if success {
 		// it is impossible for 'pos' to be out-of-bounds
 		return paramValue[:pos], true, nil
@@ -411,138 +785,148 @@ func expandParamRemoveSuffixLongestMatch(paramName, paramValue string, paramDesc
 }
 
 func expandParamUppercaseFirstChar(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	for pos, firstChar := range paramValue {
-		// empty pattern?
-		if len(paramDesc.parts[1]) == 0 {
-			return string(unicode.ToUpper(firstChar)) + paramValue[pos+1:], true, nil
-		}
+	return changeCaseOfFirstChar(paramValue, paramDesc, cb, unicode.ToUpper)
+}
 
-		g := glob.NewGlob(paramDesc.parts[1])
-		success, err := g.Match(string(firstChar))
-		if err != nil {
-			return "", false, err
-		}
-		if success {
-			return string(unicode.ToUpper(firstChar)) + paramValue[pos+1:], true, nil
-		}
+func expandParamUppercaseAllChars(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+	return changeCaseOfAllChars(paramValue, paramDesc, cb, unicode.ToUpper)
+}
 
-		return paramValue, true, nil
-	}
+func expandParamLowercaseFirstChar(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+	return changeCaseOfFirstChar(paramValue, paramDesc, cb, unicode.ToLower)
+}
+
+func expandParamLowercaseAllChars(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+	return changeCaseOfAllChars(paramValue, paramDesc, cb, unicode.ToLower)
+}
 
+// changeCaseOfFirstChar applies the given case mapping to the first
+// character of paramValue, if it matches the pattern (or if there is
+// no pattern)
+func changeCaseOfFirstChar(paramValue string, paramDesc paramDesc, cb ExpansionCallbacks, mapping func(rune) rune) (string, bool, error) {
 	// empty value
-	return "", true, nil
+	if len(paramValue) == 0 {
+		return "", true, nil
+	}
+
+	_, w := utf8.DecodeRuneInString(paramValue)
+	firstChar, err := changeCaseIfMatches(paramValue[:w], paramDesc, cb, nil, mapping)
+	if err != nil {
+		return "", false, err
+	}
+
+	return firstChar + paramValue[w:], true, nil
 }
 
-func expandParamUppercaseAllChars(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+// changeCaseOfAllChars applies the given case mapping to every character
+// of paramValue that matches the pattern (or to all of them, if there is
+// no pattern)
+func changeCaseOfAllChars(paramValue string, paramDesc paramDesc, cb ExpansionCallbacks, mapping func(rune) rune) (string, bool, error) {
 	// special case
-	if len(paramDesc.parts[1]) == 0 {
-		return strings.ToUpper(paramValue), true, nil
+	if len(paramDesc.parts[1]) == 0 && utf8.ValidString(paramValue) {
+		return strings.Map(mapping, paramValue), true, nil
 	}
 
 	// we have to do this the old-fashioned way
 	var buf strings.Builder
-	g := glob.NewGlob(paramDesc.parts[1])
+	buf.Grow(len(paramValue))
 
-	for _, c := range paramValue {
-		success, err := g.Match(string(c))
+	// we only want to compile the pattern once
+	var g *glob.Glob
+	var err error
+	if len(paramDesc.parts[1]) > 0 {
+		g, err = cb.compileGlob(paramDesc.parts[1], globMatchWhole)
 		if err != nil {
 			return "", false, err
 		}
-		if success {
-			buf.WriteRune(unicode.ToUpper(c))
-		} else {
-			buf.WriteRune(c)
-		}
 	}
 
-	// all done
-	return buf.String(), true, nil
-}
-
-func expandParamLowercaseFirstChar(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	for pos, firstChar := range paramValue {
-		// empty pattern?
-		if len(paramDesc.parts[1]) == 0 {
-			return string(unicode.ToLower(firstChar)) + paramValue[pos+1:], true, nil
-		}
-
-		g := glob.NewGlob(paramDesc.parts[1])
-		success, err := g.Match(string(firstChar))
+	for pos := 0; pos < len(paramValue); {
+		_, w := utf8.DecodeRuneInString(paramValue[pos:])
+		c, err := changeCaseIfMatches(paramValue[pos:pos+w], paramDesc, cb, g, mapping)
 		if err != nil {
 			return "", false, err
 		}
-		if success {
-			return string(unicode.ToLower(firstChar)) + paramValue[pos+1:], true, nil
-		}
-
-		return paramValue, true, nil
+		buf.WriteString(c)
+		pos += w
 	}
 
-	// empty value
-	return "", true, nil
+	// all done
+	return buf.String(), true, nil
 }
 
-func expandParamLowercaseAllChars(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
-	// special case
-	if len(paramDesc.parts[1]) == 0 {
-		return strings.ToLower(paramValue), true, nil
+// changeCaseIfMatches applies the given case mapping to a single
+// character, if it matches the pattern (or if there is no pattern)
+//
+// bytes that are not valid UTF-8 are never changed, just like in bash.
+// We pass the original bytes to the pattern, so that a `?` still
+// matches them.
+func changeCaseIfMatches(char string, paramDesc paramDesc, cb ExpansionCallbacks, g *glob.Glob, mapping func(rune) rune) (string, error) {
+	c, w := utf8.DecodeRuneInString(char)
+	if c == utf8.RuneError && w <= 1 {
+		return char, nil
 	}
 
-	// we have to do this the old-fashioned way
-	var buf strings.Builder
-	g := glob.NewGlob(paramDesc.parts[1])
+	// empty pattern?
+	if len(paramDesc.parts[1]) == 0 {
+		return string(mapping(c)), nil
+	}
 
-	for _, c := range paramValue {
-		success, err := g.Match(string(c))
+	var err error
+	if g == nil {
+		g, err = cb.compileGlob(paramDesc.parts[1], globMatchWhole)
 		if err != nil {
-			return "", false, err
-		}
-		if success {
-			buf.WriteRune(unicode.ToLower(c))
-		} else {
-			buf.WriteRune(c)
+			return "", err
 		}
 	}
+	err = cb.spendGlobMatch()
+	if err != nil {
+		return "", err
+	}
 
-	// all done
-	return buf.String(), true, nil
-}
+	success, err := g.Match(char)
+	if err != nil {
+		return "", ErrBadPattern{paramDesc.parts[1], err}
+	}
+	if success {
+		return string(mapping(c)), nil
+	}
 
-func expandParamValue(key string, lookupVar LookupVar) <-chan string {
-	// we'll send the results bit by bit via this channel
-	chn := make(chan string)
+	return char, nil
+}
 
+// expandParamValues appends the value(s) of the given parameter to
+// `values`, and returns the result
+//
+// $@ and $* have one value for each positional parameter. Everything
+// else has a single value.
+//
+// everything happens on the caller's goroutine, so that nothing is left
+// running if the caller gives up early
+func expandParamValues(key string, lookupVar LookupVar, values []string) []string {
 	// are we expanding the positional parameters?
-	if key == "$@" || key == "$*" {
-		go func() {
-			// how many positional parameters are there?
-			//
-			// we rely on $# being correctly set by the caller
-			rawMax, ok := lookupVar("$#")
-			if !ok {
-				chn <- ""
-			} else {
-				maxI, err := strconv.Atoi(rawMax)
-				if err != nil {
-					chn <- ""
-				} else {
-					for i := 1; i <= maxI; i++ {
-						retval, ok := lookupVar("$" + strconv.Itoa(i))
-						if ok {
-							chn <- retval
-						}
-					}
-				}
-			}
-			close(chn)
-		}()
-	} else {
-		go func() {
-			retval, _ := lookupVar(key)
-			chn <- retval
-			close(chn)
-		}()
+	if key != "$@" && key != "$*" {
+		retval, _ := lookupVar(key)
+		return append(values, retval)
+	}
+
+	// how many positional parameters are there?
+	//
+	// we rely on $# being correctly set by the caller
+	rawMax, ok := lookupVar("$#")
+	if !ok {
+		return append(values, "")
+	}
+	maxI, err := strconv.Atoi(rawMax)
+	if err != nil {
+		return append(values, "")
+	}
+	for i := 1; i <= maxI; i++ {
+		retval, ok := lookupVar("$" + strconv.Itoa(i))
+		if ok {
+			values = append(values, retval)
+		}
 	}
 
-	return chn
+	return values
 }
@@ -0,0 +1,104 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "context"
+
+// ExpandParamsOnly performs parameter expansion (${var}, $var and their
+// operators) on the input string, and nothing else
+//
+// There is no brace expansion, tilde expansion or quote removal. Use it
+// when you are building your own pipeline of expansions, and need to run
+// them in a different order to a UNIX shell.
+//
+// Backslashes are still removed (or not) according to the escape mode,
+// because they decide what is and is not a parameter. The words after
+// operators (such as ${var:-~/word}) are expanded as normal.
+func ExpandParamsOnly(input string, cb ExpansionCallbacks) (string, error) {
+	return ExpandParamsOnlyContext(cb.context(), input, cb)
+}
+
+// ExpandParamsOnlyContext performs parameter expansion on the input
+// string, just like ExpandParamsOnly() does. It uses the given context
+// in the same way that ExpandContext() does.
+func ExpandParamsOnlyContext(ctx context.Context, input string, cb ExpansionCallbacks) (string, error) {
+	cb.ctx = ctx
+
+	err := ctx.Err()
+	if err != nil {
+		return "", err
+	}
+
+	input, err = checkInput(input, cb)
+	if err != nil {
+		return "", err
+	}
+
+	// fast path: nothing to expand
+	if !hasExpansionChars(input) && !hasPercentVars(input, cb) && !hasSpecifiers(input, cb) {
+		return input, nil
+	}
+
+	expanded, err := expandParameters(input, cb)
+	if err != nil {
+		return "", locateExpansionError(err, input, input, 0)
+	}
+	tracePhase(cb, PhaseParameterExpansion, input, expanded)
+
+	// if nothing changed, give the caller back their own string
+	if expanded == input {
+		return input, nil
+	}
+
+	return expanded, nil
+}
+
+// ExpandParamsOnly performs parameter expansion on the input string,
+// just like the package-level ExpandParamsOnly() does
+func (e *Expander) ExpandParamsOnly(input string) (string, error) {
+	return e.ExpandParamsOnlyContext(context.Background(), input)
+}
+
+// ExpandParamsOnlyContext performs parameter expansion on the input
+// string, just like the package-level ExpandParamsOnlyContext() does
+func (e *Expander) ExpandParamsOnlyContext(ctx context.Context, input string) (string, error) {
+	ctx, done := e.withTimeout(ctx)
+	cb := e.callbacks()
+	retval, err := ExpandParamsOnlyContext(ctx, input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
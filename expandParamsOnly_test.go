@@ -0,0 +1,154 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandParamsOnlySkipsOtherPhases(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"HOST": "example.com",
+		"DIRS": "a b",
+	})
+	testData := `~/{x,y} "${HOST}" '$DIRS' ${UNSET:-fallback}`
+	expectedResult := `~/{x,y} "example.com" 'a b' fallback`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandParamsOnly(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandParamsOnlyReportsWhereErrorsAre(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(nil)
+	testData := "{a,b} ${REQUIRED:?is not set}"
+	expectedOffset := 6
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandParamsOnly(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	expErr, ok := err.(ExpansionError)
+	assert.True(t, ok)
+	assert.Equal(t, expectedOffset, expErr.Offset)
+	assert.Equal(t, PhaseParameterExpansion, expErr.Phase)
+}
+
+func TestExpandParamsOnlyReturnsInputWhenNothingToExpand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "nothing to see here"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandParamsOnly(testData, NewMapCallbacks(nil))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, testData, actualResult)
+}
+
+func TestExpandParamsOnlyContextStopsWhenCancelled(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := ExpandParamsOnlyContext(ctx, "$HOME", NewMapCallbacks(nil))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestExpanderExpandParamsOnlyUsesOptions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expander := NewExpander(NewMapCallbacks(map[string]string{"HOST": "example.com"}), WithKeepUnset())
+	testData := "~ {a,b} $HOST $PORT"
+	expectedResult := "~ {a,b} example.com $PORT"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expander.ExpandParamsOnly(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
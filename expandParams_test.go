@@ -70,7 +70,7 @@ func TestExpandParameterReturnsEmptyStringForUnsupportedParamOp(t *testing.T) {
 	assert.Equal(t, expectedResult, actualResult)
 }
 
-func TestExpandParamValueReturnsEmptyStringWhenDollarHashNotSet(t *testing.T) {
+func TestExpandParamValuesReturnsEmptyStringWhenDollarHashNotSet(t *testing.T) {
 	t.Parallel()
 
 	// ----------------------------------------------------------------
@@ -91,10 +91,7 @@ func TestExpandParamValueReturnsEmptyStringWhenDollarHashNotSet(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := []string{}
-	for r := range expandParamValue("$*", lookupVar) {
-		actualResult = append(actualResult, r)
-	}
+	actualResult := expandParamValues("$*", lookupVar, []string{})
 
 	// ----------------------------------------------------------------
 	// test the results
@@ -102,7 +99,7 @@ func TestExpandParamValueReturnsEmptyStringWhenDollarHashNotSet(t *testing.T) {
 	assert.Equal(t, expectedResult, actualResult)
 }
 
-func TestExpandParamValueReturnsEmptyStringWhenDollarHashHasEmptyValue(t *testing.T) {
+func TestExpandParamValuesReturnsEmptyStringWhenDollarHashHasEmptyValue(t *testing.T) {
 	t.Parallel()
 
 	// ----------------------------------------------------------------
@@ -123,10 +120,7 @@ func TestExpandParamValueReturnsEmptyStringWhenDollarHashHasEmptyValue(t *testin
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := []string{}
-	for r := range expandParamValue("$*", lookupVar) {
-		actualResult = append(actualResult, r)
-	}
+	actualResult := expandParamValues("$*", lookupVar, []string{})
 
 	// ----------------------------------------------------------------
 	// test the results
@@ -134,7 +128,7 @@ func TestExpandParamValueReturnsEmptyStringWhenDollarHashHasEmptyValue(t *testin
 	assert.Equal(t, expectedResult, actualResult)
 }
 
-func TestExpandParamValueReturnsEmptyStringWhenDollarHashNotNumericValue(t *testing.T) {
+func TestExpandParamValuesReturnsEmptyStringWhenDollarHashNotNumericValue(t *testing.T) {
 	t.Parallel()
 
 	// ----------------------------------------------------------------
@@ -155,10 +149,38 @@ func TestExpandParamValueReturnsEmptyStringWhenDollarHashNotNumericValue(t *test
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult := []string{}
-	for r := range expandParamValue("$*", lookupVar) {
-		actualResult = append(actualResult, r)
+	actualResult := expandParamValues("$*", lookupVar, []string{})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandParamValuesAppendsEachPositionalParam(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	lookupVar := func(key string) (string, bool) {
+		switch key {
+		case "$#":
+			return "3", true
+		case "$1":
+			return "one", true
+		case "$3":
+			return "three", true
+		default:
+			return "", false
+		}
 	}
+	expectedResult := []string{"before", "one", "three"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := expandParamValues("$@", lookupVar, []string{"before"})
 
 	// ----------------------------------------------------------------
 	// test the results
@@ -0,0 +1,273 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// streamChunkSize is how much of the input ExpandStream() tries to
+// expand at once
+const streamChunkSize = 64 * 1024
+
+// ExpandStream reads the input from src, expands it just like Expand()
+// does, and writes the results to dst.
+//
+// The input is expanded a piece at a time, so that you don't need to
+// hold all of a large file (and several copies of it) in memory at
+// once. We only ever split the input at a space that is not part of an
+// expansion, so you get exactly the same results that Expand() would give
+// you for the whole input.
+//
+// (If we cannot find a safe place to split the input - for example,
+// because there is a ${ that is never closed - we keep reading until we
+// can. In strict mode, we don't split the input inside a {...} either,
+// so that its braces are checked as a whole.)
+//
+// If the input cannot be expanded, the ExpansionError that you get back
+// tells you where the problem is in the whole input, not just the piece
+// that we were expanding. Anything before that piece has already been
+// written to dst.
+func ExpandStream(dst io.Writer, src io.Reader, cb ExpansionCallbacks) error {
+	return ExpandStreamContext(cb.context(), dst, src, cb)
+}
+
+// ExpandStreamContext expands the input from src, and writes the results
+// to dst, just like ExpandStream() does.
+//
+// The given context is used in the same way that ExpandContext() uses
+// it.
+func ExpandStreamContext(ctx context.Context, dst io.Writer, src io.Reader, cb ExpansionCallbacks) error {
+	return expandStream(ctx, dst, src, cb, streamChunkSize)
+}
+
+// ExpandStream reads the input from src, expands it, and writes the
+// results to dst, just like the package-level ExpandStream() does
+func (e *Expander) ExpandStream(dst io.Writer, src io.Reader) error {
+	ctx, done := e.withTimeout(context.Background())
+	cb := e.callbacks()
+	err := ExpandStreamContext(ctx, dst, src, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return err
+}
+
+func expandStream(ctx context.Context, dst io.Writer, src io.Reader, cb ExpansionCallbacks, chunkSize int) error {
+	// where the next chunk starts in the whole input
+	pos := streamPosition{line: 1, column: 1}
+
+	// the input that we have read, but not expanded yet
+	var pending []byte
+	readBuf := make([]byte, chunkSize)
+	eof := false
+
+	for {
+		// do we need more input?
+		if !eof && len(pending) < chunkSize {
+			n, err := src.Read(readBuf)
+			pending = append(pending, readBuf[:n]...)
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// how much can we safely expand?
+		input := string(pending)
+		end := len(input)
+		if !eof {
+			end = findChunkEnd(input, cb.checksBraces())
+			if end == 0 {
+				// we need to read more to find somewhere safe
+				chunkSize *= 2
+				continue
+			}
+		}
+
+		chunk := input[:end]
+		expanded, err := ExpandContext(ctx, chunk, cb)
+		if err != nil {
+			return pos.locate(err)
+		}
+		_, err = io.WriteString(dst, expanded)
+		if err != nil {
+			return err
+		}
+		pos.advance(chunk)
+
+		pending = append(pending[:0], input[end:]...)
+		if eof && len(pending) == 0 {
+			return nil
+		}
+	}
+}
+
+// findChunkEnd works out how much of the input can be expanded on its
+// own, without changing the results
+//
+// it returns the position just after the last space, tab or newline that
+// is not part of an expansion, or 0 if there isn't one
+//
+// if checkBraces is set, it also skips any space that is inside a {...},
+// because checkBraces() needs to see both braces in the same chunk
+func findChunkEnd(input string, checkBraces bool) int {
+	retval := 0
+
+	// how many '{' are still waiting for their '}'
+	depth := 0
+
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			// whatever comes next is escaped
+			i++
+
+		case '{':
+			depth++
+
+		case '}':
+			depth--
+
+		case '$':
+			// arithmetic expansions and command substitutions can have
+			// spaces in them too
+			spanEnd, ok := findSubstitution(input[i:])
+			if ok {
+				depth += countBraces(input[i : i+spanEnd])
+				i += spanEnd - 1
+				continue
+			}
+
+			// a $( or $[ that is never closed may be closed by input
+			// that we have not read yet
+			if strings.HasPrefix(input[i:], "$(") || strings.HasPrefix(input[i:], "$[") {
+				return retval
+			}
+
+			// variables can have spaces in them
+			varEnd, err := findVar(input[i:])
+			if err == nil {
+				depth += countBraces(input[i : i+varEnd])
+				i += varEnd - 1
+				continue
+			}
+
+			// a ${ that is never closed may be closed by input that
+			// we have not read yet
+			_, unterminated := err.(ErrMismatchedBrace)
+			if unterminated {
+				return retval
+			}
+
+		case ' ', '\t', '\n':
+			// a space ends the current word, so nothing after it can
+			// change how the input before it is expanded
+			if !checkBraces || depth <= 0 {
+				retval = i + 1
+			}
+		}
+	}
+
+	return retval
+}
+
+// countBraces returns how many more '{' than '}' there are in the input,
+// counting them the same way that matchBraces() does
+func countBraces(input string) int {
+	retval := 0
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '{':
+			retval++
+		case '}':
+			retval--
+		}
+	}
+
+	return retval
+}
+
+// streamPosition tracks where we are in the whole input
+type streamPosition struct {
+	offset int
+	line   int
+	column int
+}
+
+// advance moves us past the given chunk of input
+func (p *streamPosition) advance(chunk string) {
+	p.offset += len(chunk)
+
+	lastNewline := strings.LastIndexByte(chunk, '\n')
+	if lastNewline < 0 {
+		p.column += utf8.RuneCountInString(chunk)
+		return
+	}
+
+	p.line += strings.Count(chunk, "\n")
+	p.column = utf8.RuneCountInString(chunk[lastNewline+1:]) + 1
+}
+
+// locate turns the position of an ExpansionError in the current chunk
+// into its position in the whole input
+func (p *streamPosition) locate(err error) error {
+	e, ok := err.(ExpansionError)
+	if !ok {
+		return err
+	}
+
+	e.Offset += p.offset
+	if e.Line == 1 {
+		e.Column += p.column - 1
+	}
+	e.Line += p.line - 1
+
+	// an unterminated ${ reports its own position, which must match ours
+	braceErr, ok := e.Err.(ErrMismatchedBrace)
+	if ok {
+		braceErr.Index = e.Offset + 1
+		e.Err = braceErr
+	}
+
+	return e
+}
@@ -0,0 +1,295 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// streamTestVars are the variables that the stream tests expand
+func streamTestVars() map[string]string {
+	return map[string]string{
+		"PARAM1": "foo",
+		"PARAM2": "bar baz",
+		"$#":     "2",
+		"$1":     "one",
+		"$2":     "two",
+	}
+}
+
+// streamTestInputs are expanded both in one go, and in chunks
+var streamTestInputs = []string{
+	"",
+	"nothing to expand here at all",
+	"${PARAM1} ${PARAM2:-a default value} $PARAM1 ${UNSET:-x y z}",
+	"~/foo ~fred/bar a~b ~",
+	"a{b,c}d e{f, g}h {1..3} x{a,b}${PARAM1}",
+	"escaped \\${PARAM1} \\  spaces\\ here",
+	"${PARAM1:=assigned} ${PARAM3:=new value} $PARAM3",
+	"$* ${#PARAM2} ${PARAM2^^} ${PARAM2// /_}",
+	"multi\nline ${PARAM1}\n~/foo\n{a,b}\n",
+	"a{b,c}\nd\n\n~\n\tx{1..2}\t~fred/y\r\n\n",
+	"{ \"json\": \"${PARAM1}\", \"list\": [ 1, 2 ] }",
+	"trailing $",
+	"unbalanced { brace ${PARAM1} and more",
+	"$(( 1 + 2 )) $(( (1 + 2) * 3 )) and $((PARAM1 + 1))",
+}
+
+func TestExpandStreamGivesSameResultsAsExpand(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range streamTestInputs {
+		expectedResult, expectedErr := Expand(input, newTestCallbacks(streamTestVars()))
+		assert.Nil(t, expectedErr, input)
+
+		for chunkSize := 1; chunkSize < 24; chunkSize++ {
+			// ----------------------------------------------------------------
+			// setup your test
+
+			var dst bytes.Buffer
+			src := iotest.OneByteReader(strings.NewReader(input))
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			err := expandStream(context.Background(), &dst, src, newTestCallbacks(streamTestVars()), chunkSize)
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, err, input)
+			assert.Equal(t, expectedResult, dst.String(), "chunk size %d: %q", chunkSize, input)
+		}
+	}
+}
+
+func TestExpandStreamExpandsTheWholeInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := strings.Repeat("${PARAM1} ~/${PARAM2} a{b,c} ", 10000)
+	expectedResult := strings.Repeat("foo ~/bar baz ab ac ", 10000)
+	cb := newTestCallbacks(streamTestVars())
+	var dst bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := ExpandStream(&dst, strings.NewReader(input), cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, dst.String())
+}
+
+func TestExpandStreamExpandsSpansAcrossChunkBoundaries(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := map[string]string{
+		"$(( 1 + 2 ))": "3",
+		"$[ 1 + 2 ]":   "3",
+		"$(echo a b)":  "a b",
+	}
+
+	for span, expansion := range testDataSet {
+		for padding := streamChunkSize - len(span) - 1; padding <= streamChunkSize; padding++ {
+			// ----------------------------------------------------------------
+			// setup your test
+
+			cb := newTestCallbacks(streamTestVars())
+			cb.RunCommand = func(ctx context.Context, args []string) (string, error) {
+				return strings.Join(args[1:], " "), nil
+			}
+			unit := NewExpander(cb, WithLegacyArithmetic())
+
+			input := strings.Repeat(" ", padding) + span + " after"
+			expectedResult := strings.Repeat(" ", padding) + expansion + " after"
+			var dst bytes.Buffer
+
+			// ----------------------------------------------------------------
+			// perform the change
+
+			err := unit.ExpandStream(&dst, strings.NewReader(input))
+
+			// ----------------------------------------------------------------
+			// test the results
+
+			assert.Nil(t, err, span)
+			assert.Equal(t, expectedResult, dst.String(), "padding %d: %q", padding, span)
+		}
+	}
+}
+
+func TestExpandStreamDoesNotSplitBracesInStrictMode(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// a JSON array that is bigger than a chunk; without care, one chunk
+	// ends in the middle of a { ... } and strict mode rejects it
+	var input strings.Builder
+	input.WriteString("[\n")
+	for i := 0; i < 4000; i++ {
+		fmt.Fprintf(&input, "  { \"id\": %d, \"home\": \"${PARAM1}\" },\n", i)
+	}
+	input.WriteString("]\n")
+
+	unit := NewExpander(newTestCallbacks(streamTestVars()), WithStrict())
+	expectedResult, err := unit.Expand(input.String())
+	assert.Nil(t, err)
+	var dst bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err = unit.ExpandStream(&dst, strings.NewReader(input.String()))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, dst.String())
+}
+
+func TestExpandStreamStillReportsUnmatchedBracesInStrictMode(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := strings.Repeat("{ a b } ", streamChunkSize/4) + "{ never closed"
+	unit := NewExpander(newTestCallbacks(streamTestVars()), WithStrict())
+	var dst bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := unit.ExpandStream(&dst, strings.NewReader(input))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrMismatchedBrace{}), "%v", err)
+	expErr, ok := err.(ExpansionError)
+	assert.True(t, ok, "%v", err)
+	assert.Equal(t, len(input)-len("{ never closed"), expErr.Offset)
+}
+
+func TestExpandStreamReportsErrorPositionInWholeInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "line one has ${PARAM1}\nline two ${PARAM1##abc[} too"
+	var dst bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := expandStream(context.Background(), &dst, strings.NewReader(input), newTestCallbacks(streamTestVars()), 4)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	expErr, ok := err.(ExpansionError)
+	assert.True(t, ok)
+	assert.Equal(t, strings.Index(input, "${PARAM1##"), expErr.Offset)
+	assert.Equal(t, 2, expErr.Line)
+	assert.Equal(t, 10, expErr.Column)
+	assert.Equal(t, "${PARAM1##abc[}", expErr.Substring)
+}
+
+func TestExpandStreamReportsUnterminatedBracePositionInWholeInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "a b c d e f ${PARAM1"
+	unit := NewExpander(newTestCallbacks(streamTestVars()), WithStrict())
+	var dst bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := unit.ExpandStream(&dst, strings.NewReader(input))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	expErr, ok := err.(ExpansionError)
+	assert.True(t, ok)
+	assert.Equal(t, 12, expErr.Offset)
+	braceErr, ok := expErr.Err.(ErrMismatchedBrace)
+	assert.True(t, ok)
+	assert.Equal(t, 13, braceErr.Index)
+}
+
+func TestExpandStreamReturnsReadErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	src := iotest.TimeoutReader(strings.NewReader("${PARAM1} "))
+	var dst bytes.Buffer
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := expandStream(context.Background(), &dst, src, newTestCallbacks(streamTestVars()), 2)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, iotest.ErrTimeout, err)
+}
@@ -35,10 +35,7 @@
 
 package shellexpand
 
-import (
-	"strings"
-	"unicode/utf8"
-)
+import "unicode/utf8"
 
 // ExpandTilde will expand any '~' at the start of a word as follows:
 //
@@ -56,38 +53,26 @@ import (
 // This function is exported because (for UNIX shell compatibility), you
 // should call this function when setting variables.
 func ExpandTilde(input string, cb ExpansionCallbacks) string {
-	w := 0
-	inEscape := false
-	for i := 0; i < len(input); i += w {
-		var c rune
-		c, w = utf8.DecodeRuneInString(input[i:])
-		if inEscape {
-			// skip over escaped character
-			inEscape = false
-		} else if c == '\\' && !inEscape {
-			// skip over escaped characters
-			inEscape = true
-		} else if c == '$' {
-			varEnd, ok := matchVar(input[i:])
-			if ok {
-				i += varEnd - 1
-				w = 0
-			}
-		} else if c == '~' {
-			input, _ = matchAndExpandTilde(input[i:], cb)
-		}
-	}
-
-	return input
+	// without parameter expansion, there is nothing that can go wrong
+	retval, _ := expandSpans(input, cb, scanTilde)
+	return retval
 }
 
-func matchAndExpandTilde(input string, cb ExpansionCallbacks) (string, bool) {
+// expandTildePrefix works out what the tilde prefix at the start of the
+// input string expands to
+//
+// returns:
+//
+// - the expanded prefix
+// - the position of the end of the tilde prefix
+// - `true` on success
+func expandTildePrefix(input string, cb ExpansionCallbacks) (string, int, bool) {
 	var ok bool
 
 	// are we looking at a tilde w/ optional prefix??
-	prefixEnd, ok := matchTildePrefix(input)
+	prefixEnd, ok := matchTildePrefix(input, cb.windows())
 	if !ok {
-		return input, false
+		return "", 0, false
 	}
 
 	// what kind of prefix are we looking at?
@@ -99,37 +84,63 @@ func matchAndExpandTilde(input string, cb ExpansionCallbacks) (string, bool) {
 	// build the replacement
 	switch tildePrefix.kind {
 	case tildePrefixHome:
-		repl, ok = cb.LookupVar("HOME")
-		if !ok {
-			return input, false
+		if cb.windows() {
+			repl, ok = lookupWindowsHome(cb)
+		} else {
+			repl, ok = cb.lookupVar("HOME")
 		}
 	case tildePrefixPwd:
-		repl, ok = cb.LookupVar("PWD")
-		if !ok {
-			return input, false
-		}
+		repl, ok = cb.lookupVar("PWD")
 	case tildePrefixOldPwd:
-		repl, ok = cb.LookupVar("OLDPWD")
-		if !ok {
-			return input, false
-		}
+		repl, ok = cb.lookupVar("OLDPWD")
 	case tildePrefixUsername:
-		repl, ok = cb.LookupHomeDir(tildePrefix.prefix)
-		if !ok {
-			return input, false
-		}
+		repl, ok = cb.lookupHomeDir(tildePrefix.prefix)
+	}
+	if !ok {
+		return "", 0, false
 	}
 
-	var buf strings.Builder
-	buf.WriteString(repl)
-	if prefixEnd < len(input) {
-		buf.WriteString(input[prefixEnd:])
+	// on Windows, the backslash after the prefix is a path separator,
+	// not an escape
+	if cb.windows() && isWindowsTildeSeparator(input, prefixEnd) {
+		return repl + `\`, prefixEnd + 1, true
 	}
 
-	return buf.String(), true
+	return repl, prefixEnd, true
 }
 
-func matchTildePrefix(input string) (int, bool) {
+// isWindowsTildeSeparator returns true if there is a backslash at
+// input[prefixEnd], that separates a tilde prefix from the rest of a
+// Windows path
+func isWindowsTildeSeparator(input string, prefixEnd int) bool {
+	return prefixEnd < len(input) && input[prefixEnd] == '\\'
+}
+
+// lookupWindowsHome finds the user's home directory, using the
+// environment variables that Windows sets
+func lookupWindowsHome(cb ExpansionCallbacks) (string, bool) {
+	retval, ok := cb.lookupVar("USERPROFILE")
+	if ok && retval != "" {
+		return retval, true
+	}
+
+	drive, ok := cb.lookupVar("HOMEDRIVE")
+	if ok {
+		path, ok := cb.lookupVar("HOMEPATH")
+		if ok && path != "" {
+			return drive + path, true
+		}
+	}
+
+	// e.g. MSYS2 and Cygwin set this
+	return cb.lookupVar("HOME")
+}
+
+// matchTildePrefix finds the end of the tilde prefix at the start of
+// the input
+//
+// set `windows` to true if a backslash is a path separator
+func matchTildePrefix(input string, windows bool) (int, bool) {
 	// are we looking at the start of a prefix?
 	if input[0] != '~' {
 		return 0, false
@@ -144,10 +155,12 @@ func matchTildePrefix(input string) (int, bool) {
 		if inEscape {
 			// skip over escaped character
 			inEscape = false
+		} else if c == '\\' && windows {
+			return i, true
 		} else if c == '\\' && !inEscape {
 			// skip over escaped character
 			inEscape = true
-		} else if c == '/' || c == ' ' {
+		} else if c == '/' || isBlankChar(c) {
 			return i, true
 		}
 	}
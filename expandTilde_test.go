@@ -168,6 +168,70 @@ func TestExpandTildeUserHomedir(t *testing.T) {
 	assert.Equal(t, expectedResult, actualResult)
 }
 
+func TestExpandTildeAtStartOfEachLine(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			if key == "HOME" {
+				return "/home/stuart", true
+			}
+
+			return "invalid key", true
+		},
+		LookupHomeDir: func(key string) (string, bool) {
+			return "/home/" + key, true
+		},
+	}
+	testData := "~/bin\n~fred\n\t~/lib"
+	expectedResult := "/home/stuart/bin\n/home/fred\n\t/home/stuart/lib"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := ExpandTilde(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandTildePrefixStopsAtEndOfLine(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			if key == "HOME" {
+				return "/home/stuart", true
+			}
+
+			return "invalid key", true
+		},
+		LookupHomeDir: func(key string) (string, bool) {
+			return "/home/" + key, true
+		},
+	}
+	testData := "~\nx ~fred\n"
+	expectedResult := "/home/stuart\nx /home/fred\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := ExpandTilde(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
 func TestExpandTildeDoesNotModifyStringsWithoutTildePrefixes(t *testing.T) {
 	t.Parallel()
 
@@ -364,6 +428,38 @@ func TestExpandTildeIgnoresWhenUsernameNotKnown(t *testing.T) {
 	assert.Equal(t, expectedResult, actualResult)
 }
 
+func TestExpandTildeKeepsTextBeforeTheTilde(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			if key == "HOME" {
+				return "/home/stuart", true
+			}
+
+			return "invalid key", true
+		},
+		LookupHomeDir: func(key string) (string, bool) {
+			return "should not be called", true
+		},
+	}
+	testData := "cd ~/path and~ ~"
+	expectedResult := "cd /home/stuart/path and~ /home/stuart"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := ExpandTilde(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
 func TestParseTildePrefixWithHomedir(t *testing.T) {
 	t.Parallel()
 
@@ -481,7 +577,7 @@ func TestMatchTildePrefixWithHomedir(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult, ok := matchTildePrefix(testData)
+	actualResult, ok := matchTildePrefix(testData, false)
 
 	// ----------------------------------------------------------------
 	// test the results
@@ -502,7 +598,7 @@ func TestMatchTildePrefixWithPwd(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult, ok := matchTildePrefix(testData)
+	actualResult, ok := matchTildePrefix(testData, false)
 
 	// ----------------------------------------------------------------
 	// test the results
@@ -523,7 +619,7 @@ func TestMatchTildePrefixWithOldPwd(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult, ok := matchTildePrefix(testData)
+	actualResult, ok := matchTildePrefix(testData, false)
 
 	// ----------------------------------------------------------------
 	// test the results
@@ -544,7 +640,7 @@ func TestMatchTildePrefixWithUsername(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult, ok := matchTildePrefix(testData)
+	actualResult, ok := matchTildePrefix(testData, false)
 
 	// ----------------------------------------------------------------
 	// test the results
@@ -565,7 +661,7 @@ func TestMatchTildePrefixWithoutTilde(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult, ok := matchTildePrefix(testData)
+	actualResult, ok := matchTildePrefix(testData, false)
 
 	// ----------------------------------------------------------------
 	// test the results
@@ -586,7 +682,7 @@ func TestMatchTildePrefixIgnoresEscapedSlashes(t *testing.T) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult, ok := matchTildePrefix(testData)
+	actualResult, ok := matchTildePrefix(testData, false)
 
 	// ----------------------------------------------------------------
 	// test the results
@@ -595,7 +691,7 @@ func TestMatchTildePrefixIgnoresEscapedSlashes(t *testing.T) {
 	assert.Equal(t, expectedResult, actualResult)
 }
 
-func TestMatchAndExpandTildeIgnoresNonPrefix(t *testing.T) {
+func TestExpandTildePrefixIgnoresNonPrefix(t *testing.T) {
 	t.Parallel()
 
 	// ----------------------------------------------------------------
@@ -610,16 +706,14 @@ func TestMatchAndExpandTildeIgnoresNonPrefix(t *testing.T) {
 		},
 	}
 	testData := "/path"
-	expectedResult := testData
 
 	// ----------------------------------------------------------------
 	// perform the change
 
-	actualResult, ok := matchAndExpandTilde(testData, cb)
+	_, _, ok := expandTildePrefix(testData, cb)
 
 	// ----------------------------------------------------------------
 	// test the results
 
 	assert.False(t, ok)
-	assert.Equal(t, expectedResult, actualResult)
 }
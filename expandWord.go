@@ -43,17 +43,15 @@ package shellexpand
 // * no support for command expansion
 func expandWord(input string, cb ExpansionCallbacks) (string, error) {
 	// step 1: tilde expansion
-	input = ExpandTilde(input, cb)
-
 	// step 2: parameter expansion
-	var err error
-	input, err = expandParameters(input, cb)
+	// step 3: arithmetic expansion
+	//
+	// these all happen in a single pass
+	input, err := expandSpans(input, cb, scanTilde|scanParams|scanOperatorWord)
 	if err != nil {
 		return "", err
 	}
 
-	// step 3: arithmetic expansion
-
 	// all done
 	return input, nil
 }
@@ -36,14 +36,13 @@
 package shellexpand
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -75,6 +74,15 @@ func TestExpandBraceExpansion(t *testing.T) {
 	testExpandTestCase(t, testData)
 }
 
+func TestExpandBraceSequenceWithLeadingZeros(t *testing.T) {
+	// a leading zero pads every number in the sequence
+	testData := expandTestData{
+		input:          "web{01..03}.example.com {8..010} {0..2}",
+		expectedResult: "web01.example.com web02.example.com web03.example.com 008 009 010 0 1 2",
+	}
+	testExpandTestCase(t, testData)
+}
+
 func TestExpandUnterminatedBraceExpansion(t *testing.T) {
 	// simple string, w/ mismatched braces
 	testData := expandTestData{
@@ -742,7 +750,7 @@ func TestExpandParamErrorWritten(t *testing.T) {
 			"foo": "",
 		},
 		input:                "${foo:?not set}",
-		expectedResult:       "foo: not set",
+		expectedError:        "foo: not set",
 		resultSubstringMatch: true,
 	}
 	testExpandTestCase(t, testData)
@@ -756,7 +764,7 @@ func TestExpandParamErrorWrittenWithWordExpansion(t *testing.T) {
 			"bar": "not set",
 		},
 		input:                "${foo:?${bar}}",
-		expectedResult:       "foo: not set",
+		expectedError:        "foo: not set",
 		resultSubstringMatch: true,
 	}
 	testExpandTestCase(t, testData)
@@ -776,6 +784,19 @@ func TestExpandParamErrorWrittenWithErroredWordExpansion(t *testing.T) {
 	testExpandTestCase(t, testData)
 }
 
+func TestExpandParamErrorWrittenWithEmptyWord(t *testing.T) {
+	// simple param, error written
+	testData := expandTestData{
+		vars: map[string]string{
+			"foo": "",
+		},
+		input:                "${foo:?}",
+		expectedError:        "foo: parameter null or not set",
+		resultSubstringMatch: true,
+	}
+	testExpandTestCase(t, testData)
+}
+
 func TestExpandParamErrorNotWritten(t *testing.T) {
 	// simple param, error written
 	testData := expandTestData{
@@ -825,6 +846,106 @@ func TestExpandParamToAlternativeValueWithErroredWordExpansion(t *testing.T) {
 	testExpandTestCase(t, testData)
 }
 
+func TestExpandEmptyParamNotToDefaultValueWithoutColon(t *testing.T) {
+	// ${var-word} only uses the default value when var is unset
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "",
+		},
+		input:          "[${PARAM1-foo}]",
+		expectedResult: "[]",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandUnsetParamToDefaultValueWithoutColon(t *testing.T) {
+	// ${var-word} uses the default value when var is unset
+	testData := expandTestData{
+		input:          "${PARAM1-foo}",
+		expectedResult: "foo",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandEmptyParamNotSetToDefaultValueWithoutColon(t *testing.T) {
+	// ${var=word} only assigns the default value when var is unset
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "",
+		},
+		input: "${PARAM1=foo}",
+		shellExtra: []string{
+			"dummy=${PARAM1=foo}",
+			"echo \"[$PARAM1]\"",
+		},
+		expectedResult: "[]",
+		actualResult: func(testData expandTestData) string {
+			return "[" + testData.vars["PARAM1"] + "]"
+		},
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandUnsetParamSetToDefaultValueWithoutColon(t *testing.T) {
+	// ${var=word} assigns the default value when var is unset
+	testData := expandTestData{
+		input: "${PARAM1=foo}",
+		shellExtra: []string{
+			"dummy=${PARAM1=foo}",
+			"echo $PARAM1",
+		},
+		expectedResult: "foo",
+		actualResult: func(testData expandTestData) string {
+			return testData.vars["PARAM1"]
+		},
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandEmptyParamErrorNotWrittenWithoutColon(t *testing.T) {
+	// ${var?word} only writes an error when var is unset
+	testData := expandTestData{
+		vars: map[string]string{
+			"foo": "",
+		},
+		input:          "[${foo?not set}]",
+		expectedResult: "[]",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandUnsetParamErrorWrittenWithoutColon(t *testing.T) {
+	// ${var?word} writes an error when var is unset
+	testData := expandTestData{
+		input:                "${foo?not set}",
+		expectedError:        "foo: not set",
+		resultSubstringMatch: true,
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandEmptyParamToAlternativeValueWithoutColon(t *testing.T) {
+	// ${var+word} uses the alternative value when var is set, even if
+	// it is empty
+	testData := expandTestData{
+		vars: map[string]string{
+			"foo": "",
+		},
+		input:          "${foo+alternative}",
+		expectedResult: "alternative",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandUnsetParamNotToAlternativeValueWithoutColon(t *testing.T) {
+	// ${var+word} does not use the alternative value when var is unset
+	testData := expandTestData{
+		input:          "[${foo+alternative}]",
+		expectedResult: "[]",
+	}
+	testExpandTestCase(t, testData)
+}
+
 func TestExpandParamSubstring(t *testing.T) {
 	// simple param, expand substring to end of value
 	testData := expandTestData{
@@ -959,6 +1080,43 @@ func TestExpandParamSubstringLengthTooLength(t *testing.T) {
 	testExpandTestCase(t, testData)
 }
 
+func TestExpandParamSubstringNegativeOffset(t *testing.T) {
+	// simple param, expand substring counting back from the end
+	testData := expandTestData{
+		vars: map[string]string{
+			"foo": "1234567890",
+		},
+		input:          "${foo: -3} ${foo:(-5):2}",
+		expectedResult: "890 67",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSubstringNegativeLength(t *testing.T) {
+	// simple param, expand substring that stops before the end
+	testData := expandTestData{
+		vars: map[string]string{
+			"foo": "1234567890",
+		},
+		input:          "${foo:2: -3}",
+		expectedResult: "34567",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSubstringNegativeLengthBeforeOffset(t *testing.T) {
+	// simple param, negative length that ends before the offset
+	testData := expandTestData{
+		vars: map[string]string{
+			"foo": "1234567890",
+		},
+		input:          "${foo:5: -8}",
+		expectedResult: "",
+		expectedError:  "-8: substring expression < 0",
+	}
+	testExpandTestCase(t, testData)
+}
+
 func TestExpandParamNamesByPrefixStar(t *testing.T) {
 	// expand param names by prefix with * suffix
 	testData := expandTestData{
@@ -1091,6 +1249,21 @@ func TestExpandPositionalParamsRemoveShortestPrefix(t *testing.T) {
 	testExpandTestCase(t, testData)
 }
 
+func TestExpandPositionalParamsSubstring(t *testing.T) {
+	// substrings of $@ and $* pick out some of the positional params
+	testData := expandTestData{
+		positionalVars: map[string]string{
+			"$1": "foo",
+			"$2": "bar",
+			"$3": "alfred",
+			"$#": "3",
+		},
+		input:          "${@:2:2},${*:1:1},${@:5}",
+		expectedResult: "bar alfred,foo,",
+	}
+	testExpandTestCase(t, testData)
+}
+
 func TestExpandParamRemoveLongestPrefix(t *testing.T) {
 	// remove prefix longest match
 	testData := expandTestData{
@@ -1325,6 +1498,27 @@ func TestExpandParamUppercaseAllCharsNoPattern(t *testing.T) {
 	testExpandTestCase(t, testData)
 }
 
+func TestExpandParamCaseTransforms(t *testing.T) {
+	// ${var@U}, ${var@u} and ${var@L}, including on the positional
+	// params
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "hello wORLD",
+			"EMPTY":  "",
+		},
+		specialVars: map[string]string{
+			"$#": "2",
+		},
+		positionalVars: map[string]string{
+			"$1": "ab cd",
+			"$2": "efg",
+		},
+		input:          "[${PARAM1@U}] [${PARAM1@u}] [${PARAM1@L}] [${EMPTY@U}] [${UNSET@L}] [${1@u}] [${@@U}] [${*@u}]",
+		expectedResult: "[HELLO WORLD] [Hello wORLD] [hello world] [] [] [Ab cd] [AB CD EFG] [Ab cd Efg]",
+	}
+	testExpandTestCase(t, testData)
+}
+
 func TestExpandParamUppercaseAllCharsMatchesPattern(t *testing.T) {
 	// uppercase all chars, replacement pattern matches
 	testData := expandTestData{
@@ -1505,50 +1699,412 @@ func TestExpandParamLowercaseAllCharsInvalidPattern(t *testing.T) {
 	testExpandTestCase(t, testData)
 }
 
-func testExpandTestCase(t *testing.T, testData expandTestData) {
+func TestCaseModificationOperatorsSupportUnicode(t *testing.T) {
+	t.Parallel()
+
+	// these are what bash 5.2 gives you in the C.UTF-8 locale
+	//
+	// we don't run them through bash here, because what bash does
+	// depends on the locale that the tests happen to run in
+	testDataSet := []struct {
+		value          string
+		input          string
+		expectedResult string
+	}{
+		{"école", "${PARAM1^}", "École"},
+		{"école", "${PARAM1^^}", "ÉCOLE"},
+		{"ÉCOLE", "${PARAM1,}", "éCOLE"},
+		{"ÉCOLE", "${PARAM1,,}", "école"},
+		{"école", "${PARAM1^^?}", "ÉCOLE"},
+		{"école", "${PARAM1^[é]}", "École"},
+		{"ÉCOLE", "${PARAM1,,[ÉC]}", "écOLE"},
+		{"éΣab", "${PARAM1^^[[:lower:]]}", "ÉΣAB"},
+		{"éΣab", "${PARAM1,,[[:upper:]]}", "éσab"},
+		// the first character is the e, not the e with its combining accent
+		{"e\u0301cole", "${PARAM1^}", "E\u0301cole"},
+		{"e\u0301cole", "${PARAM1^^[e]}", "E\u0301colE"},
+		// some characters do not have a single-character upper case
+		{"straße", "${PARAM1^^}", "STRAßE"},
+		{"ǆa", "${PARAM1^}", "Ǆa"},
+		// bytes that are not valid UTF-8 are left alone
+		{"\xffab\xc3", "${PARAM1^}", "\xffab\xc3"},
+		{"\xffab\xc3", "${PARAM1^^}", "\xffAB\xc3"},
+		{"\xffAB\xc3", "${PARAM1,,?}", "\xffab\xc3"},
+	}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		cb := ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				if key == "PARAM1" {
+					return testData.value, true
+				}
+				return "", false
+			},
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Expand(testData.input, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, testData.expectedResult, actualResult, testData)
+	}
+}
+
+func TestExpandParamSearchReplaceFirstMatch(t *testing.T) {
+	// replace the first, longest match
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "docdoc",
+		},
+		input:          "${PARAM1/o*c/x} ${PARAM1/c/x}",
+		expectedResult: "dx doxdoc",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSearchReplaceAllMatches(t *testing.T) {
+	// replace every match
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "docdoc",
+		},
+		input:          "${PARAM1//[cd]/x} ${PARAM1//o}",
+		expectedResult: "xoxxox dcdc",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSearchReplacePrefix(t *testing.T) {
+	// replace the longest matching prefix
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "docdoc",
+		},
+		input:          "${PARAM1/#d*o/x} ${PARAM1/#o/x}",
+		expectedResult: "xc docdoc",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSearchReplaceSuffix(t *testing.T) {
+	// replace the longest matching suffix
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "docdoc",
+		},
+		input:          "${PARAM1/%o*c/x} ${PARAM1/%o/x}",
+		expectedResult: "dx docdoc",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSearchReplaceDoesNotMatchPattern(t *testing.T) {
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "docdoc",
+		},
+		input:          "${PARAM1/aaa/x} ${PARAM1//aaa/x}",
+		expectedResult: "docdoc docdoc",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSearchReplaceEmptyPrefixAndSuffix(t *testing.T) {
+	// an empty anchored pattern matches the start (or end) of any
+	// value that is set, even an empty one
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "abc",
+			"EMPTY":  "",
+		},
+		input:          "[${PARAM1/#/x}] [${PARAM1/%/x}] [${EMPTY/#/x}] [${EMPTY/%/x}] [${UNSET/#/x}] [${PARAM1/#}] [${PARAM1/%/}]",
+		expectedResult: "[xabc] [abcx] [x] [x] [] [abc] [abc]",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandPositionalParamsSearchReplaceEmptyPrefixAndSuffix(t *testing.T) {
+	// the empty pattern is applied to each of $@
+	testData := expandTestData{
+		positionalVars: map[string]string{
+			"$1": "foo",
+			"$2": "bar",
+		},
+		specialVars: map[string]string{
+			"$#": "2",
+		},
+		input:          "${@/#/-} ${*/%/!}",
+		expectedResult: "-foo -bar foo! bar!",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamPatternsSupportEscapedClosingBrace(t *testing.T) {
+	// the escaped '}' is part of the pattern, and does not end the
+	// parameter expansion
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "foo}barbaz",
+		},
+		input:          `${PARAM1#foo\}bar} ${PARAM1%\}bar*} ${PARAM1#*\}} ${PARAM1/#foo\}/x}`,
+		expectedResult: "baz foo barbaz xbarbaz",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSearchReplaceUnescapesReplacement(t *testing.T) {
+	// the pattern keeps its escapes, but the replacement does not
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "foo}barbaz",
+		},
+		input:          `${PARAM1/o\}/\}\}} ${PARAM1//\}b/\$x}`,
+		expectedResult: "fo}}barbaz foo$xarbaz",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSearchReplaceSplitsOnFirstUnescapedSlash(t *testing.T) {
+	// everything after the first unescaped '/' is the replacement, and
+	// an escaped '/' belongs to the pattern or the replacement
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "/usr/local/bin",
+			"PARAM2": "abcabc",
+		},
+		input:          `${PARAM1/local/a/b} ${PARAM1//\//_} ${PARAM2/a/\/} ${PARAM1/#\/usr/x}`,
+		expectedResult: "/usr/a/b/bin _usr_local_bin /bcabc x/local/bin",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandParamSearchReplaceExpandsReplacement(t *testing.T) {
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "abcabc",
+			"PARAM2": "7",
+		},
+		input:          `${PARAM1/a/$PARAM2} ${PARAM1//b/${PARAM2}x} ${PARAM1/#a/${UNSET:-z}} ${PARAM1/%c/$((PARAM2 + 1))}`,
+		expectedResult: "7bcabc a7xca7xc zbcabc abcab8",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandPositionalParamsSearchReplace(t *testing.T) {
+	// search and replace, applied to each of $*
+	testData := expandTestData{
+		positionalVars: map[string]string{
+			"$1": "foo",
+			"$2": "bar",
+			"$3": "alfred",
+			"$#": "3",
+		},
+		input:          "${*/[ao]/_}",
+		expectedResult: "f_o b_r _lfred",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandContextPassesContextToCallbacks(t *testing.T) {
+	t.Parallel()
+
 	// ----------------------------------------------------------------
-	// create the shell script we'll run
+	// setup your test
+
+	type ctxKey string
+	ctx := context.WithValue(context.Background(), ctxKey("secret"), "foo")
+	cb := ExpansionCallbacks{
+		LookupVarContext: func(ctx context.Context, key string) (string, bool) {
+			retval, ok := ctx.Value(ctxKey(key)).(string)
+			return retval, ok
+		},
+	}
+	expectedResult := "foo bar"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandContext(ctx, "${secret} bar", cb)
 
-	var buf strings.Builder
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandContextPrefersContextAwareCallbacks(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
 
-	buf.WriteString("#!/usr/bin/env bash\n\n")
-	for key, value := range testData.vars {
-		buf.WriteString(fmt.Sprintf("%s='%s'\n", key, value))
+	var assigned string
+	cb := ExpansionCallbacks{
+		AssignToVar: func(key, value string) error {
+			t.Error("AssignToVar should not have been called")
+			return nil
+		},
+		AssignToVarContext: func(ctx context.Context, key, value string) error {
+			assigned = value
+			return nil
+		},
+		LookupVar: func(key string) (string, bool) {
+			t.Error("LookupVar should not have been called")
+			return "", false
+		},
+		LookupVarContext: func(ctx context.Context, key string) (string, bool) {
+			return assigned, assigned != ""
+		},
 	}
-	if len(testData.positionalVars) > 0 {
-		buf.WriteString("set -- ")
-		for i := 1; i <= len(testData.positionalVars); i++ {
-			buf.WriteString(testData.positionalVars["$"+strconv.Itoa(i)] + " ")
-		}
-		buf.WriteString("\n")
+	expectedResult := "foo"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandContext(context.Background(), "${PARAM1:=foo}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, expectedResult, assigned)
+}
+
+func TestExpandContextReturnsErrorWhenContextAlreadyCancelled(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			t.Error("LookupVar should not have been called")
+			return "", false
+		},
 	}
 
-	// do we need to write any extra steps to get the shell to tell us
-	// what the outcome was?
-	if len(testData.shellExtra) > 0 {
-		for _, line := range testData.shellExtra {
-			buf.WriteString(line)
-			buf.WriteRune('\n')
-		}
-	} else {
-		// no, we can simply echo the string we are expanding
-		buf.WriteString("echo ")
-		buf.WriteString(testData.input)
-		buf.WriteString("\n")
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandContext(ctx, "${PARAM1}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, "", actualResult)
+}
+
+func TestExpandContextStopsWhenContextCancelledDuringExpansion(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lookups := 0
+	cb := ExpansionCallbacks{
+		LookupVarContext: func(ctx context.Context, key string) (string, bool) {
+			// the first lookup is slow enough that the caller gives up
+			lookups++
+			cancel()
+			return "foo", true
+		},
 	}
 
-	// export the shell script we'll use to verify that internal behaviour
-	// matches actual shell script behaviour
-	tmpFile, _ := ioutil.TempFile("", "shellexpand-expandParams-")
-	cleanup := func() {
-		tmpFile.Close()
-		os.Remove(tmpFile.Name())
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandContext(ctx, "${PARAM1} ${PARAM2} ${PARAM3}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, "", actualResult)
+	assert.Equal(t, 1, lookups)
+}
+
+func TestExpandReturnsLiteralStringsWithoutAllocating(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "", false
+		},
 	}
-	defer cleanup()
+	testData := "this string has nothing to expand in it: 100% (honest)"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	var actualResult string
+	var err error
+	allocs := testing.AllocsPerRun(100, func() {
+		actualResult, err = Expand(testData, cb)
+	})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, testData, actualResult)
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestExpandFastPathStillChecksContext(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandContext(ctx, "nothing to expand", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, "", actualResult)
+}
+
+func testExpandTestCase(t *testing.T, testData expandTestData) {
+	// ----------------------------------------------------------------
+	// create the shell script we'll run
 
-	tmpFile.WriteString(buf.String())
-	tmpFile.Sync()
-	tmpFile.Close()
+	shellCase := shelltest.Case{
+		Input:    testData.input,
+		Vars:     testData.vars,
+		Commands: testData.shellExtra,
+	}
+	for i := 1; i <= len(testData.positionalVars); i++ {
+		shellCase.PositionalParams = append(
+			shellCase.PositionalParams,
+			testData.positionalVars["$"+strconv.Itoa(i)],
+		)
+	}
+	script := shelltest.Script(&shellCase)
 
 	// ----------------------------------------------------------------
 	// to run the test, we need to create some helper methods
@@ -1619,9 +2175,7 @@ func testExpandTestCase(t *testing.T, testData expandTestData) {
 	// ----------------------------------------------------------------
 	// perform the change
 
-	cmd := exec.Command("/usr/bin/env", "bash", tmpFile.Name())
-	shellRawResult, _ := cmd.CombinedOutput()
-	shellActualResult := strings.TrimSpace(string(shellRawResult))
+	shellActualResult, _ := shelltest.Run("bash", &shellCase)
 
 	internalActualResult, internalActualError := Expand(input, cb)
 	// special case - the result is a side effect, not a direct string
@@ -1650,14 +2204,172 @@ func testExpandTestCase(t *testing.T, testData expandTestData) {
 
 		if testData.resultSubstringMatch {
 			if len(testData.expectedShellResult) > 0 {
-				assert.Contains(t, shellActualResult, testData.expectedShellResult, buf.String())
+				assert.Contains(t, shellActualResult, testData.expectedShellResult, script)
 			} else {
-				assert.Contains(t, shellActualResult, expectedResult, buf.String())
+				assert.Contains(t, shellActualResult, expectedResult, script)
 			}
 			assert.Contains(t, internalActualResult, expectedResult, testData)
 		} else {
-			assert.Equal(t, expectedResult, shellActualResult, buf.String())
+			assert.Equal(t, expectedResult, shellActualResult, script)
 			assert.Equal(t, expectedResult, internalActualResult, testData)
 		}
 	}
 }
+
+// benchmarkCorpus is a mix of the kinds of strings that we expand
+var benchmarkCorpus = []string{
+	"this string has nothing to expand in it",
+	"${PARAM1}",
+	"the value of PARAM1 is ${PARAM1}, and PARAM2 is $PARAM2",
+	"${PARAM3:-a default value} and ${PARAM1:+an alternative value}",
+	"${PARAM1#f} ${PARAM1%%o*} ${PARAM1^^} ${PARAM2,[A-Z]}",
+	"${PARAM1:1:2} ${#PARAM2} ${PARAM2/ar/oo}",
+	"~/.config/{foo,bar,baz}/${PARAM1}.conf",
+	"file-{1..10}.txt",
+}
+
+func BenchmarkExpand(b *testing.B) {
+	vars := map[string]string{
+		"HOME":   "/home/stuart",
+		"PARAM1": "foo",
+		"PARAM2": "BAR",
+	}
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			retval, ok := vars[key]
+			return retval, ok
+		},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, input := range benchmarkCorpus {
+			Expand(input, cb)
+		}
+	}
+}
+
+func BenchmarkExpandLargeInput(b *testing.B) {
+	vars := map[string]string{
+		"HOME":   "/home/stuart",
+		"PARAM1": "foo",
+		"PARAM2": "BAR",
+	}
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			retval, ok := vars[key]
+			return retval, ok
+		},
+	}
+	input := strings.Repeat(strings.Join(benchmarkCorpus, "\n")+"\n", 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Expand(input, cb)
+	}
+}
+
+func BenchmarkExpandManyParameters(b *testing.B) {
+	vars := map[string]string{
+		"PARAM1": "foo",
+		"PARAM2": "BAR",
+	}
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			retval, ok := vars[key]
+			return retval, ok
+		},
+	}
+	input := strings.Repeat("${PARAM1} ${PARAM2:-x} $PARAM1 ", 50)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Expand(input, cb)
+	}
+}
+
+func BenchmarkExpandLiteralHeavyInput(b *testing.B) {
+	vars := map[string]string{
+		"PARAM1": "foo",
+	}
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			retval, ok := vars[key]
+			return retval, ok
+		},
+	}
+	paragraph := "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. "
+	input := strings.Repeat(strings.Repeat(paragraph, 10)+"${PARAM1}\n", 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Expand(input, cb)
+	}
+}
+
+func TestExpandArithmeticExpansion(t *testing.T) {
+	// parameters are expanded first, and then the expression is
+	// evaluated
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "7",
+			"PARAM2": "PARAM1 * 2",
+		},
+		input:          `$((PARAM1 + 1)) $(($PARAM1 ** 2)) $(( (1 + 2) * -3 )) $((PARAM2 + ${UNSET:-1})) x$((PARAM1 % 4 == 3 && !0))`,
+		expectedResult: "8 49 -9 15 x1",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandArithmeticAssignsToVariables(t *testing.T) {
+	// assignments inside $((...)) change the variable, and later
+	// expansions see the new value
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "7",
+		},
+		input:          "$((PARAM1 += 3)) $PARAM1 $((PARAM1++)) $PARAM1 $((--PARAM1)) $((PARAM2 = PARAM1 * 2)) $PARAM2",
+		expectedResult: "10 10 10 11 10 20 20",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandArithmeticConditionalAndBitwiseOperators(t *testing.T) {
+	testData := expandTestData{
+		vars: map[string]string{
+			"PARAM1": "0755",
+		},
+		input:          "$((PARAM1 & 8#070 ? 1 : 0)) $((PARAM1 >> 6 | 1 << 3, ~PARAM1 & 0777))",
+		expectedResult: "1 18",
+	}
+	testExpandTestCase(t, testData)
+}
+
+func TestExpandKeepsEveryNewlineInMultiLineTemplates(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"HOME":   "/home/stuart",
+		"PARAM1": "foo",
+	})
+	testData := "#!/bin/sh\n\ncp a.{txt,md} ~/docs\n\n\n\techo ${PARAM1}\r\n~\n"
+	expectedResult := "#!/bin/sh\n\ncp a.txt a.md /home/stuart/docs\n\n\n\techo foo\r\n/home/stuart\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
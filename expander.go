@@ -0,0 +1,288 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Expander expands strings, using the same callbacks and options every
+// time.
+//
+// Use an Expander when you want to change how expansion works. The
+// package-level functions (such as Expand()) behave like an Expander
+// that has no options set.
+//
+// An Expander is safe to share between goroutines. Its options cannot
+// change once NewExpander() has returned, and every call gets its own
+// scratch state (its lookup cache, budget, and so on). Nothing is left
+// running in the background when a call returns. Your callbacks must be
+// safe to call from several goroutines at once too.
+type Expander struct {
+	cb   ExpansionCallbacks
+	opts options
+
+	// the glob patterns that we have already compiled
+	globs *globCache
+
+	// what we have done, if the WithStats() option is set
+	stats *expansionStats
+}
+
+// Option changes how an Expander works
+type Option func(*options)
+
+// options holds the settings that change how we expand things
+type options struct {
+	// if true, we return errors for input that a UNIX shell would reject
+	strict bool
+
+	// the UNIX shell that we are copying
+	dialect Dialect
+
+	// if set, we tell this function about everything that we do
+	trace TraceFunc
+
+	// how many compiled glob patterns we remember
+	globCacheSize int
+
+	// if true, substrings and lengths count bytes instead of characters
+	byteOffsets bool
+
+	// if true, each call remembers the result of every LookupVar call
+	memoizeLookups bool
+
+	// how many entries ExpandSlice() and ExpandMap() expand at once
+	workers int
+
+	// how much work each call is allowed to do
+	budget budgetLimits
+
+	// if true, we count what we do
+	stats bool
+
+	// if set, ${var:?message} writes its message here
+	errorWriter io.Writer
+
+	// what we do with backslashes
+	escapeMode EscapeMode
+
+	// if true, we leave variables that are not set as they are
+	keepUnset bool
+
+	// if true, we follow Windows conventions for home directories
+	// and variable names
+	windows bool
+
+	// how variables are written in the input string
+	varSyntax VarSyntax
+
+	// if set, decides which variables we can expand
+	nameFilter NameFilter
+
+	// if set, we expand systemd-style %x specifiers too
+	specifiers LookupSpecifier
+
+	// the filesystem that we look at files in
+	fsys fs.FS
+
+	// if true, nothing can reach outside of string expansion
+	sandbox bool
+
+	// if set, decides which variables are secrets
+	secrets NameFilter
+
+	// if set, decides which variables cannot be assigned to
+	readOnly NameFilter
+
+	// the `set` and `shopt` flags that we follow
+	shellOpts ShellOpts
+
+	// if set, we perform history expansion using this
+	history HistoryProvider
+
+	// if true, we report every problem in the input, not just the first
+	allErrors bool
+
+	// if true, $[expr] is arithmetic expansion too
+	legacyArithmetic bool
+
+	// if true, arithmetic expansion supports floating-point numbers
+	floatArithmetic bool
+
+	// how many times ExpandFully() expands its input before giving up
+	maxPasses int
+
+	// how long each call is allowed to take
+	timeout time.Duration
+
+	// what we do with NUL bytes and invalid UTF-8
+	invalidInput InvalidInputMode
+}
+
+// NewExpander creates an Expander that uses the given callbacks and
+// options
+func NewExpander(cb ExpansionCallbacks, opts ...Option) *Expander {
+	retval := Expander{
+		cb: cb,
+		opts: options{
+			globCacheSize: defaultGlobCacheSize,
+		},
+	}
+	for _, opt := range opts {
+		opt(&retval.opts)
+	}
+	retval.globs = newGlobCache(retval.opts.globCacheSize)
+	if retval.opts.stats {
+		retval.stats = &expansionStats{}
+	}
+
+	return &retval
+}
+
+// WithStrict makes the Expander return an error for input that a UNIX
+// shell would reject, instead of passing it through untouched:
+//
+// - ${...} that we cannot parse (ErrBadSubstitution)
+// - ${ that has no matching } (ErrMismatchedBrace)
+// - { or } that has no match (ErrMismatchedBrace, ErrMismatchedClosingBrace)
+// - operators that we do not support yet (ErrUnsupportedOperator)
+func WithStrict() Option {
+	return func(opts *options) {
+		opts.strict = true
+	}
+}
+
+// WithWindows makes the Expander follow Windows conventions:
+//
+// - ~ expands to %USERPROFILE% (or %HOMEDRIVE%%HOMEPATH%, or $HOME)
+// - both / and \ end a tilde prefix, e.g. ~\Documents
+// - variable names are not case-sensitive; this needs MatchVarNames
+//
+// A backslash is still an escape character. Use the WithEscapeMode()
+// option with EscapePOSIX to keep backslashes in Windows paths.
+func WithWindows() Option {
+	return func(opts *options) {
+		opts.windows = true
+	}
+}
+
+// WithKeepUnset leaves $var and ${var...} untouched in the output if
+// `var` is not set, so that you can expand them in a later pass. It is
+// useful for expanding a template in stages.
+//
+// ${var:-word}, ${var:=word}, ${var:?word}, ${var:+word} and
+// ${!prefix*} all do something useful when `var` is not set, so they
+// are expanded as normal.
+func WithKeepUnset() Option {
+	return func(opts *options) {
+		opts.keepUnset = true
+	}
+}
+
+// WithErrorWriter makes ${var:?message} write its message to `w`, in the
+// same way that a UNIX shell writes it to stderr. You still get the
+// ErrVarRequired back from the call that failed.
+//
+// If you use the WithWorkers() option, `w` must be safe to call from
+// several goroutines at once.
+func WithErrorWriter(w io.Writer) Option {
+	return func(opts *options) {
+		opts.errorWriter = w
+	}
+}
+
+// Expand replaces ${var} and $var in the input string, just like the
+// package-level Expand() does
+func (e *Expander) Expand(input string) (string, error) {
+	return e.ExpandContext(context.Background(), input)
+}
+
+// ExpandContext replaces ${var} and $var in the input string, just like
+// the package-level ExpandContext() does
+func (e *Expander) ExpandContext(ctx context.Context, input string) (string, error) {
+	if e.opts.allErrors {
+		return joinDiagnostics(e.ExpandBestEffortContext(ctx, input))
+	}
+
+	ctx, done := e.withTimeout(ctx)
+	cb := e.callbacks()
+	retval, err := ExpandContext(ctx, input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
+
+// ExpandArgs expands the input string into a list of words, just like
+// the package-level ExpandArgs() does
+func (e *Expander) ExpandArgs(input string) ([]string, error) {
+	ctx, done := e.withTimeout(context.Background())
+	cb := e.callbacks()
+	cb.ctx = ctx
+	retval, err := ExpandArgs(input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
+
+// callbacks returns a copy of our callbacks, that knows about our options
+func (e *Expander) callbacks() ExpansionCallbacks {
+	retval := e.cb
+	retval.opts = &e.opts
+	retval.globs = e.globs
+	retval.stats = e.stats
+
+	// every call gets its own cache, so that we never return values
+	// that have changed since the last call
+	if e.opts.memoizeLookups && retval.cache == nil {
+		retval.cache = newExpansionCache()
+	}
+
+	// every call gets its own budget to spend, too
+	if e.opts.budget != (budgetLimits{}) {
+		retval.budget = newExpansionBudget(e.opts.budget)
+	}
+
+	// and remembers the secrets that it has seen
+	if e.opts.secrets != nil {
+		retval.secrets = &secretValues{}
+	}
+
+	return retval
+}
@@ -0,0 +1,529 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestExpander(opts ...Option) *Expander {
+	vars := map[string]string{
+		"PARAM1": "foo",
+	}
+
+	return NewExpander(
+		ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				retval, ok := vars[key]
+				return retval, ok
+			},
+		},
+		opts...,
+	)
+}
+
+func TestExpanderExpandsLikeExpand(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander()
+	testData := "${PARAM1} ${++} ${PARAM1@Q} ${PARAM1"
+	expectedResult, expectedErr := Expand(testData, unit.cb)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderStrictRejectsBadSubstitution(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithStrict())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("this is all ${++}bar")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.Equal(t, "${++}: bad substitution", err.Error())
+	assert.True(t, errors.Is(err, ErrBadSubstitution{}))
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err, &expErr))
+	assert.Equal(t, 12, expErr.Offset)
+}
+
+func TestExpanderStrictRejectsUnterminatedParam(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithStrict())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err1 := unit.Expand("${PARAM1} ${++")
+	_, err2 := unit.ExpandArgs("echo ${PARAM1} ${++")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, "unmatched '{' at position 11", err1.Error())
+	assert.True(t, errors.As(err1, &ErrMismatchedBrace{}))
+	assert.Equal(t, "unmatched '{' at position 16", err2.Error())
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err2, &expErr))
+	assert.Equal(t, 15, expErr.Offset)
+	assert.Equal(t, "${++", expErr.Substring)
+}
+
+func TestExpanderStrictRejectsMismatchedBraces(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithStrict())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err1 := unit.Expand("${PARAM1} x{a,b")
+	_, err2 := unit.Expand("${PARAM1}\nx{a,b}}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var expErr ExpansionError
+	assert.True(t, errors.As(err1, &expErr))
+	assert.Equal(t, PhaseBraceExpansion, expErr.Phase)
+	assert.Equal(t, 11, expErr.Offset)
+	assert.Equal(t, "{a,b", expErr.Substring)
+	assert.True(t, errors.As(err1, &ErrMismatchedBrace{}))
+
+	assert.True(t, errors.As(err2, &expErr))
+	assert.Equal(t, PhaseBraceExpansion, expErr.Phase)
+	assert.Equal(t, 16, expErr.Offset)
+	assert.Equal(t, 2, expErr.Line)
+	assert.Equal(t, 7, expErr.Column)
+	assert.Equal(t, "}", expErr.Substring)
+	assert.True(t, errors.As(err2, &ErrMismatchedClosingBrace{}))
+}
+
+func TestExpanderIgnoresMismatchedBracesOutsideStrictMode(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander()
+	expectedResult := "foo x{a,b xa} xb}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("${PARAM1} x{a,b x{a,b}}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderStrictRejectsUnsupportedOperators(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithStrict())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${PARAM1@Q}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.Equal(t, "${PARAM1@Q}: unsupported operator", err.Error())
+	assert.True(t, errors.Is(err, ErrUnsupportedOperator{}))
+}
+
+func TestExpanderStrictRejectsKeyValueTransforms(t *testing.T) {
+	t.Parallel()
+
+	// ${var@K} and ${var@k} quote the value, just like ${var@Q}
+	for _, input := range []string{"${PARAM1@K}", "${PARAM1@k}"} {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		unit := newTestExpander(WithStrict())
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrUnsupportedOperator{}), "%s: %v", input, err)
+	}
+}
+
+func TestExpanderStrictAcceptsCaseTransforms(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithStrict())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${PARAM1@U} ${PARAM1@u} ${PARAM1@L}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+}
+
+func TestExpanderStrictAcceptsGoodInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithStrict())
+	expectedResult := "foo $ {a} $1bar"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("${PARAM1} $ {a} \\$1bar")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderWithErrorWriterWritesRequiredVarMessages(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var buf strings.Builder
+	unit := newTestExpander(WithErrorWriter(&buf))
+	expectedOutput := "UNSET: must be set\nEMPTY: parameter null or not set\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err1 := unit.Expand("${PARAM1:?not used} ${UNSET:?must be set}")
+	_, err2 := unit.ExpandArgs("echo ${EMPTY:?}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err1, ErrVarRequired{}))
+	assert.True(t, errors.Is(err2, ErrVarRequired{}))
+	assert.Equal(t, expectedOutput, buf.String())
+}
+
+func TestExpanderWithKeepUnsetLeavesUnsetVarsAlone(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithKeepUnset())
+	testData := "$PARAM1 $UNSET ${UNSET} ${UNSET#x} ${#UNSET} pre${UNSET:1}post"
+	expectedResult := "foo $UNSET ${UNSET} ${UNSET#x} ${#UNSET} pre${UNSET:1}post"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderWithKeepUnsetStillExpandsDefaultValues(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithKeepUnset())
+	testData := `${UNSET:-$PARAM1} ${UNSET:-$OTHER} [${UNSET:+alt}] ${PARAM1:+alt}`
+	expectedResult := `foo $OTHER [] alt`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderWithKeepUnsetWorksWithExpandArgs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithKeepUnset())
+	testData := `echo $PARAM1 "$UNSET" ${UNSET}`
+	expectedResult := []string{"echo", "foo", "$UNSET", "${UNSET}"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandArgs(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func newWindowsTestExpander(vars map[string]string, opts ...Option) *Expander {
+	return NewExpander(
+		ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				retval, ok := vars[key]
+				return retval, ok
+			},
+			LookupHomeDir: func(user string) (string, bool) {
+				return `C:\Users\` + user, true
+			},
+			MatchVarNames: func(prefix string) []string {
+				retval := []string{}
+				for key := range vars {
+					if strings.HasPrefix(key, prefix) {
+						retval = append(retval, key)
+					}
+				}
+				return retval
+			},
+		},
+		append([]Option{WithWindows()}, opts...)...,
+	)
+}
+
+func TestExpanderWithWindowsExpandsTildeToUserProfile(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newWindowsTestExpander(map[string]string{
+		"HOME":        "/home/me",
+		"USERPROFILE": `C:\Users\me`,
+	})
+	testData := `~\Documents ~/Downloads ~bob\Desktop`
+	expectedResult := `C:\Users\me\Documents C:\Users\me/Downloads C:\Users\bob\Desktop`
+	expectedArgs := []string{`C:\Users\me\Documents`, `C:\Users\me/Downloads`, `C:\Users\bob\Desktop`}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err1 := unit.Expand(testData)
+	actualArgs, err2 := unit.ExpandArgs(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err1)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Nil(t, err2)
+	assert.Equal(t, expectedArgs, actualArgs)
+}
+
+func TestExpanderWithWindowsFallsBackToHomeDriveAndPath(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newWindowsTestExpander(map[string]string{
+		"HOMEDRIVE": `D:`,
+		"HOMEPATH":  `\Users\me`,
+	})
+	expectedResult := `D:\Users\me\Documents`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(`~\Documents`)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderWithWindowsIgnoresCaseOfVariableNames(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newWindowsTestExpander(
+		map[string]string{
+			"Path":   `C:\bin`,
+			"windir": `C:\Windows`,
+		},
+		WithEscapeMode(EscapePOSIX),
+	)
+	expectedResult := `C:\bin C:\Windows\System32 windir`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(`$PATH ${WinDir}\System32 ${!WIN*}`)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderIsSafeToShareBetweenGoroutines(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+	//
+	// run this test with `go test -race` to make it worthwhile
+
+	env := NewEnv()
+	env.Import([]string{
+		"HOME=/home/stuart",
+		"APP_DIR=${HOME}/app",
+		"TOKEN=s3cr3t",
+	})
+	cb := FirstOf(
+		env.Callbacks(),
+		NewMapCallbacks(map[string]string{"$#": "2", "$1": "one", "$2": "two"}),
+	)
+	unit := NewExpander(
+		cb,
+		WithMemoizedLookups(),
+		WithBudget(1000, 1000, 1000),
+		WithStats(),
+		WithSecrets("TOKEN"),
+	)
+	expectedResult := "/home/stuart/app/logs ONE TWO 3 stuart xa xb"
+
+	const goroutines = 16
+	const iterations = 50
+	results := make(chan string, goroutines*iterations)
+	errs := make(chan error, goroutines*iterations)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				actualResult, err := unit.ExpandFully(`${LOG_DIR:=$APP_DIR/logs} ${@^^} $((${#TOKEN} / 2)) ${HOME##*/} x{a,b}`)
+				results <- actualResult
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(results)
+	close(errs)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+	for actualResult := range results {
+		assert.Equal(t, expectedResult, actualResult)
+	}
+	assert.Equal(t, int64(0), unit.Stats().Errors)
+}
@@ -0,0 +1,155 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "sync"
+
+// expansionCache remembers work that we have already done, so that it
+// can be shared across several calls to the expansion pipeline
+//
+// the zero value (nil) is valid, and simply means "no caching"
+//
+// it is safe to share between goroutines, so that ExpandSlice() and
+// ExpandMap() can expand several entries at once
+type expansionCache struct {
+	mu sync.Mutex
+
+	// the result of parseParameter(), keyed by the parameter expansion
+	// that we parsed
+	//
+	// these never use pooled slices, because we hand them out over and
+	// over again
+	params map[string]cachedParam
+
+	// the result of calling LookupVar, keyed by variable name
+	vars map[string]cachedVar
+}
+
+type cachedParam struct {
+	desc paramDesc
+	ok   bool
+}
+
+type cachedVar struct {
+	value string
+	ok    bool
+}
+
+func newExpansionCache() *expansionCache {
+	return &expansionCache{
+		params: make(map[string]cachedParam),
+		vars:   make(map[string]cachedVar),
+	}
+}
+
+func (c *expansionCache) parseParameter(input string) (paramDesc, bool) {
+	// are we caching?
+	if c == nil {
+		scratch := getParamScratch()
+		retval, ok := parseParameterWithScratch(input, scratch)
+		if !ok {
+			putParamScratch(scratch)
+		}
+		return retval, ok
+	}
+
+	// have we seen this before?
+	c.mu.Lock()
+	entry, ok := c.params[input]
+	c.mu.Unlock()
+	if ok {
+		return entry.desc, entry.ok
+	}
+
+	entry.desc, entry.ok = parseParameter(input)
+	c.mu.Lock()
+	c.params[input] = entry
+	c.mu.Unlock()
+
+	return entry.desc, entry.ok
+}
+
+func (c *expansionCache) lookupVar(key string) (string, bool, bool) {
+	// are we caching?
+	if c == nil {
+		return "", false, false
+	}
+
+	c.mu.Lock()
+	entry, found := c.vars[key]
+	c.mu.Unlock()
+
+	return entry.value, entry.ok, found
+}
+
+func (c *expansionCache) rememberVar(key, value string, ok bool) {
+	// are we caching?
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.vars[key] = cachedVar{value, ok}
+	c.mu.Unlock()
+}
+
+func (c *expansionCache) forgetVar(key string) {
+	// are we caching?
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	delete(c.vars, key)
+	c.mu.Unlock()
+}
+
+// WithMemoizedLookups makes the Expander remember the result of every
+// LookupVar call, for the rest of that call to Expand() (or
+// ExpandContext(), or ExpandArgs()).
+//
+// Use this when your LookupVar is expensive. Each variable is looked up
+// once per call, no matter how many times it appears in the input (for
+// example, $# and each positional parameter, every time $* is used).
+//
+// Assigning to a variable (for example, with ${var:=word}) makes us
+// forget what we remembered about it. Nothing is remembered between
+// calls.
+func WithMemoizedLookups() Option {
+	return func(opts *options) {
+		opts.memoizeLookups = true
+	}
+}
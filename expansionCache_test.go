@@ -0,0 +1,153 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMemoizedLookupsLooksUpEachVariableOncePerCall(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"$#":     "2",
+		"$1":     "one",
+		"$2":     "two",
+		"PARAM1": "foo",
+	}
+	lookups := map[string]int{}
+	unit := NewExpander(countLookups(newTestCallbacks(vars), lookups), WithMemoizedLookups())
+	expectedResult := "one two foo one two foo"
+	expectedLookups := map[string]int{
+		"$#":     1,
+		"$1":     1,
+		"$2":     1,
+		"PARAM1": 1,
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("$* $PARAM1 ${*} ${PARAM1}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, expectedLookups, lookups)
+}
+
+func TestWithMemoizedLookupsForgetsValuesBetweenCalls(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo",
+	}
+	lookups := map[string]int{}
+	unit := NewExpander(countLookups(newTestCallbacks(vars), lookups), WithMemoizedLookups())
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	firstResult, err1 := unit.Expand("$PARAM1 $PARAM1")
+	vars["PARAM1"] = "bar"
+	secondResult, err2 := unit.Expand("$PARAM1 $PARAM1")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, "foo foo", firstResult)
+	assert.Equal(t, "bar bar", secondResult)
+	assert.Equal(t, 2, lookups["PARAM1"])
+}
+
+func TestWithMemoizedLookupsSeesAssignments(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{}
+	lookups := map[string]int{}
+	unit := NewExpander(countLookups(newTestCallbacks(vars), lookups), WithMemoizedLookups())
+	expectedResult := "[] foo [foo]"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("[$PARAM1] ${PARAM1:=foo} [$PARAM1]")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderDoesNotMemoizeLookupsByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo",
+	}
+	lookups := map[string]int{}
+	unit := NewExpander(countLookups(newTestCallbacks(vars), lookups))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("$PARAM1 $PARAM1")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, lookups["PARAM1"])
+}
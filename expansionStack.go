@@ -0,0 +1,600 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"bytes"
+	"strings"
+)
+
+// what a frame's result is for, once the frame is finished
+const (
+	// the frame is the string that the caller asked us to expand
+	frameForCaller = iota
+	// the rest of a tilde prefix that we could not expand
+	frameForTildeRest
+	// the words that brace expansion gave us
+	frameForBraceWords
+	// the rest of a ${...} that we could not parse
+	frameForDollarRest
+	// the word after a parameter expansion's operator
+	frameForOperatorWord
+	// the expression inside $((...))
+	frameForArithmetic
+)
+
+// expansionFrame is a string that we are part-way through expanding
+//
+// nested expansions (such as the default value in ${a:-${b:-word}}) are
+// pushed onto a stack of frames, instead of being expanded by calling
+// ourselves. This keeps our stack depth the same, no matter how deeply
+// the input is nested.
+type expansionFrame struct {
+	// the string we are expanding
+	input string
+
+	// the phases of expansion to apply to it
+	phases int
+
+	// the parts of the input that need expanding
+	spans []expansionSpan
+
+	// the next span to expand
+	next int
+
+	// the end of the last span that we expanded
+	last int
+
+	// this is where we build up our result
+	buf *bytes.Buffer
+
+	// what our result is for
+	purpose int
+
+	// the span in the frame below us that we are expanding
+	span expansionSpan
+
+	// the parameter expansion that is waiting for our result
+	param *paramExpansion
+}
+
+// newExpansionFrame prepares to expand the given input string
+func newExpansionFrame(input string, phases, purpose int, span expansionSpan) expansionFrame {
+	return expansionFrame{
+		input:   input,
+		phases:  phases,
+		spans:   scanExpansions(input, phases),
+		purpose: purpose,
+		span:    span,
+	}
+}
+
+// finished returns true if there is nothing left in this frame to expand
+func (f *expansionFrame) finished() bool {
+	return f.next == len(f.spans)
+}
+
+// result returns what this frame expanded to
+//
+// it must only be called once the frame is finished
+func (f *expansionFrame) result() string {
+	// did we have anything to expand?
+	if f.buf == nil {
+		return f.input
+	}
+
+	f.buf.WriteString(f.input[f.last:])
+	retval := f.buf.String()
+	putBuffer(f.buf)
+	f.buf = nil
+
+	return retval
+}
+
+// outputLen returns how much of our result we have built so far
+func (f *expansionFrame) outputLen() int {
+	if f.buf == nil {
+		return 0
+	}
+
+	return f.buf.Len()
+}
+
+// release gives our buffer back, when we are abandoning the frame
+func (f *expansionFrame) release() {
+	if f.buf != nil {
+		putBuffer(f.buf)
+		f.buf = nil
+	}
+}
+
+// expandSpans applies the given phases of expansion to the input string,
+// in a single pass
+//
+// we find all of the spans that need expanding first, and then apply
+// the phases to each span in turn. Anything that is not in a span is
+// copied across as it is.
+//
+// whenever a span contains something else that needs expanding, we push
+// a new frame onto our stack, and come back to the span once that frame
+// is finished. This loop is the only place where expansion happens.
+func expandSpans(input string, cb ExpansionCallbacks, phases int) (string, error) {
+	if cb.escapeMode() == EscapeBash {
+		phases |= scanQuotes
+	}
+	if cb.windows() {
+		phases |= scanWindowsPaths
+	}
+	if !cb.dialect().backslashEscapes {
+		phases |= scanNoEscapes
+	}
+	if cb.specifiers() != nil {
+		phases |= scanSpecifiers
+	}
+	if cb.RunCommand != nil || cb.sandboxed() || cb.hasFS() {
+		phases |= scanCommands
+	}
+	if cb.dialect().arithmetic {
+		phases |= scanArithmetic
+	}
+	if cb.legacyArithmetic() {
+		phases |= scanLegacyArithmetic
+	}
+	switch cb.varSyntax() {
+	case VarSyntaxPercent:
+		phases |= scanPercentOnly
+	case VarSyntaxShellAndPercent:
+		phases |= scanPercentVars
+	case VarSyntaxShellAndMake:
+		phases |= scanMakeVars
+	}
+
+	// only the caller's own string is expanded in best-effort mode;
+	// anything that we expand along the way still fails as normal
+	diagnostics := cb.diagnostics
+	cb.diagnostics = nil
+
+	// the same goes for the source map
+	sourceMap := cb.sourceMap
+	cb.sourceMap = nil
+
+	root := newExpansionFrame(input, phases, frameForCaller, expansionSpan{})
+	if root.finished() {
+		return input, nil
+	}
+
+	// most input is not nested very deeply at all
+	var frames [4]expansionFrame
+	stack := append(frames[:0], root)
+
+	for {
+		top := &stack[len(stack)-1]
+
+		// are we done with this frame?
+		if top.finished() {
+			if len(stack) == 1 {
+				sourceMap.closeSpan(top)
+			}
+			child := *top
+			result := child.result()
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				// a callback may have given up part-way through,
+				// because the caller's context ran out
+				err := cb.context().Err()
+				if err != nil {
+					return "", err
+				}
+
+				// or because we ran out of budget
+				err = cb.budget.exceeded()
+				if err != nil {
+					return "", err
+				}
+				return result, nil
+			}
+
+			err := stack[len(stack)-1].resume(&child, result, cb)
+			if err != nil && diagnostics != nil {
+				stack, err = recoverExpansionStack(stack, err, input, diagnostics, cb)
+			}
+			if err != nil {
+				return "", unwindExpansionStack(stack, err, cb)
+			}
+			continue
+		}
+
+		if len(stack) == 1 {
+			sourceMap.openSpan(top)
+		}
+		child, pushed, err := top.step(cb)
+		if err != nil && diagnostics != nil {
+			stack, err = recoverExpansionStack(stack, err, input, diagnostics, cb)
+		}
+		if err != nil {
+			return "", unwindExpansionStack(stack, err, cb)
+		}
+		if pushed {
+			stack = append(stack, child)
+		}
+	}
+}
+
+// unwindExpansionStack abandons all of the frames on the stack, and
+// works out where the error happened in the bottom frame's input
+func unwindExpansionStack(stack []expansionFrame, err error, cb ExpansionCallbacks) error {
+	for len(stack) > 1 {
+		top := &stack[len(stack)-1]
+		top.release()
+		stack = stack[:len(stack)-1]
+
+		err = stack[len(stack)-1].fail(top, err, cb)
+	}
+	stack[0].release()
+
+	return err
+}
+
+// recoverExpansionStack abandons all of the frames above the bottom
+// one, and leaves the span that the bottom frame was expanding as it
+// is in the input
+//
+// the error is added to the diagnostics. We only return it if we
+// cannot recover from it (e.g. the caller has cancelled the context)
+func recoverExpansionStack(stack []expansionFrame, err error, input string, diagnostics *[]ExpansionError, cb ExpansionCallbacks) ([]expansionFrame, error) {
+	for len(stack) > 1 {
+		top := &stack[len(stack)-1]
+		top.release()
+		stack = stack[:len(stack)-1]
+
+		err = stack[len(stack)-1].fail(top, err, cb)
+	}
+
+	expErr, ok := err.(ExpansionError)
+	if !ok {
+		return stack, err
+	}
+	*diagnostics = append(*diagnostics, locateExpansionError(expErr, input, input, 0).(ExpansionError))
+
+	root := &stack[0]
+	span := root.spans[root.next-1]
+	root.buf.WriteString(root.input[span.start:span.end])
+	root.last = span.end
+
+	return stack, nil
+}
+
+// step expands the next span in this frame
+//
+// if the span contains something else that needs expanding first, we
+// return a new frame for it, and `true`
+func (f *expansionFrame) step(cb ExpansionCallbacks) (expansionFrame, bool, error) {
+	if f.buf == nil {
+		f.buf = getBuffer(len(f.input))
+	}
+
+	span := f.spans[f.next]
+	f.next++
+
+	f.buf.WriteString(f.input[f.last:span.start])
+	f.last = span.end
+	text := f.input[span.start:span.end]
+
+	switch span.kind {
+	case spanEscape:
+		// escapes are removed during parameter expansion
+		if f.phases&scanParams == 0 {
+			f.buf.WriteString(text)
+			return expansionFrame{}, false, nil
+		}
+		f.buf.WriteString(cb.escapeMode().unescape(text, f.phases&scanOperatorWord != 0))
+
+	case spanUnterminated:
+		// UNIX shells refuse to expand a ${ that is never closed
+		if f.phases&scanParams != 0 && (cb.strict() || cb.dialect().rejectBadSubstitutions) {
+			return expansionFrame{}, false, newExpansionError(
+				PhaseParameterExpansion,
+				f.input,
+				span.start,
+				len(f.input),
+				NewErrMismatchedBrace(f.input, span.start+1),
+			)
+		}
+		f.buf.WriteString(text)
+
+	case spanParam:
+		if f.phases&scanParams == 0 {
+			f.buf.WriteString(text)
+			return expansionFrame{}, false, nil
+		}
+		err := cb.budget.spendParam()
+		if err != nil {
+			return expansionFrame{}, false, f.paramError(span, err, cb)
+		}
+		return f.stepParameter(span, cb)
+
+	case spanTilde:
+		err := cb.budget.spend(BudgetExpansions)
+		if err != nil {
+			return expansionFrame{}, false, newExpansionError(PhaseTildeExpansion, f.input, span.start, span.end, err)
+		}
+		repl, _, ok := expandTildePrefix(text, cb)
+		if ok {
+			f.buf.WriteString(repl)
+			return expansionFrame{}, false, nil
+		}
+
+		// we could not expand the tilde prefix, but there may be
+		// other things in it that we can expand
+		return newExpansionFrame(text[1:], f.phases&^(scanBraces|scanTilde), frameForTildeRest, span), true, nil
+
+	case spanBraces:
+		// brace expansion charges our budget for every word that it
+		// generates, and stops if the caller stops waiting
+		expanded, err := expandBraces(text, newBraceLimiter(cb.context(), cb.budget))
+		if err != nil {
+			_, overBudget := err.(ErrBudgetExceeded)
+			if overBudget {
+				err = newExpansionError(PhaseBraceExpansion, f.input, span.start, span.end, err)
+			}
+			return expansionFrame{}, false, err
+		}
+
+		// the words that brace expansion gives us still need the
+		// remaining phases of expansion applied to them
+		return newExpansionFrame(expanded, f.phases&^scanBraces, frameForBraceWords, span), true, nil
+
+	case spanPercentVar:
+		err := cb.budget.spendParam()
+		if err != nil {
+			return expansionFrame{}, false, f.paramError(span, err, cb)
+		}
+		repl, err := expandPercentVar(text, cb)
+		if err != nil {
+			return expansionFrame{}, false, f.paramError(span, err, cb)
+		}
+		f.buf.WriteString(repl)
+
+	case spanSpecifier:
+		err := cb.budget.spend(BudgetExpansions)
+		if err != nil {
+			return expansionFrame{}, false, f.paramError(span, err, cb)
+		}
+		repl, err := expandSpecifier(text, cb)
+		if err != nil {
+			return expansionFrame{}, false, f.paramError(span, err, cb)
+		}
+		f.buf.WriteString(repl)
+
+	case spanMakeVar:
+		if f.phases&scanParams == 0 {
+			f.buf.WriteString(text)
+			return expansionFrame{}, false, nil
+		}
+		err := cb.budget.spendParam()
+		if err != nil {
+			return expansionFrame{}, false, f.paramError(span, err, cb)
+		}
+		repl, err := expandMakeVar(text, cb)
+		if err != nil {
+			return expansionFrame{}, false, f.paramError(span, err, cb)
+		}
+		f.buf.WriteString(repl)
+
+	case spanArithmetic:
+		if f.phases&scanParams == 0 {
+			f.buf.WriteString(text)
+			return expansionFrame{}, false, nil
+		}
+		err := cb.budget.spend(BudgetExpansions)
+		if err != nil {
+			return expansionFrame{}, false, newExpansionError(PhaseArithmeticExpansion, f.input, span.start, span.end, err)
+		}
+
+		// the expression may contain parameters that we need to expand
+		// before we can evaluate it
+		return newExpansionFrame(arithmeticExpr(text), scanParams|f.phases&scanOptions, frameForArithmetic, span), true, nil
+
+	case spanCommand:
+		if f.phases&scanParams == 0 {
+			f.buf.WriteString(text)
+			return expansionFrame{}, false, nil
+		}
+		err := cb.budget.spend(BudgetExpansions)
+		if err != nil {
+			return expansionFrame{}, false, newExpansionError(PhaseCommandSubstitution, f.input, span.start, span.end, err)
+		}
+		repl, err := expandCommand(text, cb)
+		if err != nil {
+			ctxErr := cb.context().Err()
+			if ctxErr != nil {
+				return expansionFrame{}, false, ctxErr
+			}
+			return expansionFrame{}, false, newExpansionError(PhaseCommandSubstitution, f.input, span.start, span.end, err)
+		}
+		f.buf.WriteString(repl)
+	}
+
+	return expansionFrame{}, false, nil
+}
+
+// stepParameter expands a single $var or ${...} span
+func (f *expansionFrame) stepParameter(span expansionSpan, cb ExpansionCallbacks) (expansionFrame, bool, error) {
+	// has the caller given up on us?
+	err := cb.context().Err()
+	if err != nil {
+		return expansionFrame{}, false, err
+	}
+
+	text := f.input[span.start:span.end]
+
+	// Docker Compose and Makefiles use $$ for a literal $
+	if text == "$$" && (cb.dialect().dollarEscapes || cb.varSyntax() == VarSyntaxShellAndMake) {
+		f.buf.WriteByte('$')
+		return expansionFrame{}, false, nil
+	}
+
+	braced := strings.HasPrefix(text, "${")
+	paramDesc, ok := cb.parseParameter(text)
+	if !ok {
+		// UNIX shells refuse to expand a ${...} that they do not
+		// understand
+		if braced && (cb.strict() || cb.dialect().rejectBadSubstitutions) {
+			return expansionFrame{}, false, newExpansionError(
+				PhaseParameterExpansion,
+				f.input,
+				span.start,
+				span.end,
+				ErrBadSubstitution{text},
+			)
+		}
+
+		// we treat the '$' as a normal character, and carry on from
+		// there
+		return newExpansionFrame(text[1:], scanParams|f.phases&scanOptions, frameForDollarRest, span), true, nil
+	}
+
+	// a $var that the dialect does not understand is left as it is
+	if !braced && !cb.dialect().supportsParam(paramDesc) {
+		f.buf.WriteString(text)
+		return expansionFrame{}, false, nil
+	}
+
+	param, err := startParamExpansion(text, paramDesc, cb)
+	if err != nil {
+		return expansionFrame{}, false, f.paramError(span, err, cb)
+	}
+
+	// do we need to expand the word after the operator first?
+	if param.needsWord() {
+		waiting := param
+		waiting.desc.operand = newLazyWord(paramDesc.word())
+		phases := scanParams | scanOperatorWord | f.phases&scanOptions
+		if cb.dialect().tildeExpansion {
+			phases |= scanTilde
+		}
+		child := newExpansionFrame(paramDesc.word(), phases, frameForOperatorWord, span)
+		child.param = &waiting
+		return child, true, nil
+	}
+
+	// any other operator expands its word (e.g. the replacement in
+	// ${var/pattern/word}) itself, if it needs it
+	if len(paramDesc.parts) > 1 {
+		param.desc.operand = newLazyWord(paramDesc.word())
+	}
+	return expansionFrame{}, false, f.finishParameter(&param, span, cb)
+}
+
+// finishParameter adds the result of a parameter expansion to our buffer
+func (f *expansionFrame) finishParameter(param *paramExpansion, span expansionSpan, cb ExpansionCallbacks) error {
+	replacement, err := param.finish(cb)
+	param.release()
+	if err != nil {
+		return f.paramError(span, err, cb)
+	}
+
+	f.buf.WriteString(replacement)
+	return nil
+}
+
+// paramError tells the caller which parameter expansion went wrong
+func (f *expansionFrame) paramError(span expansionSpan, err error, cb ExpansionCallbacks) error {
+	// don't hide the caller's own error from them
+	ctxErr := cb.context().Err()
+	if ctxErr != nil {
+		return ctxErr
+	}
+
+	return newExpansionError(PhaseParameterExpansion, f.input, span.start, span.end, err)
+}
+
+// resume uses the result of a frame that we pushed onto the stack
+func (f *expansionFrame) resume(child *expansionFrame, result string, cb ExpansionCallbacks) error {
+	switch child.purpose {
+	case frameForTildeRest:
+		f.buf.WriteByte('~')
+		f.buf.WriteString(result)
+
+	case frameForDollarRest:
+		f.buf.WriteByte('$')
+		f.buf.WriteString(result)
+
+	case frameForOperatorWord:
+		child.param.desc.operand.set(result)
+		return f.finishParameter(child.param, child.span, cb)
+
+	case frameForArithmetic:
+		value, err := evalArithmetic(result, cb)
+		if err != nil {
+			ctxErr := cb.context().Err()
+			if ctxErr != nil {
+				return ctxErr
+			}
+			return newExpansionError(PhaseArithmeticExpansion, f.input, child.span.start, child.span.end, err)
+		}
+		f.buf.WriteString(value)
+
+	default:
+		f.buf.WriteString(result)
+	}
+
+	return nil
+}
+
+// fail works out where an error in a frame that we pushed onto the stack
+// happened in our input
+func (f *expansionFrame) fail(child *expansionFrame, err error, cb ExpansionCallbacks) error {
+	switch child.purpose {
+	case frameForOperatorWord:
+		// the operator word is part of the parameter expansion; we
+		// report the whole parameter expansion
+		return f.paramError(child.span, err, cb)
+
+	case frameForBraceWords:
+		return locateExpansionError(err, f.input, child.input, child.span.start)
+
+	case frameForArithmetic:
+		// the child's input starts just after the $(( or $[
+		offset := child.span.start + arithmeticPrefixLen(f.input[child.span.start:])
+		return locateExpansionError(err, f.input, child.input, offset)
+
+	default:
+		// the child's input starts just after the first character of
+		// the span
+		return locateExpansionError(err, f.input, child.input, child.span.start+1)
+	}
+}
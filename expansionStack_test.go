@@ -0,0 +1,218 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nestedDefaults returns ${UNSET:-${UNSET:-...word...}}, nested `depth`
+// times
+func nestedDefaults(depth int, word string) string {
+	return strings.Repeat("${UNSET:-", depth) + word + strings.Repeat("}", depth)
+}
+
+// stackDepthAtLookup expands the input, and returns how deep the
+// call stack was when LookupHomeDir was called
+func stackDepthAtLookup(t *testing.T, input string) int {
+	depth := 0
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "", false
+		},
+		LookupHomeDir: func(user string) (string, bool) {
+			pcs := make([]uintptr, 1024)
+			depth = runtime.Callers(0, pcs)
+			return "/home/" + user, true
+		},
+	}
+
+	_, err := Expand(input, cb)
+	assert.Nil(t, err)
+
+	return depth
+}
+
+func TestExpandNestedDefaultValuesDoesNotGrowTheStack(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	shallowInput := nestedDefaults(1, "~depth")
+	deepInput := nestedDefaults(50, "~depth")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	shallowDepth := stackDepthAtLookup(t, shallowInput)
+	deepDepth := stackDepthAtLookup(t, deepInput)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.NotZero(t, shallowDepth)
+	assert.Equal(t, shallowDepth, deepDepth)
+}
+
+func TestExpandDeeplyNestedDefaultValues(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "", false
+		},
+	}
+	testData := nestedDefaults(2000, "word")
+	expectedResult := "word"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandReportsErrorsInNestedOperatorWords(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "", false
+		},
+	}
+	testData := "abc ${UNSET:-${UNSET:-${REQUIRED:?is not set}}}"
+	expectedOffset := 4
+	expectedSubstring := "${UNSET:-${UNSET:-${REQUIRED:?is not set}}}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Expand(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	expErr, ok := err.(ExpansionError)
+	assert.True(t, ok)
+	assert.Equal(t, expectedOffset, expErr.Offset)
+	assert.Equal(t, expectedSubstring, expErr.Substring)
+	_, ok = expErr.Err.(ErrVarRequired)
+	assert.True(t, ok)
+}
+
+func TestExpandLetsAssignmentsReadTheVariableTheyAssignTo(t *testing.T) {
+	t.Parallel()
+
+	// the word in ${var:=word} is only expanded once, so using var in
+	// it cannot loop; just like bash, var is still unset at that point
+	testCases := []struct {
+		vars           map[string]string
+		input          string
+		expectedResult string
+		expectedVars   map[string]string
+	}{
+		{nil, "${V:=${V}/x}", "/x", map[string]string{"V": "/x"}},
+		{nil, "${E:=${E}x}", "x", map[string]string{"E": "x"}},
+		{nil, "${K:=${#K}}", "0", map[string]string{"K": "0"}},
+		{nil, "${A:=prefix-${A}}", "prefix-", map[string]string{"A": "prefix-"}},
+		{nil, "${A:=${B:=$A}}", "", map[string]string{"A": "", "B": ""}},
+		{map[string]string{"A": "B"}, "${B:=${!A}}", "", map[string]string{"A": "B", "B": ""}},
+	}
+
+	for _, testCase := range testCases {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		env := NewEnv()
+		for name, value := range testCase.vars {
+			env.Set(name, value)
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Expand(testCase.input, env.Callbacks())
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testCase.input)
+		assert.Equal(t, testCase.expectedResult, actualResult, testCase.input)
+		for name, expectedValue := range testCase.expectedVars {
+			assert.Equal(t, expectedValue, env.Get(name), testCase.input)
+		}
+	}
+}
+
+func TestExpandAllowsAssignmentsThatDoNotLoop(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("A", "B")
+	env.Set("B", "A")
+	expectedResult := "A x x x"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${!A} ${C:=${D:=x}} $C $D", env.Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
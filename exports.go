@@ -0,0 +1,109 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "strings"
+
+// IsExported returns true if the given variable is exported to child
+// processes, just like `export` marks it in a UNIX shell
+type IsExported func(string) bool
+
+// ExportVar marks the given variable as exported. If it cannot do so,
+// it reports an error to explain why
+type ExportVar func(string) error
+
+// isExported returns true if the given variable has been exported
+func (cb ExpansionCallbacks) isExported(name string) bool {
+	if cb.IsExported == nil || cb.budget.spendCallback() != nil {
+		return false
+	}
+	return cb.IsExported(name)
+}
+
+// varAttributes returns the flags that describe the given variable, in
+// the same order that bash's `declare` uses
+func (cb ExpansionCallbacks) varAttributes(name string) string {
+	var retval strings.Builder
+	if cb.isReadOnly(name) {
+		retval.WriteByte('r')
+	}
+	if cb.isExported(name) {
+		retval.WriteByte('x')
+	}
+
+	return retval.String()
+}
+
+// expandParamDescribeFlags expands ${var@a} into the variable's attributes
+func expandParamDescribeFlags(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+	// positional and special parameters have no attributes
+	if !isName(paramName) {
+		return "", true, nil
+	}
+
+	return cb.varAttributes(paramName), true, nil
+}
+
+// expandParamAsDeclare expands ${var@A} into the `declare` statement
+// that would recreate the variable, such as:
+//
+// declare -x var='value'
+//
+// like bash, we leave out `declare` if the variable has no attributes
+func expandParamAsDeclare(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+	// positional and special parameters have no declaration
+	if !isName(paramName) {
+		return "", true, nil
+	}
+
+	attrs := cb.varAttributes(paramName)
+	switch {
+	case paramDesc.unset && attrs == "":
+		return "", true, nil
+	case paramDesc.unset:
+		return "declare -" + attrs + " " + paramName, true, nil
+	case attrs == "":
+		return paramName + "=" + singleQuote(paramValue), true, nil
+	default:
+		return "declare -" + attrs + " " + paramName + "=" + singleQuote(paramValue), true, nil
+	}
+}
+
+// singleQuote wraps the value in single quotes, so that a UNIX shell
+// would read it back unchanged
+func singleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
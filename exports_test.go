@@ -0,0 +1,120 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandParamDescribesAttributes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// these results match bash after:
+	//
+	// x=1; readonly y=2; export z="it's"; export y w; set -- a b
+	env := NewEnv()
+	env.Set("x", "1")
+	env.Set("y", "2")
+	env.Set("z", "it's")
+	env.Set("$1", "a")
+	env.Export("y")
+	env.Export("z")
+	env.Export("w")
+	unit := NewExpander(env.Callbacks(), WithReadOnly("y"))
+
+	testData := map[string]string{
+		"${x@a}":    "",
+		"${x@A}":    "x='1'",
+		"${y@a}":    "rx",
+		"${y@A}":    "declare -rx y='2'",
+		"${z@A}":    `declare -x z='it'\''s'`,
+		"${w@a}":    "x",
+		"${w@A}":    "declare -x w",
+		"${nope@a}": "",
+		"${nope@A}": "",
+		"${1@A}":    "",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestFirstOfReportsExportsFromAnySource(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	globals := NewEnv()
+	globals.Set("HOST", "example.com")
+	globals.Export("HOST")
+	scope := NewScope(globals.Callbacks())
+	scope.Locals().Set("PORT", "8080")
+	unit := scope.Callbacks()
+	expectedResult := "declare -x HOST='example.com' declare -x PORT='8080'"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := unit.ExportVar("PORT")
+	assert.Nil(t, err)
+	actualResult, err := Expand("${HOST@A} ${PORT@A}", unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+
+	// exports go into the innermost scope
+	assert.True(t, scope.Locals().IsExported("PORT"))
+	assert.False(t, globals.IsExported("PORT"))
+}
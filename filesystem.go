@@ -0,0 +1,82 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"io/fs"
+	"os"
+)
+
+// WithFS sets the filesystem that the Expander uses for any expansion
+// that needs to look at files. Today, that is $(< file), which expands
+// to what is in the file. Use it to expand against a virtual filesystem
+// (such as fstest.MapFS) in your tests, or to keep untrusted templates
+// inside a sandbox.
+//
+// Every expansion that touches files must go through this filesystem;
+// none of them use the os package directly.
+//
+// Paths are looked up using the rules for fs.FS: they are relative to
+// the root of the filesystem, and use forward slashes. Absolute paths,
+// and paths that go above the root, are refused.
+//
+// $(< file) is switched on if you use WithFS(), or if you have set the
+// RunCommand callback. If you have set RunCommand without WithFS(), we
+// use os.DirFS(".").
+func WithFS(fsys fs.FS) Option {
+	return func(opts *options) {
+		opts.fsys = fsys
+	}
+}
+
+// hasFS returns true if the WithFS() option has been used
+func (cb ExpansionCallbacks) hasFS() bool {
+	return cb.opts != nil && cb.opts.fsys != nil
+}
+
+// fs returns the filesystem that any file-touching expansion must use
+//
+// in the sandbox, the real filesystem is never used
+func (cb ExpansionCallbacks) fs() fs.FS {
+	if cb.opts == nil || cb.opts.fsys == nil {
+		if cb.sandboxed() {
+			return sandboxFS{}
+		}
+		return os.DirFS(".")
+	}
+
+	return cb.opts.fsys
+}
@@ -0,0 +1,236 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFSSetsTheFilesystem(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	fsys := fstest.MapFS{
+		"etc/app.conf": &fstest.MapFile{Data: []byte("foo")},
+	}
+	unit := NewExpander(ExpansionCallbacks{}, WithFS(fsys))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := fs.ReadFile(unit.callbacks().fs(), "etc/app.conf")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", string(actualResult))
+}
+
+func TestFilesystemDefaultsToCurrentDirectory(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(ExpansionCallbacks{})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := fs.Stat(unit.callbacks().fs(), "filesystem.go")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+}
+
+func newFileReadTestFS() fstest.MapFS {
+	return fstest.MapFS{
+		"etc/app.conf":   &fstest.MapFile{Data: []byte("hello world\n\n")},
+		"etc/secret.txt": &fstest.MapFile{Data: []byte("hunter2")},
+	}
+}
+
+func TestExpandReadsFilesThroughWithFS(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"FILE": "etc/app.conf",
+		"DIR":  "etc",
+	})
+	unit := NewExpander(cb, WithFS(newFileReadTestFS()))
+	testData := "x$(< etc/app.conf)y [$(<$FILE)] [$( < ./$DIR/secret.txt )]"
+	expectedResult := "xhello worldy [hello world] [hunter2]"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandReportsFilesThatCannotBeRead(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		input       string
+		opts        []Option
+		expectedErr error
+	}{
+		{
+			input:       "$(< etc/missing.conf)",
+			opts:        []Option{WithFS(newFileReadTestFS())},
+			expectedErr: fs.ErrNotExist,
+		},
+		{
+			input:       "$(< /etc/app.conf)",
+			opts:        []Option{WithFS(newFileReadTestFS())},
+			expectedErr: fs.ErrInvalid,
+		},
+		{
+			input:       "$(< etc/../../secret.txt)",
+			opts:        []Option{WithFS(newFileReadTestFS())},
+			expectedErr: fs.ErrInvalid,
+		},
+		{
+			input:       "$(< $UNSET)",
+			opts:        []Option{WithFS(newFileReadTestFS())},
+			expectedErr: errAmbiguousRedirect,
+		},
+	}
+
+	for _, testCase := range testCases {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		unit := NewExpander(NewMapCallbacks(nil), testCase.opts...)
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(testCase.input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrCommandFailed{}), testCase.input)
+		assert.True(t, errors.Is(err, testCase.expectedErr), testCase.input)
+	}
+}
+
+func TestExpandOnlyReadsFilesWhenAsked(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "$(< go.mod)"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand(testData, ExpansionCallbacks{})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, testData, actualResult)
+}
+
+func TestExpandReadsFilesFromCurrentDirectoryWhenCommandsAreOn(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(nil)
+	cb.RunCommand = func(ctx context.Context, args []string) (string, error) {
+		return "should not be called", nil
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("$(< go.mod)", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(actualResult, "module github.com/ganbarodigital/go_shellexpand\n"))
+}
+
+func TestWithFSDoesNotSwitchOnOtherCommands(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(NewMapCallbacks(nil), WithFS(newFileReadTestFS()))
+	testData := "$(echo hello) $(< etc/secret.txt)"
+	expectedResult := "$(echo hello) hunter2"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
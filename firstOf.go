@@ -0,0 +1,146 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "context"
+
+// FirstOf returns a set of ExpansionCallbacks that look variables up in
+// each of the given sources in turn, until one of them has the variable.
+// Use it to build a chain of fallbacks, such as:
+//
+//	FirstOf(NewOSCallbacks(), fileCallbacks, NewMapCallbacks(defaults))
+//
+// Home directories are found in the same way. MatchVarNames returns the
+// matching names from all of the sources, with any duplicates removed.
+//
+// Assignments go to the first source that has an AssignToVar (or
+// AssignToVarContext) callback, and exports go to the first source that
+// has an ExportVar callback. A variable is exported if any source says
+// that it is. The first Translate and RunCommand callbacks in the chain
+// are used as they are.
+//
+// Sources that do not have a callback are skipped.
+func FirstOf(sources ...ExpansionCallbacks) ExpansionCallbacks {
+	// take a copy, so that the caller can't change the chain afterwards
+	sources = append([]ExpansionCallbacks(nil), sources...)
+
+	retval := ExpansionCallbacks{
+		LookupVarContext: func(ctx context.Context, name string) (string, bool) {
+			for _, source := range sources {
+				if source.LookupVar == nil && source.LookupVarContext == nil {
+					continue
+				}
+				source.ctx = ctx
+				value, ok := source.callLookupVar(name)
+				if ok {
+					return value, true
+				}
+			}
+
+			return "", false
+		},
+		LookupHomeDirContext: func(ctx context.Context, name string) (string, bool) {
+			for _, source := range sources {
+				var value string
+				var ok bool
+				if source.LookupHomeDirContext != nil {
+					value, ok = source.LookupHomeDirContext(ctx, name)
+				} else if source.LookupHomeDir != nil {
+					value, ok = source.LookupHomeDir(name)
+				}
+				if ok {
+					return value, true
+				}
+			}
+
+			return "", false
+		},
+		MatchVarNamesContext: func(ctx context.Context, prefix string) []string {
+			var retval []string
+
+			// we use a map to remove any duplicates
+			seen := make(map[string]bool)
+			for _, source := range sources {
+				source.ctx = ctx
+				for _, name := range source.callMatchVarNames(prefix) {
+					if !seen[name] {
+						seen[name] = true
+						retval = append(retval, name)
+					}
+				}
+			}
+
+			return retval
+		},
+		IsExported: func(name string) bool {
+			for _, source := range sources {
+				if source.isExported(name) {
+					return true
+				}
+			}
+
+			return false
+		},
+	}
+
+	for _, source := range sources {
+		if source.AssignToVar != nil || source.AssignToVarContext != nil {
+			retval.AssignToVar = source.AssignToVar
+			retval.AssignToVarContext = source.AssignToVarContext
+			break
+		}
+	}
+	for _, source := range sources {
+		if source.ExportVar != nil {
+			retval.ExportVar = source.ExportVar
+			break
+		}
+	}
+	for _, source := range sources {
+		if source.Translate != nil {
+			retval.Translate = source.Translate
+			break
+		}
+	}
+	for _, source := range sources {
+		if source.RunCommand != nil {
+			retval.RunCommand = source.RunCommand
+			break
+		}
+	}
+
+	return retval
+}
@@ -0,0 +1,211 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirstOfLooksUpVariablesInOrder(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("HOST", "example.com")
+	unit := FirstOf(
+		env.Callbacks(),
+		NewMapCallbacks(map[string]string{
+			"HOST": "localhost",
+			"PORT": "8080",
+		}),
+	)
+	expectedResult := "example.com:8080/${PATH_PREFIX}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("$HOST:$PORT/\\${PATH_PREFIX}", unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestFirstOfTreatsVariablesAsUnsetIfNoSourceHasThem(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := FirstOf(
+		NewMapCallbacks(map[string]string{"HOST": "localhost"}),
+		ExpansionCallbacks{},
+	)
+	expectedResult := "default"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${PORT:-default}", unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestFirstOfAssignsToFirstSourceThatCanAssign(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	unit := FirstOf(
+		NewMapCallbacks(map[string]string{"HOST": "localhost"}),
+		env.Callbacks(),
+	)
+	expectedResult := "8080"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${PORT:=8080}", unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, "8080", env.Get("PORT"))
+}
+
+func TestFirstOfMatchesVarNamesFromAllSources(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("APP_NAME", "shellexpand")
+	env.Set("APP_PORT", "80")
+	unit := FirstOf(
+		env.Callbacks(),
+		NewMapCallbacks(map[string]string{
+			"APP_PORT":  "8080",
+			"APP_DEBUG": "false",
+			"HOME":      "/home/stuart",
+		}),
+	)
+	expectedResult := "APP_DEBUG APP_NAME APP_PORT"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${!APP_*}", unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestFirstOfPassesContextToSources(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "from context")
+	unit := FirstOf(
+		ExpansionCallbacks{
+			LookupVarContext: func(ctx context.Context, name string) (string, bool) {
+				retval, ok := ctx.Value(ctxKey{}).(string)
+				return retval, ok
+			},
+		},
+	)
+	expectedResult := "from context"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandContext(ctx, "$ANYTHING", unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestFirstOfLooksUpHomeDirsInOrder(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := FirstOf(
+		NewMapCallbacks(nil),
+		ExpansionCallbacks{
+			LookupHomeDir: func(name string) (string, bool) {
+				return "/home/" + name, true
+			},
+		},
+	)
+	expectedResult := "/home/stuart/bin"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("~stuart/bin", unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
@@ -0,0 +1,149 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuncMapExpandsStringsInTextTemplates(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			if key == "HOME" {
+				return "/home/alfred", true
+			}
+			return "", false
+		},
+	}
+	expander := NewExpander(cb)
+	tmpl := template.Must(
+		template.New("test").
+			Funcs(expander.FuncMap()).
+			Parse(`{{ shellexpand .Path }}|{{ range shellexpandArgs .Args }}[{{ . }}]{{ end }}`),
+	)
+	data := map[string]string{
+		"Path": "${HOME}/bin",
+		"Args": `ls "$HOME" ${UNSET:-a b}`,
+	}
+	expectedResult := "/home/alfred/bin|[ls][/home/alfred][a][b]"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	var buf strings.Builder
+	err := tmpl.Execute(&buf, data)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, buf.String())
+}
+
+func TestFuncMapWorksWithHTMLTemplates(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			if key == "GREETING" {
+				return "<hello>", true
+			}
+			return "", false
+		},
+	}
+	expander := NewExpander(cb)
+	tmpl := htmltemplate.Must(
+		htmltemplate.New("test").
+			Funcs(expander.FuncMap()).
+			Parse(`<p>{{ shellexpand "$GREETING" }}</p>`),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	var buf strings.Builder
+	err := tmpl.Execute(&buf, nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "<p>&lt;hello&gt;</p>", buf.String())
+}
+
+func TestFuncMapStopsTheTemplateIfExpansionFails(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "", false
+		},
+	}
+	expander := NewExpander(cb)
+	tmpl := template.Must(
+		template.New("test").
+			Funcs(expander.FuncMap()).
+			Parse(`{{ shellexpand "${HOME:?not set}" }}`),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	var buf strings.Builder
+	err := tmpl.Execute(&buf, nil)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "HOME: not set")
+}
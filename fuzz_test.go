@@ -0,0 +1,219 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+//go:build go1.18
+// +build go1.18
+
+package shellexpand
+
+import (
+	"strings"
+	"testing"
+)
+
+// fuzzSeeds are the inputs that every fuzz target starts from
+var fuzzSeeds = []string{
+	"",
+	"$",
+	"${",
+	"${}",
+	"${#",
+	"${!",
+	"${:",
+	"$$",
+	"$1",
+	"${#}",
+	"${#*}",
+	"${!PARAM*}",
+	"${PARAM",
+	"${PARAM:",
+	"${PARAM:-",
+	"${PARAM:-word}",
+	"${PARAM:=word}",
+	"${PARAM:?word}",
+	"${PARAM:+word}",
+	"${PARAM:1:2}",
+	"${PARAM: -1}",
+	"${PARAM#*.}",
+	"${PARAM##*.}",
+	"${PARAM%.*}",
+	"${PARAM%%.*}",
+	"${PARAM^}",
+	"${PARAM^^}",
+	"${PARAM,}",
+	"${PARAM,,}",
+	"${PARAM/a/b}",
+	"${!PARAM}",
+	"${@}",
+	"${*%.*}",
+	"{",
+	"}",
+	"{,}",
+	"a{b,c}d",
+	"a{b,{c,d}}e",
+	"{a..e}",
+	"{1..10..2}",
+	"~",
+	"~root/",
+	"~+",
+	"\\$PARAM",
+	"\xff",
+	"$\xff",
+	"${\xff}",
+	"${#\xff}",
+	"é{a,ü}",
+	"${(U)PARAM}",
+	"${(s:.:)PARAM}",
+	"${(j",
+	"${(",
+	"${'}",
+	`${"}`,
+	"*${))'#}",
+}
+
+// fuzzCallbacks returns a set of callbacks backed by a small, fixed
+// set of variables
+func fuzzCallbacks() ExpansionCallbacks {
+	vars := map[string]string{
+		"PARAM": "foo.tar.gz",
+		"EMPTY": "",
+		"UTF8":  "héllo wörld",
+		"NAME":  "PARAM",
+		"$#":    "2",
+		"$1":    "one",
+		"$2":    "two.txt",
+		"HOME":  "/home/fuzz",
+	}
+	return ExpansionCallbacks{
+		AssignToVar: func(key, value string) error {
+			vars[key] = value
+			return nil
+		},
+		LookupVar: func(key string) (string, bool) {
+			value, ok := vars[key]
+			return value, ok
+		},
+		LookupHomeDir: func(user string) (string, bool) {
+			return "/home/" + user, user != ""
+		},
+		MatchVarNames: func(prefix string) []string {
+			var retval []string
+			for key := range vars {
+				if strings.HasPrefix(key, prefix) {
+					retval = append(retval, key)
+				}
+			}
+			return retval
+		},
+	}
+}
+
+// tooExpensiveToFuzz returns true for inputs that can legitimately
+// take a very long time to expand, such as {1..99999999}
+func tooExpensiveToFuzz(input string) bool {
+	return len(input) > 256 ||
+		strings.Contains(input, "..") ||
+		strings.Count(input, "{") > 6
+}
+
+func FuzzExpandBraces(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		if tooExpensiveToFuzz(input) {
+			t.Skip()
+		}
+
+		expandBraces(input, nil)
+	})
+}
+
+func FuzzParseParameter(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		desc, ok := parseParameter(input)
+		if ok && len(desc.parts) == 0 {
+			t.Errorf("parseParameter(%q) succeeded, but returned no parts", input)
+		}
+	})
+}
+
+func FuzzMatchVar(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		end, ok := matchVar(input)
+		if !ok {
+			return
+		}
+		if end < 1 || end > len(input) {
+			t.Errorf("matchVar(%q) returned out-of-range end %d", input, end)
+		}
+	})
+}
+
+func FuzzExpand(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		if tooExpensiveToFuzz(input) {
+			t.Skip()
+		}
+
+		Expand(input, fuzzCallbacks())
+		ExpandArgs(input, fuzzCallbacks())
+		ExpandTilde(input, fuzzCallbacks())
+		Validate(input)
+		SplitWords(input)
+		BraceExpand(input)
+
+		// the other code paths that Expander options switch on
+		NewExpander(fuzzCallbacks(), WithStrict()).Expand(input)
+		NewExpander(fuzzCallbacks(), WithByteOffsets()).Expand(input)
+		NewExpander(fuzzCallbacks(), WithTrace(func(TraceEvent) {})).Expand(input)
+		for _, dialect := range []Dialect{DialectPOSIX, DialectZsh} {
+			NewExpander(fuzzCallbacks(), WithDialect(dialect)).ExpandArgs(input)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"sync"
+
+	glob "github.com/ganbarodigital/go_glob"
+)
+
+// the different ways that we match glob patterns
+const (
+	globMatchWhole = iota
+	globMatchShortestPrefix
+	globMatchLongestPrefix
+	globMatchShortestSuffix
+	globMatchLongestSuffix
+)
+
+// defaultGlobCacheSize is how many compiled glob patterns an Expander
+// remembers, unless you use WithGlobCacheSize()
+const defaultGlobCacheSize = 256
+
+// globCache remembers compiled glob patterns, so that we do not have to
+// keep compiling the same regexps over and over again
+//
+// the zero value (nil) is valid, and simply means "no caching"
+type globCache struct {
+	mu sync.Mutex
+
+	// the compiled globs, keyed by pattern and how they are matched
+	globs map[globCacheKey]*glob.Glob
+
+	// the keys of `globs`, in the order they were added, so that we
+	// know which one to throw away when the cache is full
+	keys []globCacheKey
+
+	// where the next key goes in `keys`, once the cache is full
+	next int
+
+	// how many globs we can remember
+	maxSize int
+
+	// how often we found (or did not find) a glob in the cache
+	hits   int64
+	misses int64
+}
+
+type globCacheKey struct {
+	pattern   string
+	matchType int
+}
+
+func newGlobCache(maxSize int) *globCache {
+	if maxSize < 1 {
+		return nil
+	}
+
+	return &globCache{
+		globs:   make(map[globCacheKey]*glob.Glob),
+		maxSize: maxSize,
+	}
+}
+
+// get returns a glob for the given pattern, that is ready to be matched
+// using the given matchType
+//
+// the glob package compiles its regexps on first use, and that is not
+// safe to do from multiple goroutines at once. We compile them before
+// the glob goes into the cache; after that, it is only ever read from.
+func (c *globCache) get(pattern string, matchType int) (*glob.Glob, error) {
+	// are we caching?
+	if c == nil {
+		return compileGlob(pattern, matchType)
+	}
+
+	key := globCacheKey{pattern, matchType}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// have we seen this before?
+	retval, ok := c.globs[key]
+	if ok {
+		c.hits++
+		return retval, nil
+	}
+	c.misses++
+
+	retval, err := compileGlob(pattern, matchType)
+	if err != nil {
+		return nil, err
+	}
+
+	// make room if we need to
+	if len(c.keys) < c.maxSize {
+		c.keys = append(c.keys, key)
+	} else {
+		delete(c.globs, c.keys[c.next])
+		c.keys[c.next] = key
+		c.next = (c.next + 1) % c.maxSize
+	}
+	c.globs[key] = retval
+
+	return retval, nil
+}
+
+// stats returns how many times we have found a glob in the cache, and
+// how many times we have not
+func (c *globCache) stats() (int64, int64) {
+	if c == nil {
+		return 0, 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.hits, c.misses
+}
+
+// len returns how many globs are in the cache
+func (c *globCache) len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.globs)
+}
+
+// compileGlob creates a new glob, and compiles it for the given matchType
+func compileGlob(pattern string, matchType int) (*glob.Glob, error) {
+	retval := glob.NewGlob(translateBracketExpressions(pattern))
+
+	var err error
+	switch matchType {
+	case globMatchWhole:
+		_, err = retval.Match("")
+	case globMatchShortestPrefix:
+		_, _, err = retval.MatchShortestPrefix("")
+	case globMatchLongestPrefix:
+		_, _, err = retval.MatchLongestPrefix("")
+	case globMatchShortestSuffix:
+		_, _, err = retval.MatchShortestSuffix("")
+	case globMatchLongestSuffix:
+		_, _, err = retval.MatchLongestSuffix("")
+	}
+	if err != nil {
+		return nil, ErrBadPattern{pattern, err}
+	}
+
+	return retval, nil
+}
+
+// WithGlobCacheSize sets how many compiled glob patterns the Expander
+// remembers. Use 0 to turn the cache off.
+//
+// By default, an Expander remembers 256 compiled glob patterns.
+func WithGlobCacheSize(size int) Option {
+	return func(opts *options) {
+		opts.globCacheSize = size
+	}
+}
@@ -0,0 +1,224 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobCacheReusesCompiledGlobs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newGlobCache(10)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	g1, err1 := unit.get("f*", globMatchWhole)
+	g2, err2 := unit.get("f*", globMatchWhole)
+	g3, err3 := unit.get("f*", globMatchLongestPrefix)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Nil(t, err3)
+	assert.Same(t, g1, g2)
+	assert.False(t, g1 == g3)
+	assert.Equal(t, 2, unit.len())
+}
+
+func TestGlobCacheIsSizeBounded(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newGlobCache(2)
+	g1, _ := unit.get("a*", globMatchWhole)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	unit.get("b*", globMatchWhole)
+	unit.get("c*", globMatchWhole)
+	actualResult, _ := unit.get("a*", globMatchWhole)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, 2, unit.len())
+	assert.False(t, g1 == actualResult)
+}
+
+func TestGlobCacheDoesNotRememberBadPatterns(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newGlobCache(10)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.get("[", globMatchWhole)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrBadPattern{}))
+	assert.Equal(t, 0, unit.len())
+}
+
+func TestNilGlobCacheStillCompilesGlobs(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var unit *globCache
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	g, err := unit.get("f*", globMatchWhole)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	success, _ := g.Match("foo")
+	assert.True(t, success)
+	assert.Equal(t, 0, unit.len())
+}
+
+func TestExpanderCachesGlobPatterns(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander()
+	expectedResult := "FOO fOO oo"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("${PARAM1^^[a-z]} ${PARAM1^^[o]} ${PARAM1#f}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, 3, unit.globs.len())
+}
+
+func TestExpanderWithGlobCacheSizeZeroDisablesTheCache(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithGlobCacheSize(0))
+	expectedResult := "FOO"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("${PARAM1^^[a-z]}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Nil(t, unit.globs)
+}
+
+func TestExpanderGlobCacheIsSafeForConcurrentUse(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestExpander(WithGlobCacheSize(4))
+	inputs := []string{
+		"${PARAM1^^[a-z]}",
+		"${PARAM1,,[A-Z]}",
+		"${PARAM1#f}",
+		"${PARAM1##f*}",
+		"${PARAM1%o}",
+		"${PARAM1%%o*}",
+	}
+	expectedResults := []string{"FOO", "foo", "oo", "", "fo", "f"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	var wg sync.WaitGroup
+	actualResults := make([][]string, 8)
+	for i := range actualResults {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				actualResults[i] = actualResults[i][:0]
+				for _, input := range inputs {
+					result, _ := unit.Expand(input)
+					actualResults[i] = append(actualResults[i], result)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	for _, actualResult := range actualResults {
+		assert.Equal(t, expectedResults, actualResult)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/ganbarodigital/go_shellexpand
 
-go 1.13
+go 1.16
 
 require (
 	github.com/ganbarodigital/go_glob v1.0.0
@@ -0,0 +1,397 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// HistoryProvider returns an entry from the command history, for csh-style
+// history expansion. A positive n is the entry's history number (as in
+// !n); a negative n counts back from the most recent entry, so -1 is
+// the previous command (!!).
+//
+// It returns ("", false) if there is no such entry.
+type HistoryProvider func(n int) (string, bool)
+
+// WithHistory switches on history expansion, using the given provider
+// to look up earlier commands. It is for interactive-shell emulators;
+// leave it off for anything else.
+//
+// History expansion happens before every other phase, just like it
+// does in bash. We support:
+//
+//   - event designators: !!, !n, !-n, !string and !?string?
+//   - word designators: :n, :^, :$, :*, :x-y, :x* and :x-, plus the
+//     !^, !$ and !* shortcuts
+//   - the :h, :t, :r and :e modifiers
+//
+// A ! followed by a blank, =, ( or the end of the input is left as it
+// is, as is a ! inside single quotes or after a backslash. Just like
+// bash, the ! in $!, ${!name}, ${!prefix*} and [!...] is left alone too. An event
+// that cannot be found returns ErrEventNotFound, and a word that does
+// not exist returns ErrBadWordSpecifier.
+func WithHistory(provider HistoryProvider) Option {
+	return func(opts *options) {
+		opts.history = provider
+	}
+}
+
+// history returns our history provider, or nil if history expansion is
+// switched off
+func (cb ExpansionCallbacks) history() HistoryProvider {
+	if cb.opts == nil {
+		return nil
+	}
+
+	return cb.opts.history
+}
+
+// expandHistory performs history expansion on the input, if it has
+// been switched on
+func expandHistory(input string, cb ExpansionCallbacks) (string, error) {
+	if cb.history() == nil {
+		return input, nil
+	}
+
+	expanded, err := expandHistoryReferences(input, cb.history())
+	if err != nil {
+		return "", err
+	}
+	tracePhase(cb, PhaseHistoryExpansion, input, expanded)
+
+	return expanded, nil
+}
+
+// expandHistoryReferences replaces every history reference in the input
+func expandHistoryReferences(input string, provider HistoryProvider) (string, error) {
+	// nothing to do?
+	if strings.IndexByte(input, '!') < 0 {
+		return input, nil
+	}
+
+	var buf strings.Builder
+	inSingleQuotes := false
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == '\'':
+			inSingleQuotes = !inSingleQuotes
+		case c == '\\' && !inSingleQuotes && i+1 < len(input):
+			// escaped characters are copied across as they are
+			buf.WriteString(input[i : i+2])
+			i++
+			continue
+		case c == '!' && !inSingleQuotes && !isHistoryInhibited(input, i):
+			value, end, err := expandHistoryReference(input[i:], provider)
+			if err != nil {
+				return "", newExpansionError(PhaseHistoryExpansion, input, i, i+end, err)
+			}
+			if end > 0 {
+				buf.WriteString(value)
+				i += end - 1
+				continue
+			}
+		}
+		buf.WriteByte(c)
+	}
+
+	return buf.String(), nil
+}
+
+// isHistoryInhibited returns true if the ! at input[i] is part of
+// another expansion, just like bash's bash_history_inhibit_expansion()
+//
+// that is:
+//
+// - $! (the PID of the last background job)
+// - ${!name} and ${!prefix*} (indirection and prefix listing)
+// - [!...] (a negated bracket expression in a pattern)
+func isHistoryInhibited(input string, i int) bool {
+	switch {
+	case i > 0 && input[i-1] == '$':
+		return true
+	case i > 1 && input[i-1] == '{' && input[i-2] == '$':
+		return true
+	case i > 0 && input[i-1] == '[':
+		return strings.IndexByte(input[i+1:], ']') >= 0
+	}
+
+	return false
+}
+
+// expandHistoryReference expands the history reference at the start of
+// the input, and tells you how long it was
+//
+// it returns a length of 0 if the ! is not the start of a history
+// reference
+func expandHistoryReference(input string, provider HistoryProvider) (string, int, error) {
+	// a ! on its own is just a !
+	if len(input) < 2 || isHistoryTerminator(input[1]) {
+		return "", 0, nil
+	}
+
+	// step 1: which history entry?
+	entry, i, err := findHistoryEvent(input, provider)
+	if err != nil {
+		return "", i, err
+	}
+
+	// step 2: which words from that entry?
+	words, i, err := selectHistoryWords(input, i, entry)
+	if err != nil {
+		return "", i, err
+	}
+	retval := strings.Join(words, " ")
+
+	// step 3: any modifiers?
+	for i+1 < len(input) && input[i] == ':' && strings.IndexByte("htre", input[i+1]) >= 0 {
+		retval = applyHistoryModifier(retval, input[i+1])
+		i += 2
+	}
+
+	return retval, i, nil
+}
+
+// findHistoryEvent finds the history entry that the event designator at
+// the start of the input refers to, and tells you where the event
+// designator ends
+func findHistoryEvent(input string, provider HistoryProvider) (string, int, error) {
+	i := 1
+	switch {
+	case input[i] == '!':
+		// !! is the previous command
+		i++
+		return lookupHistoryEvent(input[:i], -1, i, provider)
+	case strings.IndexByte("^$*", input[i]) >= 0:
+		// !^, !$ and !* are words from the previous command; we leave
+		// the word designator for selectHistoryWords()
+		return lookupHistoryEvent("!!", -1, i, provider)
+	case input[i] == '-' || isNumericChar(rune(input[i])):
+		// !n and !-n
+		start := i
+		if input[i] == '-' {
+			i++
+		}
+		for i < len(input) && isNumericChar(rune(input[i])) {
+			i++
+		}
+		n, err := strconv.Atoi(input[start:i])
+		if err != nil || n == 0 {
+			return "", i, ErrEventNotFound{input[:i]}
+		}
+		return lookupHistoryEvent(input[:i], n, i, provider)
+	case input[i] == '?':
+		// !?string? is the most recent command that contains string
+		end := strings.IndexByte(input[2:], '?')
+		search := input[2:]
+		i = len(input)
+		if end >= 0 {
+			search = input[2 : 2+end]
+			i = 2 + end + 1
+		}
+		return searchHistory(input[:i], i, provider, func(entry string) bool {
+			return strings.Contains(entry, search)
+		})
+	default:
+		// !string is the most recent command that starts with string
+		for i < len(input) && !isHistoryTerminator(input[i]) && input[i] != ':' {
+			i++
+		}
+		search := input[1:i]
+		return searchHistory(input[:i], i, provider, func(entry string) bool {
+			return strings.HasPrefix(entry, search)
+		})
+	}
+}
+
+// lookupHistoryEvent asks the provider for history entry n
+func lookupHistoryEvent(event string, n int, end int, provider HistoryProvider) (string, int, error) {
+	entry, ok := provider(n)
+	if !ok {
+		return "", end, ErrEventNotFound{event}
+	}
+
+	return entry, end, nil
+}
+
+// searchHistory finds the most recent history entry that matches
+func searchHistory(event string, end int, provider HistoryProvider, matches func(string) bool) (string, int, error) {
+	for n := -1; ; n-- {
+		entry, ok := provider(n)
+		if !ok {
+			return "", end, ErrEventNotFound{event}
+		}
+		if matches(entry) {
+			return entry, end, nil
+		}
+	}
+}
+
+// selectHistoryWords returns the words that the word designator at
+// input[i:] picks out of the history entry, and tells you where the
+// word designator ends
+//
+// without a word designator, you get the whole entry
+func selectHistoryWords(input string, i int, entry string) ([]string, int, error) {
+	start := i
+	if i < len(input) && input[i] == ':' {
+		i++
+	} else if i >= len(input) || strings.IndexByte("^$*", input[i]) < 0 {
+		return []string{entry}, i, nil
+	}
+
+	rawWords, err := splitWords(entry)
+	if err != nil {
+		return nil, i, err
+	}
+	words := make([]string, len(rawWords))
+	for j, word := range rawWords {
+		words[j] = word.text
+	}
+	last := len(words) - 1
+
+	// parseWordNumber reads a word number from input[i:]
+	parseWordNumber := func() (int, bool) {
+		if i >= len(input) {
+			return 0, false
+		}
+		switch input[i] {
+		case '^':
+			i++
+			return 1, true
+		case '$':
+			i++
+			return last, true
+		}
+		numStart := i
+		for i < len(input) && isNumericChar(rune(input[i])) {
+			i++
+		}
+		if i == numStart {
+			return 0, false
+		}
+		n, _ := strconv.Atoi(input[numStart:i])
+		return n, true
+	}
+
+	var from, to int
+	switch {
+	case i < len(input) && input[i] == '*':
+		// every word except the command itself; this can be empty
+		i++
+		if last < 1 {
+			return nil, i, nil
+		}
+		return words[1:], i, nil
+	case i < len(input) && input[i] == '-':
+		// -y is short for 0-y
+		from = 0
+	default:
+		var ok bool
+		from, ok = parseWordNumber()
+		if !ok {
+			// a : that is not followed by a word designator might be
+			// a modifier
+			if input[start] == ':' && i < len(input) && strings.IndexByte("htre", input[i]) >= 0 {
+				return []string{entry}, start, nil
+			}
+			return nil, i, ErrBadWordSpecifier{input[:i]}
+		}
+	}
+	to = from
+
+	// is it a range?
+	if i < len(input) && input[i] == '*' {
+		i++
+		to = last
+	} else if i < len(input) && input[i] == '-' {
+		i++
+		var ok bool
+		to, ok = parseWordNumber()
+		if !ok {
+			// x- leaves out the last word
+			to = last - 1
+		}
+	}
+
+	if from < 0 || from > last || to > last || to < from {
+		return nil, i, ErrBadWordSpecifier{input[:i]}
+	}
+
+	return words[from : to+1], i, nil
+}
+
+// applyHistoryModifier applies the :h, :t, :r or :e modifier
+func applyHistoryModifier(value string, modifier byte) string {
+	switch modifier {
+	case 'h':
+		// remove the last pathname component
+		slash := strings.LastIndexByte(value, '/')
+		if slash < 0 {
+			return value
+		}
+		if slash == 0 {
+			return "/"
+		}
+		return value[:slash]
+	case 't':
+		// remove everything but the last pathname component
+		return value[strings.LastIndexByte(value, '/')+1:]
+	case 'r':
+		// remove the suffix
+		ext := path.Ext(value)
+		return value[:len(value)-len(ext)]
+	case 'e':
+		// remove everything but the suffix
+		return path.Ext(value)
+	default:
+		return value
+	}
+}
+
+// isHistoryTerminator returns true if a ! followed by this character
+// is not a history reference
+func isHistoryTerminator(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '=', '(', '"', '\'':
+		return true
+	default:
+		return false
+	}
+}
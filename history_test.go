@@ -0,0 +1,248 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testHistory is a HistoryProvider for our tests
+func testHistory(entries ...string) HistoryProvider {
+	return func(n int) (string, bool) {
+		if n < 0 {
+			n = len(entries) + n + 1
+		}
+		if n < 1 || n > len(entries) {
+			return "", false
+		}
+		return entries[n-1], true
+	}
+}
+
+func TestWithHistoryExpandsHistoryReferences(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// these results match bash's `history -p`
+	unit := NewExpander(
+		NewEnv().Callbacks(),
+		WithHistory(testHistory("ls -l /tmp/a.txt", "echo one two three")),
+	)
+
+	testData := map[string]string{
+		"!!":        "echo one two three",
+		"!$":        "three",
+		"!^":        "one",
+		"!*":        "one two three",
+		"!ls":       "ls -l /tmp/a.txt",
+		"!?tmp?":    "ls -l /tmp/a.txt",
+		"!-2:1":     "-l",
+		"!!:1-2":    "one two",
+		"!!:2*":     "two three",
+		"!!:1-":     "one two",
+		"!ls:2:h":   "/tmp",
+		"!ls:$:t":   "a.txt",
+		"!ls:$:r":   "/tmp/a",
+		"!ls:$:e":   ".txt",
+		"x!":        "x!",
+		"a != b":    "a != b",
+		"!e:0":      "echo",
+		"!!$":       "three",
+		"!1":        "ls -l /tmp/a.txt",
+		"sudo !!":   "sudo echo one two three",
+		"'!!' \\!!": "'!!' !!",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestWithHistoryReturnsErrorsForMissingEntries(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(
+		NewEnv().Callbacks(),
+		WithHistory(testHistory("ls -l /tmp/a.txt", "echo one two three")),
+	)
+
+	testData := map[string]error{
+		"!zz":    ErrEventNotFound{},
+		"!3":     ErrEventNotFound{},
+		"!-3":    ErrEventNotFound{},
+		"!!:9":   ErrBadWordSpecifier{},
+		"!!:3-2": ErrBadWordSpecifier{},
+	}
+
+	for input, expectedErr := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, expectedErr), input)
+
+		var expErr ExpansionError
+		assert.True(t, errors.As(err, &expErr), input)
+		assert.Equal(t, PhaseHistoryExpansion, expErr.Phase, input)
+	}
+}
+
+func TestWithHistoryLeavesOtherUsesOfExclamationMarkAlone(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// these results match `bash -i`
+	env := NewEnv()
+	env.Set("R", "X")
+	env.Set("X", "banana")
+	env.Set("X2", "cherry")
+	unit := NewExpander(
+		env.Callbacks(),
+		WithHistory(testHistory("ls -l /tmp/a.txt", "echo one two three")),
+	)
+
+	testData := map[string]string{
+		"${!R}":         "banana",
+		"${!X*}":        "X X2",
+		"${!X@}":        "X X2",
+		"${X//[!a]/_}":  "_a_a_a",
+		"[!ab] !!":      "[!ab] echo one two three",
+		"[!ls":          "[ls -l /tmp/a.txt",
+		"${!R} and !ls": "banana and ls -l /tmp/a.txt",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestHistoryExpansionLeavesLastBackgroundPIDAlone(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "kill $!ls"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expandHistoryReferences(input, testHistory("ls -l"))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, input, actualResult)
+}
+
+func TestWithHistoryHappensBeforeOtherPhases(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("HOME", "/home/stuart")
+	unit := NewExpander(
+		env.Callbacks(),
+		WithHistory(testHistory("cd $HOME/{a,b}")),
+	)
+	expectedResult := []string{"ls", "/home/stuart/a", "/home/stuart/b"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandArgs("ls !$")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestHistoryExpansionIsOffByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expectedResult := "!!"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("!!", NewEnv().Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
@@ -0,0 +1,120 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// InvalidInputMode controls what the Expander does with input that
+// contains NUL bytes or invalid UTF-8
+type InvalidInputMode int
+
+// these are the ways that we can handle NUL bytes and invalid UTF-8
+const (
+	// InvalidInputPassThrough copies NUL bytes and invalid UTF-8 into
+	// the output exactly as they are. It is the default.
+	//
+	// They are never part of a variable name or an operator, so they
+	// are treated like any other plain text.
+	InvalidInputPassThrough InvalidInputMode = iota
+
+	// InvalidInputReplace replaces each NUL byte, and each byte that is
+	// not valid UTF-8, with the Unicode replacement character (U+FFFD)
+	// before the input is expanded
+	InvalidInputReplace
+
+	// InvalidInputReject returns an ErrInvalidInput if the input
+	// contains a NUL byte or invalid UTF-8. Nothing is expanded.
+	InvalidInputReject
+)
+
+func (m InvalidInputMode) String() string {
+	switch m {
+	case InvalidInputPassThrough:
+		return "pass-through"
+	case InvalidInputReplace:
+		return "replace"
+	case InvalidInputReject:
+		return "reject"
+	default:
+		return "unknown invalid input mode"
+	}
+}
+
+// WithInvalidInput changes what the Expander does with input that
+// contains NUL bytes or invalid UTF-8
+//
+// It only looks at the input string. The values that your callbacks
+// return are always passed through as they are.
+func WithInvalidInput(mode InvalidInputMode) Option {
+	return func(opts *options) {
+		opts.invalidInput = mode
+	}
+}
+
+// checkInput applies the WithInvalidInput() option to the input string
+//
+// it returns the input that we should expand
+func checkInput(input string, cb ExpansionCallbacks) (string, error) {
+	mode := cb.invalidInput()
+	if mode == InvalidInputPassThrough {
+		return input, nil
+	}
+
+	// fast path: most input is perfectly fine
+	if strings.IndexByte(input, 0) < 0 && utf8.ValidString(input) {
+		return input, nil
+	}
+
+	var buf strings.Builder
+	for i, w := 0, 0; i < len(input); i += w {
+		var c rune
+		c, w = utf8.DecodeRuneInString(input[i:])
+		if c != 0 && (c != utf8.RuneError || w > 1) {
+			buf.WriteString(input[i : i+w])
+			continue
+		}
+
+		if mode == InvalidInputReject {
+			return "", ErrInvalidInput{Offset: i, Byte: input[i]}
+		}
+		buf.WriteRune(utf8.RuneError)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,196 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandPassesInvalidInputThroughByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"X": "h\xffllo"})
+	testData := "a\xffb\x00 ${X^^} \\\xfe"
+	expectedResult := "a\xffb\x00 H\xffLLO \xfe"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandArgsPassesInvalidInputThroughByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"X": "h\xffllo w\x00rld"})
+	testData := "a\xffb \"\\\xfe\" \\\xfd $X"
+	expectedResult := []string{"a\xffb", "\\\xfe", "\xfd", "h\xffllo", "w\x00rld"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandArgs(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestWithInvalidInputReplaceReplacesBadBytes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(
+		NewMapCallbacks(map[string]string{"X": "h\xffllo"}),
+		WithInvalidInput(InvalidInputReplace),
+	)
+	testData := "a\xffb\x00 $X \xe2\x82"
+	expectedResult := "a�b� h\xffllo ��"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+	args, argsErr := unit.ExpandArgs(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Nil(t, argsErr)
+	assert.Equal(t, []string{"a�b�", "h\xffllo", "��"}, args)
+}
+
+func TestWithInvalidInputRejectReturnsErrInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(
+		NewMapCallbacks(map[string]string{"X": "h\xffllo"}),
+		WithInvalidInput(InvalidInputReject),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	goodResult, goodErr := unit.Expand("$X café")
+	_, nulErr := unit.Expand("$X\x00")
+	_, utf8Err := unit.ExpandArgs("caf\xe9 $X")
+	_, letErr := unit.EvalLet([]string{"1 +\x00 1"})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, goodErr)
+	assert.Equal(t, "h\xffllo café", goodResult)
+	assert.Equal(t, ErrInvalidInput{Offset: 2, Byte: 0}, nulErr)
+	assert.Equal(t, ErrInvalidInput{Offset: 3, Byte: 0xe9}, utf8Err)
+	assert.True(t, errors.Is(letErr, ErrInvalidInput{}))
+}
+
+func TestInvalidInputModeString(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[InvalidInputMode]string{
+		InvalidInputPassThrough: "pass-through",
+		InvalidInputReplace:     "replace",
+		InvalidInputReject:      "reject",
+		InvalidInputMode(-1):    "unknown invalid input mode",
+	}
+
+	for mode, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := mode.String()
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult)
+	}
+}
+
+func TestErrInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[string]ErrInvalidInput{
+		"NUL byte at offset 3":                {Offset: 3},
+		"invalid UTF-8 byte 0xff at offset 7": {Offset: 7, Byte: 0xff},
+	}
+
+	for expectedResult, err := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := err.Error()
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult)
+		assert.True(t, errors.Is(err, ErrInvalidInput{}))
+		assert.False(t, errors.Is(err, ErrBadSubstitution{}))
+	}
+}
@@ -0,0 +1,85 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+// lazyWord is the word that follows an operator such as ${var:-word}
+//
+// the word is only expanded when an expansion function actually needs
+// it, and it is only ever expanded once, no matter how many values
+// (e.g. from $@) the operator is applied to. This stops the unused
+// branch from having side effects, such as the assignment in
+// ${SET:-${UNSET:=value}}
+type lazyWord struct {
+	// the unexpanded word
+	text string
+
+	// how we expand the word; if nil, we use expandWord()
+	expandFunc func(string, ExpansionCallbacks) (string, error)
+
+	// have we expanded it yet?
+	done bool
+
+	// the results of expanding it
+	value string
+	err   error
+}
+
+// newLazyWord returns a lazyWord for the given (unexpanded) word
+func newLazyWord(text string) *lazyWord {
+	return &lazyWord{text: text}
+}
+
+// expand returns the expansion of the word, expanding it the first
+// time we are called
+func (w *lazyWord) expand(cb ExpansionCallbacks) (string, error) {
+	if !w.done {
+		expandFunc := w.expandFunc
+		if expandFunc == nil {
+			expandFunc = expandWord
+		}
+		w.value, w.err = expandFunc(w.text, cb)
+		w.done = true
+	}
+
+	return w.value, w.err
+}
+
+// set stores the expansion of the word, when someone else has expanded
+// it for us
+func (w *lazyWord) set(value string) {
+	w.value = value
+	w.done = true
+}
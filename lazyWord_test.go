@@ -0,0 +1,205 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandDoesNotExpandUnusedDefaultValue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"SET": "value",
+	}
+	assignments := map[string]int{}
+	cb := countAssignments(newTestCallbacks(vars), assignments)
+	expectedResult := "value"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${SET:-${OTHER:=assigned}}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Empty(t, assignments)
+	_, ok := vars["OTHER"]
+	assert.False(t, ok)
+}
+
+func TestExpandDoesNotExpandUnusedAlternativeValue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{}
+	assignments := map[string]int{}
+	cb := countAssignments(newTestCallbacks(vars), assignments)
+	expectedResult := ""
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${UNSET:+${OTHER:=assigned}}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Empty(t, assignments)
+}
+
+func TestExpandDoesNotExpandUnusedErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"SET": "value",
+	}
+	assignments := map[string]int{}
+	cb := countAssignments(newTestCallbacks(vars), assignments)
+	expectedResult := "value"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${SET:?${OTHER:=assigned}}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Empty(t, assignments)
+}
+
+func TestExpandExpandsUsedDefaultValue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{}
+	assignments := map[string]int{}
+	cb := countAssignments(newTestCallbacks(vars), assignments)
+	expectedResult := "assigned"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${UNSET:-${OTHER:=assigned}}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, map[string]int{"OTHER": 1}, assignments)
+}
+
+func TestExpandExpandsOperatorWordOnceForPositionalParams(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"$#":   "3",
+		"$1":   "one",
+		"$2":   "two",
+		"$3":   "three",
+		"WORD": "word",
+	}
+	lookups := map[string]int{}
+	cb := countLookups(NewMapCallbacks(vars), lookups)
+	expectedResult := "word word word"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${@:+$WORD}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, 1, lookups["WORD"])
+}
+
+func TestLazyWordOnlyExpandsOnce(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	lookups := 0
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			lookups++
+			return "value", true
+		},
+	}
+	unit := newLazyWord("$VAR")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	first, err1 := unit.expand(cb)
+	second, err2 := unit.expand(cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.Equal(t, "value", first)
+	assert.Equal(t, "value", second)
+	assert.Equal(t, 1, lookups)
+}
@@ -0,0 +1,313 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenKind tells you what a Token is
+type TokenKind int
+
+// these are the kinds of Token that a Lexer produces
+const (
+	// TokenLiteral is text that expansion does not change, including
+	// any quotes and backslash escapes
+	TokenLiteral TokenKind = iota + 1
+
+	// TokenVarRef is a variable reference, such as $VAR or ${VAR}.
+	// If the ${...} has an operator, this is the part up to the
+	// operator, e.g. ${VAR in ${VAR:-word}.
+	TokenVarRef
+
+	// TokenOperator is the operator inside a ${...}, such as :- or
+	// //, and the } that closes it. The tokens in between are the
+	// operator's word.
+	TokenOperator
+
+	// TokenBraceGroup is a brace expansion, such as {a,b} or {1..5}
+	TokenBraceGroup
+
+	// TokenTildePrefix is a tilde prefix, such as ~ or ~user
+	TokenTildePrefix
+
+	// TokenArithmetic is an arithmetic expansion, such as $((1+2)) or
+	// $[1+2], from the opening $ to the closing )) or ]
+	TokenArithmetic
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenLiteral:
+		return "literal"
+	case TokenVarRef:
+		return "var-ref"
+	case TokenOperator:
+		return "operator"
+	case TokenBraceGroup:
+		return "brace-group"
+	case TokenTildePrefix:
+		return "tilde-prefix"
+	case TokenArithmetic:
+		return "arithmetic"
+	default:
+		return "unknown"
+	}
+}
+
+// Token is part of the input string, as found by a Lexer
+type Token struct {
+	Kind TokenKind
+
+	// Text is the token, exactly as it appears in the input string
+	Text string
+
+	// Start and End are the byte offsets of the token in the input
+	// string
+	Start int
+	End   int
+}
+
+// Lexer breaks an input string up into tokens, using the same grammar
+// that Expand() does. Use it to build syntax highlighters and editor
+// integrations.
+//
+// The tokens cover the whole input string, in order, with no gaps.
+// Anything that cannot be expanded (such as a ${ that is never closed)
+// is a TokenLiteral; the Lexer never fails.
+type Lexer struct {
+	tokens []Token
+	next   int
+}
+
+// NewLexer creates a Lexer for the given input string
+func NewLexer(input string) *Lexer {
+	return &Lexer{
+		tokens: Tokenize(input),
+	}
+}
+
+// Next returns the next token. It returns false when there are no
+// tokens left.
+func (l *Lexer) Next() (Token, bool) {
+	if l.next >= len(l.tokens) {
+		return Token{}, false
+	}
+
+	l.next++
+	return l.tokens[l.next-1], true
+}
+
+// Tokenize breaks the input string up into tokens, just like a Lexer
+// does, and returns all of them at once
+func Tokenize(input string) []Token {
+	lx := lexer{input: input}
+	lx.lex(0, len(input))
+	lx.flush(len(input))
+
+	return lx.tokens
+}
+
+// lexer holds the state that Tokenize() needs
+type lexer struct {
+	input  string
+	tokens []Token
+
+	// where the literal text that we have not emitted yet starts
+	literalStart int
+}
+
+// lex finds the tokens in input[start:end]
+func (lx *lexer) lex(start, end int) {
+	input := lx.input[:end]
+	wordStart := start
+	inDoubleQuotes := false
+
+	w := 0
+	for i := start; i < end; i += w {
+		c, cw := utf8.DecodeRuneInString(input[i:])
+		w = cw
+
+		switch {
+		case isBlankChar(c):
+			wordStart = i + w
+
+		case c == '\\':
+			// whatever comes next is escaped
+			if i+w < end {
+				_, escW := utf8.DecodeRuneInString(input[i+w:])
+				w += escW
+			}
+
+		case c == '\'' && !inDoubleQuotes:
+			// nothing inside single quotes is expanded
+			quoteEnd := strings.IndexByte(input[i+1:], '\'')
+			if quoteEnd >= 0 {
+				w = quoteEnd + 2
+			}
+
+		case c == '"':
+			inDoubleQuotes = !inDoubleQuotes
+
+		case c == '$':
+			// $((...)) and $[...] are one token
+			arithEnd, ok := findArithmetic(input[i:])
+			if !ok {
+				arithEnd, ok = findLegacyArithmetic(input[i:])
+			}
+			if ok {
+				lx.emit(TokenArithmetic, i, i+arithEnd)
+				w = arithEnd
+				continue
+			}
+
+			varEnd, err := findVar(input[i:])
+			if err != nil {
+				continue
+			}
+			lx.lexParam(i, i+varEnd)
+			w = varEnd
+
+		case c == '~' && i == wordStart && !inDoubleQuotes:
+			prefixEnd, _ := matchTildePrefix(input[i:], false)
+			lx.emit(TokenTildePrefix, i, i+prefixEnd)
+			w = prefixEnd
+
+		case c == '{' && !inDoubleQuotes:
+			bracesEnd, ok := matchBraceExpansion(input[i:])
+			if !ok {
+				continue
+			}
+			lx.emit(TokenBraceGroup, i, i+bracesEnd)
+			w = bracesEnd
+		}
+	}
+}
+
+// lexParam splits the parameter expansion in input[start:end] into
+// tokens
+func (lx *lexer) lexParam(start, end int) {
+	text := lx.input[start:end]
+
+	// a ${...} that has an operator in it?
+	opStart, opEnd, sepEnd, ok := findParamOp(text)
+	if !ok {
+		lx.emit(TokenVarRef, start, end)
+		return
+	}
+
+	lx.emit(TokenVarRef, start, start+opStart)
+	lx.emit(TokenOperator, start+opStart, start+opEnd)
+
+	// ${var/pattern/string} has a second operator in the middle
+	if sepEnd > 0 {
+		lx.lex(start+opEnd, start+sepEnd-1)
+		lx.emit(TokenOperator, start+sepEnd-1, start+sepEnd)
+		opEnd = sepEnd
+	}
+	lx.lex(start+opEnd, end-1)
+	lx.emit(TokenOperator, end-1, end)
+}
+
+// findParamOp finds the operator in a ${...} parameter expansion
+//
+// it returns false if the parameter expansion does not have one. For
+// ${var/pattern/string}, it also tells you where the / between the
+// pattern and the string ends (or 0 if there isn't one)
+func findParamOp(text string) (int, int, int, bool) {
+	if len(text) < 4 || text[1] != '{' {
+		return 0, 0, 0, false
+	}
+
+	// ${#var}, ${!prefix*} and friends have no operator
+	desc, ok := parseParameter(text)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	switch desc.kind {
+	case paramExpandToValue, paramExpandParamLength, paramExpandNoOfPositionalParams, paramExpandPrefixNames, paramExpandPrefixNamesDoubleQuoted:
+		return 0, 0, 0, false
+	}
+
+	// the operator comes straight after the parameter's name
+	start := 2
+	if desc.indirect {
+		start++
+	}
+	_, paramEnd, ok := matchParam(text, start)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	_, opEnd, ok := matchParamOp(text[:len(text)-1], paramEnd)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	opEnd++
+
+	switch desc.kind {
+	case paramExpandSearchReplaceLongestFirstMatch:
+	case paramExpandSearchReplaceLongestAllMatches, paramExpandSearchReplaceLongestPrefix, paramExpandSearchReplaceLongestSuffix:
+		// the operator is //, /# or /%
+		opEnd++
+	default:
+		return paramEnd, opEnd, 0, true
+	}
+
+	// is there a / after the pattern?
+	sepEnd := opEnd + len(desc.parts[1]) + 1
+	if sepEnd < len(text) && text[sepEnd-1] == '/' {
+		return paramEnd, opEnd, sepEnd, true
+	}
+
+	return paramEnd, opEnd, 0, true
+}
+
+// emit adds a token for input[start:end], after any literal text that
+// comes before it
+func (lx *lexer) emit(kind TokenKind, start, end int) {
+	lx.flush(start)
+	lx.tokens = append(lx.tokens, Token{kind, lx.input[start:end], start, end})
+	lx.literalStart = end
+}
+
+// flush emits any literal text before `end`
+func (lx *lexer) flush(end int) {
+	if end > lx.literalStart {
+		lx.tokens = append(lx.tokens, Token{TokenLiteral, lx.input[lx.literalStart:end], lx.literalStart, end})
+	}
+	lx.literalStart = end
+}
@@ -0,0 +1,251 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizeReturnsTokensWithPositions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := `cd ${HOME:-~stuart}/{a,b} $USER`
+	expectedResult := []Token{
+		{TokenLiteral, "cd ", 0, 3},
+		{TokenVarRef, "${HOME", 3, 9},
+		{TokenOperator, ":-", 9, 11},
+		{TokenTildePrefix, "~stuart", 11, 18},
+		{TokenOperator, "}", 18, 19},
+		{TokenLiteral, "/", 19, 20},
+		{TokenBraceGroup, "{a,b}", 20, 25},
+		{TokenLiteral, " ", 25, 26},
+		{TokenVarRef, "$USER", 26, 31},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Tokenize(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestTokenizeSplitsSearchReplaceAtEachOperator(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[string][]string{
+		"${V/a/b}":  {"${V", "/", "a", "/", "b", "}"},
+		"${V//a/b}": {"${V", "//", "a", "/", "b", "}"},
+		"${V/#a}":   {"${V", "/#", "a", "}"},
+		"${V%.*}":   {"${V", "%", ".*", "}"},
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		var actualResult []string
+		for _, token := range Tokenize(input) {
+			actualResult = append(actualResult, token.Text)
+		}
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestTokenizeReturnsArithmeticAsOneToken(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "a $(( 1+2 )) \"$[1+2]\" ${X:-$((Y))}"
+	expectedResult := []Token{
+		{TokenLiteral, "a ", 0, 2},
+		{TokenArithmetic, "$(( 1+2 ))", 2, 12},
+		{TokenLiteral, " \"", 12, 14},
+		{TokenArithmetic, "$[1+2]", 14, 20},
+		{TokenLiteral, "\" ", 20, 22},
+		{TokenVarRef, "${X", 22, 25},
+		{TokenOperator, ":-", 25, 27},
+		{TokenArithmetic, "$((Y))", 27, 33},
+		{TokenOperator, "}", 33, 34},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Tokenize(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestTokenizeTreatsUnexpandableTextAsLiteral(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []string{
+		"'$HOME {a,b}'",
+		`\$HOME`,
+		"${HOME",
+		`"~ {a,b}"`,
+	}
+
+	for _, input := range testData {
+		expectedResult := []Token{{TokenLiteral, input, 0, len(input)}}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := Tokenize(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestTokenizeCoversTheWholeInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []string{
+		"",
+		`${#V} ${!P*} ${v:1:2} "$a${b}" ~/x`,
+		`${HOME:+"${USER:-nobody}"}/{1..3}`,
+		"é$ü {x,y}",
+	}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		tokens := Tokenize(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		var sb strings.Builder
+		pos := 0
+		for _, token := range tokens {
+			assert.Equal(t, pos, token.Start, input)
+			assert.Equal(t, token.Text, input[token.Start:token.End], input)
+			sb.WriteString(token.Text)
+			pos = token.End
+		}
+		assert.Equal(t, input, sb.String())
+	}
+}
+
+func TestLexerNextReturnsEachTokenInTurn(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewLexer("echo $HOME")
+	expectedResult := []Token{
+		{TokenLiteral, "echo ", 0, 5},
+		{TokenVarRef, "$HOME", 5, 10},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	var actualResult []Token
+	for token, ok := unit.Next(); ok; token, ok = unit.Next() {
+		actualResult = append(actualResult, token)
+	}
+	_, ok := unit.Next()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+	assert.False(t, ok)
+}
+
+func TestTokenKindString(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[TokenKind]string{
+		TokenLiteral:     "literal",
+		TokenVarRef:      "var-ref",
+		TokenOperator:    "operator",
+		TokenBraceGroup:  "brace-group",
+		TokenTildePrefix: "tilde-prefix",
+		TokenArithmetic:  "arithmetic",
+		TokenKind(99):    "unknown",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := input.String()
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult)
+	}
+}
@@ -0,0 +1,218 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// WarningKind tells you what a Warning is about
+type WarningKind int
+
+// these are the suspicious things that Lint() looks for
+const (
+	// WarnMultiDigitPositionalParam is a $ followed by more than one
+	// digit, such as $10. UNIX shells treat it as ${1}0, not ${10}.
+	WarnMultiDigitPositionalParam WarningKind = iota + 1
+
+	// WarnTildeMidWord is a ~ that looks like it is meant to be a home
+	// directory, but is not at the start of a word, such as the ~ in
+	// a~/b. Tilde expansion does not change it. A ~ straight after a
+	// '=' or ':' is not reported, because assignments such as
+	// PATH=~/bin:~/sbin expand it.
+	WarnTildeMidWord
+
+	// WarnEmptyDefault is a ${var:-} with an empty default value. It
+	// expands to the same thing as $var.
+	WarnEmptyDefault
+)
+
+func (k WarningKind) String() string {
+	switch k {
+	case WarnMultiDigitPositionalParam:
+		return "multi-digit positional parameter"
+	case WarnTildeMidWord:
+		return "tilde mid-word"
+	case WarnEmptyDefault:
+		return "empty default value"
+	default:
+		return "unknown warning"
+	}
+}
+
+// Warning is something in the input that is legal, but is probably
+// not what the author meant
+type Warning struct {
+	Kind WarningKind
+
+	// Message explains the problem, and how to fix it
+	Message string
+
+	// Offset, Line, Column and Substring tell you where the problem
+	// is, in the same way that ExpansionError does
+	Offset    int
+	Line      int
+	Column    int
+	Substring string
+}
+
+// Lint looks for things in the input that are legal, but are probably
+// mistakes. It does not expand anything, and does not need any
+// callbacks.
+//
+// It returns the warnings in the order they appear in the input. It
+// returns an empty list if it does not find anything suspicious.
+//
+// Lint looks for:
+//
+//   - $10 and friends, which UNIX shells treat as ${1}0
+//   - ~ in the middle of a word (after =, : or before a /), which is not expanded
+//   - ${var:-} with an empty default value
+//
+// Use Validate() to look for syntax errors.
+func Lint(input string) []Warning {
+	l := linter{input: input, warnings: []Warning{}}
+	l.lint(0, len(input))
+
+	return l.warnings
+}
+
+// linter holds the state that Lint() needs
+type linter struct {
+	input    string
+	warnings []Warning
+}
+
+// lint looks for warnings in input[start:end]
+func (l *linter) lint(start, end int) {
+	input := l.input[:end]
+	wordStart := start
+	inDoubleQuotes := false
+
+	w := 0
+	for i := start; i < end; i += w {
+		c, cw := utf8.DecodeRuneInString(input[i:])
+		w = cw
+
+		switch {
+		case isBlankChar(c):
+			wordStart = i + w
+
+		case c == '\\':
+			// whatever comes next is escaped
+			if i+w < end {
+				_, escW := utf8.DecodeRuneInString(input[i+w:])
+				w += escW
+			}
+
+		case c == '\'' && !inDoubleQuotes:
+			// nothing inside single quotes is expanded
+			quoteEnd := strings.IndexByte(input[i+1:], '\'')
+			if quoteEnd >= 0 {
+				w = quoteEnd + 2
+			}
+
+		case c == '"':
+			inDoubleQuotes = !inDoubleQuotes
+
+		case c == '$':
+			varEnd, err := findVar(input[i:])
+			if err != nil {
+				continue
+			}
+			l.lintParam(i, i+varEnd)
+			w = varEnd
+
+		case c == '~' && i != wordStart && !inDoubleQuotes:
+			// after '=' or ':' we are (probably) in an assignment, and
+			// a ~ at the end of a word is a backup file such as file~
+			if input[i-1] == '=' || input[i-1] == ':' || i+w == end || isBlankChar(rune(input[i+w])) {
+				continue
+			}
+			l.warn(WarnTildeMidWord, i, i+1, "~ is only expanded at the start of a word")
+		}
+	}
+}
+
+// lintParam looks for warnings in the parameter expansion in
+// input[start:end], and in any word that it contains
+func (l *linter) lintParam(start, end int) {
+	text := l.input[start:end]
+
+	// $10 is $1 followed by a 0
+	if end < len(l.input) && len(text) == 2 && isNumericChar(rune(text[1])) && isNumericChar(rune(l.input[end])) {
+		digitsEnd := end
+		for digitsEnd < len(l.input) && isNumericChar(rune(l.input[digitsEnd])) {
+			digitsEnd++
+		}
+		l.warn(
+			WarnMultiDigitPositionalParam,
+			start,
+			digitsEnd,
+			fmt.Sprintf("%s is %s followed by %s; use ${%s} if you meant the positional parameter", l.input[start:digitsEnd], text, l.input[end:digitsEnd], l.input[start+1:digitsEnd]),
+		)
+		return
+	}
+
+	_, opEnd, _, ok := findParamOp(text)
+	if !ok {
+		return
+	}
+
+	if strings.HasSuffix(text[:opEnd], ":-") && opEnd == len(text)-1 {
+		l.warn(WarnEmptyDefault, start, end, fmt.Sprintf("%s has an empty default value, and expands to the same thing as ${%s}", text, text[2:opEnd-2]))
+		return
+	}
+
+	// there may be problems in the operator's word too
+	l.lint(start+opEnd, end-1)
+}
+
+// warn adds a warning that points at input[start:end]
+func (l *linter) warn(kind WarningKind, start, end int, message string) {
+	lineStart := strings.LastIndexByte(l.input[:start], '\n') + 1
+
+	l.warnings = append(l.warnings, Warning{
+		Kind:      kind,
+		Message:   message,
+		Offset:    start,
+		Line:      strings.Count(l.input[:start], "\n") + 1,
+		Column:    utf8.RuneCountInString(l.input[lineStart:start]) + 1,
+		Substring: l.input[start:end],
+	})
+}
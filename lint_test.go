@@ -0,0 +1,232 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintWarnsAboutMultiDigitPositionalParams(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "echo $10 ${10} '$10' \\$10"
+	expectedResult := []Warning{
+		{
+			Kind:      WarnMultiDigitPositionalParam,
+			Message:   "$10 is $1 followed by 0; use ${10} if you meant the positional parameter",
+			Offset:    5,
+			Line:      1,
+			Column:    6,
+			Substring: "$10",
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Lint(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestLintWarnsAboutTildesMidWord(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "~/ok file~ a~/b \"x~/y\" a~b"
+	expectedResult := []int{12, 24}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	warnings := Lint(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	var actualResult []int
+	for _, warning := range warnings {
+		assert.Equal(t, WarnTildeMidWord, warning.Kind)
+		actualResult = append(actualResult, warning.Offset)
+	}
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestLintDoesNotWarnAboutTildesInAssignments(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "PATH=~/bin:~/sbin ./configure --prefix=~/local"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Lint(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Empty(t, actualResult)
+}
+
+func TestLintWarnsAboutEmptyDefaultValues(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "a\n  ${HOME:-} ${HOME-} ${HOME:-x}"
+	expectedResult := []Warning{
+		{
+			Kind:      WarnEmptyDefault,
+			Message:   "${HOME:-} has an empty default value, and expands to the same thing as ${HOME}",
+			Offset:    4,
+			Line:      2,
+			Column:    3,
+			Substring: "${HOME:-}",
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Lint(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestLintLooksInsideOperatorWords(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "${A:-$10}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Lint(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Len(t, actualResult, 1)
+	assert.Equal(t, 5, actualResult[0].Offset)
+	assert.Equal(t, WarnMultiDigitPositionalParam, actualResult[0].Kind)
+}
+
+func TestLintReturnsEmptyListForCleanInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "~/bin ${HOME:-/root} ${10} $1"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Lint(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, []Warning{}, actualResult)
+}
+
+func TestExpandResultIncludesWarnings(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"HOME": "/home/stuart"})
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandResult("${HOME:-}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "/home/stuart", actualResult.Value)
+	assert.Len(t, actualResult.Warnings, 1)
+	assert.Equal(t, WarnEmptyDefault, actualResult.Warnings[0].Kind)
+}
+
+func TestWarningKindString(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[WarningKind]string{
+		WarnMultiDigitPositionalParam: "multi-digit positional parameter",
+		WarnTildeMidWord:              "tilde mid-word",
+		WarnEmptyDefault:              "empty default value",
+		WarningKind(99):               "unknown warning",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := input.String()
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult)
+	}
+}
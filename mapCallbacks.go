@@ -0,0 +1,71 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"sort"
+	"strings"
+)
+
+// NewMapCallbacks returns a set of ExpansionCallbacks that look variables
+// up in the given map. It is handy for a set of default values at the
+// end of a FirstOf() chain.
+//
+// The map is read-only: there is no AssignToVar callback, and no
+// LookupHomeDir callback either. Assignments such as ${VAR:=word} and
+// $((N++)) return an ErrReadOnlyVar error. Use an Env if you need to
+// assign to variables.
+func NewMapCallbacks(vars map[string]string) ExpansionCallbacks {
+	return ExpansionCallbacks{
+		LookupVar: func(name string) (string, bool) {
+			retval, ok := vars[name]
+			return retval, ok
+		},
+		MatchVarNames: func(prefix string) []string {
+			var retval []string
+			for name := range vars {
+				if strings.HasPrefix(name, prefix) {
+					retval = append(retval, name)
+				}
+			}
+
+			// maps have no order, so we sort the names to give the
+			// caller the same answer every time
+			sort.Strings(retval)
+			return retval
+		},
+	}
+}
@@ -0,0 +1,145 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCallbacks returns NewMapCallbacks(vars), with an AssignToVar
+// callback that writes back into the map, and a LookupHomeDir callback
+// that puts everyone's home directory under /home
+//
+// it is the fixture that most of our tests share
+func newTestCallbacks(vars map[string]string) ExpansionCallbacks {
+	cb := NewMapCallbacks(vars)
+	cb.AssignToVar = func(key, value string) error {
+		vars[key] = value
+		return nil
+	}
+	cb.LookupHomeDir = func(user string) (string, bool) {
+		return "/home/" + user, true
+	}
+
+	return cb
+}
+
+// countLookups wraps the callbacks' LookupVar, so that the test can
+// see how many times each variable was looked up
+//
+// it is safe to call from several goroutines at once
+func countLookups(cb ExpansionCallbacks, lookups map[string]int) ExpansionCallbacks {
+	var mu sync.Mutex
+	lookupVar := cb.LookupVar
+	cb.LookupVar = func(key string) (string, bool) {
+		mu.Lock()
+		lookups[key]++
+		mu.Unlock()
+		return lookupVar(key)
+	}
+
+	return cb
+}
+
+// countAssignments wraps the callbacks' AssignToVar, so that the test
+// can see how many times each variable was assigned to
+func countAssignments(cb ExpansionCallbacks, assignments map[string]int) ExpansionCallbacks {
+	assignToVar := cb.AssignToVar
+	cb.AssignToVar = func(key, value string) error {
+		assignments[key]++
+		return assignToVar(key, value)
+	}
+
+	return cb
+}
+
+func TestNewMapCallbacksLooksUpVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewMapCallbacks(map[string]string{
+		"PARAM1": "foo",
+		"PARAM2": "bar",
+		"OTHER":  "baz",
+	})
+	expectedResult := "foo PARAM1 PARAM2"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("$PARAM1 ${!PARAM*}", unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestNewMapCallbacksDoesNotAllowAssignments(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := []string{
+		"${Z:=x}",
+		"$((N++))",
+		"$((N = 1))",
+	}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		vars := map[string]string{"N": "1"}
+		unit := NewMapCallbacks(vars)
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := Expand(testData, unit)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrReadOnlyVar{}), testData)
+		assert.Equal(t, map[string]string{"N": "1"}, vars, testData)
+	}
+}
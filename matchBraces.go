@@ -76,7 +76,7 @@ func matchBraces(input string) ([]bracePair, error) {
 			braceStack = append(braceStack, bracePair{i, -1})
 		} else if r == '}' {
 			if pairIndex < 0 {
-				return []bracePair{}, ErrMismatchedClosingBrace{i + w}
+				return []bracePair{}, NewErrMismatchedClosingBrace(input, i)
 			}
 
 			braceStack[pairIndex].end = i
@@ -89,7 +89,7 @@ func matchBraces(input string) ([]bracePair, error) {
 
 	// did we run into mismatched braces?
 	if len(braceStack) > 0 {
-		return []bracePair{}, ErrMismatchedBrace{braceStack[0].start}
+		return []bracePair{}, NewErrMismatchedBrace(input, braceStack[0].start)
 	}
 
 	// all done
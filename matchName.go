@@ -50,13 +50,19 @@ func matchName(input string) (int, int, bool) {
 		return paramTypeInvalid, 0, false
 	}
 
-	for i := w; i < len(input); i += w {
-		r, w = utf8.DecodeRuneInString(input[i:])
-
-		if !isNameBodyChar(r) {
+	// names are pure ASCII, so we don't need to decode the rest of
+	// the input one rune at a time
+	for i := w; i < len(input); i++ {
+		if !isNameBodyChar(rune(input[i])) {
 			return paramTypeName, i, true
 		}
 	}
 
 	return paramTypeName, len(input), true
 }
+
+// isName returns true if the whole input is a valid name
+func isName(input string) bool {
+	_, nameEnd, ok := matchName(input)
+	return ok && nameEnd == len(input)
+}
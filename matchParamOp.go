@@ -56,6 +56,10 @@ const (
 	paramOpEscape
 	paramOpExpandAsPrompt
 	paramOpExpandDoubleQuotes
+	paramOpTransformUppercase
+	paramOpTransformUppercaseFirstChar
+	paramOpTransformLowercase
+	paramOpExpandKeyValuePairs
 	// this has been added to help us test unsupported operand rejection
 	// in the parameter parser
 	paramOpEmptyObject
@@ -85,6 +89,14 @@ func matchParamOp(input string, start int) (int, int, bool) {
 		default:
 			return paramOpSubstring, start, true
 		}
+	case '-':
+		return paramOpUseDefaultValue, start, true
+	case '=':
+		return paramOpAssignDefaultValue, start, true
+	case '?':
+		return paramOpWriteError, start, true
+	case '+':
+		return paramOpUseAlternativeValue, start, true
 	case '#':
 		if start < maxInput && input[startPlus1] == '#' {
 			return paramOpRemoveLongestPrefix, startPlus1, true
@@ -123,6 +135,14 @@ func matchParamOp(input string, start int) (int, int, bool) {
 			return paramOpExpandAsPrompt, startPlus1, true
 		case 'Q':
 			return paramOpExpandDoubleQuotes, startPlus1, true
+		case 'U':
+			return paramOpTransformUppercase, startPlus1, true
+		case 'u':
+			return paramOpTransformUppercaseFirstChar, startPlus1, true
+		case 'L':
+			return paramOpTransformLowercase, startPlus1, true
+		case 'K', 'k':
+			return paramOpExpandKeyValuePairs, startPlus1, true
 		default:
 			return paramOpInvalid, 0, false
 		}
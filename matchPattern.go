@@ -0,0 +1,74 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+// MatchShellPattern returns true if the whole of `value` matches the
+// shell pattern, just like a `case` statement or `[[ value == pattern ]]`
+// does in bash
+//
+// It uses exactly the same pattern matching as our own expansions (such
+// as ${var#pattern}), including bracket expressions like [[:alpha:]].
+// Set opts.NoCaseMatch to ignore upper / lower case.
+//
+// You get an ErrBadPattern error if we cannot use the pattern.
+func MatchShellPattern(pattern, value string, opts ShellOpts) (bool, error) {
+	cb := ExpansionCallbacks{
+		opts: &options{shellOpts: opts},
+	}
+
+	return matchShellPattern(pattern, value, cb)
+}
+
+// MatchShellPattern returns true if the whole of `value` matches the
+// shell pattern, just like MatchShellPattern() does. It follows the
+// Expander's shell options, and uses its glob cache.
+func (e *Expander) MatchShellPattern(pattern, value string) (bool, error) {
+	return matchShellPattern(pattern, value, e.callbacks())
+}
+
+func matchShellPattern(pattern, value string, cb ExpansionCallbacks) (bool, error) {
+	g, err := cb.compileGlob(cb.searchPattern(pattern), globMatchWhole)
+	if err != nil {
+		return false, err
+	}
+
+	success, err := g.Match(value)
+	if err != nil {
+		return false, ErrBadPattern{pattern, err}
+	}
+
+	return success, nil
+}
@@ -0,0 +1,138 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchShellPatternMatchesTheWholeValue(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{"*.go", "matchPattern.go", true},
+		{"*.go", "matchPattern.go.orig", false},
+		{"a?c", "abc", true},
+		{"a?c", "abbc", false},
+		{"[[:digit:]][[:alpha:]]", "1a", true},
+		{"[!0-9]*", "1a", false},
+		{`foo\*`, "foo*", true},
+		{`foo\*`, "foobar", false},
+		{"", "", true},
+		{"", "a", false},
+		{"ABC", "abc", false},
+	}
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := MatchShellPattern(testCase.pattern, testCase.value, ShellOpts{})
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testCase.pattern)
+		assert.Equal(t, testCase.expected, actualResult, testCase.pattern+" "+testCase.value)
+	}
+}
+
+func TestMatchShellPatternSupportsNoCaseMatch(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	opts := ShellOpts{NoCaseMatch: true}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	matchUpper, err1 := MatchShellPattern("ABC*", "abcdef", opts)
+	matchLower, err2 := MatchShellPattern("abc*", "ABCDEF", opts)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err1)
+	assert.Nil(t, err2)
+	assert.True(t, matchUpper)
+	assert.True(t, matchLower)
+}
+
+func TestMatchShellPatternReturnsErrBadPattern(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := MatchShellPattern("[", "[", ShellOpts{})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrBadPattern{}))
+}
+
+func TestExpanderMatchShellPatternUsesShellOpts(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	expander := NewExpander(NewMapCallbacks(nil), WithShellOpts(ShellOpts{NoCaseMatch: true}))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expander.MatchShellPattern("*.TXT", "readme.txt")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.True(t, actualResult)
+}
@@ -35,63 +35,111 @@
 
 package shellexpand
 
-import "unicode/utf8"
+import (
+	"strings"
+	"unicode/utf8"
+)
 
+// errNotAVar is returned by findVar() when the '$' at the start of the
+// input is just a '$'
+type errNotAVar struct{}
+
+func (e errNotAVar) Error() string {
+	return "not a variable"
+}
+
+// matchVar checks the input string to see if it starts with a variable
+//
+// returns:
+//
+// - the position just after the end of the variable
+// - `true` on success
 func matchVar(input string) (int, bool) {
+	varEnd, err := findVar(input)
+	return varEnd, err == nil
+}
+
+// findVar checks the input string to see if it starts with a variable,
+// and tells you why not if it doesn't
+//
+// returns:
+//
+// - the position just after the end of the variable
+// - errNotAVar if the input does not start with a variable
+// - ErrMismatchedBrace if the input starts with a ${ that is never closed
+func findVar(input string) (int, error) {
 	// have we started on a dollar?
-	if input[0] != '$' {
-		return 0, false
+	c, w := utf8.DecodeRuneInString(input)
+	if c != '$' {
+		return 0, errNotAVar{}
 	}
 
-	// no, it is not
+	// a '$' on its own is just a '$'
+	if len(input) == w {
+		return 0, errNotAVar{}
+	}
+
+	// special case: a var that is not wrapped in braces ends as soon as
+	// its name does
 	//
-	// special case: positional parameters are not subject to normal
-	// matching rules (sigh)
-	if isNumericChar(rune(input[1])) {
-		return 2, true
+	// this also takes care of positional parameters, which are not subject
+	// to normal matching rules (sigh)
+	c, _ = utf8.DecodeRuneInString(input[w:])
+	if c != '{' {
+		_, paramEnd, ok := matchParam(input, w)
+		if !ok {
+			return 0, errNotAVar{}
+		}
+		return paramEnd, nil
 	}
 
-	// general case - a non-positional parameter that may be wrapped
-	// in braces
-	braceDepth := 0
-	inEscape := false
-	w := 0
-	var c rune
-	for i := 0; i < len(input); i += w {
+	// general case - a non-positional parameter that is wrapped in
+	// braces
+	//
+	// just like bash, only a nested ${ or $( stops the next '}' from
+	// ending the parameter. Any other '{' in the operator's word, such
+	// as in ${FOO:-{literal}}, is just a character.
+	//
+	// a '}' inside quotes, such as in ${FOO:-"}"}, is part of the word.
+	// A quote that is never closed is just a character.
+	braceDepth := 1
+	for i := 2; i < len(input); i += w {
 		// what are we looking at?
 		c, w = utf8.DecodeRuneInString(input[i:])
 
-		// are we dealing with an escaped char?
-		if inEscape {
-			inEscape = false
-		} else if c == '\\' && !inEscape {
+		switch c {
+		case '\\':
 			// skip escaped chars
-			inEscape = true
-		} else if c == '{' {
-			braceDepth++
-		} else if c == '}' {
-			braceDepth--
+			if i+w < len(input) {
+				_, escW := utf8.DecodeRuneInString(input[i+w:])
+				w += escW
+			}
+		case '\'', '"':
+			quoteEnd, ok := matchQuotes(input[i:])
+			if ok {
+				w = quoteEnd
+			}
+		case '$':
+			if strings.HasPrefix(input[i:], "${") {
+				braceDepth++
+				w = 2
+				break
+			}
 
-			if braceDepth == 0 {
-				return i + w, true
+			// a '}' inside a command substitution belongs to the
+			// command
+			cmdEnd, ok := findCommand(input[i:])
+			if ok {
+				w = cmdEnd
 			}
-		} else if c == ' ' {
+		case '}':
+			braceDepth--
 			if braceDepth == 0 {
-				// we must be looking at a var that was not surrounded
-				// by braces
-				return i, true
+				return i + w, nil
 			}
-
-			// no spaces allowed inside a var name
-			// return 0, false
 		}
 	}
 
-	// end of the string
-	if braceDepth == 0 {
-		return len(input), true
-	}
-
 	// we did not find a matching closing brace
-	return 0, false
+	return 0, NewErrMismatchedBrace(input, 1)
 }
@@ -38,6 +38,7 @@ package shellexpand
 import (
 	"testing"
 
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -223,6 +224,11 @@ func TestMatchVarKnownParamOperators(t *testing.T) {
 		"${var@E}",
 		"${var@P}",
 		"${var@Q}",
+		"${var@U}",
+		"${var@u}",
+		"${var@L}",
+		"${var@K}",
+		"${var@k}",
 	}
 
 	// ----------------------------------------------------------------
@@ -239,3 +245,174 @@ func TestMatchVarKnownParamOperators(t *testing.T) {
 	// test the results
 
 }
+
+func TestMatchVarRejectsTruncatedInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []string{
+		"",
+		"$",
+		"${",
+		"${var",
+		"${var:-${word}",
+		"$é",
+	}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualEnd, ok := matchVar(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.False(t, ok, input)
+		assert.Equal(t, 0, actualEnd, input)
+	}
+}
+
+func TestFindVarReportsUnterminatedBraces(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "${var:-${word}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := findVar(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, NewErrMismatchedBrace(testData, 1), err)
+}
+
+func TestFindVarReportsDollarOnItsOwn(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []string{"", "$", "$ ", "$é", "$}"}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := findVar(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, errNotAVar{}, err, input)
+	}
+}
+
+func TestMatchVarOnlyNestsParamsInOperatorWords(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[string]int{
+		"${UNSET:-{literal}}":        18,
+		"${UNSET:-a{b}c}x":           13,
+		"${UNSET:-${FOO:-{y}}}z":     20,
+		"${FOO/\\}/X}":               11,
+		"${UNSET:-\\${FOO}}":         16,
+		"${UNSET:-$(echo })}":        19,
+		"${UNSET:-$((1+2))}":         18,
+		"${UNSET:-{${FOO}}${FOO}}x":  17,
+		"${UNSET:-${UNSET:-\\}}}...": 22,
+	}
+
+	for input, expectedEnd := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualEnd, ok := matchVar(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, ok, input)
+		assert.Equal(t, expectedEnd, actualEnd, input)
+	}
+}
+
+func TestMatchVarSkipsQuotedBracesInOperatorWords(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[string]int{
+		`${UNSET:-"}"}x`:      13,
+		`${UNSET:-'}'}x`:      13,
+		`${UNSET:-a"}"b}x`:    15,
+		`${UNSET:-"\"}"}x`:    15,
+		`${UNSET:-${A:-"}"}}`: 19,
+		`${'}x`:               4,
+		`${X:-"}x`:            7,
+	}
+
+	for input, expectedEnd := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualEnd, ok := matchVar(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, ok, input)
+		assert.Equal(t, expectedEnd, actualEnd, input)
+	}
+}
+
+func TestExpandMatchesBashForBracesInOperatorWords(t *testing.T) {
+	t.Parallel()
+
+	if !shelltest.Available("bash") {
+		t.Skip("bash is not available")
+	}
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"FOO": "a}b{c",
+	}
+	testData := []string{
+		"${UNSET:-{literal}}",
+		"${UNSET:-a{b}c}x",
+		"${UNSET:-${FOO:-{y}}}z",
+		"${UNSET:-${NOPE:-{y}}}z",
+		`${FOO/\}/X}`,
+		`${FOO#*\}}`,
+		`${FOO%\{*}`,
+		`${UNSET:-\}}`,
+		"${UNSET:-}}",
+	}
+
+	for _, input := range testData {
+		shellCase := shelltest.Case{
+			Input: input,
+			Vars:  vars,
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change and test the results
+
+		shelltest.AssertMatch(t, "bash", &shellCase, func(input string) (string, error) {
+			return Expand(input, NewMapCallbacks(vars))
+		})
+	}
+}
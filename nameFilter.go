@@ -0,0 +1,124 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NameFilter decides whether or not a variable can be expanded
+//
+// It is given the variable's name, and returns true if the variable is
+// allowed
+type NameFilter func(name string) bool
+
+// AllowNames returns a NameFilter that only allows the given variables
+func AllowNames(names ...string) NameFilter {
+	allowed := namesToSet(names)
+	return func(name string) bool {
+		_, ok := allowed[name]
+		return ok
+	}
+}
+
+// DenyNames returns a NameFilter that allows every variable, except for
+// the given ones
+func DenyNames(names ...string) NameFilter {
+	denied := namesToSet(names)
+	return func(name string) bool {
+		_, ok := denied[name]
+		return !ok
+	}
+}
+
+// AllowNamesMatching returns a NameFilter that only allows variables
+// whose names match the given regular expression
+//
+// Remember to anchor your regex (e.g. ^APP_[A-Z_]+$) if you want it to
+// match the whole name.
+func AllowNamesMatching(re *regexp.Regexp) NameFilter {
+	return re.MatchString
+}
+
+// WithNameFilter limits which variables the Expander can expand. Use it
+// when you are expanding templates that you do not trust, so that they
+// cannot read secrets from (for example) the environment.
+//
+// Any variable that the filter does not allow is treated as if it is
+// not set, and cannot be assigned to. In strict mode, you get an
+// ErrNameNotAllowed instead.
+//
+// The filter is not used for special parameters such as $1, $# and $@.
+func WithNameFilter(filter NameFilter) Option {
+	return func(opts *options) {
+		opts.nameFilter = filter
+	}
+}
+
+// namesToSet turns a list of names into something we can search quickly
+func namesToSet(names []string) map[string]struct{} {
+	retval := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		retval[name] = struct{}{}
+	}
+
+	return retval
+}
+
+// nameAllowed returns true if our name filter allows the given variable
+func (cb ExpansionCallbacks) nameAllowed(name string) bool {
+	if cb.opts == nil || cb.opts.nameFilter == nil {
+		return true
+	}
+
+	// special parameters are always allowed
+	if strings.HasPrefix(name, "$") {
+		return true
+	}
+
+	return cb.opts.nameFilter(name)
+}
+
+// checkName returns ErrNameNotAllowed in strict mode, if our name filter
+// does not allow the given variable
+func (cb ExpansionCallbacks) checkName(name string) error {
+	if cb.strict() && !cb.nameAllowed(name) {
+		return ErrNameNotAllowed{name}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func nameFilterTestVars() map[string]string {
+	return map[string]string{
+		"$#":          "1",
+		"$1":          "one",
+		"APP_NAME":    "myapp",
+		"APP_VERSION": "1.0",
+		"REF":         "SECRET",
+		"SECRET":      "hunter2",
+	}
+}
+
+func TestWithNameFilterTreatsDeniedVarsAsUnset(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		filter         NameFilter
+		input          string
+		expectedResult string
+	}{
+		{AllowNames("APP_NAME"), "$APP_NAME:$APP_VERSION:$SECRET", "myapp::"},
+		{DenyNames("SECRET"), "$APP_NAME:${SECRET:-hidden}", "myapp:hidden"},
+		{AllowNamesMatching(regexp.MustCompile(`^APP_`)), "${APP_VERSION}${SECRET}", "1.0"},
+		{DenyNames("SECRET"), "${!REF}", ""},
+		{DenyNames("REF"), "${!REF}", ""},
+		{DenyNames("SECRET"), "${!APP_*} ${!S*}", "APP_NAME APP_VERSION "},
+		{AllowNames("APP_NAME"), "$# $1 $@", "1 one one"},
+	}
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		unit := NewExpander(newTestCallbacks(nameFilterTestVars()), WithNameFilter(testCase.filter))
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(testCase.input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.expectedResult, actualResult, testCase.input)
+	}
+}
+
+func TestWithNameFilterStopsAssignments(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := newTestCallbacks(nameFilterTestVars())
+	assigned := false
+	cb.AssignToVar = func(key, value string) error {
+		assigned = true
+		return nil
+	}
+	unit := NewExpander(cb, WithNameFilter(DenyNames("SECRET")))
+	testData := "${SECRET:=changed} $SECRET"
+	expectedResult := " "
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.False(t, assigned)
+}
+
+func TestWithNameFilterReturnsErrorInStrictMode(t *testing.T) {
+	t.Parallel()
+
+	testData := []string{
+		"$SECRET",
+		"${SECRET:-hidden}",
+		"${!REF}",
+		"${SECRET:=changed}",
+	}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		unit := NewExpander(
+			newTestCallbacks(nameFilterTestVars()),
+			WithNameFilter(DenyNames("SECRET", "REF")),
+			WithStrict(),
+		)
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrNameNotAllowed{}), input)
+	}
+}
+
+func TestWithNameFilterAppliesToPercentVars(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(
+		newTestCallbacks(nameFilterTestVars()),
+		WithNameFilter(DenyNames("SECRET")),
+		WithVarSyntax(VarSyntaxPercent),
+	)
+	testData := "%APP_NAME% %SECRET%"
+	expectedResult := "myapp %SECRET%"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
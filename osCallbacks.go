@@ -0,0 +1,132 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+)
+
+// NewOSCallbacks returns a set of ExpansionCallbacks that use your
+// program's environment as the backing store:
+//
+// - AssignToVar calls os.Setenv()
+// - LookupVar calls os.LookupEnv()
+// - LookupHomeDir calls LookupOSHomeDir()
+// - MatchVarNames searches os.Environ()
+// - IsExported returns true for every variable that is set
+func NewOSCallbacks() ExpansionCallbacks {
+	return ExpansionCallbacks{
+		AssignToVar:   os.Setenv,
+		LookupVar:     os.LookupEnv,
+		LookupHomeDir: LookupOSHomeDir,
+		MatchVarNames: matchOSVarNames,
+		IsExported:    isOSVarExported,
+	}
+}
+
+// isOSVarExported returns true if the variable is in our environment
+func isOSVarExported(name string) bool {
+	_, ok := os.LookupEnv(name)
+	return ok
+}
+
+// osHomeDir is what LookupOSHomeDir() found for a user
+type osHomeDir struct {
+	dir string
+	ok  bool
+}
+
+// osHomeDirs remembers every user that LookupOSHomeDir() has looked up
+var osHomeDirs sync.Map
+
+// LookupOSHomeDir finds the given user's home directory, using os/user.
+// An empty username means the user that your program is running as.
+//
+// Looking up a user can mean reading /etc/passwd, or asking a directory
+// service, so we remember the answer (including when the user does not
+// exist) for as long as your program runs.
+//
+// Use it as your LookupHomeDir callback, so that ~username works without
+// you having to write your own passwd lookups. NewOSCallbacks() already
+// does this for you.
+func LookupOSHomeDir(username string) (string, bool) {
+	cached, ok := osHomeDirs.Load(username)
+	if ok {
+		homeDir := cached.(osHomeDir)
+		return homeDir.dir, homeDir.ok
+	}
+
+	var homeDir osHomeDir
+	u, err := lookupOSUser(username)
+	if err == nil {
+		homeDir = osHomeDir{u.HomeDir, true}
+	}
+	osHomeDirs.Store(username, homeDir)
+
+	return homeDir.dir, homeDir.ok
+}
+
+// lookupOSUser finds the given user, or the current user if username is
+// empty
+func lookupOSUser(username string) (*user.User, error) {
+	if username == "" {
+		return user.Current()
+	}
+
+	return user.Lookup(username)
+}
+
+func matchOSVarNames(prefix string) []string {
+	var retval []string
+
+	for _, pair := range os.Environ() {
+		name := strings.SplitN(pair, "=", 2)[0]
+
+		// on Windows, there are some hidden entries that have no name
+		if len(name) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(name, prefix) {
+			retval = append(retval, name)
+		}
+	}
+
+	return retval
+}
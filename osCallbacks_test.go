@@ -0,0 +1,208 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"os"
+	"os/user"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOSCallbacksLooksUpEnvironmentVariables(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	os.Setenv("SHELLEXPAND_TEST_LOOKUP", "foo")
+	defer os.Unsetenv("SHELLEXPAND_TEST_LOOKUP")
+
+	cb := NewOSCallbacks()
+	expectedResult := "foo/bar"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${SHELLEXPAND_TEST_LOOKUP}/bar", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestNewOSCallbacksAssignsEnvironmentVariables(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	os.Unsetenv("SHELLEXPAND_TEST_ASSIGN")
+	defer os.Unsetenv("SHELLEXPAND_TEST_ASSIGN")
+
+	cb := NewOSCallbacks()
+	expectedResult := "foo"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${SHELLEXPAND_TEST_ASSIGN:=foo}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, expectedResult, os.Getenv("SHELLEXPAND_TEST_ASSIGN"))
+}
+
+func TestNewOSCallbacksMatchesEnvironmentVariableNames(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	os.Setenv("SHELLEXPAND_TEST_MATCH_A", "foo")
+	defer os.Unsetenv("SHELLEXPAND_TEST_MATCH_A")
+	os.Setenv("SHELLEXPAND_TEST_MATCH_B", "bar")
+	defer os.Unsetenv("SHELLEXPAND_TEST_MATCH_B")
+
+	cb := NewOSCallbacks()
+	expectedResult := "SHELLEXPAND_TEST_MATCH_A SHELLEXPAND_TEST_MATCH_B"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${!SHELLEXPAND_TEST_MATCH_*}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestNewOSCallbacksLooksUpHomeDirectories(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	currentUser, err := user.Current()
+	if err != nil {
+		t.Skip("unable to find current user:", err)
+	}
+
+	cb := NewOSCallbacks()
+	expectedResult := currentUser.HomeDir + "/bin"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("~"+currentUser.Username+"/bin", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestNewOSCallbacksLeavesUnknownUsersAlone(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewOSCallbacks()
+	expectedResult := "~shellexpand-no-such-user/bin"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand(expectedResult, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestLookupOSHomeDirFindsTheCurrentUser(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	currentUser, err := user.Current()
+	if err != nil {
+		t.Skip("unable to find current user:", err)
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, ok := LookupOSHomeDir("")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, ok)
+	assert.Equal(t, currentUser.HomeDir, actualResult)
+}
+
+func TestLookupOSHomeDirRemembersEachUser(t *testing.T) {
+	// ----------------------------------------------------------------
+	// setup your test
+
+	currentUser, err := user.Current()
+	if err != nil {
+		t.Skip("unable to find current user:", err)
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	firstResult, firstOk := LookupOSHomeDir(currentUser.Username)
+	secondResult, secondOk := LookupOSHomeDir(currentUser.Username)
+	_, missingOk := LookupOSHomeDir("shellexpand-no-such-user")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, firstOk)
+	assert.True(t, secondOk)
+	assert.Equal(t, currentUser.HomeDir, firstResult)
+	assert.Equal(t, firstResult, secondResult)
+	assert.False(t, missingOk)
+
+	_, cached := osHomeDirs.Load(currentUser.Username)
+	assert.True(t, cached)
+	_, cached = osHomeDirs.Load("shellexpand-no-such-user")
+	assert.True(t, cached)
+}
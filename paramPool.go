@@ -0,0 +1,112 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "sync"
+
+// we don't put really big slices back into the pool, for the same
+// reason that we don't pool really big buffers
+const maxPooledSliceLen = 64
+
+// paramScratch holds the slices that we need to expand a single
+// parameter
+//
+// we pool these, so that busy services don't allocate new slices for
+// every parameter in every string that they expand
+type paramScratch struct {
+	// the backing array for paramDesc.parts
+	parts []string
+
+	// the backing array for paramExpansion.values
+	values []string
+
+	// the backing array for the results of expanding each value
+	results []string
+}
+
+var paramScratchPool = sync.Pool{
+	New: func() interface{} {
+		return &paramScratch{
+			// most operators have no more than 2 parts after the
+			// param name
+			parts: make([]string, 0, 3),
+		}
+	},
+}
+
+// getParamScratch returns a paramScratch with empty slices in it
+//
+// call putParamScratch() when you have finished with it
+func getParamScratch() *paramScratch {
+	return paramScratchPool.Get().(*paramScratch)
+}
+
+// putParamScratch hands a paramScratch back to the pool
+//
+// you must not use the paramScratch (or any of its slices) after calling
+// this
+func putParamScratch(s *paramScratch) {
+	if cap(s.parts) > maxPooledSliceLen || cap(s.values) > maxPooledSliceLen || cap(s.results) > maxPooledSliceLen {
+		return
+	}
+
+	// don't keep the strings alive while we sit in the pool
+	s.parts = clearStrings(s.parts)
+	s.values = clearStrings(s.values)
+	s.results = clearStrings(s.results)
+
+	paramScratchPool.Put(s)
+}
+
+// newParamParts returns a paramDesc.parts slice that holds the given
+// param name, using the paramScratch's backing array if we have one
+func newParamParts(s *paramScratch, name string) []string {
+	if s == nil {
+		return append(make([]string, 0, 3), name)
+	}
+
+	return append(s.parts[:0], name)
+}
+
+// clearStrings empties the given slice, without giving up its backing
+// array
+func clearStrings(input []string) []string {
+	for i := range input {
+		input[i] = ""
+	}
+
+	return input[:0]
+}
@@ -0,0 +1,113 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutParamScratchForgetsTheStrings(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := &paramScratch{
+		parts:   []string{"PARAM1", "word"},
+		values:  []string{"foo"},
+		results: []string{"foo"},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	putParamScratch(unit)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Empty(t, unit.parts)
+	assert.Equal(t, []string{"", ""}, unit.parts[:2])
+	assert.Equal(t, []string{""}, unit.values[:1])
+	assert.Equal(t, []string{""}, unit.results[:1])
+}
+
+func TestPooledParamSlicesAreNotSharedBetweenExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "value of " + key, true
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	var wg sync.WaitGroup
+	results := make([]string, 8)
+	errs := make([]error, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				input := fmt.Sprintf("${P%d_%d:-x} ${P%d:+y}/${P%d#value}", i, j, i, i)
+				expected := fmt.Sprintf("value of P%d_%d y/ of P%d", i, j, i)
+				results[i], errs[i] = Expand(input, cb)
+				if errs[i] != nil || results[i] != expected {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	for i := range results {
+		assert.Nil(t, errs[i])
+		assert.Equal(t, fmt.Sprintf("value of P%d_99 y/ of P%d", i, i), results[i])
+	}
+}
@@ -37,6 +37,7 @@ package shellexpand
 
 import (
 	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -101,15 +102,102 @@ const (
 	paramExpandAsPrompt
 	// ${var@Q} -> single quoted value of var
 	paramExpandSingleQuoted
+	// ${var@K} / ${var@k} -> quoted value of var, as key-value pairs for arrays
+	paramExpandAsKeyValuePairs
 )
 
 type paramDesc struct {
 	kind     int
 	parts    []string
 	indirect bool
+
+	// true for ${var-word}, ${var=word}, ${var?word} and ${var+word},
+	// which only care whether var is set, not whether it is empty
+	unsetOnly bool
+
+	// true if the parameter was not set when we looked it up
+	//
+	// startParamExpansion() sets this up before calling any of the
+	// expansion functions
+	unset bool
+
+	// any zsh ${(flags)var} flags
+	flags *zshFlags
+
+	// the word that follows the operator, which is only expanded
+	// if it is needed
+	//
+	// expandParameter() sets this up before calling any of the
+	// expansion functions
+	operand *lazyWord
+
+	// where our slices came from, if they came from the pool
+	scratch *paramScratch
+}
+
+// word returns the word that follows the operator (e.g. the default
+// value in ${var:-word}), or an empty string if there isn't one
+//
+// in ${var/pattern/word}, it is the replacement, because that is the
+// part that gets expanded
+func (p paramDesc) word() string {
+	if p.isSearchReplace() {
+		return p.parts[2]
+	}
+	if len(p.parts) < 2 {
+		return ""
+	}
+
+	return p.parts[1]
+}
+
+// isSearchReplace returns true for ${var/pattern/replacement} and its
+// variants
+func (p paramDesc) isSearchReplace() bool {
+	switch p.kind {
+	case paramExpandSearchReplaceLongestFirstMatch,
+		paramExpandSearchReplaceLongestAllMatches,
+		paramExpandSearchReplaceLongestPrefix,
+		paramExpandSearchReplaceLongestSuffix:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNull returns true if the operator should treat the parameter as
+// having no value
+func (p paramDesc) isNull(paramValue string) bool {
+	if p.unsetOnly {
+		return p.unset
+	}
+
+	return paramValue == ""
+}
+
+// expandWord returns the expansion of the word that follows the
+// operator
+//
+// the word is expanded the first time this is called, and never again
+func (p paramDesc) expandWord(cb ExpansionCallbacks) (string, error) {
+	if p.operand == nil {
+		return expandWord(p.word(), cb)
+	}
+
+	return p.operand.expand(cb)
 }
 
 func parseParameter(input string) (paramDesc, bool) {
+	return parseParameterWithScratch(input, nil)
+}
+
+// parseParameterWithScratch parses the given parameter, just like
+// parseParameter() does
+//
+// if `scratch` is not nil, the paramDesc that we return uses its slices;
+// paramExpansion.release() hands it back to the pool once the parameter
+// has been expanded
+func parseParameterWithScratch(input string, scratch *paramScratch) (paramDesc, bool) {
 	// shorthand
 	inputLen := len(input)
 	maxInput := inputLen - 1
@@ -123,7 +211,7 @@ func parseParameter(input string) (paramDesc, bool) {
 	var retval paramDesc
 
 	// make sure we're looking at something that has the shape of a parameter
-	if input[0] != '$' {
+	if inputLen < 2 || input[0] != '$' {
 		return paramDesc{}, false
 	}
 	if input[1] == '{' && input[maxInput] != '}' {
@@ -147,13 +235,15 @@ func parseParameter(input string) (paramDesc, bool) {
 		switch paramType {
 		case paramTypeName:
 			return paramDesc{
-				kind:  paramExpandToValue,
-				parts: []string{input[1:inputLen]},
+				kind:    paramExpandToValue,
+				parts:   newParamParts(scratch, input[1:inputLen]),
+				scratch: scratch,
 			}, true
 		default:
 			return paramDesc{
-				kind:  paramExpandToValue,
-				parts: []string{input},
+				kind:    paramExpandToValue,
+				parts:   newParamParts(scratch, input),
+				scratch: scratch,
 			}, true
 		}
 	}
@@ -175,13 +265,15 @@ func parseParameter(input string) (paramDesc, bool) {
 		switch paramType {
 		case paramTypeName:
 			return paramDesc{
-				kind:  paramExpandToValue,
-				parts: []string{input[2:inputLen]},
+				kind:    paramExpandToValue,
+				parts:   newParamParts(scratch, input[2:inputLen]),
+				scratch: scratch,
 			}, true
 		default:
 			return paramDesc{
-				kind:  paramExpandToValue,
-				parts: []string{"$" + input[2:inputLen]},
+				kind:    paramExpandToValue,
+				parts:   newParamParts(scratch, "$"+input[2:inputLen]),
+				scratch: scratch,
 			}, true
 		}
 	}
@@ -192,28 +284,32 @@ func parseParameter(input string) (paramDesc, bool) {
 	// have been wrapped in brances
 	if isNumericStringWithoutLeadingZero(input[2:inputLen]) {
 		return paramDesc{
-			kind:  paramExpandToValue,
-			parts: []string{"$" + input[2:inputLen]},
+			kind:    paramExpandToValue,
+			parts:   newParamParts(scratch, "$"+input[2:inputLen]),
+			scratch: scratch,
 		}, true
 	}
 
 	// special case - handle ${!prefix*} and ${prefix@} here
-	if input[0:3] == "${!" {
+	if input[0:3] == "${!" && isName(input[3:maxInput]) {
 		if input[maxInput:] == "*}" {
 			return paramDesc{
-				kind:  paramExpandPrefixNames,
-				parts: []string{input[3:maxInput]},
+				kind:    paramExpandPrefixNames,
+				parts:   newParamParts(scratch, input[3:maxInput]),
+				scratch: scratch,
 			}, true
 		} else if input[maxInput:] == "@}" {
 			return paramDesc{
-				kind:  paramExpandPrefixNamesDoubleQuoted,
-				parts: []string{input[3:maxInput]},
+				kind:    paramExpandPrefixNamesDoubleQuoted,
+				parts:   newParamParts(scratch, input[3:maxInput]),
+				scratch: scratch,
 			}, true
 		}
 	}
 
 	// special case - handle ${#parameter} here
-	if input[0:3] == "${#" && (isNameStartChar(rune(input[3])) || isNumericStartChar(rune(input[3])) || isShellSpecialChar(rune(input[3]))) {
+	firstChar, _ := utf8.DecodeRuneInString(input[3:])
+	if input[0:3] == "${#" && (isNameStartChar(firstChar) || isNumericStartChar(firstChar) || isShellSpecialChar(firstChar)) {
 		// we don't check the boolean return value, because we're 100%
 		// guaranteed to match the 1st char
 		paramType, paramEnd, _ = matchParam(input, 3)
@@ -223,25 +319,29 @@ func parseParameter(input string) (paramDesc, bool) {
 			switch paramType {
 			case paramTypeName:
 				return paramDesc{
-					kind:  paramExpandParamLength,
-					parts: []string{input[3:inputLen]},
+					kind:    paramExpandParamLength,
+					parts:   newParamParts(scratch, input[3:inputLen]),
+					scratch: scratch,
 				}, true
 			case paramTypeSpecial:
 				if input[3] == '@' || input[3] == '*' {
 					return paramDesc{
-						kind:  paramExpandNoOfPositionalParams,
-						parts: []string{"$" + input[3:4]},
+						kind:    paramExpandNoOfPositionalParams,
+						parts:   newParamParts(scratch, "$"+input[3:4]),
+						scratch: scratch,
 					}, true
 				}
 				return paramDesc{
-					kind:  paramExpandParamLength,
-					parts: []string{"$" + input[3:inputLen]},
+					kind:    paramExpandParamLength,
+					parts:   newParamParts(scratch, "$"+input[3:inputLen]),
+					scratch: scratch,
 				}, true
 
 			default:
 				return paramDesc{
-					kind:  paramExpandParamLength,
-					parts: []string{"$" + input[3:inputLen]},
+					kind:    paramExpandParamLength,
+					parts:   newParamParts(scratch, "$"+input[3:inputLen]),
+					scratch: scratch,
 				}, true
 			}
 		}
@@ -261,8 +361,8 @@ func parseParameter(input string) (paramDesc, bool) {
 	// this is not the easy question it should be
 	if input[2] == '!' {
 		// special case - indirect expansion is not supported for '$!'
-		// according to my testing
-		if input[3] == '!' {
+		// or '$$' according to my testing
+		if input[3] == '!' || input[3] == '$' {
 			return paramDesc{}, false
 		}
 
@@ -283,11 +383,15 @@ func parseParameter(input string) (paramDesc, bool) {
 	if !ok {
 		return paramDesc{}, false
 	}
+	// most operators have no more than 2 parts after the param name,
+	// so we make room for them now
+	retval.parts = newParamParts(scratch, "")
+	retval.scratch = scratch
 	switch paramType {
 	case paramTypeName:
-		retval.parts = append(retval.parts, input[start:paramEnd])
+		retval.parts[0] = input[start:paramEnd]
 	default:
-		retval.parts = append(retval.parts, "$"+input[start:paramEnd])
+		retval.parts[0] = "$" + input[start:paramEnd]
 	}
 
 	// special case - is that it?
@@ -308,6 +412,21 @@ func parseParameter(input string) (paramDesc, bool) {
 	return parseParameterOp(input[:inputLen], retval, opType, opEnd)
 }
 
+// caseChangeBadSubstitutions are the special parameters that bash refuses
+// to change the case of, according to my testing
+//
+// they are different when the parameter is used for indirection
+var caseChangeBadSubstitutions = map[bool]map[string]bool{
+	false: {"$#": true, "$?": true, "$-": true},
+	true:  {"$#": true, "$?": true, "$@": true},
+}
+
+// canChangeCase returns false if bash reports a bad substitution when
+// we try to change the case of the given parameter
+func canChangeCase(retval paramDesc) bool {
+	return !caseChangeBadSubstitutions[retval.indirect][retval.parts[0]]
+}
+
 func parseParameterOp(input string, retval paramDesc, opType, opEnd int) (paramDesc, bool) {
 	// shorthand
 	inputLen := len(input)
@@ -316,24 +435,28 @@ func parseParameterOp(input string, retval paramDesc, opType, opEnd int) (paramD
 	switch opType {
 	case paramOpUseDefaultValue:
 		retval.kind = paramExpandWithDefaultValue
+		retval.unsetOnly = input[opEnd-1] != ':'
 		if opEnd < maxInput {
 			retval.parts = append(retval.parts, input[opEnd+1:inputLen])
 		}
 		return retval, true
 	case paramOpAssignDefaultValue:
 		retval.kind = paramExpandSetDefaultValue
+		retval.unsetOnly = input[opEnd-1] != ':'
 		if opEnd < maxInput {
 			retval.parts = append(retval.parts, input[opEnd+1:inputLen])
 		}
 		return retval, true
 	case paramOpWriteError:
 		retval.kind = paramExpandWriteError
+		retval.unsetOnly = input[opEnd-1] != ':'
 		if opEnd < maxInput {
 			retval.parts = append(retval.parts, input[opEnd+1:inputLen])
 		}
 		return retval, true
 	case paramOpUseAlternativeValue:
 		retval.kind = paramExpandAlternativeValue
+		retval.unsetOnly = input[opEnd-1] != ':'
 		if opEnd < maxInput {
 			retval.parts = append(retval.parts, input[opEnd+1:inputLen])
 		}
@@ -420,14 +543,8 @@ func parseParameterOp(input string, retval paramDesc, opType, opEnd int) (paramD
 			}
 
 			retval.kind = paramExpandSearchReplaceLongestAllMatches
-			retval.parts = append(retval.parts, strings.Split(input[opEnd+2:inputLen], "/")...)
-
-			// if the replace string is missing, default is an empty string
-			if len(retval.parts) < 3 {
-				retval.parts = append(retval.parts, "")
-			}
-
-			// all done
+			pattern, replacement := splitSearchReplace(input[opEnd+2 : inputLen])
+			retval.parts = append(retval.parts, pattern, replacement)
 			return retval, true
 		case '%':
 			// according to my testing, if there's nothing after the
@@ -438,12 +555,8 @@ func parseParameterOp(input string, retval paramDesc, opType, opEnd int) (paramD
 			}
 
 			retval.kind = paramExpandSearchReplaceLongestSuffix
-			retval.parts = append(retval.parts, strings.Split(input[opEnd+2:inputLen], "/")...)
-
-			// if the replace string is missing, default is an empty string
-			if len(retval.parts) < 3 {
-				retval.parts = append(retval.parts, "")
-			}
+			pattern, replacement := splitSearchReplace(input[opEnd+2 : inputLen])
+			retval.parts = append(retval.parts, pattern, replacement)
 			return retval, true
 		case '#':
 			// according to my testing, if there's nothing after the
@@ -454,42 +567,46 @@ func parseParameterOp(input string, retval paramDesc, opType, opEnd int) (paramD
 			}
 
 			retval.kind = paramExpandSearchReplaceLongestPrefix
-			retval.parts = append(retval.parts, strings.Split(input[opEnd+2:inputLen], "/")...)
-
-			// if the replace string is missing, default is an empty string
-			if len(retval.parts) < 3 {
-				retval.parts = append(retval.parts, "")
-			}
+			pattern, replacement := splitSearchReplace(input[opEnd+2 : inputLen])
+			retval.parts = append(retval.parts, pattern, replacement)
 			return retval, true
 
 		default:
 			// this is the easy bit!
 			retval.kind = paramExpandSearchReplaceLongestFirstMatch
-			retval.parts = append(retval.parts, strings.Split(input[opEnd+1:inputLen], "/")...)
-
-			// if the replace string is missing, default is an empty string
-			if len(retval.parts) < 3 {
-				retval.parts = append(retval.parts, "")
-			}
+			pattern, replacement := splitSearchReplace(input[opEnd+1 : inputLen])
+			retval.parts = append(retval.parts, pattern, replacement)
 			return retval, true
 		}
 
 	case paramOpUppercaseFirstChar:
+		if !canChangeCase(retval) {
+			return paramDesc{}, false
+		}
 		retval.kind = paramExpandUppercaseFirstChar
 		retval.parts = append(retval.parts, input[opEnd+1:inputLen])
 		return retval, true
 
 	case paramOpUppercaseAllMatches:
+		if !canChangeCase(retval) {
+			return paramDesc{}, false
+		}
 		retval.kind = paramExpandUppercaseAllChars
 		retval.parts = append(retval.parts, input[opEnd+1:inputLen])
 		return retval, true
 
 	case paramOpLowercaseFirstChar:
+		if !canChangeCase(retval) {
+			return paramDesc{}, false
+		}
 		retval.kind = paramExpandLowercaseFirstChar
 		retval.parts = append(retval.parts, input[opEnd+1:inputLen])
 		return retval, true
 
 	case paramOpLowercaseAllMatches:
+		if !canChangeCase(retval) {
+			return paramDesc{}, false
+		}
 		retval.kind = paramExpandLowercaseAllChars
 		retval.parts = append(retval.parts, input[opEnd+1:inputLen])
 		return retval, true
@@ -509,9 +626,80 @@ func parseParameterOp(input string, retval paramDesc, opType, opEnd int) (paramD
 	case paramOpExpandDoubleQuotes:
 		retval.kind = paramExpandSingleQuoted
 		return retval, true
+	case paramOpExpandKeyValuePairs:
+		retval.kind = paramExpandAsKeyValuePairs
+		return retval, true
+
+	// ${var@U}, ${var@u} and ${var@L} are the same as ${var^^},
+	// ${var^} and ${var,,}, except that bash lets you use them on
+	// every special parameter
+	case paramOpTransformUppercase:
+		retval.kind = paramExpandUppercaseAllChars
+		retval.parts = append(retval.parts, "")
+		return retval, true
+	case paramOpTransformUppercaseFirstChar:
+		retval.kind = paramExpandUppercaseFirstChar
+		retval.parts = append(retval.parts, "")
+		return retval, true
+	case paramOpTransformLowercase:
+		retval.kind = paramExpandLowercaseAllChars
+		retval.parts = append(retval.parts, "")
+		return retval, true
 
 	default:
 		// unknown or unsupported operand
 		return paramDesc{}, false
 	}
 }
+
+// splitSearchReplace splits the `pattern/replacement` in
+// ${var/pattern/replacement} at the first '/' that is not escaped,
+// quoted, or part of a nested ${...}
+//
+// if there is no '/', the replacement is an empty string. Just like
+// bash, we remove the backslash from any \/ in the replacement, even
+// inside double quotes.
+func splitSearchReplace(input string) (string, string) {
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			// skip the escaped char
+			i++
+		case '\'', '"':
+			quoteEnd, ok := matchQuotes(input[i:])
+			if ok {
+				i += quoteEnd - 1
+			}
+		case '$':
+			varEnd, ok := matchVar(input[i:])
+			if ok {
+				i += varEnd - 1
+			}
+		case '/':
+			return input[:i], unescapeSlashes(input[i+1:])
+		}
+	}
+
+	// if we get here, there is no replacement
+	return input, ""
+}
+
+// unescapeSlashes turns every \/ in the input into a plain '/'
+func unescapeSlashes(input string) string {
+	if !strings.Contains(input, `\/`) {
+		return input
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(input); i++ {
+		if input[i] == '\\' && i+1 < len(input) {
+			i++
+			if input[i] != '/' {
+				buf.WriteByte('\\')
+			}
+		}
+		buf.WriteByte(input[i])
+	}
+
+	return buf.String()
+}
@@ -36,10 +36,12 @@
 package shellexpand
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 	"testing"
 
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -646,7 +648,6 @@ func TestParseParamShellSpecialDefaultValueWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$:-foo}",
 		"${!*:-foo}",
 		"${!@:-foo}",
 		"${!#:-foo}",
@@ -701,6 +702,70 @@ func TestParseParamPlingDoesNotSupportIndirection(t *testing.T) {
 	assert.Equal(t, expectedResult, actualResult)
 }
 
+func TestParseParamRejectsWhatBashCallsABadSubstitution(t *testing.T) {
+	t.Parallel()
+
+	// these are the combinations of parameter and operator that bash
+	// refuses to expand
+	testDataSet := []string{
+		// length, combined with anything else
+		"${#var:-x}",
+		"${#var#x}",
+		"${#var/a/b}",
+		"${#var:1}",
+		"${#var@Q}",
+		"${#!var}",
+		"${!#var}",
+		// indirection through $! or $$
+		"${!!}",
+		"${!$}",
+		"${!$:-x}",
+		"${!$#x}",
+		"${!$@Q}",
+		// changing the case of some special parameters
+		"${#^}",
+		"${?,}",
+		"${-^^}",
+		"${!@^}",
+		"${!#,,}",
+		"${!?^}",
+	}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		cb := ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				return "", false
+			},
+		}
+		unit := NewExpander(cb, WithStrict())
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, ok := parseParameter(testData)
+		_, err := unit.Expand(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.False(t, ok, testData)
+		assert.True(t, errors.Is(err, ErrBadSubstitution{}), testData)
+
+		// make sure that bash agrees with us
+		shellCase := shelltest.Case{
+			Vars: map[string]string{
+				"var": "abc",
+			},
+			Commands: []string{": " + testData},
+		}
+		shellActualResult, _ := shelltest.Run("bash", &shellCase)
+		assert.Contains(t, shellActualResult, "bad substitution", testData)
+	}
+}
+
 func TestParseParamSetDefaultValue(t *testing.T) {
 	t.Parallel()
 
@@ -893,7 +958,6 @@ func TestParseParamShellSpecialSetDefaultValueWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$:=foo}",
 		"${!*:=foo}",
 		"${!@:=foo}",
 		"${!#:=foo}",
@@ -1140,7 +1204,6 @@ func TestParseParamShellSpecialWriteErrorWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$:?foo}",
 		"${!*:?foo}",
 		"${!@:?foo}",
 		"${!#:?foo}",
@@ -1387,7 +1450,6 @@ func TestParseParamShellSpecialAlternativeValueWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$:+foo}",
 		"${!*:+foo}",
 		"${!@:+foo}",
 		"${!#:+foo}",
@@ -1687,7 +1749,6 @@ func TestParseParamShellSpecialSubstringWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$:500}",
 		"${!*:500}",
 		"${!@:500}",
 		"${!#:500}",
@@ -2043,7 +2104,6 @@ func TestParseParamShellSpecialSubstringLengthWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$:500:1000}",
 		"${!*:500:1000}",
 		"${!@:500:1000}",
 		"${!#:500:1000}",
@@ -2579,7 +2639,6 @@ func TestParseParamShellSpecialShortestPrefixWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$#FOO}",
 		"${!*#FOO}",
 		"${!@#FOO}",
 		"${!?#FOO}",
@@ -2849,7 +2908,6 @@ func TestParseParamShellSpecialLongestPrefixWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$##FOO}",
 		"${!*##FOO}",
 		"${!@##FOO}",
 		"${!###FOO}",
@@ -3120,7 +3178,6 @@ func TestParseParamShellSpecialShortestSuffixWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$%FOO}",
 		"${!*%FOO}",
 		"${!@%FOO}",
 		"${!?%FOO}",
@@ -3390,7 +3447,6 @@ func TestParseParamShellSpecialLongestSuffixWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$%%FOO}",
 		"${!*%%FOO}",
 		"${!@%%FOO}",
 		"${!#%%FOO}",
@@ -3691,7 +3747,6 @@ func TestParseParamShellSpecialSearchReplaceLongestFirstMatchWithIndirection(t *
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$/FOO/BAR}",
 		"${!*/FOO/BAR}",
 		"${!@/FOO/BAR}",
 		"${!#/FOO/BAR}",
@@ -3992,7 +4047,6 @@ func TestParseParamShellSpecialSearchReplaceLongestAllMatchesWithIndirection(t *
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$//FOO/BAR}",
 		"${!*//FOO/BAR}",
 		"${!@//FOO/BAR}",
 		"${!#//FOO/BAR}",
@@ -4293,7 +4347,6 @@ func TestParseParamShellSpecialSearchReplaceLongestPrefixWithIndirection(t *test
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$/#FOO/BAR}",
 		"${!*/#FOO/BAR}",
 		"${!@/#FOO/BAR}",
 		"${!#/#FOO/BAR}",
@@ -4594,7 +4647,6 @@ func TestParseParamShellSpecialSearchReplaceLongestSuffixWithIndirection(t *test
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$/%FOO/BAR}",
 		"${!*/%FOO/BAR}",
 		"${!@/%FOO/BAR}",
 		"${!#/%FOO/BAR}",
@@ -4838,9 +4890,6 @@ func TestParseParamShellSpecialUppercaseFirstChar(t *testing.T) {
 		"${$^abcde}",
 		"${*^abcde}",
 		"${@^abcde}",
-		"${#^abcde}",
-		"${?^abcde}",
-		"${-^abcde}",
 		"${0^abcde}",
 	}
 
@@ -4870,11 +4919,7 @@ func TestParseParamShellSpecialUppercaseFirstCharWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$^abcde}",
 		"${!*^abcde}",
-		"${!@^abcde}",
-		"${!#^abcde}",
-		"${!?^abcde}",
 		"${!-^abcde}",
 		"${!0^abcde}",
 	}
@@ -5114,9 +5159,6 @@ func TestParseParamShellSpecialUppercaseAllChars(t *testing.T) {
 		"${$^^abcde}",
 		"${*^^abcde}",
 		"${@^^abcde}",
-		"${#^^abcde}",
-		"${?^^abcde}",
-		"${-^^abcde}",
 		"${0^^abcde}",
 	}
 
@@ -5146,11 +5188,7 @@ func TestParseParamShellSpecialUppercaseAllCharsWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$^^abcde}",
 		"${!*^^abcde}",
-		"${!@^^abcde}",
-		"${!#^^abcde}",
-		"${!?^^abcde}",
 		"${!-^^abcde}",
 		"${!0^^abcde}",
 	}
@@ -5390,9 +5428,6 @@ func TestParseParamShellSpecialLowercaseFirstChar(t *testing.T) {
 		"${$,abcde}",
 		"${*,abcde}",
 		"${@,abcde}",
-		"${#,abcde}",
-		"${?,abcde}",
-		"${-,abcde}",
 		"${0,abcde}",
 	}
 
@@ -5422,11 +5457,7 @@ func TestParseParamShellSpecialLowercaseFirstCharWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$,abcde}",
 		"${!*,abcde}",
-		"${!@,abcde}",
-		"${!#,abcde}",
-		"${!?,abcde}",
 		"${!-,abcde}",
 		"${!0,abcde}",
 	}
@@ -5666,9 +5697,6 @@ func TestParseParamShellSpecialLowercaseAllChars(t *testing.T) {
 		"${$,,abcde}",
 		"${*,,abcde}",
 		"${@,,abcde}",
-		"${#,,abcde}",
-		"${?,,abcde}",
-		"${-,,abcde}",
 		"${0,,abcde}",
 	}
 
@@ -5698,11 +5726,7 @@ func TestParseParamShellSpecialLowercaseAllCharsWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$,,abcde}",
 		"${!*,,abcde}",
-		"${!@,,abcde}",
-		"${!#,,abcde}",
-		"${!?,,abcde}",
 		"${!-,,abcde}",
 		"${!0,,abcde}",
 	}
@@ -5945,7 +5969,6 @@ func TestParseParamShellSpecialDescribeFlagsWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$@a}",
 		"${!*@a}",
 		"${!@@a}",
 		"${!#@a}",
@@ -6192,7 +6215,6 @@ func TestParseParamShellSpecialAsDeclareWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$@A}",
 		"${!*@A}",
 		"${!@@A}",
 		"${!#@A}",
@@ -6439,7 +6461,6 @@ func TestParseParamShellSpecialEscapedWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$@E}",
 		"${!*@E}",
 		"${!@@E}",
 		"${!#@E}",
@@ -6686,7 +6707,6 @@ func TestParseParamShellSpecialAsPromptWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$@P}",
 		"${!*@P}",
 		"${!@@P}",
 		"${!#@P}",
@@ -6765,6 +6785,32 @@ func TestParseParamSingleQuoted(t *testing.T) {
 	assert.Equal(t, expectedResult, actualResult)
 }
 
+func TestParseParamCaseTransforms(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := map[string]paramDesc{
+		"${VAR@U}": {kind: paramExpandUppercaseAllChars, parts: []string{"VAR", ""}},
+		"${VAR@u}": {kind: paramExpandUppercaseFirstChar, parts: []string{"VAR", ""}},
+		"${VAR@L}": {kind: paramExpandLowercaseAllChars, parts: []string{"VAR", ""}},
+		"${?@U}":   {kind: paramExpandUppercaseAllChars, parts: []string{"$?", ""}},
+		"${VAR@K}": {kind: paramExpandAsKeyValuePairs, parts: []string{"VAR"}},
+		"${VAR@k}": {kind: paramExpandAsKeyValuePairs, parts: []string{"VAR"}},
+	}
+
+	for testData, expectedResult := range testDataSet {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, ok := parseParameter(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, ok, testData)
+		assert.Equal(t, expectedResult, actualResult, testData)
+	}
+}
+
 func TestParseParamSingleQuotedWithIndirection(t *testing.T) {
 	t.Parallel()
 
@@ -6933,7 +6979,6 @@ func TestParseParamShellSpecialSingleQuotedWithIndirection(t *testing.T) {
 	t.Parallel()
 
 	testDataSet := []string{
-		"${!$@Q}",
 		"${!*@Q}",
 		"${!@@Q}",
 		"${!#@Q}",
@@ -7007,3 +7052,46 @@ func TestParseParameterOpReturnsEmptyResultForUnknownOperationType(t *testing.T)
 	assert.False(t, ok)
 	assert.Equal(t, expectedResult, actualResult)
 }
+
+func TestParseParamRejectsTruncatedInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []string{"", "$", "${", "${VAR", "${VAR:"}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, ok := parseParameter(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.False(t, ok, input)
+		assert.Equal(t, paramDesc{}, actualResult, input)
+	}
+}
+
+func TestParseParamRejectsInvalidMultibyteNames(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := []string{"${é}", "${#é}", "${!é*}", "${!1*}", "${é:-word}", "${VAR\xff}"}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, ok := parseParameter(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.False(t, ok, input)
+	}
+}
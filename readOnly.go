@@ -0,0 +1,61 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+// WithReadOnly marks the given variables as read-only, just like the
+// `readonly` builtin in a UNIX shell does. Any attempt to assign to
+// them, such as ${var:=word}, fails with an ErrReadOnlyVar error, and
+// your AssignToVar callback is never called.
+//
+// ${var:=word} only assigns if `var` is unset or empty, so it is not
+// an error to use it on a read-only variable that has a value.
+func WithReadOnly(names ...string) Option {
+	return WithReadOnlyFilter(AllowNames(names...))
+}
+
+// WithReadOnlyFilter marks every variable that the filter returns true
+// for as read-only, in the same way that WithReadOnly() does
+func WithReadOnlyFilter(filter NameFilter) Option {
+	return func(opts *options) {
+		opts.readOnly = filter
+	}
+}
+
+// isReadOnly returns true if we are not allowed to assign to the given
+// variable
+func (cb ExpansionCallbacks) isReadOnly(name string) bool {
+	return cb.opts != nil && cb.opts.readOnly != nil && cb.opts.readOnly(name)
+}
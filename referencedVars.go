@@ -0,0 +1,68 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "sort"
+
+// ReferencedVars returns the names of all the variables that the input
+// refers to, sorted, without expanding anything. Use it to find out
+// which variables a template needs before you expand it.
+//
+// ${!prefix*} and ${!prefix@} refer to every variable whose name starts
+// with the prefix; they are listed as the prefix followed by a `*`.
+// Names inside $((...)) and $[...] are variables too.
+//
+// Shell special parameters and positional parameters are not included.
+func ReferencedVars(input string) []string {
+	names, prefixes, _ := findVarRefs(input)
+
+	// we use a map to remove any duplicates
+	seen := make(map[string]bool, len(names)+len(prefixes))
+	for _, name := range names {
+		seen[name] = true
+	}
+	for _, prefix := range prefixes {
+		seen[prefix+"*"] = true
+	}
+
+	retval := make([]string, 0, len(seen))
+	for name := range seen {
+		retval = append(retval, name)
+	}
+	sort.Strings(retval)
+
+	return retval
+}
@@ -0,0 +1,215 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"sort"
+)
+
+// Result is what ExpandResult() and ExpandDetailed() give you back
+type Result struct {
+	// Value is the expanded string
+	//
+	// if nothing changed, it is the input string itself, not a copy
+	Value string
+
+	// Changed is false if expanding the input did not change it. Use
+	// it to skip work, such as re-writing a file that has not changed.
+	Changed bool
+
+	// Warnings lists the things in the input that are legal, but are
+	// probably mistakes. See Lint() for what we look for. It is nil if
+	// we did not find any.
+	Warnings []Warning
+
+	// the fields below are only set by ExpandDetailed()
+
+	// VarsRead lists the variables (and positional and special
+	// parameters) that were looked up, in alphabetical order
+	VarsRead []string
+
+	// DefaultsUsed lists the variables whose default value was used,
+	// by ${var:-word}, ${var:=word} and friends, in alphabetical order
+	DefaultsUsed []string
+
+	// Assignments holds the values that were assigned to variables by
+	// ${var:=word} and ${var=word}, and by arithmetic (e.g. $((N=5)))
+	Assignments map[string]string
+
+	// PhasesChanged has an entry for each phase of expansion that ran.
+	// It is true if that phase changed the string.
+	PhasesChanged map[ExpansionPhase]bool
+}
+
+// ExpandResult expands the input string, just like Expand() does, and
+// also tells you whether anything in it changed, and whether anything
+// in it looks like a mistake.
+func ExpandResult(input string, cb ExpansionCallbacks) (Result, error) {
+	expanded, err := Expand(input, cb)
+	if err != nil {
+		return Result{}, err
+	}
+
+	retval := Result{
+		Value:   expanded,
+		Changed: expanded != input,
+	}
+	warnings := Lint(input)
+	if len(warnings) > 0 {
+		retval.Warnings = warnings
+	}
+
+	return retval, nil
+}
+
+// ExpandResult expands the input string, just like the package-level
+// ExpandResult() does
+func (e *Expander) ExpandResult(input string) (Result, error) {
+	ctx, done := e.withTimeout(context.Background())
+	cb := e.callbacks()
+	cb.ctx = ctx
+	retval, err := ExpandResult(input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
+
+// ExpandDetailed expands the input string, just like ExpandResult()
+// does, and also tells you what happened along the way: which
+// variables were read, which default values were used, what was
+// assigned, and which phases of expansion changed the string. Use it
+// in tools that need to make decisions based on what an expansion
+// actually did.
+//
+// It uses the same machinery as WithTrace(). If the callbacks already
+// have a TraceFunc, it still receives every TraceEvent.
+func ExpandDetailed(input string, cb ExpansionCallbacks) (Result, error) {
+	rec := resultRecorder{
+		vars:          map[string]bool{},
+		defaults:      map[string]bool{},
+		assignments:   map[string]string{},
+		phasesChanged: map[ExpansionPhase]bool{},
+	}
+
+	// we must not change the caller's options
+	var opts options
+	if cb.opts != nil {
+		opts = *cb.opts
+	}
+	next := opts.trace
+	opts.trace = func(event TraceEvent) {
+		rec.record(event)
+		if next != nil {
+			next(event)
+		}
+	}
+	cb.opts = &opts
+
+	retval, err := ExpandResult(input, cb)
+	if err != nil {
+		return Result{}, err
+	}
+
+	retval.VarsRead = sortedKeys(rec.vars)
+	retval.DefaultsUsed = sortedKeys(rec.defaults)
+	retval.Assignments = rec.assignments
+	retval.PhasesChanged = rec.phasesChanged
+	return retval, nil
+}
+
+// ExpandDetailed expands the input string, just like the package-level
+// ExpandDetailed() does
+func (e *Expander) ExpandDetailed(input string) (Result, error) {
+	ctx, done := e.withTimeout(context.Background())
+	cb := e.callbacks()
+	cb.ctx = ctx
+	retval, err := ExpandDetailed(input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, err
+}
+
+// resultRecorder builds up the details of an expansion for
+// ExpandDetailed(), from the TraceEvents that we send
+type resultRecorder struct {
+	vars          map[string]bool
+	defaults      map[string]bool
+	assignments   map[string]string
+	phasesChanged map[ExpansionPhase]bool
+}
+
+func (r *resultRecorder) record(event TraceEvent) {
+	switch event.Kind {
+	case TracePhase:
+		r.phasesChanged[event.Phase] = r.phasesChanged[event.Phase] || event.Input != event.Result
+
+	case TraceVarRead:
+		r.vars[event.Name] = true
+
+	case TraceAssignment:
+		r.assignments[event.Name] = event.Result
+
+	case TraceParameter:
+		if event.Name == "" {
+			return
+		}
+		r.vars[event.Name] = true
+
+		switch event.Operator {
+		case paramOperatorNames[paramExpandWithDefaultValue]:
+			if event.WordUsed {
+				r.defaults[event.Name] = true
+			}
+		case paramOperatorNames[paramExpandSetDefaultValue]:
+			if event.WordUsed {
+				r.defaults[event.Name] = true
+				r.assignments[event.Name] = event.Result
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of the given set, in alphabetical order
+func sortedKeys(set map[string]bool) []string {
+	retval := make([]string, 0, len(set))
+	for key := range set {
+		retval = append(retval, key)
+	}
+	sort.Strings(retval)
+
+	return retval
+}
@@ -0,0 +1,253 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandResultReportsUnchangedInput(t *testing.T) {
+	t.Parallel()
+
+	testData := []string{
+		"nothing to expand here at all",
+		"a lonely $ sign",
+		"an unterminated ${PARAM1",
+		"~unknown/user",
+	}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		cb := newTestCallbacks(streamTestVars())
+		cb.LookupHomeDir = func(user string) (string, bool) {
+			return "", false
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := ExpandResult(input, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, input, actualResult.Value)
+		assert.False(t, actualResult.Changed, input)
+	}
+}
+
+func TestExpandResultReportsChangedInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "hello ${PARAM1}"
+	expectedResult := Result{
+		Value:   "hello foo",
+		Changed: true,
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandResult(testData, newTestCallbacks(streamTestVars()))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderExpandResultReturnsErrors(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(streamTestVars()), WithStrict())
+	testData := "an unterminated ${PARAM1"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandResult(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.NotNil(t, err)
+	assert.Equal(t, Result{}, actualResult)
+}
+
+func TestExpandDetailedReportsWhatHappened(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("HOME", "/home/stuart")
+	cb := env.Callbacks()
+	input := "$HOME ${PORT:-8080} ${HOST:=localhost} ${HOME:-/root}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandDetailed(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "/home/stuart 8080 localhost /home/stuart", actualResult.Value)
+	assert.True(t, actualResult.Changed)
+	assert.Equal(t, []string{"HOME", "HOST", "PORT"}, actualResult.VarsRead)
+	assert.Equal(t, []string{"HOST", "PORT"}, actualResult.DefaultsUsed)
+	assert.Equal(t, map[string]string{"HOST": "localhost"}, actualResult.Assignments)
+	assert.Equal(
+		t,
+		map[ExpansionPhase]bool{
+			PhaseBraceExpansion:     false,
+			PhaseTildeExpansion:     false,
+			PhaseParameterExpansion: true,
+		},
+		actualResult.PhasesChanged,
+	)
+}
+
+func TestExpandDetailedStillCallsTheTraceFunc(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var events []TraceEvent
+	cb := NewMapCallbacks(map[string]string{"HOME": "/home/stuart"})
+	unit := NewExpander(cb, WithTrace(func(event TraceEvent) {
+		events = append(events, event)
+	}))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandDetailed("~{a,b}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, events)
+	assert.True(t, actualResult.PhasesChanged[PhaseBraceExpansion])
+	assert.Empty(t, actualResult.VarsRead)
+}
+
+func TestExpandDetailedReportsArithmeticReads(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("X", "41")
+	cb := env.Callbacks()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandDetailed("$((X+1))", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "42", actualResult.Value)
+	assert.Equal(t, []string{"X"}, actualResult.VarsRead)
+	assert.Empty(t, actualResult.Assignments)
+}
+
+func TestExpandDetailedReportsArithmeticAssignments(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	cb := env.Callbacks()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandDetailed("$((N=5))", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "5", actualResult.Value)
+	assert.Equal(t, map[string]string{"N": "5"}, actualResult.Assignments)
+	assert.Empty(t, actualResult.DefaultsUsed)
+}
+
+func TestExpandDetailedReportsIndirectionReferences(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("R", "X")
+	env.Set("X", "banana")
+	cb := env.Callbacks()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandDetailed("${!R}", cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "banana", actualResult.Value)
+	assert.Equal(t, []string{"R", "X"}, actualResult.VarsRead)
+}
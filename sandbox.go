@@ -0,0 +1,76 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "io/fs"
+
+// WithSandbox switches off everything that lets the input reach outside
+// of string expansion, no matter which callbacks you have supplied. Use
+// it when you expand templates that you do not trust.
+//
+// In the sandbox:
+//
+//   - $(...) command substitution returns ErrSandboxed, even if you have
+//     set the RunCommand callback
+//   - process substitution is never supported
+//   - assignments (such as ${var:=word}) return ErrSandboxed, and your
+//     AssignToVar callback is never called
+//   - $(< file) returns ErrSandboxed, unless you have used WithFS(); the
+//     real filesystem is never read
+//   - ~username is left as it is, because looking up a user's home
+//     directory means reading the password database
+//
+// Variables are still looked up. Combine WithSandbox() with
+// WithNameFilter() and WithBudget() to limit what a template can read,
+// and how much work it can do.
+func WithSandbox() Option {
+	return func(opts *options) {
+		opts.sandbox = true
+	}
+}
+
+func (cb ExpansionCallbacks) sandboxed() bool {
+	return cb.opts != nil && cb.opts.sandbox
+}
+
+// sandboxFS is the filesystem that we use in the sandbox
+//
+// it refuses to open anything
+type sandboxFS struct{}
+
+func (sandboxFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+}
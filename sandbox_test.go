@@ -0,0 +1,212 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSandboxExpander(called *[]string) *Expander {
+	vars := map[string]string{
+		"PARAM1": "foo",
+		"HOME":   "/home/me",
+	}
+
+	return NewExpander(
+		ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				retval, ok := vars[key]
+				return retval, ok
+			},
+			AssignToVar: func(key, value string) error {
+				*called = append(*called, "AssignToVar")
+				vars[key] = value
+				return nil
+			},
+			LookupHomeDir: func(user string) (string, bool) {
+				*called = append(*called, "LookupHomeDir")
+				return "/home/" + user, true
+			},
+			RunCommand: func(ctx context.Context, args []string) (string, error) {
+				*called = append(*called, "RunCommand")
+				return "output", nil
+			},
+		},
+		WithSandbox(),
+		WithFS(fstest.MapFS{
+			"secret.txt": &fstest.MapFile{Data: []byte("secret")},
+		}),
+	)
+}
+
+func TestWithSandboxRejectsCommandSubstitution(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var called []string
+	unit := newTestSandboxExpander(&called)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("$(cat /etc/passwd)")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrSandboxed{}))
+	assert.Equal(t, "command substitution is not allowed in the sandbox", err.Error())
+	assert.Empty(t, called)
+}
+
+func TestWithSandboxRejectsAssignments(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var called []string
+	unit := newTestSandboxExpander(&called)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${PARAM2:=bar}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrSandboxed{}))
+	assert.Empty(t, called)
+}
+
+func TestWithSandboxDoesNotLookUpHomeDirectories(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var called []string
+	unit := newTestSandboxExpander(&called)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("~root/bin ~/bin")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "~root/bin /home/me/bin", actualResult)
+	assert.Empty(t, called)
+}
+
+func TestWithSandboxRefusesToReadFilesWithoutWithFS(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(NewMapCallbacks(nil), WithSandbox())
+
+	for _, testData := range []string{"$(< /etc/passwd)", "$(< go.mod)"} {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrSandboxed{}), "%s: %v", testData, err)
+		assert.Equal(t, "reading files is not allowed in the sandbox", err.Error())
+	}
+
+	_, err := fs.ReadFile(unit.callbacks().fs(), "go.mod")
+	assert.True(t, errors.Is(err, fs.ErrPermission))
+}
+
+func TestWithSandboxReadsFilesFromWithFS(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var called []string
+	unit := newTestSandboxExpander(&called)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("$(< secret.txt)")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", actualResult)
+	assert.Empty(t, called)
+}
+
+func TestWithSandboxStillExpandsVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var called []string
+	unit := newTestSandboxExpander(&called)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("${PARAM1} ${PARAM2:-default} {a,b}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo default a b", actualResult)
+}
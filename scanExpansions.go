@@ -0,0 +1,329 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// the phases of expansion that expandSpans() can apply
+const (
+	scanBraces = 1 << iota
+	scanTilde
+	scanParams
+
+	// not a phase: treat quotes the way that bash does
+	scanQuotes
+
+	// not a phase: we are scanning the word after a parameter
+	// expansion's operator
+	scanOperatorWord
+
+	// not a phase: a backslash ends a tilde prefix, like it does on
+	// Windows
+	scanWindowsPaths
+
+	// not a phase: %var% is a variable too
+	scanPercentVars
+
+	// not a phase: %var% is the only kind of variable
+	scanPercentOnly
+
+	// not a phase: a backslash is just a backslash
+	scanNoEscapes
+
+	// not a phase: %x is a systemd-style specifier
+	scanSpecifiers
+
+	// not a phase: $(var) is a variable too
+	scanMakeVars
+
+	// not a phase: $(...) is command substitution
+	scanCommands
+
+	// not a phase: $((...)) is arithmetic expansion
+	scanArithmetic
+
+	// not a phase: $[...] is arithmetic expansion too
+	scanLegacyArithmetic
+
+	// the options that every frame inherits from its parent
+	scanOptions = scanQuotes | scanWindowsPaths | scanPercentVars | scanPercentOnly | scanNoEscapes | scanSpecifiers | scanMakeVars | scanCommands | scanArithmetic | scanLegacyArithmetic
+)
+
+// the kinds of span that scanExpansions() looks for
+const (
+	// a backslash, and the character that it escapes
+	spanEscape = iota
+	// a ${ that is never closed
+	spanUnterminated
+	// $var or ${...}
+	spanParam
+	// a tilde prefix at the start of a word
+	spanTilde
+	// a whole word that contains a brace sequence or brace pattern
+	spanBraces
+	// %var% or %%
+	spanPercentVar
+	// a systemd-style %x specifier, or %%
+	spanSpecifier
+	// $(var)
+	spanMakeVar
+	// $(...) command substitution
+	spanCommand
+	// $((...)) arithmetic expansion
+	spanArithmetic
+)
+
+// expansionSpan is a part of the input string that expandSpans() needs
+// to look at
+//
+// anything in between two spans is copied across untouched
+type expansionSpan struct {
+	kind  int
+	start int
+	end   int
+}
+
+// scanExpansions walks the input string once, and finds everything in it
+// that the given phases of expansion may change
+//
+// the spans that we return never overlap, and are in the order that
+// they appear in the input string
+func scanExpansions(input string, phases int) []expansionSpan {
+	var retval []expansionSpan
+
+	// these are the only characters that can start an expansion
+	stopChars := "\\$"
+
+	// brace expansion and tilde expansion both care about words
+	wordsMatter := phases&(scanBraces|scanTilde) != 0
+	if wordsMatter {
+		stopChars = "\\$~{"
+	}
+	if phases&scanNoEscapes != 0 {
+		stopChars = stopChars[1:]
+	}
+	quotesMatter := phases&scanQuotes != 0
+	if quotesMatter {
+		stopChars += "'\""
+	}
+	switch {
+	case phases&scanPercentOnly != 0:
+		stopChars = "%"
+	case phases&(scanPercentVars|scanSpecifiers) != 0:
+		stopChars += "%"
+	}
+	wordStart := 0
+
+	// are we inside double quotes?
+	inDoubleQuotes := false
+
+	// the end of any tilde prefix that we are inside
+	//
+	// we don't record spans inside a tilde prefix, but we still need to
+	// look for braces in there
+	tildeEnd := 0
+
+	w := 0
+	for i := 0; i < len(input); i += w {
+		// jump straight to the next character that we are interested
+		// in; they are all ASCII, so we can never land in the middle of
+		// a multi-byte character
+		next := strings.IndexAny(input[i:], stopChars)
+		if next < 0 {
+			break
+		}
+
+		// any space, tab or newline in the text that we have just
+		// jumped over is the end of a word
+		if wordsMatter {
+			lastSpace := strings.LastIndexAny(input[i:i+next], blankChars)
+			if lastSpace >= 0 {
+				wordStart = i + lastSpace + 1
+			}
+		}
+		i += next
+		w = 1
+
+		switch input[i] {
+		case '\'':
+			// nothing inside single quotes is expanded
+			if inDoubleQuotes {
+				continue
+			}
+			quoteEnd := strings.IndexByte(input[i+w:], '\'')
+			if quoteEnd >= 0 {
+				w += quoteEnd + 1
+			}
+
+		case '"':
+			inDoubleQuotes = !inDoubleQuotes
+
+		case '\\':
+			// inside double quotes, only a few characters can be
+			// escaped
+			if inDoubleQuotes && (i+w == len(input) || !isDoubleQuoteEscapeChar(rune(input[i+w]))) {
+				continue
+			}
+
+			// whatever comes next is escaped
+			end := i + w
+			if end < len(input) {
+				_, escW := utf8.DecodeRuneInString(input[end:])
+				end += escW
+			}
+			if i >= tildeEnd {
+				retval = append(retval, expansionSpan{spanEscape, i, end})
+			}
+			w = end - i
+
+		case '$':
+			if phases&scanMakeVars != 0 {
+				varEnd, ok := findMakeVar(input[i:])
+				if ok {
+					if i >= tildeEnd {
+						retval = append(retval, expansionSpan{spanMakeVar, i, i + varEnd})
+					}
+					w = varEnd
+					continue
+				}
+			}
+
+			if phases&(scanArithmetic|scanLegacyArithmetic) != 0 {
+				exprEnd, ok := findArithmetic(input[i:])
+				if !ok && phases&scanLegacyArithmetic != 0 {
+					exprEnd, ok = findLegacyArithmetic(input[i:])
+				}
+				if ok {
+					if i >= tildeEnd {
+						retval = append(retval, expansionSpan{spanArithmetic, i, i + exprEnd})
+					}
+					w = exprEnd
+					continue
+				}
+			}
+
+			if phases&scanCommands != 0 {
+				cmdEnd, ok := findCommand(input[i:])
+				if ok {
+					if i >= tildeEnd {
+						retval = append(retval, expansionSpan{spanCommand, i, i + cmdEnd})
+					}
+					w = cmdEnd
+					continue
+				}
+			}
+
+			// variables are immune to brace and tilde expansion
+			varEnd, err := findVar(input[i:])
+			if err != nil {
+				// a ${ that is never closed is only a problem in
+				// strict mode
+				_, unterminated := err.(ErrMismatchedBrace)
+				if unterminated && i >= tildeEnd {
+					retval = append(retval, expansionSpan{spanUnterminated, i, i + w})
+				}
+				continue
+			}
+			if i >= tildeEnd {
+				retval = append(retval, expansionSpan{spanParam, i, i + varEnd})
+			}
+			w = varEnd
+
+		case '%':
+			if phases&scanParams == 0 {
+				continue
+			}
+			if phases&scanSpecifiers != 0 {
+				specEnd, ok := findSpecifier(input[i:])
+				if !ok {
+					continue
+				}
+				if i >= tildeEnd {
+					retval = append(retval, expansionSpan{spanSpecifier, i, i + specEnd})
+				}
+				w = specEnd
+				continue
+			}
+			varEnd, ok := findPercentVar(input[i:])
+			if !ok {
+				continue
+			}
+			if i >= tildeEnd {
+				retval = append(retval, expansionSpan{spanPercentVar, i, i + varEnd})
+			}
+			w = varEnd
+
+		case '~':
+			// tilde expansion only happens at the start of a word
+			if phases&scanTilde == 0 || i != wordStart || inDoubleQuotes {
+				continue
+			}
+			windows := phases&scanWindowsPaths != 0
+			prefixEnd, _ := matchTildePrefix(input[i:], windows)
+			if windows && isWindowsTildeSeparator(input[i:], prefixEnd) {
+				prefixEnd++
+			}
+			retval = append(retval, expansionSpan{spanTilde, i, i + prefixEnd})
+			tildeEnd = i + prefixEnd
+
+		case '{':
+			if phases&scanBraces == 0 || inDoubleQuotes {
+				continue
+			}
+			bracesEnd, ok := matchBraceExpansion(input[i:])
+			if !ok {
+				continue
+			}
+
+			// brace expansion happens first, and it applies to the whole
+			// word ... so it replaces anything else that we have found
+			// in this word
+			for len(retval) > 0 && retval[len(retval)-1].start >= wordStart {
+				retval = retval[:len(retval)-1]
+			}
+			wordEnd := findPostscriptEnd(input, i+bracesEnd)
+			retval = append(retval, expansionSpan{spanBraces, wordStart, wordEnd})
+			tildeEnd = 0
+			w = wordEnd - i
+		}
+	}
+
+	// all done
+	return retval
+}
@@ -0,0 +1,178 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanExpansionsFindsEverythingInOnePass(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "~/bin $HOME \\$ a{b,c} $ ${HOME"
+	expectedResult := []expansionSpan{
+		{spanTilde, 0, 1},
+		{spanParam, 6, 11},
+		{spanEscape, 12, 14},
+		{spanBraces, 15, 21},
+		{spanUnterminated, 24, 25},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := scanExpansions(testData, scanBraces|scanTilde|scanParams)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestScanExpansionsBraceWordReplacesEarlierSpans(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "x ~/${PARAM1:-a b}{1,2} y"
+	expectedResult := []expansionSpan{
+		{spanBraces, 2, 23},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := scanExpansions(testData, scanBraces|scanTilde|scanParams)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestScanExpansionsOnlyFindsTildesAtStartOfWord(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "a~b ~c d~"
+	expectedResult := []expansionSpan{
+		{spanTilde, 4, 6},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := scanExpansions(testData, scanTilde)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandSpansMatchesExpandingInPhases(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"HOME":   "/home/stuart",
+		"PARAM1": "foo",
+	}
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			retval, ok := vars[key]
+			return retval, ok
+		},
+		LookupHomeDir: func(key string) (string, bool) {
+			return "/home/" + key, true
+		},
+	}
+	testData := []string{
+		"foo ~/bar",
+		"~ ~root a~b",
+		"~/{a,b}/$PARAM1",
+		"${PARAM2:-a b}{1,2} c",
+		"{a,{b,c}}d \\{1,2} \\$PARAM1 $ ${++}",
+		"file-{1..3}.txt",
+	}
+	phased := NewExpander(cb, WithTrace(func(TraceEvent) {}))
+
+	for _, input := range testData {
+		expectedResult, expectedErr := phased.Expand(input)
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Expand(input, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedErr, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func BenchmarkScanExpansions(b *testing.B) {
+	input := strings.Repeat(strings.Join(benchmarkCorpus, "\n")+"\n", 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scanExpansions(input, scanBraces|scanTilde|scanParams)
+	}
+}
+
+func BenchmarkMatchVar(b *testing.B) {
+	input := "$ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz_0123456789"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matchVar(input)
+	}
+}
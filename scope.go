@@ -0,0 +1,124 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "sort"
+
+// Scope is a layer of local variables, on top of another set of
+// callbacks (such as NewOSCallbacks(), or another Scope).
+//
+// Local variables shadow the variables underneath them. Assignments
+// (such as ${var:=word}) and exports always go into the innermost
+// Scope, so that expanding a request-scoped template never changes the
+// variables that everyone else is using.
+//
+// Scope is safe to use from multiple goroutines.
+type Scope struct {
+	// the variables that belong to this scope
+	locals *Env
+
+	// where we look for any variables that we don't have
+	parent ExpansionCallbacks
+}
+
+// NewScope creates an empty Scope on top of the given callbacks
+func NewScope(parent ExpansionCallbacks) *Scope {
+	return &Scope{
+		locals: NewEnv(),
+		parent: parent,
+	}
+}
+
+// NewScope creates an empty Scope on top of this one
+func (s *Scope) NewScope() *Scope {
+	return NewScope(s.Callbacks())
+}
+
+// Locals returns the variables that belong to this Scope. Use it to set
+// your overrides, and to see what has been assigned to afterwards.
+func (s *Scope) Locals() *Env {
+	return s.locals
+}
+
+// Lookup returns the value of the given variable, from this Scope if it
+// is set here, or from the callbacks underneath if it is not
+func (s *Scope) Lookup(name string) (string, bool) {
+	return s.Callbacks().callLookupVar(name)
+}
+
+// Callbacks returns a set of ExpansionCallbacks that use the Scope as
+// their backing store
+//
+// Home directories, translations and command substitution are all
+// handled by the callbacks underneath.
+func (s *Scope) Callbacks() ExpansionCallbacks {
+	return FirstOf(
+		ExpansionCallbacks{
+			AssignToVar:   s.locals.Set,
+			LookupVar:     s.locals.Lookup,
+			MatchVarNames: s.locals.MatchVarNames,
+			IsExported:    s.locals.IsExported,
+			ExportVar:     s.locals.Export,
+		},
+		s.parent,
+	)
+}
+
+// Scoped returns a copy of the Expander that expands in a new Scope,
+// on top of the Expander's callbacks. The copy has the same options as
+// the original Expander, and shares its caches and stats.
+//
+// The locals are copied into the new Scope. Anything that the copy
+// assigns to stays in the copy. Use NewScope() instead if you need to
+// see what was assigned.
+func (e *Expander) Scoped(locals map[string]string) *Expander {
+	// we sort the names, so that the Scope's Environ() always comes
+	// out in the same order
+	names := make([]string, 0, len(locals))
+	for name := range locals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	scope := NewScope(e.cb)
+	for _, name := range names {
+		scope.locals.Set(name, locals[name])
+	}
+
+	retval := *e
+	retval.cb = scope.Callbacks()
+	return &retval
+}
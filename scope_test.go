@@ -0,0 +1,130 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeShadowsVariablesUnderneath(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	globals := NewEnv()
+	globals.Set("HOST", "example.com")
+	globals.Set("PORT", "80")
+	unit := NewScope(globals.Callbacks())
+	unit.Locals().Set("PORT", "8080")
+	expectedResult := "example.com:8080"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("$HOST:$PORT", unit.Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestScopeAssignsToInnermostScope(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	globals := NewEnv()
+	globals.Set("HOST", "example.com")
+	outer := NewScope(globals.Callbacks())
+	unit := outer.NewScope()
+	expectedResult := "example.com:8080"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand("${HOST:=localhost}:${PORT:=8080}", unit.Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, []string{"PORT=8080"}, unit.Locals().Environ())
+	assert.Empty(t, outer.Locals().Environ())
+	assert.Equal(t, []string{"HOST=example.com"}, globals.Environ())
+
+	value, ok := unit.Lookup("PORT")
+	assert.True(t, ok)
+	assert.Equal(t, "8080", value)
+	_, ok = outer.Lookup("PORT")
+	assert.False(t, ok)
+}
+
+func TestExpanderScopedDoesNotChangeSharedVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	globals := NewEnv()
+	globals.Set("GREETING", "hello")
+	expander := NewExpander(globals.Callbacks(), WithStrict())
+	unit := expander.Scoped(map[string]string{"NAME": "stuart"})
+	expectedResult := "hello stuart, from shellexpand"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("$GREETING $NAME, from ${APP:=shellexpand}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, []string{"GREETING=hello"}, globals.Environ())
+
+	// the original Expander cannot see the locals
+	actualResult, err = expander.Expand("${NAME:-nobody}")
+	assert.Nil(t, err)
+	assert.Equal(t, "nobody", actualResult)
+}
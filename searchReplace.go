@@ -0,0 +1,188 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// expandParamSearchReplaceFirstMatch replaces the first (longest) match
+// of the pattern in ${var/pattern/replacement}
+func expandParamSearchReplaceFirstMatch(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+	return searchReplace(paramValue, paramDesc, cb, false)
+}
+
+// expandParamSearchReplaceAllMatches replaces every match of the pattern
+// in ${var//pattern/replacement}
+func expandParamSearchReplaceAllMatches(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+	return searchReplace(paramValue, paramDesc, cb, true)
+}
+
+// expandParamSearchReplacePrefix replaces the longest prefix that matches
+// the pattern in ${var/#pattern/replacement}
+func expandParamSearchReplacePrefix(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+	pattern := paramDesc.parts[1]
+
+	// just like bash, an empty pattern matches the start of any value
+	// that is set, e.g. ${var/#/x} puts an `x` in front of it
+	if pattern == "" {
+		if paramDesc.unset {
+			return paramValue, true, nil
+		}
+		replacement, err := paramDesc.expandWord(cb)
+		return replacement + paramValue, true, err
+	}
+
+	g, err := cb.compileGlob(cb.searchPattern(pattern), globMatchLongestPrefix)
+	if err != nil {
+		return "", false, err
+	}
+
+	err = cb.spendGlobMatch()
+	if err != nil {
+		return "", false, err
+	}
+
+	pos, success, err := g.MatchLongestPrefix(paramValue)
+	if err != nil {
+		return "", false, ErrBadPattern{pattern, err}
+	}
+	if success {
+		replacement, err := paramDesc.expandWord(cb)
+		return replacement + paramValue[pos:], true, err
+	}
+
+	return paramValue, true, nil
+}
+
+// expandParamSearchReplaceSuffix replaces the longest suffix that matches
+// the pattern in ${var/%pattern/replacement}
+func expandParamSearchReplaceSuffix(paramName, paramValue string, paramDesc paramDesc, cb ExpansionCallbacks) (string, bool, error) {
+	pattern := paramDesc.parts[1]
+
+	// and it matches the end of it too, e.g. ${var/%/x} puts an `x`
+	// after it
+	if pattern == "" {
+		if paramDesc.unset {
+			return paramValue, true, nil
+		}
+		replacement, err := paramDesc.expandWord(cb)
+		return paramValue + replacement, true, err
+	}
+
+	g, err := cb.compileGlob(cb.searchPattern(pattern), globMatchLongestSuffix)
+	if err != nil {
+		return "", false, err
+	}
+
+	err = cb.spendGlobMatch()
+	if err != nil {
+		return "", false, err
+	}
+
+	pos, success, err := g.MatchLongestSuffix(paramValue)
+	if err != nil {
+		return "", false, ErrBadPattern{pattern, err}
+	}
+	if success {
+		replacement, err := paramDesc.expandWord(cb)
+		return paramValue[:pos] + replacement, true, err
+	}
+
+	return paramValue, true, nil
+}
+
+// searchReplace replaces the leftmost, longest match of the pattern in
+// the value; set `all` to replace every match that follows it too
+//
+// just like UNIX shells, an empty pattern matches nothing
+func searchReplace(paramValue string, paramDesc paramDesc, cb ExpansionCallbacks, all bool) (string, bool, error) {
+	pattern := paramDesc.parts[1]
+	if pattern == "" {
+		return paramValue, true, nil
+	}
+
+	g, err := cb.compileGlob(cb.searchPattern(pattern), globMatchLongestPrefix)
+	if err != nil {
+		return "", false, err
+	}
+
+	var buf strings.Builder
+	last := 0
+	w := 0
+	for i := 0; i < len(paramValue); i += w {
+		_, w = utf8.DecodeRuneInString(paramValue[i:])
+
+		err = cb.spendGlobMatch()
+		if err != nil {
+			return "", false, err
+		}
+
+		// does the pattern match here?
+		end, success, err := g.MatchLongestPrefix(paramValue[i:])
+		if err != nil {
+			return "", false, ErrBadPattern{pattern, err}
+		}
+		if !success || end == 0 {
+			continue
+		}
+
+		// the replacement is only expanded once, no matter how many
+		// matches there are
+		replacement, err := paramDesc.expandWord(cb)
+		if err != nil {
+			return "", false, err
+		}
+		buf.WriteString(paramValue[last:i])
+		buf.WriteString(replacement)
+		last = i + end
+		if !all {
+			break
+		}
+
+		// carry on from the end of the match
+		w = end
+	}
+
+	// did we replace anything at all?
+	if buf.Len() == 0 && last == 0 {
+		return paramValue, true, nil
+	}
+
+	buf.WriteString(paramValue[last:])
+	return buf.String(), true, nil
+}
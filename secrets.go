@@ -0,0 +1,215 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"sync"
+)
+
+// RedactionMarker is what we show instead of the value of a secret
+// variable
+const RedactionMarker = "[REDACTED]"
+
+// WithSecrets marks the given variables as secrets. Their values never
+// appear in the Expander's error messages or TraceEvents; you see
+// RedactionMarker instead.
+//
+// Secrets are still expanded as normal. Only what we report about the
+// expansion is masked.
+func WithSecrets(names ...string) Option {
+	return WithSecretFilter(AllowNames(names...))
+}
+
+// WithSecretFilter marks every variable that the filter returns true for
+// as a secret, in the same way that WithSecrets() does
+//
+// Wherever a secret's value appears in an error message or in a
+// TraceEvent, it is replaced by RedactionMarker. So is anything that a
+// parameter expansion derives from it, such as ${SECRET:0:4} or
+// ${SECRET^^}. A TraceParameter event for a secret still tells you the
+// variable's name, but not its value, or what it expanded to.
+//
+// Masking works by searching for each of these values, so a very short
+// value (such as ${SECRET:0:1}) masks every copy of it in the message.
+//
+// Error messages are masked, but you can still use errors.As() to get
+// at the error underneath, which is not masked. Don't show those errors
+// to anyone who must not see your secrets.
+func WithSecretFilter(filter NameFilter) Option {
+	return func(opts *options) {
+		opts.secrets = filter
+	}
+}
+
+// secretValues remembers the values of the secret variables that we
+// have looked up, so that we can mask them
+type secretValues struct {
+	mu     sync.Mutex
+	values []string
+}
+
+// isSecret returns true if the given variable is a secret
+func (cb ExpansionCallbacks) isSecret(name string) bool {
+	return cb.opts != nil && cb.opts.secrets != nil && cb.opts.secrets(name)
+}
+
+// rememberSecret makes a note of the value, if it belongs to a secret
+// variable
+func (cb ExpansionCallbacks) rememberSecret(name, value string) {
+	if cb.secrets == nil || value == "" || !cb.isSecret(name) {
+		return
+	}
+
+	cb.secrets.mu.Lock()
+	defer cb.secrets.mu.Unlock()
+	for _, known := range cb.secrets.values {
+		if known == value {
+			return
+		}
+	}
+	cb.secrets.values = append(cb.secrets.values, value)
+}
+
+// rememberDerivedSecret makes a note of what a secret variable expanded
+// to, so that anything derived from its value (such as ${SECRET:0:4} or
+// ${SECRET^^}) is masked too
+//
+// ${#SECRET} only tells you how long the secret is, and the word in
+// ${SECRET:+word} is not part of the secret, so we leave those alone
+func (cb ExpansionCallbacks) rememberDerivedSecret(name string, desc paramDesc, values []string, result string) {
+	if cb.secrets == nil || !cb.isSecret(name) {
+		return
+	}
+	switch desc.kind {
+	case paramExpandParamLength, paramExpandAlternativeValue:
+		return
+	}
+
+	// if the secret is empty, there is nothing to derive anything from
+	if desc.isNull(strings.Join(values, "")) {
+		return
+	}
+
+	cb.rememberSecret(name, result)
+}
+
+// mask replaces every secret value in the input with RedactionMarker
+func (s *secretValues) mask(input string) string {
+	if s == nil {
+		return input
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, value := range s.values {
+		input = strings.ReplaceAll(input, value, RedactionMarker)
+	}
+
+	return input
+}
+
+// empty returns true if we have not seen any secrets yet
+func (s *secretValues) empty() bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.values) == 0
+}
+
+// maskEvent removes any secrets from a TraceEvent
+func (cb ExpansionCallbacks) maskEvent(event TraceEvent) TraceEvent {
+	if cb.secrets == nil {
+		return event
+	}
+
+	// we don't even show part of a secret's value
+	if event.Kind != TracePhase && cb.isSecret(event.Name) {
+		for i := range event.Values {
+			event.Values[i] = RedactionMarker
+		}
+		event.Result = RedactionMarker
+		return event
+	}
+
+	event.Input = cb.secrets.mask(event.Input)
+	event.Result = cb.secrets.mask(event.Result)
+
+	// ${!REF} looks up the variable that REF names, so a secret can
+	// end up as the name of a variable
+	event.Name = cb.secrets.mask(event.Name)
+	for i, value := range event.Values {
+		event.Values[i] = cb.secrets.mask(value)
+	}
+
+	return event
+}
+
+// maskError removes any secrets from the error's message
+func (cb ExpansionCallbacks) maskError(err error) error {
+	if err == nil || cb.secrets.empty() {
+		return err
+	}
+
+	// callers use ExpansionError to show where the problem is, so we
+	// keep it at the top
+	expErr, ok := err.(ExpansionError)
+	if ok {
+		expErr.Substring = cb.secrets.mask(expErr.Substring)
+		expErr.Err = maskedError{expErr.Err, cb.secrets.mask(expErr.Err.Error())}
+		return expErr
+	}
+
+	return maskedError{err, cb.secrets.mask(err.Error())}
+}
+
+// maskedError is an error whose message has had any secrets removed
+type maskedError struct {
+	err error
+	msg string
+}
+
+func (e maskedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the error underneath, which is not masked
+func (e maskedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,276 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSecretsTestExpander(opts ...Option) *Expander {
+	vars := map[string]string{
+		"TOKEN": "s3cr3t",
+		"USER":  "stuart",
+		"REF":   "hunter2",
+	}
+
+	return NewExpander(
+		ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				retval, ok := vars[key]
+				return retval, ok
+			},
+		},
+		opts...,
+	)
+}
+
+func TestWithSecretsMasksErrorMessages(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var buf bytes.Buffer
+	unit := newSecretsTestExpander(
+		WithSecrets("TOKEN"),
+		WithErrorWriter(&buf),
+	)
+	expectedMessage := "MISSING: bad token " + RedactionMarker
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${MISSING:?bad token $TOKEN}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), expectedMessage)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+	assert.Equal(t, expectedMessage+"\n", buf.String())
+
+	// the error underneath is still there
+	assert.True(t, errors.Is(err, ErrVarRequired{}))
+	var expErr ExpansionError
+	assert.True(t, errors.As(err, &expErr))
+}
+
+func TestWithSecretsMasksTraceEvents(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var events []TraceEvent
+	unit := newSecretsTestExpander(
+		WithSecrets("TOKEN"),
+		WithTrace(func(event TraceEvent) {
+			events = append(events, event)
+		}),
+	)
+	expectedResult := "stuart:s3cr3t"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("$USER:${TOKEN:-none}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.NotEmpty(t, events)
+
+	sawToken := false
+	for _, event := range events {
+		assert.NotContains(t, event.Result, "s3cr3t")
+		assert.NotContains(t, strings.Join(event.Values, " "), "s3cr3t")
+		if event.Kind == TraceParameter && event.Name == "TOKEN" {
+			sawToken = true
+			assert.Equal(t, RedactionMarker, event.Result)
+		}
+	}
+	assert.True(t, sawToken)
+}
+
+func TestWithSecretsMasksArithmeticTraceEvents(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var events []TraceEvent
+	unit := newSecretsTestExpander(
+		WithSecrets("TOKEN"),
+		WithTrace(func(event TraceEvent) {
+			events = append(events, event)
+		}),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("$((TOKEN + 1))")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+
+	sawToken := false
+	for _, event := range events {
+		assert.NotContains(t, event.Result, "s3cr3t")
+		assert.NotContains(t, event.Name, "s3cr3t")
+		if event.Kind == TraceVarRead && event.Name == "TOKEN" {
+			sawToken = true
+			assert.Equal(t, RedactionMarker, event.Result)
+		}
+	}
+	assert.True(t, sawToken)
+}
+
+func TestWithSecretsMasksValuesDerivedFromSecrets(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := map[string]string{
+		"${MISSING:?${TOKEN:0:4}}":          "MISSING: " + RedactionMarker,
+		"${MISSING:?${TOKEN^^}}":            "MISSING: " + RedactionMarker,
+		"${MISSING:?${TOKEN/3/e}}":          "MISSING: " + RedactionMarker,
+		"${MISSING:?x${TOKEN: -3}x}":        "MISSING: x" + RedactionMarker + "x",
+		"${MISSING:?${#TOKEN} ${TOKEN:+y}}": "MISSING: 6 y",
+	}
+
+	for input, expectedMessage := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		unit := newSecretsTestExpander(WithSecrets("TOKEN"))
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Error(t, err, input)
+		assert.Contains(t, err.Error(), expectedMessage, input)
+	}
+}
+
+func TestWithSecretsMasksIndirectNamesInTraceEvents(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var events []TraceEvent
+	unit := newSecretsTestExpander(
+		WithSecrets("REF"),
+		WithTrace(func(event TraceEvent) {
+			events = append(events, event)
+		}),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${!REF}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, events)
+	for _, event := range events {
+		assert.NotContains(t, event.Name, "hunter2")
+		assert.NotContains(t, event.Input, "hunter2")
+		assert.NotContains(t, event.Result, "hunter2")
+	}
+}
+
+func TestWithSecretFilterMatchesNames(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newSecretsTestExpander(
+		WithSecretFilter(func(name string) bool {
+			return strings.HasSuffix(name, "TOKEN")
+		}),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${TOKEN:0:1}${USER:?$TOKEN}${MISSING:?$TOKEN}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+	assert.Contains(t, err.Error(), RedactionMarker)
+}
+
+func TestSecretsAreNotMaskedByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newSecretsTestExpander()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${MISSING:?$TOKEN}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "s3cr3t")
+}
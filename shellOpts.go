@@ -0,0 +1,244 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ShellOpts holds the `set` and `shopt` flags that change how a UNIX
+// shell expands things. Use the WithShellOpts() option to give them to
+// an Expander.
+//
+// Every flag is off by default, so the zero value behaves just like
+// bash does out of the box.
+//
+// We do not perform pathname expansion, so the flags that control it
+// (such as noglob, nullglob and dotglob) are not here.
+//
+// There is no extglob flag either. Our pattern matcher does not support
+// bash's extended patterns, such as @(a|b) or !(x), so there is nothing
+// for it to switch on. Using one in a pattern (e.g. ${var#@(a|b)})
+// returns an ErrBadPattern.
+type ShellOpts struct {
+	// AllExport (set -a) exports every variable that is assigned to,
+	// using the ExportVar callback
+	AllExport bool
+
+	// NoBraceExpand (set +B) switches off brace expansion
+	NoBraceExpand bool
+
+	// NoUnset (set -u) makes it an error to expand a variable that is
+	// not set, or to read one in an arithmetic expression such as
+	// $((N + 1)). Expansions that do something useful with unset
+	// variables, such as ${var:-word}, are not affected, and neither
+	// are special parameters such as $# and $?, which a shell always
+	// has a value for.
+	NoUnset bool
+
+	// NoCaseMatch (shopt -s nocasematch) ignores upper / lower case
+	// when ${var/pattern/string} looks for the pattern
+	NoCaseMatch bool
+}
+
+// alwaysSetParams are the special parameters that a shell always has a
+// value for, so ShellOpts.NoUnset never complains about them
+//
+// $! and the positional parameters are not here; bash reports them as
+// unbound if they have not been set. We treat $_ as an ordinary variable
+// called `_`.
+var alwaysSetParams = map[string]bool{
+	"$#": true,
+	"$?": true,
+	"$-": true,
+	"$$": true,
+	"$0": true,
+	"_":  true,
+}
+
+// WithShellOpts sets the `set` and `shopt` flags that the Expander
+// follows
+func WithShellOpts(shellOpts ShellOpts) Option {
+	return func(opts *options) {
+		opts.shellOpts = shellOpts
+	}
+}
+
+func (cb ExpansionCallbacks) shellOpts() ShellOpts {
+	if cb.opts == nil {
+		return ShellOpts{}
+	}
+
+	return cb.opts.shellOpts
+}
+
+// searchPattern returns the pattern that ${var/pattern/string} should
+// look for
+func (cb ExpansionCallbacks) searchPattern(pattern string) string {
+	if cb.shellOpts().NoCaseMatch {
+		return foldGlobCase(pattern)
+	}
+
+	return pattern
+}
+
+// foldGlobCase rewrites the glob pattern so that every letter in it
+// matches both its upper and lower case forms, e.g. `ab*` becomes
+// `[aA][bB]*`
+//
+// just like bash, [:upper:] and [:lower:] are left alone
+func foldGlobCase(pattern string) string {
+	var buf strings.Builder
+	for i := 0; i < len(pattern); {
+		c, w := utf8.DecodeRuneInString(pattern[i:])
+		switch {
+		case c == '\\':
+			// escaped characters are copied across as they are
+			end := i + 1
+			if end < len(pattern) {
+				_, w = utf8.DecodeRuneInString(pattern[end:])
+				end += w
+			}
+			buf.WriteString(pattern[i:end])
+			i = end
+			continue
+		case c == '[':
+			class, end, ok := foldBracketExpression(pattern[i:])
+			if ok {
+				buf.WriteString(class)
+				i += end
+				continue
+			}
+			buf.WriteByte('[')
+		case otherCase(c) != c:
+			buf.WriteByte('[')
+			buf.WriteRune(c)
+			buf.WriteRune(otherCase(c))
+			buf.WriteByte(']')
+		default:
+			buf.WriteString(pattern[i : i+w])
+		}
+		i += w
+	}
+
+	return buf.String()
+}
+
+// foldBracketExpression adds the other case of every letter and range
+// of letters to the bracket expression at the start of the input, and
+// tells you how long it was
+//
+// it returns false if the [ is not the start of a bracket expression
+func foldBracketExpression(input string) (string, int, bool) {
+	var buf strings.Builder
+	buf.WriteByte('[')
+
+	i := 1
+	if i < len(input) && (input[i] == '!' || input[i] == '^') {
+		buf.WriteByte(input[i])
+		i++
+	}
+
+	// a ] straight after the [ (or [!) is part of the set
+	first := i
+	var extra strings.Builder
+	for i < len(input) && (input[i] != ']' || i == first) {
+		// POSIX character classes are copied across as they are
+		if strings.HasPrefix(input[i:], "[:") {
+			classEnd := strings.Index(input[i+2:], ":]")
+			if classEnd >= 0 {
+				buf.WriteString(input[i : i+classEnd+4])
+				i += classEnd + 4
+				continue
+			}
+		}
+
+		lo, end := bracketChar(input, i)
+		buf.WriteString(input[i:end])
+		i = end
+
+		// is this a range?
+		if i+1 < len(input) && input[i] == '-' && input[i+1] != ']' {
+			hi, end := bracketChar(input, i+1)
+			buf.WriteString(input[i:end])
+			i = end
+
+			if unicode.IsLetter(lo) && unicode.IsLetter(hi) && otherCase(lo) != lo && otherCase(hi) != hi {
+				extra.WriteRune(otherCase(lo))
+				extra.WriteByte('-')
+				extra.WriteRune(otherCase(hi))
+			}
+			continue
+		}
+
+		if otherCase(lo) != lo {
+			extra.WriteRune(otherCase(lo))
+		}
+	}
+
+	// no closing ]? then it isn't a bracket expression
+	if i >= len(input) {
+		return "", 0, false
+	}
+
+	buf.WriteString(extra.String())
+	buf.WriteByte(']')
+	return buf.String(), i + 1, true
+}
+
+// bracketChar returns the (possibly escaped) character at input[i],
+// and where the next one starts
+func bracketChar(input string, i int) (rune, int) {
+	if input[i] == '\\' && i+1 < len(input) {
+		c, w := utf8.DecodeRuneInString(input[i+1:])
+		return c, i + 1 + w
+	}
+
+	c, w := utf8.DecodeRuneInString(input[i:])
+	return c, i + w
+}
+
+// otherCase returns the upper case form of a lower case letter, and
+// the lower case form of an upper case letter
+func otherCase(c rune) rune {
+	if unicode.IsUpper(c) {
+		return unicode.ToLower(c)
+	}
+
+	return unicode.ToUpper(c)
+}
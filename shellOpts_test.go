@@ -0,0 +1,341 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShellOptsNoUnsetRejectsUnsetVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	env.Set("v", "x")
+	unit := NewExpander(env.Callbacks(), WithShellOpts(ShellOpts{NoUnset: true}))
+
+	testData := []string{"$X", "${X}", "${#X}", "before ${X^^} after"}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrUnboundVariable{}), input)
+		assert.Equal(t, "X: unbound variable", err.Error(), input)
+	}
+}
+
+func TestShellOptsNoUnsetAllowsOperatorsThatHandleUnsetVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// this matches bash after `set -u; v=x`
+	env := NewEnv()
+	env.Set("v", "x")
+	unit := NewExpander(env.Callbacks(), WithShellOpts(ShellOpts{NoUnset: true}))
+	expectedResult := "x d  v"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("${v} ${X:-d} ${X+a} ${!v*}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestShellOptsNoUnsetRejectsUnsetVariablesInArithmetic(t *testing.T) {
+	t.Parallel()
+
+	testData := []string{"$((X+1))", "$((X))", "$((X++))", "$((X+=1))", "${v:-$((X))}"}
+
+	for _, input := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		env := NewEnv()
+		unit := NewExpander(env.Callbacks(), WithShellOpts(ShellOpts{NoUnset: true}))
+		var unboundErr ErrUnboundVariable
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		_, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.As(err, &unboundErr), "%s: %v", input, err)
+		assert.Equal(t, "X", unboundErr.Name, input)
+	}
+}
+
+func TestShellOptsNoUnsetRejectsUnsetVariablesInEvalLet(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(NewEnv().Callbacks(), WithShellOpts(ShellOpts{NoUnset: true}))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.EvalLet([]string{"X + 1"})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrUnboundVariable{}), "%v", err)
+}
+
+func TestShellOptsNoUnsetAllowsUnusedAndAssignedArithmeticVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// this matches bash after `set -u`
+	env := NewEnv()
+	unit := NewExpander(env.Callbacks(), WithShellOpts(ShellOpts{NoUnset: true}))
+	expectedResult := "0 2 3 4"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("$((0 && X)) $((1 ? 2 : X)) $((X=3)) $((X+1))")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestShellOptsNoUnsetAllowsSpecialParams(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// a shell always has a value for these, even if our callbacks
+	// do not
+	unit := NewExpander(NewEnv().Callbacks(), WithShellOpts(ShellOpts{NoUnset: true}))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("$# ${#} $? $- $$ $0 $_")
+	_, bangErr := unit.Expand("$!")
+	_, positionalErr := unit.Expand("$1")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(bangErr, ErrUnboundVariable{}))
+	assert.True(t, errors.Is(positionalErr, ErrUnboundVariable{}))
+}
+
+func TestShellOptsNoUnsetSeesZeroPositionalParamsByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// these results match bash, when it has no positional parameters
+	unit := NewExpander(NewEnv().Callbacks(), WithShellOpts(ShellOpts{NoUnset: true}))
+	testData := map[string]string{
+		"$#":            "0",
+		"${#}":          "0",
+		"${#:-x}":       "0",
+		"$(( $# + 1 ))": "1",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestShellOptsNoCaseMatchIgnoresCaseInSearchReplace(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// these results match bash after `shopt -s nocasematch`
+	env := NewEnv()
+	env.Set("v", "HelloWorld")
+	unit := NewExpander(env.Callbacks(), WithShellOpts(ShellOpts{NoCaseMatch: true}))
+
+	testData := map[string]string{
+		"${v/world/X}":        "HelloX",
+		"${v//[a-z]/_}":       "__________",
+		"${v//[[:lower:]]/_}": "H____W____",
+		"${v/#HELLO/X}":       "XWorld",
+		"${v/%[!a-m]LD/X}":    "HelloWoX",
+		"${v//L/1}":           "He11oWor1d",
+		"${v#hello}":          "HelloWorld",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestShellOptsNoBraceExpandSwitchesOffBraceExpansion(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(NewEnv().Callbacks(), WithShellOpts(ShellOpts{NoBraceExpand: true}))
+	expectedResult := "a{b,c}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("a{b,c}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestShellOptsAllExportExportsAssignments(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	env := NewEnv()
+	unit := NewExpander(env.Callbacks(), WithShellOpts(ShellOpts{AllExport: true}))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${PORT:=8080}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PORT=8080"}, env.Exports())
+}
+
+func TestExtendedPatternsReturnErrBadPattern(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	// there is no extglob flag, because we do not support these
+	env := NewEnv()
+	env.Set("v", "abc")
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Expand("${v#@(a|b)}", env.Callbacks())
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrBadPattern{}), "%v", err)
+}
+
+func TestFoldGlobCase(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[string]string{
+		"ab*":         "[aA][bB]*",
+		`\a?1`:        `\a?1`,
+		"[a-cX]":      "[a-cXA-Cx]",
+		"[!]a]":       "[!]aA]",
+		"[[:upper:]]": "[[:upper:]]",
+		"[abc":        "[[aA][bB][cC]",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := foldGlobCase(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
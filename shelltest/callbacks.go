@@ -0,0 +1,98 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shelltest
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The methods in this file give you a simple backing store for your
+// callbacks, using the variables in the test case. Their signatures
+// match the fields in shellexpand.ExpansionCallbacks.
+
+// AssignToVar sets the given variable in the test case
+func (c *Case) AssignToVar(key, value string) error {
+	if c.Vars == nil {
+		c.Vars = make(map[string]string)
+	}
+	c.Vars[key] = value
+
+	return nil
+}
+
+// LookupVar returns the value of the given variable from the test case
+//
+// It also supports the positional parameters ($1, $2 ...) and $#.
+func (c *Case) LookupVar(key string) (string, bool) {
+	// special case - the number of positional parameters
+	if key == "$#" {
+		return strconv.Itoa(len(c.PositionalParams)), true
+	}
+
+	// special case - a positional parameter
+	if len(key) > 1 && key[0] == '$' {
+		i, err := strconv.Atoi(key[1:])
+		if err != nil || i < 1 || i > len(c.PositionalParams) {
+			return "", false
+		}
+		return c.PositionalParams[i-1], true
+	}
+
+	// general case
+	retval, ok := c.Vars[key]
+	return retval, ok
+}
+
+// LookupHomeDir returns the home directory of the given user, from the
+// test case's HomeDirs
+func (c *Case) LookupHomeDir(user string) (string, bool) {
+	retval, ok := c.HomeDirs[user]
+	return retval, ok
+}
+
+// MatchVarNames returns the names of the test case's variables that
+// start with the given prefix
+func (c *Case) MatchVarNames(prefix string) []string {
+	retval := []string{}
+	for key := range c.Vars {
+		if strings.HasPrefix(key, prefix) {
+			retval = append(retval, key)
+		}
+	}
+
+	return retval
+}
@@ -0,0 +1,270 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package shelltest compares string expansion against a real UNIX shell.
+//
+// We use it to make sure that shellexpand behaves the same way that bash
+// does. You can use it to check that your own ExpansionCallbacks (or
+// anything else that expands strings) gives the results that a shell
+// would:
+//
+//	c := shelltest.Case{
+//		Input: "${PARAM1:-default}/$1",
+//		Vars: map[string]string{
+//			"PARAM1": "foo",
+//		},
+//		PositionalParams: []string{"bar"},
+//	}
+//	cb := shellexpand.ExpansionCallbacks{
+//		AssignToVar:   c.AssignToVar,
+//		LookupVar:     c.LookupVar,
+//		LookupHomeDir: c.LookupHomeDir,
+//		MatchVarNames: c.MatchVarNames,
+//	}
+//	result, err := shelltest.Compare("bash", &c, func(input string) (string, error) {
+//		return shellexpand.Expand(input, cb)
+//	})
+//
+// The shell is run as a separate process, from a temporary script file.
+package shelltest
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Case is a single string that we want to expand, along with the
+// variables that it needs
+type Case struct {
+	// Input is the string to expand
+	Input string
+
+	// Vars are the variables to set before expanding Input
+	Vars map[string]string
+
+	// PositionalParams are the values of $1, $2 and so on
+	PositionalParams []string
+
+	// HomeDirs are the home directories that LookupHomeDir() returns,
+	// keyed by username
+	//
+	// the shell always uses the real home directories, so only use this
+	// for users that don't exist
+	HomeDirs map[string]string
+
+	// Commands, if set, are run instead of `echo Input`
+	//
+	// use this when the result you want to compare is a side effect
+	// of the expansion (such as the assignment in ${var:=word})
+	Commands []string
+}
+
+// Result holds the outcome of comparing an expansion against a real
+// shell
+type Result struct {
+	// Script is the shell script that we ran
+	Script string
+
+	// Shell is what the shell printed, with any leading and trailing
+	// whitespace removed
+	Shell string
+
+	// Expanded is what your expansion function returned
+	Expanded string
+
+	// Err is the error that your expansion function returned
+	Err error
+}
+
+// Match returns true if your expansion function gave the same result
+// as the shell, without returning an error
+func (r Result) Match() bool {
+	return r.Err == nil && r.Shell == r.Expanded
+}
+
+// Available returns true if the given shell can be found on the PATH
+func Available(shell string) bool {
+	_, err := exec.LookPath(shell)
+	return err == nil
+}
+
+// Script returns the shell script that runs the given test case
+//
+// The script sets the test case's variables and positional parameters,
+// and then echoes the input (or runs the test case's Commands instead).
+func Script(c *Case) string {
+	var buf strings.Builder
+
+	// we sort the variables, so that the script is always the same
+	keys := make([]string, 0, len(c.Vars))
+	for key := range c.Vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		buf.WriteString(key)
+		buf.WriteRune('=')
+		buf.WriteString(Quote(c.Vars[key]))
+		buf.WriteRune('\n')
+	}
+
+	if len(c.PositionalParams) > 0 {
+		buf.WriteString("set --")
+		for _, param := range c.PositionalParams {
+			buf.WriteRune(' ')
+			buf.WriteString(Quote(param))
+		}
+		buf.WriteRune('\n')
+	}
+
+	if len(c.Commands) > 0 {
+		for _, line := range c.Commands {
+			buf.WriteString(line)
+			buf.WriteRune('\n')
+		}
+	} else {
+		buf.WriteString("echo ")
+		buf.WriteString(c.Input)
+		buf.WriteRune('\n')
+	}
+
+	return buf.String()
+}
+
+// Quote returns the given value as a single-quoted shell word
+func Quote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// Run runs the given test case through the given shell (e.g. "bash"),
+// and returns what the shell printed to stdout and stderr, with any
+// leading and trailing whitespace removed
+//
+// A non-zero exit status is not an error: a shell that fails to expand
+// the input (e.g. because of ${var:?word}) tells us why on stderr, and
+// that is what you get back. You only get an error if we cannot run the
+// shell at all.
+func Run(shell string, c *Case) (string, error) {
+	return runScript(shell, Script(c))
+}
+
+// Compare runs the test case through both the given shell, and your
+// expansion function, and returns both results
+//
+// You only get an error if we cannot run the shell at all. Use
+// Result.Match() to find out if the results are the same.
+func Compare(shell string, c *Case, expand func(string) (string, error)) (Result, error) {
+	retval := Result{
+		Script: Script(c),
+	}
+
+	var err error
+	retval.Shell, err = runScript(shell, retval.Script)
+	if err != nil {
+		return retval, err
+	}
+
+	retval.Expanded, retval.Err = expand(c.Input)
+	return retval, nil
+}
+
+// TB is the part of testing.TB that AssertMatch() uses
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertMatch is a test helper. It compares the test case's expansion
+// against the given shell, and reports a test failure if they are not
+// the same.
+func AssertMatch(t TB, shell string, c *Case, expand func(string) (string, error)) bool {
+	t.Helper()
+
+	result, err := Compare(shell, c, expand)
+	if err != nil {
+		t.Errorf("%s", err)
+		return false
+	}
+	if result.Err != nil {
+		t.Errorf("expanding %q: unexpected error: %s", c.Input, result.Err)
+		return false
+	}
+	if !result.Match() {
+		t.Errorf(
+			"expanding %q:\n\t%s gave: %q\n\twe gave: %q\n\tscript:\n%s",
+			c.Input,
+			shell,
+			result.Shell,
+			result.Expanded,
+			result.Script,
+		)
+		return false
+	}
+
+	return true
+}
+
+func runScript(shell, script string) (string, error) {
+	tmpFile, err := ioutil.TempFile("", "shelltest-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.WriteString(script)
+	if err == nil {
+		err = tmpFile.Close()
+	} else {
+		tmpFile.Close()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(shell, tmpFile.Name())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		_, exited := err.(*exec.ExitError)
+		if !exited {
+			return "", ErrShellFailed{shell, err}
+		}
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,309 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shelltest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScriptSetsVarsAndPositionalParams(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := Case{
+		Input: "$PARAM1 $1",
+		Vars: map[string]string{
+			"PARAM2": "it's",
+			"PARAM1": "foo",
+		},
+		PositionalParams: []string{"one two", "three"},
+	}
+	expectedResult := "PARAM1='foo'\n" +
+		"PARAM2='it'\\''s'\n" +
+		"set -- 'one two' 'three'\n" +
+		"echo $PARAM1 $1\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Script(&testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestScriptRunsCommandsInsteadOfEchoingInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := Case{
+		Input:    "${PARAM1:=foo}",
+		Commands: []string{": ${PARAM1:=foo}", "echo $PARAM1"},
+	}
+	expectedResult := ": ${PARAM1:=foo}\necho $PARAM1\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Script(&testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestRunReturnsWhatTheShellPrinted(t *testing.T) {
+	if !Available("bash") {
+		t.Skip("bash is not installed")
+	}
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := Case{
+		Input: "${PARAM1^^} $2",
+		Vars: map[string]string{
+			"PARAM1": "foo",
+		},
+		PositionalParams: []string{"one", "two"},
+	}
+	expectedResult := "FOO two"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Run("bash", &testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestRunReturnsErrShellFailedIfShellCannotRun(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := Case{
+		Input: "foo",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := Run("shelltest-no-such-shell", &testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	_, ok := err.(ErrShellFailed)
+	assert.True(t, ok)
+	assert.NotNil(t, errors.Unwrap(err))
+}
+
+func TestCompareReportsMatchingResults(t *testing.T) {
+	if !Available("bash") {
+		t.Skip("bash is not installed")
+	}
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := Case{
+		Input: "$PARAM1",
+		Vars: map[string]string{
+			"PARAM1": "foo",
+		},
+	}
+	expand := func(input string) (string, error) {
+		value, _ := testData.LookupVar(strings.TrimPrefix(input, "$"))
+		return value, nil
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	result, err := Compare("bash", &testData, expand)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.True(t, result.Match())
+	assert.Equal(t, "foo", result.Shell)
+	assert.Equal(t, "foo", result.Expanded)
+}
+
+func TestCompareReportsDifferentResults(t *testing.T) {
+	if !Available("bash") {
+		t.Skip("bash is not installed")
+	}
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := Case{
+		Input: "$PARAM1",
+		Vars: map[string]string{
+			"PARAM1": "foo",
+		},
+	}
+	expand := func(input string) (string, error) {
+		return "bar", nil
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	result, err := Compare("bash", &testData, expand)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.False(t, result.Match())
+	assert.Equal(t, "foo", result.Shell)
+	assert.Equal(t, "bar", result.Expanded)
+}
+
+// fakeTB records the failures that AssertMatch() reports
+type fakeTB struct {
+	failures []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertMatchReportsDifferentResults(t *testing.T) {
+	if !Available("bash") {
+		t.Skip("bash is not installed")
+	}
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := Case{
+		Input: "$PARAM1",
+		Vars: map[string]string{
+			"PARAM1": "foo",
+		},
+	}
+	expand := func(input string) (string, error) {
+		return "bar", nil
+	}
+	unit := fakeTB{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	ok := AssertMatch(&unit, "bash", &testData, expand)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.False(t, ok)
+	assert.Len(t, unit.failures, 1)
+	assert.Contains(t, unit.failures[0], `bash gave: "foo"`)
+	assert.Contains(t, unit.failures[0], `we gave: "bar"`)
+}
+
+func TestCaseCallbacksUseTheCaseVariables(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := Case{
+		PositionalParams: []string{"one", "two"},
+		HomeDirs: map[string]string{
+			"fred": "/home/fred",
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	err := unit.AssignToVar("PARAM1", "foo")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+
+	value, ok := unit.LookupVar("PARAM1")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", value)
+
+	value, ok = unit.LookupVar("$#")
+	assert.True(t, ok)
+	assert.Equal(t, "2", value)
+
+	value, ok = unit.LookupVar("$2")
+	assert.True(t, ok)
+	assert.Equal(t, "two", value)
+
+	_, ok = unit.LookupVar("$3")
+	assert.False(t, ok)
+
+	value, ok = unit.LookupHomeDir("fred")
+	assert.True(t, ok)
+	assert.Equal(t, "/home/fred", value)
+
+	assert.Equal(t, []string{"PARAM1"}, unit.MatchVarNames("PARAM"))
+}
@@ -0,0 +1,173 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "context"
+
+// SourceSegment is a part of the output string that an expansion
+// produced, and the part of the input string that it came from
+type SourceSegment struct {
+	// OutputStart and OutputEnd are byte offsets into the output string
+	OutputStart int
+	OutputEnd   int
+
+	// InputStart and InputEnd are byte offsets into the input string
+	InputStart int
+	InputEnd   int
+}
+
+// SourceMap translates byte offsets in the output of an expansion back
+// into byte offsets in the input string. Use it when something else
+// (such as a shell that runs your expanded script) reports a problem
+// in the output, and you want to point your users at the template that
+// caused it.
+//
+// A SourceMap is created by ExpandWithSourceMap().
+type SourceMap struct {
+	// the parts of the output that were expanded, in order
+	segments []SourceSegment
+}
+
+// Segments returns the parts of the output string that expansions
+// produced, in the order that they appear. Everything in between was
+// copied from the input string as it is.
+func (m SourceMap) Segments() []SourceSegment {
+	return append([]SourceSegment(nil), m.segments...)
+}
+
+// InputOffset returns the byte offset in the input string that the
+// given byte offset in the output string came from.
+//
+// If the output offset is in text that was copied from the input, you
+// get back its exact position in the input. If it is in the result of
+// an expansion, you get back the start of that expansion (e.g. the $
+// of ${HOME}).
+func (m SourceMap) InputOffset(outputOffset int) int {
+	if outputOffset < 0 {
+		return 0
+	}
+
+	// how far the input is ahead of the output, in the text that was
+	// copied across
+	delta := 0
+	for _, seg := range m.segments {
+		if outputOffset < seg.OutputStart {
+			break
+		}
+		if outputOffset < seg.OutputEnd {
+			return seg.InputStart
+		}
+		delta = seg.InputEnd - seg.OutputEnd
+	}
+
+	return outputOffset + delta
+}
+
+// ExpandWithSourceMap expands the input string, just like Expand()
+// does, and also returns a SourceMap that translates positions in the
+// output back into positions in the input.
+//
+// If history expansion is switched on, the SourceMap refers to the
+// input after history expansion. If you use WithTrace(), you receive
+// the TraceEvents for each parameter, but not for each phase: a source
+// map can only be built when every phase happens in a single pass.
+func ExpandWithSourceMap(input string, cb ExpansionCallbacks) (string, SourceMap, error) {
+	return ExpandWithSourceMapContext(cb.context(), input, cb)
+}
+
+// ExpandWithSourceMapContext expands the input string, just like
+// ExpandWithSourceMap() does. It uses the given context in the same
+// way that ExpandContext() does.
+func ExpandWithSourceMapContext(ctx context.Context, input string, cb ExpansionCallbacks) (string, SourceMap, error) {
+	builder := sourceMapBuilder{}
+	cb.sourceMap = &builder
+
+	retval, err := ExpandContext(ctx, input, cb)
+	if err != nil {
+		return "", SourceMap{}, err
+	}
+
+	return retval, SourceMap{builder.segments}, nil
+}
+
+// ExpandWithSourceMap expands the input string, just like the
+// package-level ExpandWithSourceMap() does
+func (e *Expander) ExpandWithSourceMap(input string) (string, SourceMap, error) {
+	ctx, done := e.withTimeout(context.Background())
+	cb := e.callbacks()
+	cb.ctx = ctx
+	retval, sourceMap, err := ExpandWithSourceMap(input, cb)
+	err = cb.maskError(done(err))
+	e.stats.countError(err)
+	return retval, sourceMap, err
+}
+
+// sourceMapBuilder records where each span of the caller's input ends
+// up in the output, as expandSpans() works through it
+type sourceMapBuilder struct {
+	segments []SourceSegment
+
+	// true if the last segment is still being expanded
+	open bool
+}
+
+// openSpan is called just before the caller's frame expands its next
+// span
+func (b *sourceMapBuilder) openSpan(f *expansionFrame) {
+	if b == nil {
+		return
+	}
+	b.closeSpan(f)
+
+	// the text before the span is copied across as it is
+	span := f.spans[f.next]
+	b.segments = append(b.segments, SourceSegment{
+		OutputStart: f.outputLen() + span.start - f.last,
+		InputStart:  span.start,
+		InputEnd:    span.end,
+	})
+	b.open = true
+}
+
+// closeSpan records where the last span's output ends
+func (b *sourceMapBuilder) closeSpan(f *expansionFrame) {
+	if b == nil || !b.open {
+		return
+	}
+
+	b.segments[len(b.segments)-1].OutputEnd = f.outputLen()
+	b.open = false
+}
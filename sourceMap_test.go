@@ -0,0 +1,162 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandWithSourceMapMapsOutputOffsetsToInputOffsets(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{
+		"HOME": "/home/stuart",
+		"CMD":  "ls",
+	})
+	input := "cd ${HOME}\n$CMD -l\n"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, sourceMap, err := ExpandWithSourceMap(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "cd /home/stuart\nls -l\n", actualResult)
+
+	// "cd " is copied across
+	assert.Equal(t, 0, sourceMap.InputOffset(0))
+	assert.Equal(t, 2, sourceMap.InputOffset(2))
+
+	// "/home/stuart" came from ${HOME}
+	assert.Equal(t, 3, sourceMap.InputOffset(3))
+	assert.Equal(t, 3, sourceMap.InputOffset(10))
+
+	// "ls" came from $CMD
+	lsOffset := strings.Index(actualResult, "ls")
+	assert.Equal(t, strings.Index(input, "$CMD"), sourceMap.InputOffset(lsOffset))
+
+	// "-l" is copied across
+	flagOffset := strings.Index(actualResult, "-l")
+	assert.Equal(t, strings.Index(input, "-l"), sourceMap.InputOffset(flagOffset))
+
+	// the end of the output is the end of the input
+	assert.Equal(t, len(input), sourceMap.InputOffset(len(actualResult)))
+}
+
+func TestExpandWithSourceMapReturnsEachExpandedSegment(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(map[string]string{"A": "alpha"})
+	input := "x $A ${UNSET} y"
+	expectedResult := []SourceSegment{
+		{OutputStart: 2, OutputEnd: 7, InputStart: 2, InputEnd: 4},
+		{OutputStart: 8, OutputEnd: 8, InputStart: 5, InputEnd: 13},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, sourceMap, err := ExpandWithSourceMap(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, sourceMap.Segments())
+	assert.Equal(t, 13, sourceMap.InputOffset(8))
+}
+
+func TestExpandWithSourceMapIsTheIdentityWhenNothingIsExpanded(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(nil)
+	input := "nothing to see here"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, sourceMap, err := ExpandWithSourceMap(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, input, actualResult)
+	assert.Empty(t, sourceMap.Segments())
+	assert.Equal(t, 7, sourceMap.InputOffset(7))
+}
+
+func TestExpanderExpandWithSourceMapWorksWithTracing(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	var events []TraceEvent
+	cb := NewMapCallbacks(map[string]string{"A": "alpha"})
+	unit := NewExpander(cb, WithTrace(func(event TraceEvent) {
+		events = append(events, event)
+	}))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, sourceMap, err := unit.ExpandWithSourceMap("$A!")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "alpha!", actualResult)
+	assert.Equal(t, 2, sourceMap.InputOffset(5))
+	assert.Len(t, events, 1)
+	assert.Equal(t, TraceParameter, events[0].Kind)
+}
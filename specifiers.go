@@ -0,0 +1,123 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "strings"
+
+// LookupSpecifier returns the value of a systemd-style specifier, such
+// as the 'i' in %i. It returns ("", false) if it does not know the
+// specifier.
+type LookupSpecifier func(specifier rune) (string, bool)
+
+// WithSpecifiers makes the Expander expand systemd-style specifiers
+// (%i, %n, %h and so on), as well as variables. Use it to expand unit
+// files, so that %i and ${VAR} are both handled in one place.
+//
+// The lookup function provides the value of each specifier. %% is a
+// literal percent sign. Just like systemd, a % followed by a letter or
+// a digit that the lookup function does not know is an error
+// (ErrUnknownSpecifier); a % followed by anything else is left as it
+// is.
+//
+// Specifiers are expanded in the same pass as variables. Their values
+// are not expanded any further. The %var% syntax cannot be used at the
+// same time.
+//
+// It does not change ExpandArgs().
+func WithSpecifiers(lookup LookupSpecifier) Option {
+	return func(opts *options) {
+		opts.specifiers = lookup
+	}
+}
+
+// specifiers returns our specifier lookup function, or nil if we are
+// not expanding specifiers
+func (cb ExpansionCallbacks) specifiers() LookupSpecifier {
+	if cb.opts == nil {
+		return nil
+	}
+
+	return cb.opts.specifiers
+}
+
+// findSpecifier returns the length of the %x or %% at the start of the
+// input string
+//
+// it returns false if there isn't one
+func findSpecifier(input string) (int, bool) {
+	if len(input) < 2 {
+		return 0, false
+	}
+
+	c := input[1]
+	if c == '%' || isSpecifierChar(c) {
+		return 2, true
+	}
+
+	return 0, false
+}
+
+// isSpecifierChar returns true if systemd would treat the character as
+// a specifier
+func isSpecifierChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// expandSpecifier returns what a %x or %% expands to
+func expandSpecifier(text string, cb ExpansionCallbacks) (string, error) {
+	if text == "%%" {
+		return "%", nil
+	}
+
+	specifier := rune(text[1])
+	err := cb.budget.spendCallback()
+	if err != nil {
+		return "", err
+	}
+	value, ok := cb.specifiers()(specifier)
+	if !ok {
+		return "", ErrUnknownSpecifier{specifier}
+	}
+
+	cb.stats.inc(statParamsExpanded)
+	return value, nil
+}
+
+// hasSpecifiers returns false if the input definitely has no specifiers
+// in it that Expand() can change
+func hasSpecifiers(input string, cb ExpansionCallbacks) bool {
+	return cb.specifiers() != nil && strings.IndexByte(input, '%') >= 0
+}
@@ -0,0 +1,161 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSpecifierExpander() *Expander {
+	vars := map[string]string{
+		"PARAM1": "foo",
+		"HOME":   "/home/me",
+	}
+	specifiers := map[rune]string{
+		'i': "instance",
+		'n': "app@instance.service",
+		'h': "/root",
+		'p': "$PARAM1",
+	}
+
+	return NewExpander(
+		ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				retval, ok := vars[key]
+				return retval, ok
+			},
+		},
+		WithSpecifiers(func(specifier rune) (string, bool) {
+			retval, ok := specifiers[specifier]
+			return retval, ok
+		}),
+	)
+}
+
+func TestWithSpecifiersExpandsSpecifiers(t *testing.T) {
+	t.Parallel()
+
+	unit := newTestSpecifierExpander()
+	testData := []varSyntaxTestData{
+		{`%i`, `instance`},
+		{`/run/%n/%i.pid`, `/run/app@instance.service/instance.pid`},
+		{`%h/${PARAM1}/$PARAM1`, `/root/foo/foo`},
+		{`${UNSET:-%i}`, `instance`},
+		{`100%% of %i`, `100% of instance`},
+		{`50% off, 20%`, `50% off, 20%`},
+		{`%p`, `$PARAM1`},
+		{`\%i`, `%i`},
+	}
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(testCase.input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.expectedResult, actualResult, testCase.input)
+	}
+}
+
+func TestWithSpecifiersRejectsUnknownSpecifiers(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestSpecifierExpander()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("/run/%Z")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(err, ErrUnknownSpecifier{}))
+	assert.Contains(t, err.Error(), "%Z: unknown specifier")
+}
+
+func TestSpecifiersAreNotExpandedByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "%i %% %Z"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand(testData, ExpansionCallbacks{})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, testData, actualResult)
+}
+
+func TestWithSpecifiersExpandsBytes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestSpecifierExpander()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.ExpandBytes([]byte("%i"))
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "instance", string(actualResult))
+}
@@ -0,0 +1,410 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Word is a single word from a command line, as found by SplitWords()
+type Word struct {
+	// Raw is the word, exactly as it appears in the input string
+	Raw string
+
+	// Text is the word after quote removal. Nothing in it has been
+	// expanded.
+	Text string
+
+	// Quoting tells you how the word was quoted
+	Quoting QuoteStyle
+
+	// Start and End are the byte offsets of the word in the input
+	// string
+	Start int
+	End   int
+}
+
+// QuoteStyle describes how a Word was quoted
+type QuoteStyle int
+
+// these are the ways that a Word can be quoted
+const (
+	// QuoteNone means that the word has no quotes or escapes in it
+	QuoteNone QuoteStyle = iota
+
+	// QuoteBackslash means that the word has backslash escapes, but
+	// no quotes
+	QuoteBackslash
+
+	// QuoteSingle means that the word has 'single quotes' in it
+	QuoteSingle
+
+	// QuoteDouble means that the word has "double quotes" in it
+	QuoteDouble
+
+	// QuoteLocale means that the word has $"locale strings" in it
+	QuoteLocale
+
+	// QuoteMixed means that the word uses more than one kind of quote,
+	// such as 'it'"'"'s'
+	QuoteMixed
+)
+
+func (q QuoteStyle) String() string {
+	switch q {
+	case QuoteNone:
+		return "none"
+	case QuoteBackslash:
+		return "backslash"
+	case QuoteSingle:
+		return "single"
+	case QuoteDouble:
+		return "double"
+	case QuoteLocale:
+		return "locale"
+	case QuoteMixed:
+		return "mixed"
+	default:
+		return "unknown"
+	}
+}
+
+// SplitWords breaks the input string up into words, the same way that
+// a UNIX shell breaks up a command line, without expanding anything.
+// Use it when you need to parse a command line into argv, but do not
+// want any expansion.
+//
+// Words are separated by unquoted, unescaped blanks (spaces, tabs and
+// newlines). A ${...} expansion, $((...)) arithmetic expansion, or
+// $(...) or `...` command substitution is kept in a single word, even if
+// it has blanks inside it. Quotes inside them are not removed.
+//
+// It returns ErrUnterminatedQuote if a quote is never closed.
+func SplitWords(input string) ([]Word, error) {
+	words, err := splitWords(input)
+	if err != nil {
+		return nil, err
+	}
+
+	retval := make([]Word, len(words))
+	for i, word := range words {
+		text, quoting := unquoteWord(word.text)
+		retval[i] = Word{
+			Raw:     word.text,
+			Text:    text,
+			Quoting: quoting,
+			Start:   word.start,
+			End:     word.start + len(word.text),
+		}
+	}
+
+	return retval, nil
+}
+
+// unquoteWord performs quote removal on the given word, and tells you
+// how it was quoted
+//
+// any quote that is never closed is kept as a literal character
+func unquoteWord(word string) (string, QuoteStyle) {
+	// nothing to do?
+	if !strings.ContainsAny(word, `\'"`) {
+		return word, QuoteNone
+	}
+
+	var buf strings.Builder
+	quoting := QuoteNone
+	addQuoting := func(style QuoteStyle) {
+		switch quoting {
+		case QuoteNone, QuoteBackslash:
+			quoting = style
+		case style:
+		default:
+			quoting = QuoteMixed
+		}
+	}
+
+	inDoubleQuotes := false
+	var c rune
+	w := 0
+	for i := 0; i < len(word); i += w {
+		c, w = utf8.DecodeRuneInString(word[i:])
+
+		// quotes inside ${...} are removed when the parameter is
+		// expanded, not before; quotes inside $(...) and `...` belong
+		// to the command
+		spanEnd, ok := matchCommandOrBracedVar(word[i:])
+		if ok {
+			buf.WriteString(word[i : i+spanEnd])
+			w = spanEnd
+			continue
+		}
+
+		switch {
+		case c == '\\' && i+w < len(word):
+			escC, escW := utf8.DecodeRuneInString(word[i+w:])
+
+			// inside double quotes, only a few characters can be escaped
+			if inDoubleQuotes && !isDoubleQuoteEscapeChar(escC) {
+				buf.WriteRune(c)
+				continue
+			}
+			if quoting == QuoteNone {
+				quoting = QuoteBackslash
+			}
+
+			// an escaped newline is a line continuation
+			if escC != '\n' {
+				buf.WriteString(word[i+w : i+w+escW])
+			}
+			w += escW
+
+		case c == '\'' && !inDoubleQuotes && hasClosingQuote(word[i:]):
+			quoteEnd, _ := matchQuotes(word[i:])
+			addQuoting(QuoteSingle)
+			buf.WriteString(word[i+1 : i+quoteEnd-1])
+			w = quoteEnd
+
+		case c == '$' && !inDoubleQuotes && strings.HasPrefix(word[i:], `$"`) && hasClosingQuote(word[i+1:]):
+			addQuoting(QuoteLocale)
+			inDoubleQuotes = true
+			w = 2
+
+		case c == '"' && !inDoubleQuotes && hasClosingQuote(word[i:]):
+			addQuoting(QuoteDouble)
+			inDoubleQuotes = true
+
+		case c == '"' && inDoubleQuotes:
+			inDoubleQuotes = false
+
+		default:
+			buf.WriteString(word[i : i+w])
+		}
+	}
+
+	return buf.String(), quoting
+}
+
+// hasClosingQuote returns true if the quote at the start of the input
+// string is closed somewhere later on
+//
+// splitWords() keeps a ${...} expansion in a single word without
+// checking the quotes inside it, so a word can contain a quote that is
+// never closed. We treat that as a literal character.
+func hasClosingQuote(input string) bool {
+	_, ok := matchQuotes(input)
+	return ok
+}
+
+// rawWord is a single word from the input string, before any expansion
+// or quote removal has been done to it
+type rawWord struct {
+	// the word, exactly as it appears in the input string
+	text string
+
+	// where the word starts in the input string
+	start int
+}
+
+// splitWords breaks the input string up into words, the same way that a
+// UNIX shell breaks up a command line
+//
+// words are separated by unquoted, unescaped blanks (spaces, tabs and
+// newlines). Quotes and escapes are left in place; they are dealt with
+// when each word is expanded.
+func splitWords(input string) ([]rawWord, error) {
+	var retval []rawWord
+
+	// where does the current word start?
+	//
+	// -1 means we are between words
+	wordStart := -1
+
+	var c rune
+	w := 0
+	for i := 0; i < len(input); i += w {
+		c, w = utf8.DecodeRuneInString(input[i:])
+
+		// are we looking at a word separator?
+		if isBlankChar(c) {
+			if wordStart >= 0 {
+				retval = append(retval, rawWord{input[wordStart:i], wordStart})
+				wordStart = -1
+			}
+			continue
+		}
+
+		// if we get here, we're looking at part of a word
+		if wordStart < 0 {
+			wordStart = i
+		}
+
+		switch c {
+		case '\\':
+			// skip over the escaped character
+			if i+w < len(input) {
+				_, escW := utf8.DecodeRuneInString(input[i+w:])
+				w += escW
+			}
+		case '\'', '"':
+			quoteEnd, ok := matchQuotes(input[i:])
+			if !ok {
+				return nil, ErrUnterminatedQuote{c, i}
+			}
+			w = quoteEnd
+		case '$':
+			// arithmetic and parameter expansions can contain blanks
+			//
+			// bash always keeps $[...] together, even though we only
+			// expand it if the caller asks us to
+			exprEnd, ok := findArithmetic(input[i:])
+			if !ok {
+				exprEnd, ok = findLegacyArithmetic(input[i:])
+			}
+			if ok {
+				w = exprEnd
+				continue
+			}
+
+			// so can command substitutions
+			cmdEnd, ok := findCommand(input[i:])
+			if ok {
+				w = cmdEnd
+				continue
+			}
+			varEnd, ok := matchVar(input[i:])
+			if ok {
+				w = varEnd
+			}
+		case '`':
+			// a backtick that is never closed is just a character
+			cmdEnd, ok := findBackticks(input[i:])
+			if ok {
+				w = cmdEnd
+			}
+		}
+	}
+
+	// don't forget the last word!
+	if wordStart >= 0 {
+		retval = append(retval, rawWord{input[wordStart:], wordStart})
+	}
+
+	// all done
+	return retval, nil
+}
+
+// matchQuotes finds the end of the quoted string at the start of the
+// input string
+//
+// returns the position just after the closing quote
+func matchQuotes(input string) (int, bool) {
+	quote := input[0]
+
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case quote:
+			return i + 1, true
+		case '\\':
+			// backslash only escapes inside double quotes
+			if quote == '"' {
+				i++
+			}
+		case '$', '`':
+			// inside double quotes, a ${...}, $(...) or `...` can
+			// contain double quotes of its own
+			if quote == '"' {
+				spanEnd, ok := matchVar(input[i:])
+				if !ok {
+					spanEnd, ok = matchCommandOrBracedVar(input[i:])
+				}
+				if ok {
+					i += spanEnd - 1
+				}
+			}
+		}
+	}
+
+	// if we get here, the quotes were never closed
+	return 0, false
+}
+
+// findBackticks returns the length of the `...` at the start of the
+// input string
+//
+// we do not run these commands, but like a UNIX shell, we keep them
+// together in a single word. It returns false if there isn't one, or if
+// it is never closed.
+func findBackticks(input string) (int, bool) {
+	if len(input) == 0 || input[0] != '`' {
+		return 0, false
+	}
+
+	for i := 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '`':
+			return i + 1, true
+		}
+	}
+
+	// if we get here, the ` was never closed
+	return 0, false
+}
+
+// matchCommandOrBracedVar returns the length of the ${...}, $(...) or
+// `...` at the start of the input string
+//
+// quote removal leaves all of these alone
+func matchCommandOrBracedVar(input string) (int, bool) {
+	switch {
+	case strings.HasPrefix(input, "${"):
+		return matchVar(input)
+	case strings.HasPrefix(input, "$("):
+		return findCommand(input)
+	default:
+		return findBackticks(input)
+	}
+}
+
+func isBlankChar(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// blankChars holds every character that isBlankChar() returns true for,
+// for use with strings.IndexAny() and friends
+const blankChars = " \t\n"
@@ -0,0 +1,262 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitWordsReturnsEachWord(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := `ls -l  'my file' "$HOME/a b" it\'s 'it'"'"'s' ${X:-a b} $"hello"`
+	expectedResult := []Word{
+		{Raw: "ls", Text: "ls", Quoting: QuoteNone, Start: 0, End: 2},
+		{Raw: "-l", Text: "-l", Quoting: QuoteNone, Start: 3, End: 5},
+		{Raw: "'my file'", Text: "my file", Quoting: QuoteSingle, Start: 7, End: 16},
+		{Raw: `"$HOME/a b"`, Text: "$HOME/a b", Quoting: QuoteDouble, Start: 17, End: 28},
+		{Raw: `it\'s`, Text: "it's", Quoting: QuoteBackslash, Start: 29, End: 34},
+		{Raw: `'it'"'"'s'`, Text: "it's", Quoting: QuoteMixed, Start: 35, End: 45},
+		{Raw: "${X:-a b}", Text: "${X:-a b}", Quoting: QuoteNone, Start: 46, End: 55},
+		{Raw: `$"hello"`, Text: "hello", Quoting: QuoteLocale, Start: 56, End: 64},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := SplitWords(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	for _, word := range actualResult {
+		assert.Equal(t, word.Raw, input[word.Start:word.End])
+	}
+}
+
+func TestSplitWordsHandlesEscapesInDoubleQuotes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := `"a \"b\" \c" "line\` + "\n" + `continued"`
+	expectedResult := []string{`a "b" \c`, "linecontinued"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	words, err := SplitWords(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	var actualResult []string
+	for _, word := range words {
+		actualResult = append(actualResult, word.Text)
+		assert.Equal(t, QuoteDouble, word.Quoting)
+	}
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestSplitWordsReturnsErrorForUnterminatedQuotes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := `echo "hello`
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := SplitWords(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, ErrUnterminatedQuote{'"', 5}, err)
+}
+
+func TestSplitWordsKeepsQuotesInsideOperatorWords(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := `"${Z:-"a b"}" ${Z:-'c d'}`
+	expectedResult := []Word{
+		{Raw: `"${Z:-"a b"}"`, Text: `${Z:-"a b"}`, Quoting: QuoteDouble, Start: 0, End: 13},
+		{Raw: `${Z:-'c d'}`, Text: `${Z:-'c d'}`, Quoting: QuoteNone, Start: 14, End: 25},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := SplitWords(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestSplitWordsKeepsCommandSubstitutionsInASingleWord(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "a $(echo b c) x`echo y z`w \"$(echo \")\")\" $(echo 'd e')f `a \\` b` $(( 1 + 2 ))"
+	expectedResult := []Word{
+		{Raw: "a", Text: "a", Quoting: QuoteNone, Start: 0, End: 1},
+		{Raw: "$(echo b c)", Text: "$(echo b c)", Quoting: QuoteNone, Start: 2, End: 13},
+		{Raw: "x`echo y z`w", Text: "x`echo y z`w", Quoting: QuoteNone, Start: 14, End: 26},
+		{Raw: `"$(echo ")")"`, Text: `$(echo ")")`, Quoting: QuoteDouble, Start: 27, End: 40},
+		{Raw: "$(echo 'd e')f", Text: "$(echo 'd e')f", Quoting: QuoteNone, Start: 41, End: 55},
+		{Raw: "`a \\` b`", Text: "`a \\` b`", Quoting: QuoteNone, Start: 56, End: 64},
+		{Raw: "$(( 1 + 2 ))", Text: "$(( 1 + 2 ))", Quoting: QuoteNone, Start: 65, End: 77},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := SplitWords(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	for _, word := range actualResult {
+		assert.Equal(t, word.Raw, input[word.Start:word.End])
+	}
+}
+
+func TestSplitWordsTreatsUnterminatedCommandSubstitutionsAsCharacters(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	input := "it`s $(not closed"
+	expectedResult := []string{"it`s", "$(not", "closed"}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	words, err := SplitWords(input)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	var actualResult []string
+	for _, word := range words {
+		actualResult = append(actualResult, word.Text)
+	}
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestSplitWordsKeepsUnmatchedQuotesInsideVars(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := []string{
+		"${'}",
+		`${"}`,
+		`a${X'}b`,
+		`${X:-"}`,
+		`*${))'#}`,
+	}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		expectedResult := []Word{
+			{Raw: testData, Text: testData, Quoting: QuoteNone, Start: 0, End: len(testData)},
+		}
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := SplitWords(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testData)
+		assert.Equal(t, expectedResult, actualResult, testData)
+	}
+}
+
+func TestQuoteStyleString(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := map[QuoteStyle]string{
+		QuoteNone:      "none",
+		QuoteBackslash: "backslash",
+		QuoteSingle:    "single",
+		QuoteDouble:    "double",
+		QuoteLocale:    "locale",
+		QuoteMixed:     "mixed",
+		QuoteStyle(99): "unknown",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult := input.String()
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Equal(t, expectedResult, actualResult)
+	}
+}
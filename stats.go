@@ -0,0 +1,146 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "sync/atomic"
+
+// Stats holds the counters that an Expander keeps, when you create it
+// with the WithStats() option
+type Stats struct {
+	// ParamsExpanded is how many parameters have been expanded
+	ParamsExpanded int64
+
+	// DefaultsUsed is how many times ${var:-word} or ${var:=word} used
+	// its word, because the variable was unset or empty
+	DefaultsUsed int64
+
+	// Assignments is how many times we have assigned a value to a
+	// variable (for example, via ${var:=word})
+	Assignments int64
+
+	// LookupCacheHits is how many variable lookups were answered by
+	// the WithMemoizedLookups() cache
+	LookupCacheHits int64
+
+	// LookupCacheMisses is how many variable lookups had to call
+	// LookupVar, because the WithMemoizedLookups() cache did not have
+	// the answer yet
+	LookupCacheMisses int64
+
+	// GlobCacheHits is how many glob patterns we found already compiled
+	// in the Expander's glob cache
+	GlobCacheHits int64
+
+	// GlobCacheMisses is how many glob patterns we had to compile
+	GlobCacheMisses int64
+
+	// Errors is how many calls to the Expander's methods returned an
+	// error
+	Errors int64
+}
+
+// the counters that expansionStats keeps
+const (
+	statParamsExpanded = iota
+	statDefaultsUsed
+	statAssignments
+	statLookupCacheHits
+	statLookupCacheMisses
+	statErrors
+	statCounters
+)
+
+// expansionStats holds the counters that the WithStats() option turns on
+//
+// the zero value (nil) is valid, and simply means "no counting"
+//
+// it is updated atomically, so that it is safe to share between
+// goroutines
+type expansionStats struct {
+	counters [statCounters]int64
+}
+
+// inc adds one to the given counter
+func (s *expansionStats) inc(counter int) {
+	// are we counting?
+	if s == nil {
+		return
+	}
+
+	atomic.AddInt64(&s.counters[counter], 1)
+}
+
+// countError adds one to our error counter, if `err` is an error
+func (s *expansionStats) countError(err error) {
+	if err != nil {
+		s.inc(statErrors)
+	}
+}
+
+func (s *expansionStats) get(counter int) int64 {
+	return atomic.LoadInt64(&s.counters[counter])
+}
+
+// WithStats makes the Expander count what it does, so that you can keep
+// an eye on how your templates behave in production. Use Stats() to get
+// the counters.
+func WithStats() Option {
+	return func(opts *options) {
+		opts.stats = true
+	}
+}
+
+// Stats returns a snapshot of the Expander's counters
+//
+// All of the counters are zero unless the Expander was created with the
+// WithStats() option.
+func (e *Expander) Stats() Stats {
+	if e.stats == nil {
+		return Stats{}
+	}
+
+	globHits, globMisses := e.globs.stats()
+	return Stats{
+		ParamsExpanded:    e.stats.get(statParamsExpanded),
+		DefaultsUsed:      e.stats.get(statDefaultsUsed),
+		Assignments:       e.stats.get(statAssignments),
+		LookupCacheHits:   e.stats.get(statLookupCacheHits),
+		LookupCacheMisses: e.stats.get(statLookupCacheMisses),
+		GlobCacheHits:     globHits,
+		GlobCacheMisses:   globMisses,
+		Errors:            e.stats.get(statErrors),
+	}
+}
@@ -0,0 +1,139 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func statsTestVars() map[string]string {
+	return map[string]string{
+		"PARAM1": "foo",
+		"PARAM2": "hello world",
+	}
+}
+
+func TestExpanderStatsAreZeroWithoutWithStats(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(statsTestVars()))
+	expectedResult := Stats{}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${PARAM1} ${UNSET:-bar} ${PARAM1#f*}")
+	assert.Nil(t, err)
+	actualResult := unit.Stats()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderStatsCountWhatHasBeenDone(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(
+		newTestCallbacks(statsTestVars()),
+		WithStats(),
+		WithMemoizedLookups(),
+	)
+	expectedResult := Stats{
+		ParamsExpanded:    6,
+		DefaultsUsed:      2,
+		Assignments:       1,
+		LookupCacheHits:   2,
+		LookupCacheMisses: 6,
+		GlobCacheHits:     1,
+		GlobCacheMisses:   1,
+		Errors:            1,
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${PARAM1} ${PARAM1} ${UNSET:-bar} ${NEW:=baz} ${PARAM1#f*}")
+	assert.Nil(t, err)
+	_, err = unit.Expand("${PARAM1#f*}")
+	assert.Nil(t, err)
+	_, err = unit.Expand("${UNSET:?not set}")
+	assert.Error(t, err)
+	actualResult := unit.Stats()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderStatsAreSafeToShareBetweenGoroutines(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newTestCallbacks(statsTestVars()), WithStats())
+	var wg sync.WaitGroup
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unit.Expand("${PARAM1} ${PARAM2}")
+		}()
+	}
+	wg.Wait()
+	actualResult := unit.Stats()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, int64(20), actualResult.ParamsExpanded)
+}
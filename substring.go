@@ -0,0 +1,147 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"unicode/utf8"
+
+	"github.com/ganbarodigital/go_shellexpand/arith"
+)
+
+// evalSubstringNumber works out the offset or length in
+// ${var:offset:length}
+//
+// just like UNIX shells, we expand any parameters, command substitutions
+// and arithmetic in it first, and then evaluate the result as an
+// arithmetic expression. That means that ${var:N}, ${var:$N:1} and
+// ${var:1+1} all work, as do ${var: -2} and ${var:(-2)}.
+func evalSubstringNumber(input string, cb ExpansionCallbacks) (int, error) {
+	expanded, err := expandSpans(input, cb, scanParams)
+	if err != nil {
+		return 0, err
+	}
+
+	retval, err := arith.Eval(expanded, arithVars(cb))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(retval), nil
+}
+
+// valueLength returns the length of the given value, in characters
+// (or in bytes, if the WithByteOffsets() option is set)
+func valueLength(value string, cb ExpansionCallbacks) int {
+	if cb.byteOffsets() {
+		return len(value)
+	}
+
+	return utf8.RuneCountInString(value)
+}
+
+// substring returns the part of the input that starts `offset`
+// characters in, and ends at character `end`
+//
+// both are counted in bytes instead, if the WithByteOffsets() option is
+// set ... but we still never split a UTF-8 character in two
+func substring(input string, offset, end int, cb ExpansionCallbacks) string {
+	if cb.byteOffsets() {
+		// don't start half-way through a character
+		for offset < end && !utf8.RuneStart(input[offset]) {
+			offset++
+		}
+		// and don't end half-way through one either
+		for end < len(input) && end > offset && !utf8.RuneStart(input[end]) {
+			end--
+		}
+		return input[offset:end]
+	}
+
+	return input[runeIndex(input, offset):runeIndex(input, end)]
+}
+
+// runeIndex returns the byte position of the n'th character in the input
+func runeIndex(input string, n int) int {
+	for i := range input {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+
+	return len(input)
+}
+
+// substringBounds works out where ${var:offset:length} starts and ends,
+// in the same way that bash does:
+//
+// - a negative offset counts back from the end of the value
+// - a negative length stops that many characters before the end
+//
+// set `length` to nil if there is no length
+func substringBounds(valueLen, offset int, length *int) (int, int, bool) {
+	if offset < 0 {
+		offset += valueLen
+		if offset < 0 {
+			return 0, 0, false
+		}
+	}
+	if offset > valueLen {
+		return 0, 0, false
+	}
+
+	end := valueLen
+	if length != nil {
+		if *length < 0 {
+			end = valueLen + *length
+		} else if *length < valueLen-offset {
+			end = offset + *length
+		}
+	}
+
+	return offset, end, true
+}
+
+// WithByteOffsets makes ${var:offset:length} and ${#var} count bytes
+// instead of characters, like bash does in the C locale.
+//
+// Substrings never split a UTF-8 character in two, even with this option
+// set.
+func WithByteOffsets() Option {
+	return func(opts *options) {
+		opts.byteOffsets = true
+	}
+}
@@ -0,0 +1,220 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/arith"
+	"github.com/stretchr/testify/assert"
+)
+
+var substringTestVars = map[string]string{
+	"PARAM1": "héllo wörld",
+}
+
+func TestExpandSubstringCountsCharacters(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := NewMapCallbacks(substringTestVars)
+	testData := "${#PARAM1} ${PARAM1:1:2} ${PARAM1: -4} ${PARAM1:7}"
+	expectedResult := "11 él örld örld"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandWithByteOffsetsCountsBytes(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(NewMapCallbacks(substringTestVars), WithByteOffsets())
+	testData := "${#PARAM1} ${PARAM1:1:2} ${PARAM1:8}"
+	expectedResult := "13 é örld"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandWithByteOffsetsNeverSplitsACharacter(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(NewMapCallbacks(substringTestVars), WithByteOffsets())
+
+	// bytes 2 and 9 are both half-way through a character
+	testData := "[${PARAM1:2}] [${PARAM1:0:2}] [${PARAM1:7:2}]"
+	expectedResult := "[llo wörld] [h] [w]"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandCaseOperatorsHandleMultibyteFirstChar(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "élan", true
+		},
+	}
+	testData := "${PARAM1^} ${PARAM1^[é]} ${PARAM1,}"
+	expectedResult := "Élan Élan élan"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := Expand(testData, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpandSubstringEvaluatesOffsetAndLengthAsArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// these results match bash
+	testData := map[string]string{
+		"${FOO:$N:1}":       "c",
+		"${FOO:1+1}":        "cdef",
+		"${FOO:N}":          "cdef",
+		"${FOO:N:N*2}":      "cdef",
+		"${FOO:$((N+1)):1}": "d",
+		"${FOO:(-2)}":       "ef",
+		"${FOO: -N:1}":      "e",
+		"${FOO:UNSET:2}":    "ab",
+		"${@:N}":            "two three",
+		"${@:1:N-1}":        "one",
+	}
+
+	for input, expectedResult := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		cb := NewMapCallbacks(map[string]string{
+			"FOO": "abcdef",
+			"N":   "2",
+			"$1":  "one",
+			"$2":  "two",
+			"$3":  "three",
+			"$#":  "3",
+		})
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := Expand(input, cb)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, input)
+		assert.Equal(t, expectedResult, actualResult, input)
+	}
+}
+
+func TestExpandSubstringReturnsArithmeticErrors(t *testing.T) {
+	t.Parallel()
+
+	testData := map[string]error{
+		"${FOO:1:$((1/0))}": arith.ErrDivisionByZero{},
+		"${FOO:1/0}":        arith.ErrDivisionByZero{},
+		"${FOO:x y}":        arith.ErrSyntax{},
+		"${@:1:1 +}":        arith.ErrSyntax{},
+	}
+
+	for input, expectedErr := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		cb := NewMapCallbacks(map[string]string{
+			"FOO": "abcdef",
+			"$1":  "one",
+			"$2":  "two",
+			"$#":  "2",
+		})
+		unit := NewExpander(cb, WithStrict())
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, expectedErr), input)
+		assert.Equal(t, "", actualResult, input)
+	}
+}
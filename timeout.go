@@ -0,0 +1,92 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeout limits how long each call to the Expander can take. Once
+// the timeout has passed, the call stops and returns an
+// ErrDeadlineExceeded.
+//
+// The timeout is applied on top of any context that you pass in.
+// Context-aware callbacks (such as LookupVarContext) are given a context
+// that runs out when the timeout does.
+//
+// We check the timeout between expansions, while brace expansion
+// generates its words, and each time we match a pattern, so that even
+// {1..10000000} or ${var//pattern/x} on a huge value stops on time. We
+// cannot interrupt a single pattern match, or a callback that ignores
+// its context; either of those can still run past the timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+// withTimeout returns a context that runs out when the WithTimeout()
+// option says it should, unless the given context runs out first
+//
+// call the returned function with the call's error (or nil) once the
+// call has finished. It releases the context, and turns our timeout
+// into an ErrDeadlineExceeded.
+func (e *Expander) withTimeout(ctx context.Context) (context.Context, func(error) error) {
+	timeout := e.opts.timeout
+	if timeout <= 0 {
+		return ctx, func(err error) error { return err }
+	}
+
+	// the caller's own deadline wins if it is sooner
+	deadline, ok := ctx.Deadline()
+	if ok && deadline.Before(time.Now().Add(timeout)) {
+		return ctx, func(err error) error { return err }
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func(err error) error {
+		// if our timeout has passed, that is why the call failed, even
+		// if the error we have been given (e.g. ErrSliceExpansion)
+		// does not say so
+		timedOut := ctx.Err() == context.DeadlineExceeded
+		cancel()
+		if err != nil && timedOut {
+			return ErrDeadlineExceeded{Timeout: timeout}
+		}
+		return err
+	}
+}
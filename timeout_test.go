@@ -0,0 +1,241 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSlowTestCallbacks returns callbacks where $SLOW does not return
+// until its context runs out
+func newSlowTestCallbacks() ExpansionCallbacks {
+	return ExpansionCallbacks{
+		LookupVarContext: func(ctx context.Context, name string) (string, bool) {
+			if name != "SLOW" {
+				return "fast", true
+			}
+			<-ctx.Done()
+			return "slow", true
+		},
+	}
+}
+
+func TestExpanderWithTimeoutStopsSlowExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newSlowTestCallbacks(), WithTimeout(10*time.Millisecond))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("$FAST $SLOW")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, ErrDeadlineExceeded{Timeout: 10 * time.Millisecond}, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestExpanderWithTimeoutAppliesToEveryMethod(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newSlowTestCallbacks(), WithTimeout(10*time.Millisecond))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, argsErr := unit.ExpandArgs("$FAST $SLOW")
+	_, sliceErr := unit.ExpandSlice([]string{"$FAST", "$SLOW"})
+	_, resultErr := unit.ExpandResult("$FAST $SLOW")
+	_, letErr := unit.EvalLet([]string{"SLOW + 1"})
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.True(t, errors.Is(argsErr, ErrDeadlineExceeded{}))
+	assert.True(t, errors.Is(sliceErr, ErrDeadlineExceeded{}))
+	assert.True(t, errors.Is(resultErr, ErrDeadlineExceeded{}))
+	assert.True(t, errors.Is(letErr, ErrDeadlineExceeded{}))
+}
+
+func TestExpanderWithTimeoutStopsHugeBraceExpansions(t *testing.T) {
+	t.Parallel()
+
+	testDataSet := []string{
+		"{1..10000000}",
+		"{1..200}{1..200}{1..200}",
+		"x{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}{a,b,c,d,e}",
+	}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		unit := NewExpander(ExpansionCallbacks{}, WithTimeout(200*time.Millisecond))
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		start := time.Now()
+		_, expandErr := unit.Expand(testData)
+		_, argsErr := unit.ExpandArgs(testData)
+		_, braceErr := BraceExpand(testData, WithTimeout(200*time.Millisecond))
+		elapsed := time.Since(start)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(expandErr, ErrDeadlineExceeded{}), testData)
+		assert.True(t, errors.Is(argsErr, ErrDeadlineExceeded{}), testData)
+		assert.True(t, errors.Is(braceErr, ErrDeadlineExceeded{}), testData)
+		assert.Less(t, int64(elapsed), int64(3*time.Second), testData)
+	}
+}
+
+func TestExpanderWithTimeoutStopsSlowPatternMatches(t *testing.T) {
+	t.Parallel()
+
+	// each of these matches the pattern against the rest of the value,
+	// once for every character in it
+	testDataSet := []string{
+		"${HUGE//*b/x}",
+		"${HUGE/*b/x}",
+	}
+
+	for _, testData := range testDataSet {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		huge := strings.Repeat("a", 200000)
+		cb := ExpansionCallbacks{
+			LookupVar: func(name string) (string, bool) {
+				return huge, true
+			},
+		}
+		unit := NewExpander(cb, WithTimeout(50*time.Millisecond))
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		start := time.Now()
+		_, err := unit.Expand(testData)
+		elapsed := time.Since(start)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.True(t, errors.Is(err, ErrDeadlineExceeded{}), "%s: %v", testData, err)
+		assert.Less(t, int64(elapsed), int64(3*time.Second), testData)
+	}
+}
+
+func TestExpanderWithTimeoutDoesNotAffectFastExpansions(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newSlowTestCallbacks(), WithTimeout(time.Minute))
+	expectedResult := "fast fast"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand("$FAST ${FASTER}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+}
+
+func TestExpanderWithTimeoutLeavesCallersDeadlineAlone(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(newSlowTestCallbacks(), WithTimeout(time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.ExpandContext(ctx, "$SLOW")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestErrDeadlineExceeded(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := ErrDeadlineExceeded{Timeout: 2 * time.Second}
+	expectedResult := "expansion did not finish within 2s"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := testData.Error()
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Equal(t, expectedResult, actualResult)
+	assert.True(t, errors.Is(testData, ErrDeadlineExceeded{}))
+	assert.True(t, errors.Is(testData, context.DeadlineExceeded))
+	assert.False(t, errors.Is(testData, ErrTooManyPasses{}))
+}
@@ -0,0 +1,222 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "strings"
+
+// TraceFunc is called with each TraceEvent, as it happens
+type TraceFunc func(TraceEvent)
+
+// TraceKind tells you what a TraceEvent describes
+type TraceKind int
+
+// these are the kinds of TraceEvent that we send
+const (
+	// TracePhase describes one phase of expansion, from start to finish
+	TracePhase TraceKind = iota + 1
+
+	// TraceParameter describes the expansion of a single parameter
+	TraceParameter
+
+	// TraceVarRead describes a variable that was looked up outside of
+	// a parameter's own expansion: a name in arithmetic (e.g. `X` in
+	// $((X+1))), or the reference variable in ${!ref}
+	TraceVarRead
+
+	// TraceAssignment describes a value that arithmetic assigned to a
+	// variable, e.g. $((N=5)) or $((N++))
+	TraceAssignment
+
+	// TraceArithmetic describes a single arithmetic expression that was
+	// evaluated, e.g. the X+1 in $((X+1))
+	TraceArithmetic
+)
+
+// TraceEvent describes one decision that was made during expansion.
+// Use the WithTrace() option to receive them.
+type TraceEvent struct {
+	// Kind tells you which of the fields below are set
+	Kind TraceKind
+
+	// Phase is the phase of expansion that the event belongs to
+	Phase ExpansionPhase
+
+	// Input is the text that was expanded; for TraceParameter events,
+	// it is the parameter itself (e.g. "${HOME:-/tmp}"), and for
+	// TraceArithmetic events, it is the expression once any parameters
+	// in it have been expanded (e.g. "X+1"). It is empty for
+	// TraceVarRead and TraceAssignment events.
+	Input string
+
+	// Result is what Input expanded to. For TraceVarRead events, it is
+	// the variable's value; for TraceAssignment events, it is the value
+	// that was assigned.
+	Result string
+
+	// Operator is the name of the parameter expansion that was used,
+	// e.g. "expand-with-default-value" (TraceParameter only)
+	Operator string
+
+	// Name is the name of the variable that was looked up (or, for
+	// TraceAssignment events, assigned to)
+	Name string
+
+	// Values holds the values of the variable that the operator was
+	// applied to. Positional parameters ($@ and $*) can have several
+	// values. (TraceParameter only)
+	Values []string
+
+	// WordUsed is true if the word after the operator was expanded and
+	// used, e.g. the default value in ${var:-word} (TraceParameter only)
+	WordUsed bool
+}
+
+// WithTrace makes the Expander call fn with a TraceEvent for each phase
+// of expansion, and for each parameter that it expands
+func WithTrace(fn TraceFunc) Option {
+	return func(opts *options) {
+		opts.trace = fn
+	}
+}
+
+// paramOperatorNames holds the name of each kind of parameter expansion,
+// for use in TraceEvents
+var paramOperatorNames = map[int]string{
+	paramExpandToValue:                          "expand-to-value",
+	paramExpandWithDefaultValue:                 "expand-with-default-value",
+	paramExpandSetDefaultValue:                  "expand-assign-default-value",
+	paramExpandWriteError:                       "expand-write-error",
+	paramExpandAlternativeValue:                 "expand-use-alternate-value",
+	paramExpandSubstring:                        "expand-to-substring",
+	paramExpandSubstringLength:                  "expand-to-substring-length",
+	paramExpandPrefixNames:                      "expand-prefix-match-names",
+	paramExpandPrefixNamesDoubleQuoted:          "expand-prefix-match-names",
+	paramExpandParamLength:                      "expand-parameter-length",
+	paramExpandNoOfPositionalParams:             "expand-no-positional-params",
+	paramExpandRemovePrefixShortestMatch:        "expand-remove-shortest-prefix",
+	paramExpandRemovePrefixLongestMatch:         "expand-remove-longest-prefix",
+	paramExpandRemoveSuffixShortestMatch:        "expand-remove-shortest-suffix",
+	paramExpandRemoveSuffixLongestMatch:         "expand-remove-longest-suffix",
+	paramExpandSearchReplaceLongestFirstMatch:   "expand-search-replace-first-match",
+	paramExpandSearchReplaceLongestAllMatches:   "expand-search-replace-all-matches",
+	paramExpandSearchReplaceLongestPrefix:       "expand-search-replace-prefix",
+	paramExpandSearchReplaceLongestSuffix:       "expand-search-replace-suffix",
+	paramExpandAllPositionalParamsSearchReplace: "expand-search-replace-all-matches",
+	paramExpandUppercaseFirstChar:               "expand-uppercase-first-char",
+	paramExpandUppercaseAllChars:                "expand-uppercase-all-chars",
+	paramExpandLowercaseFirstChar:               "expand-lowercase-first-char",
+	paramExpandLowercaseAllChars:                "expand-lowercase-all-chars",
+	paramExpandDescribeFlags:                    "expand-parameter-transform",
+	paramExpandAsDeclare:                        "expand-parameter-transform",
+	paramExpandEscaped:                          "expand-parameter-transform",
+	paramExpandAsPrompt:                         "expand-parameter-transform",
+	paramExpandSingleQuoted:                     "expand-parameter-transform",
+	paramExpandAsKeyValuePairs:                  "expand-parameter-transform",
+}
+
+// traceParameter sends a TraceEvent for a parameter that we have expanded
+func traceParameter(cb ExpansionCallbacks, original, paramName string, paramDesc paramDesc, values []string, result string) {
+	if !cb.tracing() {
+		return
+	}
+
+	// was the word after the operator used?
+	isEmpty := paramDesc.isNull(strings.Join(values, ""))
+	wordUsed := false
+	switch paramDesc.kind {
+	case paramExpandWithDefaultValue, paramExpandSetDefaultValue:
+		wordUsed = isEmpty
+	case paramExpandAlternativeValue:
+		wordUsed = !isEmpty
+	}
+
+	// we copy the values, because our slice goes back into the pool
+	// once we are done, and the caller may hang onto the event
+	cb.trace(TraceEvent{
+		Kind:     TraceParameter,
+		Phase:    PhaseParameterExpansion,
+		Input:    original,
+		Result:   result,
+		Operator: paramOperatorNames[paramDesc.kind],
+		Name:     paramName,
+		Values:   append([]string(nil), values...),
+		WordUsed: wordUsed,
+	})
+}
+
+// traceVar sends a TraceVarRead or TraceAssignment event for a variable
+// that was read or written outside of a parameter's own expansion
+func traceVar(cb ExpansionCallbacks, kind TraceKind, phase ExpansionPhase, name, value string) {
+	if !cb.tracing() {
+		return
+	}
+
+	cb.trace(TraceEvent{
+		Kind:   kind,
+		Phase:  phase,
+		Result: value,
+		Name:   name,
+	})
+}
+
+// traceArithmetic sends a TraceEvent for an arithmetic expression that
+// has been evaluated
+func traceArithmetic(cb ExpansionCallbacks, expr, result string) {
+	if !cb.tracing() {
+		return
+	}
+
+	cb.trace(TraceEvent{
+		Kind:   TraceArithmetic,
+		Phase:  PhaseArithmeticExpansion,
+		Input:  expr,
+		Result: result,
+	})
+}
+
+// tracePhase sends a TraceEvent for a phase of expansion that has finished
+func tracePhase(cb ExpansionCallbacks, phase ExpansionPhase, input, result string) {
+	if !cb.tracing() {
+		return
+	}
+
+	cb.trace(TraceEvent{
+		Kind:   TracePhase,
+		Phase:  phase,
+		Input:  input,
+		Result: result,
+	})
+}
@@ -0,0 +1,192 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTraceReportsPhasesAndParameters(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"PARAM1": "foo",
+		"PARAM2": "",
+	}
+	var events []TraceEvent
+	unit := NewExpander(
+		ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				retval, ok := vars[key]
+				return retval, ok
+			},
+		},
+		WithTrace(func(event TraceEvent) {
+			events = append(events, event)
+		}),
+	)
+	expectedResult := []TraceEvent{
+		{
+			Kind:   TracePhase,
+			Phase:  PhaseBraceExpansion,
+			Input:  "a{b,c} ${PARAM2:-$PARAM1}",
+			Result: "ab ac ${PARAM2:-$PARAM1}",
+		},
+		{
+			Kind:   TracePhase,
+			Phase:  PhaseTildeExpansion,
+			Input:  "ab ac ${PARAM2:-$PARAM1}",
+			Result: "ab ac ${PARAM2:-$PARAM1}",
+		},
+		{
+			Kind:     TraceParameter,
+			Phase:    PhaseParameterExpansion,
+			Input:    "$PARAM1",
+			Result:   "foo",
+			Operator: "expand-to-value",
+			Name:     "PARAM1",
+			Values:   []string{"foo"},
+		},
+		{
+			Kind:     TraceParameter,
+			Phase:    PhaseParameterExpansion,
+			Input:    "${PARAM2:-$PARAM1}",
+			Result:   "foo",
+			Operator: "expand-with-default-value",
+			Name:     "PARAM2",
+			Values:   []string{""},
+			WordUsed: true,
+		},
+		{
+			Kind:   TracePhase,
+			Phase:  PhaseParameterExpansion,
+			Input:  "ab ac ${PARAM2:-$PARAM1}",
+			Result: "ab ac foo",
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("a{b,c} ${PARAM2:-$PARAM1}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, events)
+}
+
+func TestWithTraceReportsArithmetic(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	vars := map[string]string{
+		"X": "2",
+	}
+	var events []TraceEvent
+	unit := NewExpander(
+		newTestCallbacks(vars),
+		WithTrace(func(event TraceEvent) {
+			if event.Kind != TracePhase {
+				events = append(events, event)
+			}
+		}),
+	)
+	expectedResult := []TraceEvent{
+		{
+			Kind:   TraceVarRead,
+			Phase:  PhaseArithmeticExpansion,
+			Name:   "X",
+			Result: "2",
+		},
+		{
+			Kind:   TraceAssignment,
+			Phase:  PhaseArithmeticExpansion,
+			Name:   "N",
+			Result: "3",
+		},
+		{
+			Kind:   TraceArithmetic,
+			Phase:  PhaseArithmeticExpansion,
+			Input:  "N = X + 1",
+			Result: "3",
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("$((N = X + 1))")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, events)
+}
+
+func TestTraceIsOffByDefault(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "foo", true
+		},
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := NewExpander(cb).Expand("$PARAM1")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", actualResult)
+	assert.False(t, cb.tracing())
+}
@@ -0,0 +1,105 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "strings"
+
+// Translate looks up the translation of a message, in the same way
+// that gettext does. It is given the text domain (the value of
+// $TEXTDOMAIN, or "" if that is not set) and the message, and returns
+// the translated message.
+//
+// The message is the text between the quotes, exactly as it appears in
+// the input; any parameters in it are expanded after it has been
+// translated, just like bash does.
+//
+// If there is no translation, it must return the message unchanged.
+type Translate func(domain, msg string) string
+
+// translate returns the translation of the given $"..." message
+func (cb ExpansionCallbacks) translate(msg string) string {
+	if cb.Translate == nil {
+		return msg
+	}
+
+	domain, _ := cb.lookupVar("TEXTDOMAIN")
+	if cb.budget.spendCallback() != nil {
+		return msg
+	}
+	return cb.Translate(domain, msg)
+}
+
+// translateLocaleString translates the $"..." string at the start of the
+// input, and tells you how long it was
+//
+// the translation comes back as a double-quoted string, ready to be
+// expanded in place of the original
+func translateLocaleString(input string, cb ExpansionCallbacks) (string, int, bool) {
+	// are we looking at a $"..." string?
+	if len(input) < 2 || input[0] != '$' || input[1] != '"' {
+		return "", 0, false
+	}
+	quoteEnd, ok := matchQuotes(input[1:])
+	if !ok {
+		return "", 0, false
+	}
+
+	// the message is what is inside the quotes, before any expansion
+	msg := input[2:quoteEnd]
+	translated := cb.translate(msg)
+
+	// the translation may contain double quotes of its own
+	var buf strings.Builder
+	buf.Grow(len(translated) + 2)
+	buf.WriteByte('"')
+	for i := 0; i < len(translated); i++ {
+		switch translated[i] {
+		case '\\':
+			buf.WriteByte('\\')
+			if i+1 < len(translated) {
+				i++
+				buf.WriteByte(translated[i])
+			}
+		case '"':
+			buf.WriteString(`\"`)
+		default:
+			buf.WriteByte(translated[i])
+		}
+	}
+	buf.WriteByte('"')
+
+	return buf.String(), quoteEnd + 1, true
+}
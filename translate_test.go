@@ -0,0 +1,125 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslateIsCalledForLocaleStrings(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	catalog := map[string]string{
+		"hello $NAME":  "bonjour $NAME",
+		`say \"hi\"`:   `dis "salut"`,
+		"goodbye":      "au revoir",
+		"untranslated": "",
+	}
+	var domains []string
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			switch key {
+			case "NAME":
+				return "Alfred  Smith", true
+			case "TEXTDOMAIN":
+				return "myapp", true
+			}
+			return "", false
+		},
+		Translate: func(domain, msg string) string {
+			domains = append(domains, domain)
+			retval, ok := catalog[msg]
+			if !ok {
+				return msg
+			}
+			return retval
+		},
+	}
+	input := `$"hello $NAME" $"say \"hi\"" '$"goodbye"' "$"goodbye"" $"not in the catalog"`
+	expectedResult := []string{
+		"bonjour Alfred  Smith",
+		`dis "salut"`,
+		`$"goodbye"`,
+		"$goodbye",
+		"not in the catalog",
+	}
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := ExpandArgs(input, cb)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, actualResult)
+	assert.Equal(t, []string{"myapp", "myapp", "myapp"}, domains)
+}
+
+func TestTranslateIsNotUsedByThePOSIXDialect(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	cb := ExpansionCallbacks{
+		LookupVar: func(key string) (string, bool) {
+			return "", false
+		},
+		Translate: func(domain, msg string) string {
+			return "translated"
+		},
+	}
+	expander := NewExpander(cb, WithDialect(DialectPOSIX))
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := expander.ExpandArgs(`$"hello"`)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"$hello"}, actualResult)
+}
@@ -35,16 +35,57 @@
 
 package shellexpand
 
-func isAlphaChar(char rune) bool {
-	return 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z'
+import "unicode/utf8"
+
+// these are the classes that an ASCII character can belong to
+const (
+	charNumeric = 1 << iota
+	charNameStart
+	charNameBody
+	charShellSpecial
+)
+
+// charClasses tells us which classes each ASCII character belongs to
+//
+// it has an entry for every possible byte, so that looking up a byte
+// never needs a bounds check
+//
+// the scanners call our is...Char() functions for nearly every character
+// that they look at, so it's worth doing a single lookup instead of a
+// series of comparisons
+var charClasses = newCharClasses()
+
+func newCharClasses() [256]uint8 {
+	var retval [256]uint8
+
+	for c := 'a'; c <= 'z'; c++ {
+		retval[c] |= charNameStart | charNameBody
+		retval[c-'a'+'A'] |= charNameStart | charNameBody
+	}
+	for c := '0'; c <= '9'; c++ {
+		retval[c] |= charNumeric | charNameBody
+	}
+	retval['_'] |= charNameStart | charNameBody
+	for _, c := range "#*?!$-@0" {
+		retval[c] |= charShellSpecial
+	}
+
+	return retval
 }
 
-func isAlphaNumericChar(char rune) bool {
-	return isNumericChar(char) || isAlphaChar(char)
+// isCharClass returns true if the given character belongs to any of
+// the given classes
+func isCharClass(char rune, classes uint8) bool {
+	// slow path: none of our classes contain anything outside of ASCII
+	if uint32(char) >= utf8.RuneSelf {
+		return false
+	}
+
+	return charClasses[uint8(char)]&classes != 0
 }
 
 func isNumericChar(char rune) bool {
-	return '0' <= char && char <= '9'
+	return isCharClass(char, charNumeric)
 }
 
 func isNumericStartChar(char rune) bool {
@@ -61,26 +102,6 @@ func isNumericString(input string) bool {
 	return true
 }
 
-func isSignedNumericString(input string) bool {
-	// special case - zero
-	if input == "0" {
-		return true
-	}
-
-	// no leading zeros allowed ... but can be a negative value
-	if input[0] != '-' && !isNumericStartChar(rune(input[0])) {
-		return false
-	}
-
-	for i := 1; i < len(input); i++ {
-		if !isNumericChar(rune(input[i])) {
-			return false
-		}
-	}
-
-	return true
-}
-
 func isNumericStringWithoutLeadingZero(input string) bool {
 	if len(input) == 0 {
 		return false
@@ -100,13 +121,13 @@ func isNumericStringWithoutLeadingZero(input string) bool {
 }
 
 func isNameBodyChar(char rune) bool {
-	return isAlphaNumericChar(char) || char == '_'
+	return isCharClass(char, charNameBody)
 }
 
 func isNameStartChar(char rune) bool {
-	return isAlphaChar(char) || char == '_'
+	return isCharClass(char, charNameStart)
 }
 
 func isShellSpecialChar(char rune) bool {
-	return char == '#' || char == '*' || char == '?' || char == '!' || char == '$' || char == '-' || char == '@' || char == '0'
+	return isCharClass(char, charShellSpecial)
 }
@@ -36,152 +36,13 @@
 package shellexpand
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestIsAlphaCharReturnsTrueForLowercaseChars(t *testing.T) {
-	t.Parallel()
-
-	for _, testData := range "abcdefghijklmnopqrstuvwxyz" {
-		// ----------------------------------------------------------------
-		// setup your test
-
-		expectedResult := true
-
-		// ----------------------------------------------------------------
-		// perform the change
-
-		actualResult := isAlphaChar(testData)
-
-		// ----------------------------------------------------------------
-		// test the results
-
-		assert.Equal(t, expectedResult, actualResult)
-	}
-}
-
-func TestIsAlphaCharReturnsTrueForUppercaseChars(t *testing.T) {
-	t.Parallel()
-
-	for _, testData := range "ABCDEFGHIJKLMNOPQRSTUVWXYZ" {
-		// ----------------------------------------------------------------
-		// setup your test
-
-		expectedResult := true
-
-		// ----------------------------------------------------------------
-		// perform the change
-
-		actualResult := isAlphaChar(testData)
-
-		// ----------------------------------------------------------------
-		// test the results
-
-		assert.Equal(t, expectedResult, actualResult)
-	}
-}
-
-func TestIsAlphaCharReturnsFalseOtherwise(t *testing.T) {
-	t.Parallel()
-
-	for _, testData := range "1234567890!\"£$%^&*()[]{};:'@#~,<.>/?`" {
-		// ----------------------------------------------------------------
-		// setup your test
-
-		expectedResult := false
-
-		// ----------------------------------------------------------------
-		// perform the change
-
-		actualResult := isAlphaChar(testData)
-
-		// ----------------------------------------------------------------
-		// test the results
-
-		assert.Equal(t, expectedResult, actualResult)
-	}
-}
-
-func TestIsSignedNumericStringReturnsTrueForZero(t *testing.T) {
-	t.Parallel()
-
-	// ----------------------------------------------------------------
-	// setup your test
-
-	testData := "0"
-	expectedResult := true
-
-	// ----------------------------------------------------------------
-	// perform the change
-
-	actualResult := isSignedNumericString(testData)
-
-	// ----------------------------------------------------------------
-	// test the results
-
-	assert.Equal(t, expectedResult, actualResult)
-}
-
-func TestIsSignedNumericStringReturnsTrueForPositiveNumbers(t *testing.T) {
-	t.Parallel()
-
-	for _, testData := range []string{
-		"1",
-		"2",
-		"3",
-		"4",
-		"5",
-		"6",
-		"7",
-		"8",
-		"9",
-		"10",
-		"11",
-		"12",
-		"23",
-		"14",
-		"1576",
-		"15157",
-	} {
-		// ----------------------------------------------------------------
-		// setup your test
-
-		expectedResult := true
-
-		// ----------------------------------------------------------------
-		// perform the change
-
-		actualResult := isSignedNumericString(testData)
-
-		// ----------------------------------------------------------------
-		// test the results
-
-		assert.Equal(t, expectedResult, actualResult)
-	}
-}
-
-func TestIsSignedNumericStringReturnsFalseIfNumberStartsWithLeadingZero(t *testing.T) {
-	t.Parallel()
-
-	// ----------------------------------------------------------------
-	// setup your test
-
-	testData := "0123"
-	expectedResult := false
-
-	// ----------------------------------------------------------------
-	// perform the change
-
-	actualResult := isSignedNumericString(testData)
-
-	// ----------------------------------------------------------------
-	// test the results
-
-	assert.Equal(t, expectedResult, actualResult)
-}
-
 func TestIsNumericStringWithoutLeadingZeroReturnsFalseForEmptyString(t *testing.T) {
 	t.Parallel()
 
@@ -202,22 +63,46 @@ func TestIsNumericStringWithoutLeadingZeroReturnsFalseForEmptyString(t *testing.
 	assert.Equal(t, expectedResult, actualResult)
 }
 
-func TestIsSignedNumericStringReturnsFalseIfNumberContainsLetters(t *testing.T) {
+func TestCharClassLookupsMatchTheirDefinitions(t *testing.T) {
 	t.Parallel()
 
 	// ----------------------------------------------------------------
 	// setup your test
 
-	testData := "123abc"
-	expectedResult := false
+	alpha := func(c rune) bool {
+		return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+	}
+	numeric := func(c rune) bool {
+		return '0' <= c && c <= '9'
+	}
 
-	// ----------------------------------------------------------------
-	// perform the change
+	testData := []rune{-1, utf8.RuneError, utf8.MaxRune, utf8.MaxRune + 1}
+	for c := rune(0); c < 1024; c++ {
+		testData = append(testData, c)
+	}
 
-	actualResult := isSignedNumericString(testData)
+	for _, c := range testData {
+		// ----------------------------------------------------------------
+		// perform the change
 
-	// ----------------------------------------------------------------
-	// test the results
+		actualResult := []bool{
+			isNumericChar(c),
+			isNameStartChar(c),
+			isNameBodyChar(c),
+			isShellSpecialChar(c),
+		}
 
-	assert.Equal(t, expectedResult, actualResult)
+		// ----------------------------------------------------------------
+		// test the results
+
+		expectedResult := []bool{
+			numeric(c),
+			alpha(c) || c == '_',
+			alpha(c) || numeric(c) || c == '_',
+			strings.ContainsRune("#*?!$-@0", c),
+		}
+		if !assert.Equal(t, expectedResult, actualResult, "%q", c) {
+			return
+		}
+	}
 }
@@ -0,0 +1,189 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validate checks the input for syntax errors, without expanding it.
+// It does not need any callbacks, so you can use it to check templates
+// long before you have any variables to expand them with.
+//
+// It returns every problem that it finds, in the order they appear in
+// the input. It returns an empty list if it does not find any problems.
+//
+// Validate looks for:
+//
+//   - ${ without a matching }
+//   - ${...} that we do not know how to expand (ErrBadSubstitution)
+//   - glob patterns that we cannot use (ErrBadPattern)
+//   - { and } that do not match up (ErrMismatchedBrace and
+//     ErrMismatchedClosingBrace)
+//
+// Glob patterns that contain a parameter expansion cannot be checked
+// until they are expanded.
+func Validate(input string) []ExpansionError {
+	retval := []ExpansionError{}
+
+	for _, err := range validateParams(input) {
+		retval = append(retval, locateExpansionError(err, input, input, 0).(ExpansionError))
+	}
+
+	_, err := matchBraces(input)
+	switch braceErr := err.(type) {
+	case ErrMismatchedBrace:
+		// don't report the same '{' twice
+		alreadyReported := false
+		for _, problem := range retval {
+			if problem.Offset+1 == braceErr.Index {
+				alreadyReported = true
+			}
+		}
+		if !alreadyReported {
+			err = newBraceExpansionError(input, err)
+			retval = append(retval, locateExpansionError(err, input, input, 0).(ExpansionError))
+		}
+	case ErrMismatchedClosingBrace:
+		err = newBraceExpansionError(input, err)
+		retval = append(retval, locateExpansionError(err, input, input, 0).(ExpansionError))
+	}
+
+	sort.SliceStable(retval, func(i, j int) bool {
+		return retval[i].Offset < retval[j].Offset
+	})
+
+	return retval
+}
+
+// validateParams checks every ${...} in the input, including any that
+// are nested inside other ${...}
+//
+// The errors that we return are ExpansionErrors, with their offsets
+// relative to the start of the input
+func validateParams(input string) []error {
+	var retval []error
+
+	inEscape := false
+	var c rune
+	w := 0
+	for i := 0; i < len(input); i += w {
+		c, w = utf8.DecodeRuneInString(input[i:])
+		if inEscape {
+			inEscape = false
+			continue
+		}
+		if c == '\\' {
+			inEscape = true
+			continue
+		}
+		if c != '$' {
+			continue
+		}
+
+		varEnd, err := findVar(input[i:])
+		if err != nil {
+			_, unterminated := err.(ErrMismatchedBrace)
+			if unterminated {
+				// the rest of the input is part of the unterminated
+				// parameter
+				err := newExpansionError(PhaseParameterExpansion, input, i, len(input), NewErrMismatchedBrace(input, i+1))
+				return append(retval, err)
+			}
+			continue
+		}
+		varEnd += i
+		w = varEnd - i
+
+		paramDesc, ok := parseParameter(input[i:varEnd])
+		if !ok {
+			if strings.HasPrefix(input[i:varEnd], "${") {
+				retval = append(retval, newExpansionError(
+					PhaseParameterExpansion,
+					input,
+					i,
+					varEnd,
+					ErrBadSubstitution{input[i:varEnd]},
+				))
+			}
+			continue
+		}
+
+		for _, err := range validateParamDesc(paramDesc) {
+			retval = append(retval, newExpansionError(PhaseParameterExpansion, input, i, varEnd, err))
+		}
+	}
+
+	return retval
+}
+
+// validateParamDesc checks the parts of a parameter that has already
+// been parsed
+func validateParamDesc(paramDesc paramDesc) []error {
+	var retval []error
+
+	switch paramDesc.kind {
+	case paramExpandSubstring, paramExpandSubstringLength:
+		// these parts are numbers, not words
+		return nil
+	case paramExpandRemovePrefixShortestMatch,
+		paramExpandRemovePrefixLongestMatch,
+		paramExpandRemoveSuffixShortestMatch,
+		paramExpandRemoveSuffixLongestMatch,
+		paramExpandUppercaseFirstChar,
+		paramExpandUppercaseAllChars,
+		paramExpandLowercaseFirstChar,
+		paramExpandLowercaseAllChars:
+		// we can only check patterns that will not change when they
+		// are expanded
+		pattern := paramDesc.word()
+		if !strings.Contains(pattern, "$") {
+			_, err := compileGlob(pattern, globMatchWhole)
+			if err != nil {
+				retval = append(retval, err)
+			}
+		}
+	}
+
+	// any words inside the parameter can contain problems too
+	for _, part := range paramDesc.parts[1:] {
+		retval = append(retval, validateParams(part)...)
+	}
+
+	return retval
+}
@@ -0,0 +1,103 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAcceptsGoodInput(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "$HOME ${PARAM1:-${PARAM2#abc*}} {a,b,c} ${PARAM3%%[0-9]} \\$HOME $1 ${#*}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Validate(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Empty(t, actualResult)
+}
+
+func TestValidateFindsAllProblems(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	testData := "${++} ${PARAM1#abc[}\n${PARAM2:-${PARAM3^^[}} ${PARAM4#$PATTERN}} ${PARAM5"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult := Validate(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Len(t, actualResult, 5)
+
+	assert.Equal(t, 0, actualResult[0].Offset)
+	assert.Equal(t, "${++}", actualResult[0].Substring)
+	assert.True(t, errors.Is(actualResult[0], ErrBadSubstitution{}))
+
+	assert.Equal(t, 6, actualResult[1].Offset)
+	assert.Equal(t, "${PARAM1#abc[}", actualResult[1].Substring)
+	assert.True(t, errors.Is(actualResult[1], ErrBadPattern{}))
+
+	assert.Equal(t, 2, actualResult[2].Line)
+	assert.Equal(t, 1, actualResult[2].Column)
+	assert.Equal(t, "${PARAM2:-${PARAM3^^[}}", actualResult[2].Substring)
+	assert.True(t, errors.Is(actualResult[2], ErrBadPattern{}))
+
+	assert.Equal(t, PhaseBraceExpansion, actualResult[3].Phase)
+	assert.Equal(t, 2, actualResult[3].Line)
+	assert.Equal(t, 43, actualResult[3].Column)
+	assert.Equal(t, "}", actualResult[3].Substring)
+
+	assert.Equal(t, PhaseParameterExpansion, actualResult[4].Phase)
+	assert.Equal(t, "${PARAM5", actualResult[4].Substring)
+	assert.Equal(t, "unmatched '{' at position 66", actualResult[4].Error())
+}
@@ -0,0 +1,180 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import "strings"
+
+// VarSyntax is the way that variables are written in the input string
+type VarSyntax int
+
+// these are the variable syntaxes that we understand
+const (
+	// VarSyntaxShell is $var and ${...}, just like a UNIX shell. It is
+	// the default.
+	VarSyntaxShell VarSyntax = iota
+
+	// VarSyntaxPercent is %var%, just like cmd.exe on Windows. %% is a
+	// literal percent sign.
+	//
+	// Nothing else is expanded: $, \, ~ and braces are just normal
+	// characters.
+	VarSyntaxPercent
+
+	// VarSyntaxShellAndPercent understands both $var and %var%
+	VarSyntaxShellAndPercent
+
+	// VarSyntaxShellAndMake understands $(var) as well as $var and
+	// ${...}, just like a Makefile. $(var) is always a variable; it is
+	// never treated as command substitution.
+	//
+	// Just like a Makefile, $$ is a literal $, so $$(var) is the text
+	// $(var). That means that $$ is never the shell's process ID.
+	VarSyntaxShellAndMake
+)
+
+func (s VarSyntax) String() string {
+	switch s {
+	case VarSyntaxShell:
+		return "shell"
+	case VarSyntaxPercent:
+		return "percent"
+	case VarSyntaxShellAndPercent:
+		return "shell+percent"
+	case VarSyntaxShellAndMake:
+		return "shell+make"
+	default:
+		return "unknown var syntax"
+	}
+}
+
+// WithVarSyntax changes how the Expander finds variables in the input
+// string. Use it to expand config strings that were written for
+// Windows or for Makefiles, using the same callbacks.
+//
+// Just like cmd.exe, a %var% that is not set is left as it is.
+//
+// It does not change ExpandArgs(), which always follows the shell's
+// rules.
+func WithVarSyntax(syntax VarSyntax) Option {
+	return func(opts *options) {
+		opts.varSyntax = syntax
+	}
+}
+
+// findPercentVar returns the length of the %var% or %% at the start of
+// the input string
+//
+// it returns false if there isn't one
+func findPercentVar(input string) (int, bool) {
+	end := strings.IndexByte(input[1:], '%')
+	if end < 0 {
+		return 0, false
+	}
+
+	// variable names cannot contain whitespace, or an '='; this stops
+	// us from treating text like '50% of 20%' as a variable
+	name := input[1 : end+1]
+	if strings.ContainsAny(name, " \t\r\n=") {
+		return 0, false
+	}
+
+	return end + 2, true
+}
+
+// expandPercentVar returns what a %var% or %% expands to
+func expandPercentVar(text string, cb ExpansionCallbacks) (string, error) {
+	if text == "%%" {
+		return "%", nil
+	}
+
+	name := text[1 : len(text)-1]
+	err := cb.checkName(name)
+	if err != nil {
+		return "", err
+	}
+	value, ok := cb.lookupVar(name)
+	if !ok {
+		return text, nil
+	}
+
+	cb.stats.inc(statParamsExpanded)
+	return value, nil
+}
+
+// hasPercentVars returns false if the input definitely has no %var% in
+// it that Expand() can change
+func hasPercentVars(input string, cb ExpansionCallbacks) bool {
+	return cb.percentVars() && strings.IndexByte(input, '%') >= 0
+}
+
+// percentVars returns true if %var% is a variable
+func (cb ExpansionCallbacks) percentVars() bool {
+	return cb.varSyntax() == VarSyntaxPercent || cb.varSyntax() == VarSyntaxShellAndPercent
+}
+
+// findMakeVar returns the length of the $(var) at the start of the input
+// string
+//
+// it returns false if there isn't one
+func findMakeVar(input string) (int, bool) {
+	if len(input) < 2 || input[0] != '$' || input[1] != '(' {
+		return 0, false
+	}
+	end := strings.IndexByte(input, ')')
+	if end < 0 || !isName(input[2:end]) {
+		return 0, false
+	}
+
+	return end + 1, true
+}
+
+// expandMakeVar returns what a $(var) expands to
+func expandMakeVar(text string, cb ExpansionCallbacks) (string, error) {
+	name := text[2 : len(text)-1]
+	err := cb.checkName(name)
+	if err != nil {
+		return "", err
+	}
+	value, ok := cb.lookupVar(name)
+
+	// with WithKeepUnset(), we leave it for someone else to expand
+	if !ok && cb.keepUnset() {
+		return text, nil
+	}
+
+	cb.stats.inc(statParamsExpanded)
+	return value, nil
+}
@@ -0,0 +1,137 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"testing"
+
+	"github.com/ganbarodigital/go_shellexpand/shelltest"
+	"github.com/stretchr/testify/assert"
+)
+
+type varSyntaxTestData struct {
+	input          string
+	expectedResult string
+}
+
+func TestVarSyntaxPercentOnlyExpandsPercentVars(t *testing.T) {
+	testData := []varSyntaxTestData{
+		{`%ProgramFiles(x86)%\app`, `C:\Program Files (x86)\app`},
+		{`%PARAM1% $PARAM1 ${PARAM1} ~ {a,b} \%PARAM1%`, `foo $PARAM1 ${PARAM1} ~ {a,b} \foo`},
+		{`100%% of %UNSET%`, `100% of %UNSET%`},
+		{`50% off, was 20% more`, `50% off, was 20% more`},
+		{`%PARAM1`, `%PARAM1`},
+	}
+	testVarSyntaxTestCases(t, VarSyntaxPercent, testData)
+}
+
+func TestVarSyntaxShellAndPercentExpandsBoth(t *testing.T) {
+	testData := []varSyntaxTestData{
+		{`%PARAM1% $PARAM1 ~ {a,b}`, `foo foo /home/me a b`},
+		{`${UNSET:-%PARAM1%}`, `foo`},
+		{`'%PARAM1%' \%PARAM1%`, `'foo' %PARAM1%`},
+		{`100%% of %UNSET%`, `100% of %UNSET%`},
+	}
+	testVarSyntaxTestCases(t, VarSyntaxShellAndPercent, testData)
+}
+
+func TestVarSyntaxShellIgnoresPercentVars(t *testing.T) {
+	testData := []varSyntaxTestData{
+		{`%PARAM1% 100%%`, `%PARAM1% 100%%`},
+	}
+	testVarSyntaxTestCases(t, VarSyntaxShell, testData)
+}
+
+func testVarSyntaxTestCases(t *testing.T, syntax VarSyntax, testData []varSyntaxTestData) {
+	t.Parallel()
+
+	for _, testCase := range testData {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		shellCase := shelltest.Case{
+			Vars: map[string]string{
+				"HOME":              "/home/me",
+				"PARAM1":            "foo",
+				"ProgramFiles(x86)": `C:\Program Files (x86)`,
+			},
+		}
+		cb := ExpansionCallbacks{
+			LookupVar:     shellCase.LookupVar,
+			LookupHomeDir: shellCase.LookupHomeDir,
+		}
+		unit := NewExpander(cb, WithVarSyntax(syntax))
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(testCase.input)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.expectedResult, actualResult, "%s: %s", syntax, testCase.input)
+	}
+}
+
+func TestVarSyntaxShellAndMakeExpandsParenthesisedVars(t *testing.T) {
+	testData := []varSyntaxTestData{
+		{`$(PARAM1) $PARAM1 ${PARAM1} ~`, `foo foo foo /home/me`},
+		{`CFLAGS=$(UNSET) -O2`, `CFLAGS= -O2`},
+		{`${UNSET:-$(PARAM1)}`, `foo`},
+		{`\$(PARAM1) $(not a var) $(`, `$(PARAM1) $(not a var) $(`},
+		{`%PARAM1%`, `%PARAM1%`},
+	}
+	testVarSyntaxTestCases(t, VarSyntaxShellAndMake, testData)
+}
+
+func TestVarSyntaxShellAndMakeTreatsDoubleDollarAsLiteralDollar(t *testing.T) {
+	testData := []varSyntaxTestData{
+		{`$$(PARAM1)`, `$(PARAM1)`},
+		{`$$PARAM1 $(PARAM1)`, `$PARAM1 foo`},
+		{`cost: $$5`, `cost: $5`},
+		{`$$$(PARAM1)`, `$foo`},
+	}
+	testVarSyntaxTestCases(t, VarSyntaxShellAndMake, testData)
+}
+
+func TestVarSyntaxShellIgnoresMakeVars(t *testing.T) {
+	testData := []varSyntaxTestData{
+		{`$(PARAM1)`, `$(PARAM1)`},
+	}
+	testVarSyntaxTestCases(t, VarSyntaxShell, testData)
+}
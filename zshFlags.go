@@ -0,0 +1,199 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// zshFlags holds the parameter expansion flags from ${(flags)var}
+//
+// these are only supported in the zsh dialect
+type zshFlags struct {
+	// (U) - convert to uppercase
+	upper bool
+
+	// (L) - convert to lowercase
+	lower bool
+
+	// (C) - capitalise each word
+	capitalise bool
+
+	// (P) - use the value of var as the name of the variable to expand
+	indirect bool
+
+	// (s:sep:) - split the value into words on sep
+	split *string
+
+	// (j:sep:) - join the words together with sep
+	join *string
+}
+
+// parseZshFlaggedParameter parses a ${(flags)var...} parameter
+//
+// everything after the flags is parsed just like any other parameter
+func parseZshFlaggedParameter(input string) (paramDesc, bool) {
+	// where do the flags end?
+	flagsEnd := strings.IndexByte(input, ')')
+	if flagsEnd < 0 {
+		return paramDesc{}, false
+	}
+
+	flags, ok := parseZshFlags(input[3:flagsEnd])
+	if !ok {
+		return paramDesc{}, false
+	}
+
+	retval, ok := parseParameter("${" + input[flagsEnd+1:])
+	if !ok {
+		return paramDesc{}, false
+	}
+
+	// (P) and ${!var} cannot be used together
+	if flags.indirect {
+		if retval.indirect {
+			return paramDesc{}, false
+		}
+		retval.indirect = true
+	}
+	retval.flags = &flags
+
+	return retval, true
+}
+
+// parseZshFlags parses the flags found between ${( and )
+func parseZshFlags(input string) (zshFlags, bool) {
+	var retval zshFlags
+
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case 'U':
+			retval.upper = true
+		case 'L':
+			retval.lower = true
+		case 'C':
+			retval.capitalise = true
+		case 'P':
+			retval.indirect = true
+		case 's', 'j':
+			// the separator is wrapped in a delimiter of the user's
+			// choice, e.g. (s:,:) or (j.-.)
+			if i+1 >= len(input) {
+				return zshFlags{}, false
+			}
+			delim := input[i+1]
+			sepEnd := strings.IndexByte(input[i+2:], delim)
+			if sepEnd < 0 {
+				return zshFlags{}, false
+			}
+			sep := input[i+2 : i+2+sepEnd]
+
+			if input[i] == 's' {
+				retval.split = &sep
+			} else {
+				retval.join = &sep
+			}
+			i += sepEnd + 2
+		default:
+			return zshFlags{}, false
+		}
+	}
+
+	return retval, true
+}
+
+// apply uses the flags to turn the expanded values into a single string
+func (f *zshFlags) apply(values []string) string {
+	if f.split != nil {
+		var words []string
+		for _, value := range values {
+			for _, word := range strings.Split(value, *f.split) {
+				// zsh drops empty words when splitting
+				if len(word) > 0 {
+					words = append(words, word)
+				}
+			}
+		}
+		values = words
+	}
+
+	for i := range values {
+		switch {
+		case f.upper:
+			values[i] = strings.ToUpper(values[i])
+		case f.lower:
+			values[i] = strings.ToLower(values[i])
+		case f.capitalise:
+			values[i] = capitaliseWords(values[i])
+		}
+	}
+
+	sep := " "
+	if f.join != nil {
+		sep = *f.join
+	}
+
+	return strings.Join(values, sep)
+}
+
+// capitaliseWords converts the first letter of each word to uppercase,
+// and the rest to lowercase, just like zsh's (C) flag
+func capitaliseWords(input string) string {
+	var buf strings.Builder
+
+	inWord := false
+	for i, w := 0, 0; i < len(input); i += w {
+		var c rune
+		c, w = utf8.DecodeRuneInString(input[i:])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			// invalid UTF-8 must come through untouched
+			inWord = false
+			buf.WriteString(input[i : i+w])
+			continue
+		}
+
+		if inWord {
+			buf.WriteRune(unicode.ToLower(c))
+		} else {
+			buf.WriteRune(unicode.ToUpper(c))
+		}
+		inWord = true
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,152 @@
+// shellexpand is a replacement for Golang's `os.Expand()` that supports
+// UNIX shell string expansion and substituation
+//
+// Copyright 2019-present Ganbaro Digital Ltd
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//   * Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer.
+//
+//   * Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in
+//     the documentation and/or other materials provided with the
+//     distribution.
+//
+//   * Neither the names of the copyright holders nor the names of his
+//     contributors may be used to endorse or promote products derived
+//     from this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS
+// FOR A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE
+// COPYRIGHT OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING,
+// BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES;
+// LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+// LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN
+// ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package shellexpand
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestZshExpander(dialect Dialect) *Expander {
+	vars := map[string]string{
+		"PARAM1": "hello World",
+		"PARAM2": "PARAM1",
+		"PARAM3": "a,b,,c",
+		"PARAM5": "h\xffllo wORLD",
+		"$#":     "3",
+		"$1":     "one",
+		"$2":     "two",
+		"$3":     "three",
+	}
+
+	return NewExpander(
+		ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				retval, ok := vars[key]
+				return retval, ok
+			},
+		},
+		WithDialect(dialect),
+	)
+}
+
+func TestZshFlags(t *testing.T) {
+	t.Parallel()
+
+	unit := newTestZshExpander(DialectZsh)
+	testCases := map[string]string{
+		"${(U)PARAM1}":           "HELLO WORLD",
+		"${(L)PARAM1}":           "hello world",
+		"${(C)PARAM1}":           "Hello World",
+		"${(C)PARAM5}":           "H\xffLlo World",
+		"${(P)PARAM2}":           "hello World",
+		"${(PU)PARAM2}":          "HELLO WORLD",
+		"${(s:,:)PARAM3}":        "a b c",
+		"${(s:,:j:-:)PARAM3}":    "a-b-c",
+		"${(j.,.)@}":             "one,two,three",
+		"${(U)PARAM4:-default}":  "DEFAULT",
+		"${(L)PARAM1#hello }":    "world",
+		"${(j::)*}":              "onetwothree",
+		"${(X)PARAM1} ${PARAM1}": "${(X)PARAM1} hello World",
+	}
+
+	for testData, expectedResult := range testCases {
+		// ----------------------------------------------------------------
+		// setup your test
+
+		// ----------------------------------------------------------------
+		// perform the change
+
+		actualResult, err := unit.Expand(testData)
+
+		// ----------------------------------------------------------------
+		// test the results
+
+		assert.Nil(t, err, testData)
+		assert.Equal(t, expectedResult, actualResult, testData)
+	}
+}
+
+func TestZshFlagsAreIgnoredByOtherDialects(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := newTestZshExpander(DialectBash)
+	testData := "${(U)PARAM1}"
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	actualResult, err := unit.Expand(testData)
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Nil(t, err)
+	assert.Equal(t, testData, actualResult)
+}
+
+func TestZshFlagsAreRejectedInStrictMode(t *testing.T) {
+	t.Parallel()
+
+	// ----------------------------------------------------------------
+	// setup your test
+
+	unit := NewExpander(
+		ExpansionCallbacks{
+			LookupVar: func(key string) (string, bool) {
+				return "", false
+			},
+		},
+		WithDialect(DialectZsh),
+		WithStrict(),
+	)
+
+	// ----------------------------------------------------------------
+	// perform the change
+
+	_, err := unit.Expand("${(X)PARAM1}")
+
+	// ----------------------------------------------------------------
+	// test the results
+
+	assert.Error(t, err)
+	assert.True(t, strings.HasSuffix(err.Error(), "bad substitution"))
+}